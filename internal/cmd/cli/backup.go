@@ -0,0 +1,94 @@
+package cli
+
+import (
+	"os"
+
+	"github.com/ybalcin/event-schema-manager/pkg/backup"
+)
+
+type backupView struct {
+	File     string `json:"file"`
+	Subjects int    `json:"subjects"`
+	Versions int    `json:"versions"`
+}
+
+func (s *session) backup(args []string) error {
+	fs := s.flagSet()
+	file := fs.String("file", "", "archive file")
+	if _, err := s.parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+	if *file == "" {
+		return newUsageError("-file is required")
+	}
+
+	client, err := s.client()
+	if err != nil {
+		return err
+	}
+	a, err := backup.Export(client)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(*file)
+	if err != nil {
+		return err
+	}
+	if err = a.Write(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+
+	view := backupView{File: *file, Subjects: len(a.Subjects), Versions: a.Versions()}
+	return s.print(view, func(t *tableWriter) {
+		t.row("FILE", "SUBJECTS", "VERSIONS")
+		t.row(view.File, view.Subjects, view.Versions)
+	})
+}
+
+// restore imports an archive, the mismatches found by the verification are printed before the error is returned
+func (s *session) restore(args []string) error {
+	fs := s.flagSet()
+	file := fs.String("file", "", "archive file")
+	if _, err := s.parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+	if *file == "" {
+		return newUsageError("-file is required")
+	}
+
+	f, err := os.Open(*file)
+	if err != nil {
+		return err
+	}
+	a, err := backup.ReadArchive(f)
+	f.Close()
+	if err != nil {
+		return err
+	}
+
+	client, err := s.client()
+	if err != nil {
+		return err
+	}
+	result, err := backup.Restore(client, a)
+	if result == nil {
+		return err
+	}
+
+	if printErr := s.print(result, func(t *tableWriter) {
+		t.row("SUBJECTS", "VERSIONS", "MISMATCHES")
+		t.row(result.Subjects, result.Versions, len(result.Mismatches))
+		for _, m := range result.Mismatches {
+			t.row("  - " + m.String())
+		}
+	}); printErr != nil {
+		return printErr
+	}
+
+	return err
+}
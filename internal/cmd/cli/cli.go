@@ -0,0 +1,140 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/ybalcin/event-schema-manager/pkg/schemaregistry"
+)
+
+type (
+	// CLI runs the commands of the esm binary
+	CLI struct {
+		Stdout io.Writer
+		Stderr io.Writer
+		// Getenv returns the environment variables, os.Getenv in the binary
+		Getenv func(key string) string
+		// NewClient creates the registry client of the commands, schemaregistry.NewClient in the binary
+		NewClient func(baseUrl string, opts ...schemaregistry.Option) (schemaregistry.Client, error)
+	}
+
+	command struct {
+		usage   string
+		summary string
+		run     func(s *session, args []string) error
+	}
+
+	// usageError is a wrong invocation, it is reported with the usage of the command
+	usageError struct {
+		msg string
+	}
+)
+
+// exit codes of the commands
+const (
+	ExitOK           = 0
+	ExitError        = 1
+	ExitUsage        = 2
+	ExitIncompatible = 3
+	ExitNotFound     = 4
+)
+
+var (
+	// errIncompatible is returned by commands whose schema is not compatible, the result is printed already
+	errIncompatible = errors.New("schema is not compatible")
+
+	commands = map[string]command{
+		"subjects": {"subjects [-prefix prefix]", "list subjects", (*session).subjects},
+		"versions": {"versions <subject>", "list the versions of a subject", (*session).versions},
+		"get":      {"get <subject> [-version latest] | get -id <id>", "get a schema by version or id", (*session).get},
+		"register": {"register <subject> -file <path> [-type AVRO]", "register a schema", (*session).register},
+		"check":    {"check <subject> -file <path> [-version latest]", "check the compatibility of a schema", (*session).check},
+		"delete":   {"delete <subject> [-version <version>]", "delete a subject or a version", (*session).delete},
+		"diff":     {"diff <subject> -file <path> [-version latest] | diff <subject> <version> <version> [-markdown]", "show the structural changes between a schema and a version or two versions", (*session).diff},
+		"export":   {"export -dir <dir> [-prefix prefix]", "write the latest schema of every subject to a directory", (*session).export},
+		"import":   {"import -dir <dir> [-dry-run]", "register the schema files of a directory", (*session).importDir},
+		"config":   {"config [subject] [-set LEVEL]", "get or set the compatibility level", (*session).config},
+		"backup":   {"backup -file <path>", "write every version of every subject to an archive", (*session).backup},
+		"restore":  {"restore -file <path>", "import an archive with its ids and verify the registry", (*session).restore},
+		"mirror":   {"mirror -to <url> [-include globs] [-exclude globs] [-preserve-ids] [-watch]", "copy new versions to another registry", (*session).mirror},
+	}
+)
+
+func newUsageError(format string, args ...interface{}) error {
+	return &usageError{msg: fmt.Sprintf(format, args...)}
+}
+
+func (err *usageError) Error() string {
+	return err.msg
+}
+
+// Run runs the command of args and returns its exit code
+func (c *CLI) Run(args []string) int {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		c.usage()
+		if len(args) == 0 {
+			return ExitUsage
+		}
+		return ExitOK
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(c.Stderr, "esm: unknown command %q\n\n", args[0])
+		c.usage()
+		return ExitUsage
+	}
+
+	s := &session{cli: c, name: args[0]}
+	err := cmd.run(s, args[1:])
+
+	var usageErr *usageError
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.Is(err, flag.ErrHelp):
+		return ExitOK
+	case errors.As(err, &usageErr):
+		fmt.Fprintf(c.Stderr, "esm %s: %v\nusage: esm %s\n", args[0], err, cmd.usage)
+		return ExitUsage
+	case errors.Is(err, errIncompatible):
+		return ExitIncompatible
+	case schemaregistry.IsIncompatibleSchema(err):
+		fmt.Fprintf(c.Stderr, "esm %s: %v\n", args[0], err)
+		return ExitIncompatible
+	case schemaregistry.IsSubjectNotFound(err) || schemaregistry.IsVersionNotFound(err) || schemaregistry.IsSchemaNotFound(err):
+		fmt.Fprintf(c.Stderr, "esm %s: %v\n", args[0], err)
+		return ExitNotFound
+	default:
+		fmt.Fprintf(c.Stderr, "esm %s: %v\n", args[0], err)
+		return ExitError
+	}
+}
+
+func (c *CLI) usage() {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString("usage: esm <command> [flags]\n\ncommands:\n")
+	for _, name := range names {
+		fmt.Fprintf(&b, "  %-10s %s\n", name, commands[name].summary)
+	}
+	b.WriteString(`
+flags of every command:
+  -url, -username, -password, -token  registry url and credentials
+  -profile, -profiles                 profile name and profile file
+  -o                                  output format: table, json or yaml
+
+exit codes: 0 ok, 1 error, 2 usage, 3 incompatible schema, 4 not found
+`)
+
+	fmt.Fprint(c.Stderr, b.String())
+}
@@ -0,0 +1,285 @@
+package cli
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ybalcin/event-schema-manager/pkg/schemaregistry"
+	"github.com/ybalcin/event-schema-manager/pkg/schemaregistry/schemaregistrytest"
+)
+
+const (
+	userSchema = `{"type":"record","name":"User","fields":[{"name":"id","type":"string"}]}`
+)
+
+type testCLI struct {
+	*CLI
+	registry *schemaregistrytest.Registry
+	// others are the registries of other urls than the one of registry
+	others map[string]*schemaregistrytest.Registry
+	stdout *bytes.Buffer
+	stderr *bytes.Buffer
+	url    string
+	opts   []schemaregistry.Option
+}
+
+func mustEqual(t *testing.T, actual, expected interface{}) {
+	t.Helper()
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("expected %#v, got %#v", expected, actual)
+	}
+}
+
+func newTestCLI(t *testing.T, env map[string]string) *testCLI {
+	tc := &testCLI{
+		registry: schemaregistrytest.NewRegistry(),
+		others:   make(map[string]*schemaregistrytest.Registry),
+		stdout:   &bytes.Buffer{},
+		stderr:   &bytes.Buffer{},
+	}
+	if env == nil {
+		env = map[string]string{}
+	}
+	if _, ok := env[envProfiles]; !ok {
+		env[envProfiles] = filepath.Join(t.TempDir(), "profiles.json")
+	}
+
+	tc.CLI = &CLI{
+		Stdout: tc.stdout,
+		Stderr: tc.stderr,
+		Getenv: func(key string) string { return env[key] },
+		NewClient: func(baseUrl string, opts ...schemaregistry.Option) (schemaregistry.Client, error) {
+			tc.url, tc.opts = baseUrl, opts
+			if r, ok := tc.others[baseUrl]; ok {
+				return r, nil
+			}
+			return tc.registry, nil
+		},
+	}
+
+	return tc
+}
+
+func (tc *testCLI) run(args ...string) int {
+	tc.stdout.Reset()
+	tc.stderr.Reset()
+	return tc.Run(args)
+}
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestCLI_Run_Usage(t *testing.T) {
+	tc := newTestCLI(t, nil)
+
+	mustEqual(t, tc.run(), ExitUsage)
+	mustEqual(t, tc.run("unknown"), ExitUsage)
+	mustEqual(t, tc.run("versions"), ExitUsage)
+	mustEqual(t, tc.run("subjects", "-o", "xml"), ExitUsage)
+	mustEqual(t, tc.run("help"), ExitOK)
+	mustEqual(t, strings.Contains(tc.stderr.String(), "register"), true)
+}
+
+func TestCLI_Run_RegisterAndGet(t *testing.T) {
+	tc := newTestCLI(t, nil)
+	file := writeFile(t, t.TempDir(), "user.avsc", userSchema)
+
+	mustEqual(t, tc.run("register", "users-value", "-file", file, "-o", "json"), ExitOK)
+	mustEqual(t, tc.stdout.String(), "{\n  \"subject\": \"users-value\",\n  \"id\": 1\n}\n")
+
+	mustEqual(t, tc.run("subjects", "-o", "yaml"), ExitOK)
+	mustEqual(t, tc.stdout.String(), "- users-value\n")
+
+	mustEqual(t, tc.run("get", "users-value", "-o", "yaml"), ExitOK)
+	mustEqual(t, strings.HasPrefix(tc.stdout.String(), "subject: users-value\nversion: 1\nid: 1\nschemaType: AVRO\n"), true)
+
+	mustEqual(t, tc.run("get", "-id", "1"), ExitOK)
+	mustEqual(t, strings.HasPrefix(tc.stdout.String(), "SUBJECT   VERSION   ID   TYPE\n-         0         1    AVRO\n"), true)
+
+	mustEqual(t, tc.run("get", "orders-value"), ExitNotFound)
+	mustEqual(t, tc.run("get", "users-value", "-version", "2"), ExitNotFound)
+}
+
+func TestCLI_Run_Check(t *testing.T) {
+	tc := newTestCLI(t, nil)
+	dir := t.TempDir()
+	file := writeFile(t, dir, "user.avsc", userSchema)
+	mustEqual(t, tc.run("register", "users-value", "-file", file), ExitOK)
+
+	mustEqual(t, tc.run("check", "users-value", "-file", file, "-o", "json"), ExitOK)
+
+	tc.registry.Incompatible = func(subject, schema string, versions []schemaregistry.Schema) []string {
+		return []string{"field id removed"}
+	}
+	mustEqual(t, tc.run("check", "-file", file, "users-value", "-o", "json"), ExitIncompatible)
+	mustEqual(t, tc.stdout.String(), `{
+  "subject": "users-value",
+  "version": "latest",
+  "compatible": false,
+  "reasons": [
+    "field id removed"
+  ]
+}
+`)
+}
+
+func TestCLI_Run_Options(t *testing.T) {
+	dir := t.TempDir()
+	profiles := writeFile(t, dir, "profiles.json", `{
+		"current": "dev",
+		"profiles": {
+			"dev": {"url": "http://dev:8081", "username": "dev", "password": "secret", "output": "json"},
+			"prod": {"url": "http://prod:8081", "token": "token"}
+		}
+	}`)
+
+	tc := newTestCLI(t, map[string]string{envProfiles: profiles})
+	mustEqual(t, tc.run("subjects"), ExitOK)
+	mustEqual(t, tc.url, "http://dev:8081")
+	mustEqual(t, len(tc.opts), 1)
+	mustEqual(t, tc.stdout.String(), "[]\n")
+
+	tc = newTestCLI(t, map[string]string{envProfiles: profiles, envProfile: "prod", envURL: "http://env:8081"})
+	mustEqual(t, tc.run("subjects"), ExitOK)
+	mustEqual(t, tc.url, "http://env:8081")
+	mustEqual(t, tc.stdout.String(), "SUBJECT\n")
+
+	mustEqual(t, tc.run("subjects", "-url", "http://flag:8081", "-profile", "dev"), ExitOK)
+	mustEqual(t, tc.url, "http://flag:8081")
+
+	tc = newTestCLI(t, nil)
+	mustEqual(t, tc.run("subjects"), ExitOK)
+	mustEqual(t, tc.url, defaultURL)
+	mustEqual(t, len(tc.opts), 0)
+
+	mustEqual(t, tc.run("subjects", "-profile", "missing"), ExitError)
+}
+
+func TestCLI_Run_ExportImport(t *testing.T) {
+	tc := newTestCLI(t, nil)
+	dir := t.TempDir()
+	mustEqual(t, tc.run("register", "users-value", "-file", writeFile(t, dir, "user.avsc", userSchema)), ExitOK)
+	mustEqual(t, tc.run("register", "orders-value", "-file", writeFile(t, dir, "order.json", `{"type":"object"}`)), ExitOK)
+	if _, err := tc.registry.RegisterNewSchema("team/orders-value", userSchema); err != nil {
+		t.Fatal(err)
+	}
+
+	// subjects which can not be file names are skipped
+	exported := t.TempDir()
+	mustEqual(t, tc.run("export", "-dir", exported, "-o", "json"), ExitOK)
+	mustEqual(t, tc.stderr.String(), "esm export: skipping subject team/orders-value, it can not be a file name\n")
+	b, err := ioutil.ReadFile(filepath.Join(exported, "orders-value.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustEqual(t, string(b), "{\n  \"type\": \"object\"\n}\n")
+
+	target := newTestCLI(t, nil)
+	mustEqual(t, target.run("import", "-dir", exported, "-dry-run"), ExitOK)
+	subjects, _ := target.registry.Subjects()
+	mustEqual(t, len(subjects), 0)
+
+	mustEqual(t, target.run("import", "-dir", exported), ExitOK)
+	subjects, _ = target.registry.Subjects()
+	mustEqual(t, subjects, []string{"orders-value", "users-value"})
+
+	sc, err := target.registry.GetLatestSchema("orders-value")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustEqual(t, sc.SchemaType, "JSON")
+}
+
+func TestCLI_Run_DiffDeleteConfig(t *testing.T) {
+	tc := newTestCLI(t, nil)
+	dir := t.TempDir()
+	mustEqual(t, tc.run("register", "users-value", "-file", writeFile(t, dir, "v1.avsc",
+		`{"type":"record","name":"User","fields":[{"name":"id","type":"int"}]}`)), ExitOK)
+	mustEqual(t, tc.run("register", "users-value", "-file", writeFile(t, dir, "v2.avsc",
+		`{"type":"record","name":"User","fields":[{"name":"id","type":"long"},{"name":"email","type":"string"}]}`)), ExitOK)
+
+	mustEqual(t, tc.run("diff", "users-value", "1", "2"), ExitOK)
+	mustEqual(t, tc.stdout.String(), "~ $.id: type changed from int to long\n+ $.email: field added with type string\n")
+	mustEqual(t, tc.run("diff", "users-value", "1", "2", "-markdown"), ExitOK)
+	mustEqual(t, strings.Contains(tc.stdout.String(), "| field-added | `$.email` |  | `string` |\n"), true)
+	mustEqual(t, tc.run("diff", "users-value", "-file", filepath.Join(dir, "v1.avsc"), "-o", "json"), ExitOK)
+	mustEqual(t, strings.Contains(tc.stdout.String(), `"kind": "field-removed"`), true)
+
+	mustEqual(t, tc.run("register", "events-value", "-file", writeFile(t, dir, "v1.json", "{\n\"a\": 1\n}")), ExitOK)
+	mustEqual(t, tc.run("diff", "events-value", "-file", writeFile(t, dir, "v2.json", "{\n\"a\": 2\n}")), ExitOK)
+	mustEqual(t, tc.stdout.String(), "-  \"a\": 1\n+  \"a\": 2\n")
+	mustEqual(t, tc.run("diff", "users-value", "1"), ExitUsage)
+
+	mustEqual(t, tc.run("config", "users-value", "-set", "full", "-o", "json"), ExitOK)
+	mustEqual(t, tc.stdout.String(), "{\n  \"subject\": \"users-value\",\n  \"compatibilityLevel\": \"FULL\"\n}\n")
+
+	mustEqual(t, tc.run("delete", "users-value", "-version", "1"), ExitOK)
+	versions, _ := tc.registry.Versions("users-value")
+	mustEqual(t, versions, []int{2})
+
+	mustEqual(t, tc.run("delete", "users-value"), ExitOK)
+	mustEqual(t, tc.run("versions", "users-value"), ExitNotFound)
+}
+
+func TestCLI_Run_BackupRestore(t *testing.T) {
+	tc := newTestCLI(t, nil)
+	dir := t.TempDir()
+	mustEqual(t, tc.run("register", "users-value", "-file", writeFile(t, dir, "v1.avsc", `{"type":"string"}`)), ExitOK)
+	mustEqual(t, tc.run("register", "users-value", "-file", writeFile(t, dir, "v2.avsc", `{"type":"int"}`)), ExitOK)
+
+	archive := filepath.Join(dir, "backup.json")
+	mustEqual(t, tc.run("backup", "-file", archive, "-o", "json"), ExitOK)
+	mustEqual(t, tc.stdout.String(), "{\n  \"file\": \""+archive+"\",\n  \"subjects\": 1,\n  \"versions\": 2\n}\n")
+
+	target := newTestCLI(t, nil)
+	mustEqual(t, target.run("restore", "-file", archive), ExitOK)
+	mustEqual(t, target.stdout.String(), "SUBJECTS   VERSIONS   MISMATCHES\n1          2          0\n")
+
+	sc, err := target.registry.GetSchemaByVersion("users-value", "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustEqual(t, sc.ID, 2)
+
+	mustEqual(t, target.run("restore", "-file", filepath.Join(dir, "v1.avsc")), ExitError)
+}
+
+func TestCLI_Run_Mirror(t *testing.T) {
+	tc := newTestCLI(t, nil)
+	dir := t.TempDir()
+	mustEqual(t, tc.run("register", "users-value", "-file", writeFile(t, dir, "users.avsc", `"string"`)), ExitOK)
+	mustEqual(t, tc.run("register", "orders-value", "-file", writeFile(t, dir, "orders.avsc", `"int"`)), ExitOK)
+
+	target := schemaregistrytest.NewRegistry()
+	tc.others["http://target:8081"] = target
+
+	checkpoints := filepath.Join(dir, "checkpoints.json")
+	mustEqual(t, tc.run("mirror", "-to", "http://target:8081", "-to-token", "secret", "-exclude", "orders-*",
+		"-preserve-ids", "-checkpoints", checkpoints), ExitOK)
+	mustEqual(t, tc.stdout.String(), "SUBJECT       VERSION   SOURCE ID   TARGET ID   CONFLICT\nusers-value   1         1           1           -\n")
+	mustEqual(t, len(tc.opts), 1)
+
+	subjects, _ := target.Subjects()
+	mustEqual(t, subjects, []string{"users-value"})
+
+	_, err := target.ImportSchema("orders-value", schemaregistry.Schema{Schema: `"long"`, ID: 5, Version: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustEqual(t, tc.run("mirror", "-to", "http://target:8081", "-preserve-ids", "-checkpoints", checkpoints), ExitError)
+	mustEqual(t, strings.Contains(tc.stdout.String(), "orders-value   1         -           -           diverged-version"), true)
+
+	mustEqual(t, tc.run("mirror"), ExitUsage)
+	mustEqual(t, tc.run("mirror", "-to", "http://target:8081", "-include", "["), ExitUsage)
+}
@@ -0,0 +1,522 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ybalcin/event-schema-manager/pkg/gitops"
+	"github.com/ybalcin/event-schema-manager/pkg/schemadiff"
+	"github.com/ybalcin/event-schema-manager/pkg/schemaregistry"
+)
+
+type (
+	schemaView struct {
+		Subject    string `json:"subject,omitempty"`
+		Version    int    `json:"version,omitempty"`
+		ID         int    `json:"id"`
+		SchemaType string `json:"schemaType"`
+		Schema     string `json:"schema"`
+	}
+
+	compatibilityView struct {
+		Subject    string   `json:"subject"`
+		Version    string   `json:"version"`
+		Compatible bool     `json:"compatible"`
+		Reasons    []string `json:"reasons,omitempty"`
+	}
+
+	configView struct {
+		Subject            string `json:"subject,omitempty"`
+		CompatibilityLevel string `json:"compatibilityLevel"`
+	}
+
+	exportView struct {
+		Subject string `json:"subject"`
+		Version int    `json:"version"`
+		File    string `json:"file"`
+	}
+)
+
+// extensions are the file extensions of the schema types, the registry omits the type of avro schemas
+var extensions = map[string]string{
+	"":                        ".avsc",
+	gitops.SchemaTypeAvro:     ".avsc",
+	gitops.SchemaTypeJSON:     ".json",
+	gitops.SchemaTypeProtobuf: ".proto",
+}
+
+func newSchemaView(sc *schemaregistry.Schema) schemaView {
+	schemaType := sc.SchemaType
+	if schemaType == "" {
+		schemaType = gitops.SchemaTypeAvro
+	}
+
+	return schemaView{Subject: sc.Subject, Version: sc.Version, ID: sc.ID, SchemaType: schemaType, Schema: sc.Schema}
+}
+
+func (s *session) subjects(args []string) error {
+	fs := s.flagSet()
+	prefix := fs.String("prefix", "", "list only subjects starting with prefix")
+	if _, err := s.parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+
+	client, err := s.client()
+	if err != nil {
+		return err
+	}
+	all, err := client.Subjects()
+	if err != nil {
+		return err
+	}
+
+	subjects := []string{}
+	for _, subject := range all {
+		if strings.HasPrefix(subject, *prefix) {
+			subjects = append(subjects, subject)
+		}
+	}
+
+	return s.print(subjects, func(t *tableWriter) {
+		t.row("SUBJECT")
+		for _, subject := range subjects {
+			t.row(subject)
+		}
+	})
+}
+
+func (s *session) versions(args []string) error {
+	fs := s.flagSet()
+	positional, err := s.parse(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	client, err := s.client()
+	if err != nil {
+		return err
+	}
+	versions, err := client.Versions(positional[0])
+	if err != nil {
+		return err
+	}
+
+	return s.print(versions, func(t *tableWriter) {
+		t.row("VERSION")
+		for _, v := range versions {
+			t.row(v)
+		}
+	})
+}
+
+func (s *session) get(args []string) error {
+	fs := s.flagSet()
+	version := fs.String("version", schemaregistry.SchemaLatestVersion, "version number or latest")
+	id := fs.Int("id", 0, "schema id, instead of a subject and version")
+	positional, err := s.parse(fs, args, 0, 1)
+	if err != nil {
+		return err
+	}
+	if (*id == 0) == (len(positional) == 0) {
+		return newUsageError("a subject or an id is required")
+	}
+
+	client, err := s.client()
+	if err != nil {
+		return err
+	}
+
+	var sc *schemaregistry.Schema
+	if *id != 0 {
+		sc, err = client.GetSchema(*id)
+	} else {
+		sc, err = client.GetSchemaByVersion(positional[0], *version)
+	}
+	if err != nil {
+		return err
+	}
+
+	view := newSchemaView(sc)
+	return s.print(view, func(t *tableWriter) {
+		t.row("SUBJECT", "VERSION", "ID", "TYPE")
+		t.row(firstOf(view.Subject, "-"), view.Version, view.ID, view.SchemaType)
+		t.row()
+		t.row(indentJSON(view.Schema))
+	})
+}
+
+func (s *session) register(args []string) error {
+	fs := s.flagSet()
+	file := fs.String("file", "", "schema file")
+	schemaType := fs.String("type", "", "schema type, taken from the file extension if it is empty")
+	positional, err := s.parse(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	sc, err := readSchemaFile(*file, *schemaType)
+	if err != nil {
+		return err
+	}
+
+	client, err := s.client()
+	if err != nil {
+		return err
+	}
+	id, err := client.RegisterSchema(positional[0], sc)
+	if err != nil {
+		return err
+	}
+
+	view := struct {
+		Subject string `json:"subject"`
+		ID      int    `json:"id"`
+	}{positional[0], id}
+	return s.print(view, func(t *tableWriter) {
+		t.row("SUBJECT", "ID")
+		t.row(view.Subject, view.ID)
+	})
+}
+
+// check prints the compatibility of a schema file and returns errIncompatible if it is not compatible
+func (s *session) check(args []string) error {
+	fs := s.flagSet()
+	file := fs.String("file", "", "schema file")
+	version := fs.String("version", schemaregistry.SchemaLatestVersion, "version number or latest")
+	positional, err := s.parse(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	sc, err := readSchemaFile(*file, "")
+	if err != nil {
+		return err
+	}
+
+	client, err := s.client()
+	if err != nil {
+		return err
+	}
+	compatible, reasons, err := client.CheckSchemaCompatibility(positional[0], sc, *version)
+	if err != nil {
+		return err
+	}
+
+	view := compatibilityView{Subject: positional[0], Version: *version, Compatible: compatible, Reasons: reasons}
+	err = s.print(view, func(t *tableWriter) {
+		t.row("SUBJECT", "VERSION", "COMPATIBLE")
+		t.row(view.Subject, view.Version, view.Compatible)
+		for _, r := range view.Reasons {
+			t.row("  - " + r)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	if !compatible {
+		return errIncompatible
+	}
+	return nil
+}
+
+func (s *session) delete(args []string) error {
+	fs := s.flagSet()
+	version := fs.String("version", "", "version number or latest, the subject is deleted if it is empty")
+	positional, err := s.parse(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	client, err := s.client()
+	if err != nil {
+		return err
+	}
+
+	var deleted []int
+	if *version == "" {
+		if deleted, err = client.DeleteSubject(positional[0]); err != nil {
+			return err
+		}
+	} else {
+		v, err := client.DeleteSchemaVersion(positional[0], *version)
+		if err != nil {
+			return err
+		}
+		deleted = []int{v}
+	}
+
+	view := struct {
+		Subject  string `json:"subject"`
+		Versions []int  `json:"versions"`
+	}{positional[0], deleted}
+	return s.print(view, func(t *tableWriter) {
+		versions := make([]string, len(view.Versions))
+		for i, v := range view.Versions {
+			versions[i] = strconv.Itoa(v)
+		}
+		t.row("SUBJECT", "DELETED VERSIONS")
+		t.row(view.Subject, strings.Join(versions, ", "))
+	})
+}
+
+// diff prints the structural changes from the first schema to the second, schema types without a structural
+// diff are compared line by line
+func (s *session) diff(args []string) error {
+	fs := s.flagSet()
+	file := fs.String("file", "", "schema file compared with -version")
+	version := fs.String("version", schemaregistry.SchemaLatestVersion, "version the file is compared with")
+	markdown := fs.Bool("markdown", false, "print the changes as a markdown table instead of text")
+	positional, err := s.parse(fs, args, 1, 3)
+	if err != nil {
+		return err
+	}
+	if (*file == "") == (len(positional) == 1) || len(positional) == 2 {
+		return newUsageError("a file or two versions are required")
+	}
+
+	client, err := s.client()
+	if err != nil {
+		return err
+	}
+
+	var previous, current schemaregistry.Schema
+	if *file != "" {
+		if current, err = readSchemaFile(*file, ""); err != nil {
+			return err
+		}
+		registered, err := client.GetSchemaByVersion(positional[0], *version)
+		if err != nil {
+			return err
+		}
+		previous = *registered
+	} else {
+		for i, v := range positional[1:] {
+			sc, err := client.GetSchemaByVersion(positional[0], v)
+			if err != nil {
+				return err
+			}
+			if i == 0 {
+				previous = *sc
+			} else {
+				current = *sc
+			}
+		}
+	}
+
+	diff, err := schemadiff.Compare(previous.SchemaType, previous.Schema, current.Schema)
+	if errors.Is(err, schemadiff.ErrUnsupportedSchemaType) || previous.SchemaType != current.SchemaType {
+		return s.printLineDiff(positional[0], schemadiff.LineDiff(previous.Schema, current.Schema))
+	}
+	if err != nil {
+		return err
+	}
+
+	format := schemadiff.FormatText
+	if *markdown {
+		format = schemadiff.FormatMarkdown
+	}
+	return s.print(diff, func(t *tableWriter) {
+		_ = diff.Write(s.cli.Stdout, format)
+	})
+}
+
+// printLineDiff prints the lines removed with - and the lines added with +
+func (s *session) printLineDiff(subject, diff string) error {
+	view := struct {
+		Subject string `json:"subject"`
+		Diff    string `json:"diff"`
+	}{subject, diff}
+	return s.print(view, func(t *tableWriter) {
+		if diff != "" {
+			fmt.Fprintln(s.cli.Stdout, diff)
+		}
+	})
+}
+
+// export writes the latest schema of every subject as <subject>.<ext>, the files can be imported or synced back.
+// Subjects which can not be file names are skipped with a warning since their files could not be mapped back.
+func (s *session) export(args []string) error {
+	fs := s.flagSet()
+	dir := fs.String("dir", "", "directory the schema files are written to")
+	prefix := fs.String("prefix", "", "export only subjects starting with prefix")
+	if _, err := s.parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+	if *dir == "" {
+		return newUsageError("-dir is required")
+	}
+
+	client, err := s.client()
+	if err != nil {
+		return err
+	}
+	subjects, err := client.Subjects()
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(*dir, 0o755); err != nil {
+		return err
+	}
+
+	exported := []exportView{}
+	for _, subject := range subjects {
+		if !strings.HasPrefix(subject, *prefix) {
+			continue
+		}
+		if strings.ContainsAny(subject, `/\`) {
+			fmt.Fprintf(s.cli.Stderr, "esm export: skipping subject %s, it can not be a file name\n", subject)
+			continue
+		}
+
+		sc, err := client.GetLatestSchema(subject)
+		if err != nil {
+			return err
+		}
+
+		name := subject + extensions[sc.SchemaType]
+		if err = ioutil.WriteFile(filepath.Join(*dir, name), []byte(indentJSON(sc.Schema)+"\n"), 0o644); err != nil {
+			return err
+		}
+		exported = append(exported, exportView{Subject: subject, Version: sc.Version, File: name})
+	}
+
+	return s.print(exported, func(t *tableWriter) {
+		t.row("SUBJECT", "VERSION", "FILE")
+		for _, e := range exported {
+			t.row(e.Subject, e.Version, e.File)
+		}
+	})
+}
+
+// importDir registers the schema files of a directory, mapped to subjects like the gitops sync maps them
+func (s *session) importDir(args []string) error {
+	fs := s.flagSet()
+	dir := fs.String("dir", "", "directory of the schema files")
+	dryRun := fs.Bool("dry-run", false, "print the plan without registering")
+	if _, err := s.parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+	if *dir == "" {
+		return newUsageError("-dir is required")
+	}
+
+	files, err := gitops.LoadDir(*dir)
+	if err != nil {
+		return err
+	}
+
+	client, err := s.client()
+	if err != nil {
+		return err
+	}
+
+	syncer := gitops.NewSyncer(client)
+	plan, err := syncer.Plan(files)
+	if err != nil {
+		return err
+	}
+
+	// subjects that are not imported are not orphans of an import
+	var changes []gitops.Change
+	for _, c := range plan.Changes {
+		if c.Action != gitops.ActionOrphaned {
+			changes = append(changes, c)
+		}
+	}
+	plan.Changes = changes
+
+	if err = plan.Write(s.cli.Stdout); err != nil {
+		return err
+	}
+	if plan.Count(gitops.ActionIncompatible) > 0 {
+		return errIncompatible
+	}
+	if *dryRun || !plan.HasChanges() {
+		return nil
+	}
+
+	results, err := syncer.Apply(plan)
+	for _, r := range results {
+		fmt.Fprintln(s.cli.Stdout, r)
+	}
+
+	return err
+}
+
+func (s *session) config(args []string) error {
+	fs := s.flagSet()
+	level := fs.String("set", "", "compatibility level to set")
+	positional, err := s.parse(fs, args, 0, 1)
+	if err != nil {
+		return err
+	}
+
+	subject := ""
+	if len(positional) == 1 {
+		subject = positional[0]
+	}
+
+	client, err := s.client()
+	if err != nil {
+		return err
+	}
+
+	view := configView{Subject: subject}
+	if *level != "" {
+		view.CompatibilityLevel, err = client.SetCompatibilityLevel(subject, strings.ToUpper(*level))
+	} else {
+		view.CompatibilityLevel, err = client.GetCompatibilityLevel(subject)
+	}
+	if err != nil {
+		return err
+	}
+
+	return s.print(view, func(t *tableWriter) {
+		t.row("SUBJECT", "COMPATIBILITY")
+		t.row(firstOf(view.Subject, "(global)"), view.CompatibilityLevel)
+	})
+}
+
+// readSchemaFile reads a schema file, the schema type is taken from the extension if it is empty
+func readSchemaFile(path, schemaType string) (schemaregistry.Schema, error) {
+	if path == "" {
+		return schemaregistry.Schema{}, newUsageError("-file is required")
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return schemaregistry.Schema{}, err
+	}
+
+	if schemaType == "" {
+		for t, ext := range extensions {
+			if t != "" && strings.EqualFold(filepath.Ext(path), ext) {
+				schemaType = t
+			}
+		}
+	}
+
+	sc := schemaregistry.Schema{Schema: strings.TrimSpace(string(b))}
+	// the registry defaults to avro
+	if schemaType != "" && schemaType != gitops.SchemaTypeAvro {
+		sc.SchemaType = strings.ToUpper(schemaType)
+	}
+
+	return sc, nil
+}
+
+// indentJSON indents json schemas for reading, other schemas are returned as they are
+func indentJSON(schema string) string {
+	var b strings.Builder
+	if err := jsonIndent(&b, schema); err != nil {
+		return schema
+	}
+
+	return b.String()
+}
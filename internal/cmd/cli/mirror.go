@@ -0,0 +1,128 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/ybalcin/event-schema-manager/pkg/mirror"
+	"github.com/ybalcin/event-schema-manager/pkg/schemaregistry"
+)
+
+// mirror copies the versions of the registry to the registry of -to, once or every -interval with -watch
+func (s *session) mirror(args []string) error {
+	fs := s.flagSet()
+	to := fs.String("to", "", "url of the target registry")
+	toUsername := fs.String("to-username", "", "username of the target registry")
+	toPassword := fs.String("to-password", "", "password of the target registry")
+	toToken := fs.String("to-token", "", "bearer token of the target registry")
+	include := fs.String("include", "", "comma separated subject globs to mirror, every subject if it is empty")
+	exclude := fs.String("exclude", "", "comma separated subject globs not to mirror")
+	preserveIDs := fs.Bool("preserve-ids", false, "import versions with their ids, the target is switched to IMPORT mode")
+	watch := fs.Bool("watch", false, "keep mirroring every interval until interrupted")
+	interval := fs.Duration("interval", 30*time.Second, "polling interval of -watch")
+	checkpoints := fs.String("checkpoints", "", "file the last mirrored versions are kept in")
+	metricsAddr := fs.String("metrics", "", "address metrics are served on at /metrics, e.g. :9102")
+	if _, err := s.parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+	if *to == "" {
+		return newUsageError("-to is required")
+	}
+
+	source, err := s.client()
+	if err != nil {
+		return err
+	}
+	var targetOpts []schemaregistry.Option
+	if *toUsername != "" {
+		targetOpts = append(targetOpts, schemaregistry.WithBasicAuth(*toUsername, *toPassword))
+	}
+	if *toToken != "" {
+		targetOpts = append(targetOpts, schemaregistry.WithBearerToken(*toToken))
+	}
+	target, err := s.cli.NewClient(*to, targetOpts...)
+	if err != nil {
+		return err
+	}
+
+	opts := []mirror.Option{
+		mirror.WithFilter(mirror.Filter{Include: splitList(*include), Exclude: splitList(*exclude)}),
+		mirror.WithInterval(*interval),
+	}
+	if *preserveIDs {
+		opts = append(opts, mirror.WithPreservedIDs())
+	}
+	if *checkpoints != "" {
+		opts = append(opts, mirror.WithCheckpoints(mirror.NewFileCheckpointStore(*checkpoints)))
+	}
+	m, err := mirror.New(source, target, opts...)
+	if err != nil {
+		return newUsageError("%v", err)
+	}
+
+	if *metricsAddr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", m.Metrics())
+		server := &http.Server{Addr: *metricsAddr, Handler: mux}
+		go func() {
+			if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				fmt.Fprintf(s.cli.Stderr, "esm mirror: metrics: %v\n", err)
+			}
+		}()
+		defer server.Close()
+	}
+
+	if !*watch {
+		report, err := m.RunOnce()
+		if printErr := s.printReport(report); printErr != nil {
+			return printErr
+		}
+		if err == nil && len(report.Conflicts) > 0 {
+			err = fmt.Errorf("%d conflicts, %d versions are not mirrored", len(report.Conflicts), report.Pending)
+		}
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	m.Run(ctx, func(report *mirror.Report, err error) {
+		if err != nil {
+			fmt.Fprintf(s.cli.Stderr, "esm mirror: %v\n", err)
+		}
+		if printErr := s.printReport(report); printErr != nil {
+			fmt.Fprintf(s.cli.Stderr, "esm mirror: %v\n", printErr)
+		}
+	})
+
+	return nil
+}
+
+func (s *session) printReport(report *mirror.Report) error {
+	return s.print(report, func(t *tableWriter) {
+		t.row("SUBJECT", "VERSION", "SOURCE ID", "TARGET ID", "CONFLICT")
+		for _, m := range report.Mirrored {
+			t.row(m.Subject, m.Version, m.SourceID, m.TargetID, "-")
+		}
+		for _, c := range report.Conflicts {
+			t.row(c.Subject, c.Version, "-", "-", string(c.Kind)+": "+c.Reason)
+		}
+	})
+}
+
+// splitList splits a comma separated list, an empty list has no items
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
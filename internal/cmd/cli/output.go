@@ -0,0 +1,217 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+type (
+	// tableWriter writes tab separated rows aligned in columns
+	tableWriter struct {
+		tw *tabwriter.Writer
+	}
+
+	// yamlField is a key and value of a json object, in the order of the object
+	yamlField struct {
+		key   string
+		value interface{}
+	}
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+func (t *tableWriter) row(columns ...interface{}) {
+	values := make([]string, len(columns))
+	for i, c := range columns {
+		values[i] = fmt.Sprint(c)
+	}
+
+	fmt.Fprintln(t.tw, strings.Join(values, "\t"))
+}
+
+func write(w io.Writer, format string, v interface{}, table func(t *tableWriter)) error {
+	switch format {
+	case outputJSON:
+		b, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s\n", b)
+		return err
+	case outputYAML:
+		return writeYAML(w, v)
+	default:
+		t := &tableWriter{tw: tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)}
+		table(t)
+		return t.tw.Flush()
+	}
+}
+
+// writeYAML writes v as YAML, v is encoded to json first so the json tags and field order are kept
+func writeYAML(w io.Writer, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	node, err := decodeOrdered(dec)
+	if err != nil {
+		return err
+	}
+
+	var buf strings.Builder
+	writeYAMLNode(&buf, node, 0)
+	_, err = io.WriteString(w, buf.String())
+	return err
+}
+
+// decodeOrdered decodes the next json value keeping the order of object fields as []yamlField
+func decodeOrdered(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tok {
+	case json.Delim('{'):
+		fields := []yamlField{}
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeOrdered(dec)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, yamlField{key: key.(string), value: value})
+		}
+		_, err = dec.Token()
+		return fields, err
+	case json.Delim('['):
+		items := []interface{}{}
+		for dec.More() {
+			item, err := decodeOrdered(dec)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		_, err = dec.Token()
+		return items, err
+	default:
+		return tok, nil
+	}
+}
+
+func writeYAMLNode(b *strings.Builder, node interface{}, indent int) {
+	pad := strings.Repeat("  ", indent)
+
+	switch n := node.(type) {
+	case []yamlField:
+		if len(n) == 0 {
+			b.WriteString(pad + "{}\n")
+			return
+		}
+		for _, f := range n {
+			b.WriteString(pad + yamlScalar(f.key) + ":")
+			writeYAMLValue(b, f.value, indent)
+		}
+	case []interface{}:
+		if len(n) == 0 {
+			b.WriteString(pad + "[]\n")
+			return
+		}
+		for _, item := range n {
+			if fields, ok := item.([]yamlField); ok && len(fields) > 0 {
+				// the first field of an object follows the item marker
+				var object strings.Builder
+				writeYAMLNode(&object, fields, indent+1)
+				b.WriteString(pad + "- " + strings.TrimPrefix(object.String(), pad+"  "))
+				continue
+			}
+			b.WriteString(pad + "-")
+			writeYAMLValue(b, item, indent)
+		}
+	default:
+		b.WriteString(pad + yamlScalar(n) + "\n")
+	}
+}
+
+// writeYAMLValue writes the value of a key or an item after its marker
+func writeYAMLValue(b *strings.Builder, value interface{}, indent int) {
+	switch v := value.(type) {
+	case []yamlField:
+		if len(v) == 0 {
+			b.WriteString(" {}\n")
+			return
+		}
+		b.WriteString("\n")
+		writeYAMLNode(b, v, indent+1)
+	case []interface{}:
+		if len(v) == 0 {
+			b.WriteString(" []\n")
+			return
+		}
+		b.WriteString("\n")
+		writeYAMLNode(b, v, indent+1)
+	default:
+		b.WriteString(" " + yamlScalar(v) + "\n")
+	}
+}
+
+func yamlScalar(v interface{}) string {
+	switch s := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(s)
+	case json.Number:
+		return s.String()
+	case string:
+		if needsQuotes(s) {
+			return strconv.Quote(s)
+		}
+		return s
+	default:
+		return fmt.Sprint(s)
+	}
+}
+
+// needsQuotes returns true if s would not be read back as the same string
+func needsQuotes(s string) bool {
+	if s == "" || strings.TrimSpace(s) != s {
+		return true
+	}
+
+	switch strings.ToLower(s) {
+	case "null", "~", "true", "false", "yes", "no", "on", "off":
+		return true
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return true
+	}
+
+	return strings.ContainsAny(s, ":#{}[],&*!|>'\"%@`\n\t") || strings.HasPrefix(s, "-") || strings.HasPrefix(s, "?")
+}
+
+func jsonIndent(w io.Writer, s string) error {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, []byte(s), "", "  "); err != nil {
+		return err
+	}
+
+	_, err := buf.WriteTo(w)
+	return err
+}
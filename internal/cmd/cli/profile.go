@@ -0,0 +1,100 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+type (
+	// Profile is a named registry and its credentials in the profile file
+	Profile struct {
+		URL      string `json:"url"`
+		Username string `json:"username,omitempty"`
+		Password string `json:"password,omitempty"`
+		Token    string `json:"token,omitempty"`
+		Output   string `json:"output,omitempty"`
+	}
+
+	// ProfileFile is the json file of profiles, Current is used when no profile is selected
+	ProfileFile struct {
+		Current  string             `json:"current"`
+		Profiles map[string]Profile `json:"profiles"`
+	}
+
+	// options are the registry and output options resolved from flags, environment variables and the profile,
+	// in that order
+	options struct {
+		Profile
+		profile  string
+		profiles string
+	}
+)
+
+// environment variables of the options
+const (
+	envURL      = "ESM_REGISTRY_URL"
+	envUsername = "ESM_USERNAME"
+	envPassword = "ESM_PASSWORD"
+	envToken    = "ESM_TOKEN"
+	envOutput   = "ESM_OUTPUT"
+	envProfile  = "ESM_PROFILE"
+	envProfiles = "ESM_PROFILES"
+
+	defaultURL    = "http://localhost:8081"
+	defaultOutput = outputTable
+)
+
+// defaultProfilesPath returns ~/.esm/profiles.json
+func defaultProfilesPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(home, ".esm", "profiles.json")
+}
+
+// loadProfile returns the profile named name, the current profile if name is empty. A missing profile file is
+// only an error if a profile is named.
+func loadProfile(path, name string) (Profile, error) {
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) && name == "" {
+		return Profile{}, nil
+	}
+	if err != nil {
+		return Profile{}, err
+	}
+
+	var file ProfileFile
+	if err = json.Unmarshal(b, &file); err != nil {
+		return Profile{}, fmt.Errorf("%s: %w", path, err)
+	}
+
+	if name == "" {
+		name = file.Current
+	}
+	if name == "" {
+		return Profile{}, nil
+	}
+
+	profile, ok := file.Profiles[name]
+	if !ok {
+		return Profile{}, fmt.Errorf("profile %s is not in %s", name, path)
+	}
+
+	return profile, nil
+}
+
+// firstOf returns the first value that is not empty
+func firstOf(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,100 @@
+package cli
+
+import (
+	"flag"
+	"io/ioutil"
+
+	"github.com/ybalcin/event-schema-manager/pkg/schemaregistry"
+)
+
+// session is a run of a command with its options
+type session struct {
+	cli   *CLI
+	name  string
+	flags options
+}
+
+// flagSet returns the flag set of the command with the flags of every command
+func (s *session) flagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet(s.name, flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+
+	fs.StringVar(&s.flags.URL, "url", "", "registry url, "+envURL)
+	fs.StringVar(&s.flags.Username, "username", "", "registry username, "+envUsername)
+	fs.StringVar(&s.flags.Password, "password", "", "registry password, "+envPassword)
+	fs.StringVar(&s.flags.Token, "token", "", "registry bearer token, "+envToken)
+	fs.StringVar(&s.flags.Output, "o", "", "output format: table, json or yaml, "+envOutput)
+	fs.StringVar(&s.flags.profile, "profile", "", "profile name, "+envProfile)
+	fs.StringVar(&s.flags.profiles, "profiles", "", "profile file, "+envProfiles)
+
+	return fs
+}
+
+// parse parses flags placed before, between and after the positional arguments and checks their number
+func (s *session) parse(fs *flag.FlagSet, args []string, minArgs, maxArgs int) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			if err == flag.ErrHelp {
+				fs.SetOutput(s.cli.Stderr)
+				fs.PrintDefaults()
+				return nil, err
+			}
+			return nil, newUsageError("%v", err)
+		}
+
+		args = fs.Args()
+		if len(args) == 0 {
+			break
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+
+	if len(positional) < minArgs || len(positional) > maxArgs {
+		return nil, newUsageError("expected %d to %d arguments, got %d", minArgs, maxArgs, len(positional))
+	}
+
+	return positional, s.resolve()
+}
+
+// resolve fills the options missing in the flags from the environment variables and the profile
+func (s *session) resolve() error {
+	getenv := s.cli.Getenv
+
+	path := firstOf(s.flags.profiles, getenv(envProfiles), defaultProfilesPath())
+	profile, err := loadProfile(path, firstOf(s.flags.profile, getenv(envProfile)))
+	if err != nil {
+		return err
+	}
+
+	s.flags.URL = firstOf(s.flags.URL, getenv(envURL), profile.URL, defaultURL)
+	s.flags.Username = firstOf(s.flags.Username, getenv(envUsername), profile.Username)
+	s.flags.Password = firstOf(s.flags.Password, getenv(envPassword), profile.Password)
+	s.flags.Token = firstOf(s.flags.Token, getenv(envToken), profile.Token)
+	s.flags.Output = firstOf(s.flags.Output, getenv(envOutput), profile.Output, defaultOutput)
+
+	switch s.flags.Output {
+	case outputTable, outputJSON, outputYAML:
+		return nil
+	default:
+		return newUsageError("unknown output format %q", s.flags.Output)
+	}
+}
+
+func (s *session) client() (schemaregistry.Client, error) {
+	var opts []schemaregistry.Option
+	if s.flags.Username != "" {
+		opts = append(opts, schemaregistry.WithBasicAuth(s.flags.Username, s.flags.Password))
+	}
+	if s.flags.Token != "" {
+		opts = append(opts, schemaregistry.WithBearerToken(s.flags.Token))
+	}
+
+	return s.cli.NewClient(s.flags.URL, opts...)
+}
+
+// print writes v in the output format, tables are written by table
+func (s *session) print(v interface{}, table func(t *tableWriter)) error {
+	return write(s.cli.Stdout, s.flags.Output, v, table)
+}
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"os"
+
+	"github.com/ybalcin/event-schema-manager/internal/cmd/cli"
+	"github.com/ybalcin/event-schema-manager/pkg/schemaregistry"
+)
+
+func main() {
+	c := &cli.CLI{
+		Stdout:    os.Stdout,
+		Stderr:    os.Stderr,
+		Getenv:    os.Getenv,
+		NewClient: schemaregistry.NewClient,
+	}
+
+	os.Exit(c.Run(os.Args[1:]))
+}
@@ -0,0 +1,118 @@
+package gitops
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ybalcin/event-schema-manager/internal/shared/config"
+	"github.com/ybalcin/event-schema-manager/pkg/gitops"
+	"github.com/ybalcin/event-schema-manager/pkg/schemaregistry"
+)
+
+// exit codes of the sync command
+const (
+	exitOK           = 0
+	exitError        = 1
+	exitIncompatible = 2
+)
+
+// environment variables of the registry credentials, the same as the cli
+const (
+	envUsername = "ESM_USERNAME"
+	envPassword = "ESM_PASSWORD"
+	envToken    = "ESM_TOKEN"
+)
+
+// Run reconciles a directory of schema files into the registry: it prints the plan and applies it
+// unless -dry-run is set
+func Run(cfg *config.AppConfig, args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("sync", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	dir := flags.String("dir", "schemas", "directory of the schema files")
+	registryUrl := flags.String("registry", cfg.SchemaRegistryUrl, "url of the schema registry")
+	// credentials are read from the environment like the cli, so they do not end up in the shell history
+	username := flags.String("username", os.Getenv(envUsername), "registry username, "+envUsername)
+	password := flags.String("password", os.Getenv(envPassword), "registry password, "+envPassword)
+	token := flags.String("token", os.Getenv(envToken), "registry bearer token, "+envToken)
+	prune := flags.Bool("prune", false, "delete registered subjects without a schema file")
+	prefixes := flags.String("prefix", "", "comma separated prefixes of the subjects pruned, required by -prune")
+	dryRun := flags.Bool("dry-run", false, "print the plan without applying it")
+	if err := flags.Parse(args); err != nil {
+		return exitError
+	}
+
+	var clientOpts []schemaregistry.Option
+	if *username != "" {
+		clientOpts = append(clientOpts, schemaregistry.WithBasicAuth(*username, *password))
+	}
+	if *token != "" {
+		clientOpts = append(clientOpts, schemaregistry.WithBearerToken(*token))
+	}
+	client, err := schemaregistry.NewClient(*registryUrl, clientOpts...)
+	if err != nil {
+		fmt.Fprintln(stderr, "Error:", err)
+		return exitError
+	}
+
+	files, err := gitops.LoadDir(*dir)
+	if err != nil {
+		fmt.Fprintln(stderr, "Error:", err)
+		return exitError
+	}
+
+	var opts []gitops.Option
+	if *prune {
+		opts = append(opts, gitops.WithPrune(splitList(*prefixes)...))
+	}
+	syncer := gitops.NewSyncer(client, opts...)
+
+	plan, err := syncer.Plan(files)
+	if err != nil {
+		fmt.Fprintln(stderr, "Error:", err)
+		return exitError
+	}
+	if err = plan.Write(stdout); err != nil {
+		return exitError
+	}
+
+	if plan.Count(gitops.ActionIncompatible) > 0 {
+		fmt.Fprintln(stderr, "Error:", gitops.ErrIncompatible)
+		return exitIncompatible
+	}
+	if *dryRun || !plan.HasChanges() {
+		return exitOK
+	}
+
+	fmt.Fprintln(stdout)
+	results, err := syncer.Apply(plan)
+	for _, r := range results {
+		fmt.Fprintln(stdout, r)
+	}
+	if errors.Is(err, gitops.ErrIncompatible) {
+		fmt.Fprintln(stderr, "Error:", err)
+		return exitIncompatible
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "Error:", err)
+		return exitError
+	}
+
+	fmt.Fprintf(stdout, "\nApply complete! %d changes applied.\n", len(results))
+	return exitOK
+}
+
+// splitList splits a comma separated flag value
+func splitList(value string) []string {
+	var values []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+
+	return values
+}
@@ -1,17 +1,46 @@
 package http
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
 	"github.com/ybalcin/event-schema-manager/internal/core/application/ports"
 	"github.com/ybalcin/event-schema-manager/internal/shared/config"
 )
 
+const shutdownTimeout = 15 * time.Second
+
 func StartServer(cfg *config.AppConfig) {
 	if cfg == nil {
 		panic("AppConfig is nil!")
 	}
 
-	ports.NewHttpServer(cfg)
+	server := ports.NewHttpServer(cfg)
+
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+
+		stop := make(chan os.Signal, 1)
+		signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+		<-stop
+
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(ctx); err != nil {
+			fmt.Println("HttpServer shutdown failed:", err)
+		}
+	}()
+
+	fmt.Println("HttpServer running on", server.Address())
+	if err := server.ListenAndServe(); err != nil {
+		panic(err)
+	}
 
-	fmt.Println("HttpServer running!")
+	<-stopped
+	fmt.Println("HttpServer stopped!")
 }
@@ -1,6 +1,9 @@
 package main
 
 import (
+	"os"
+
+	"github.com/ybalcin/event-schema-manager/internal/cmd/gitops"
 	"github.com/ybalcin/event-schema-manager/internal/cmd/http"
 	"github.com/ybalcin/event-schema-manager/internal/shared/config"
 )
@@ -8,5 +11,9 @@ import (
 func main() {
 	cfg := config.LoadConfig()
 
+	if len(os.Args) > 1 && os.Args[1] == "sync" {
+		os.Exit(gitops.Run(cfg, os.Args[2:], os.Stdout, os.Stderr))
+	}
+
 	http.StartServer(cfg)
 }
@@ -1,7 +1,23 @@
 package application
 
 import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/ybalcin/event-schema-manager/internal/core/application/audit"
+	"github.com/ybalcin/event-schema-manager/internal/core/application/auth"
+	"github.com/ybalcin/event-schema-manager/internal/core/application/linting"
+	"github.com/ybalcin/event-schema-manager/internal/core/application/outbox"
+	"github.com/ybalcin/event-schema-manager/internal/core/application/ownership"
+	"github.com/ybalcin/event-schema-manager/internal/core/application/proposals"
+	"github.com/ybalcin/event-schema-manager/internal/core/application/search"
 	"github.com/ybalcin/event-schema-manager/internal/core/application/services"
+	"github.com/ybalcin/event-schema-manager/internal/core/application/webhooks"
+	"github.com/ybalcin/event-schema-manager/internal/core/domain/schema"
 	"github.com/ybalcin/event-schema-manager/internal/infrastructure/adapters"
 	"github.com/ybalcin/event-schema-manager/internal/shared/config"
 	"github.com/ybalcin/event-schema-manager/pkg/schemaregistry"
@@ -9,17 +25,314 @@ import (
 
 type (
 	Application struct {
-		schemaService services.SchemaService
+		schemaService  services.SchemaService
+		eventPublisher schema.EventPublisher
+		outbox         *outbox.Outbox
+		outboxStore    *adapters.FileOutboxStore
+		webhooks       *webhooks.Dispatcher
+		auditStore     *adapters.JSONLAuditStore
+		proposals      *proposals.Service
+		authenticator  auth.Authenticator
+		ownership      *ownership.Registry
+		notifier       *ownership.Notifier
+		search         *search.Index
+		registryClient schemaregistry.Client
+		stop           context.CancelFunc
+		stopped        sync.WaitGroup
 	}
+
+	Option func(*Application)
 )
 
-func NewApplication(cfg *config.AppConfig) *Application {
-	schemaRegistryClient, err := schemaregistry.NewClient(cfg.SchemaRegistryUrl)
+// WithEventPublisher makes the services publish their domain events to publisher
+func WithEventPublisher(publisher schema.EventPublisher) Option {
+	return func(a *Application) {
+		a.eventPublisher = publisher
+	}
+}
+
+// WithSchemaRegistryClient makes the application use client instead of a client of the configured registry url
+func WithSchemaRegistryClient(client schemaregistry.Client) Option {
+	return func(a *Application) {
+		a.registryClient = client
+	}
+}
+
+func NewApplication(cfg *config.AppConfig, opts ...Option) *Application {
+	app := &Application{}
+	for _, opt := range opts {
+		opt(app)
+	}
+
+	var clientOpts []schemaregistry.Option
+	if cfg.SchemaRegistryCacheDir != "" {
+		clientOpts = append(clientOpts,
+			schemaregistry.WithDiskCache(cfg.SchemaRegistryCacheDir),
+			schemaregistry.WithStaleHandler(logStaleResponse),
+		)
+	}
+	if cfg.SchemaRegistryNormalize {
+		clientOpts = append(clientOpts, schemaregistry.WithNormalization())
+	}
+
+	schemaRegistryClient := app.registryClient
+	if schemaRegistryClient == nil {
+		var err error
+		if schemaRegistryClient, err = schemaregistry.NewClient(cfg.SchemaRegistryUrl, clientOpts...); err != nil {
+			panic(err)
+		}
+	}
+
+	linter, err := linting.NewLinter(lintConfigs(cfg.Lint))
 	if err != nil {
 		panic(err)
 	}
 
 	schemaRegistryAdapter := adapters.NewSchemaRegistryRepository(schemaRegistryClient)
-	schemaService := services.NewSchemaService(schemaRegistryAdapter)
-	return &Application{schemaService: schemaService}
+	var auditStore audit.Store = audit.NewMemoryStore()
+	if cfg.AuditLogPath != "" {
+		if app.auditStore, err = adapters.NewJSONLAuditStore(cfg.AuditLogPath); err != nil {
+			panic(err)
+		}
+		auditStore = app.auditStore
+	}
+
+	var ownershipStore ownership.Store = ownership.NewMemoryStore()
+	if cfg.OwnershipStorePath != "" {
+		if ownershipStore, err = adapters.NewFileOwnershipStore(cfg.OwnershipStorePath); err != nil {
+			panic(err)
+		}
+	}
+	if app.authenticator, err = authenticatorOf(cfg.Auth); err != nil {
+		panic(err)
+	}
+
+	var ownershipOpts []ownership.Option
+	if cfg.EnforceOwnership {
+		// the teams of unauthenticated callers are taken from a header anyone can set
+		if app.authenticator == nil {
+			panic(fmt.Errorf("ownership can not be enforced without authentication, configure api keys or jwt"))
+		}
+		ownershipOpts = append(ownershipOpts, ownership.WithEnforcement())
+	}
+	app.ownership = ownership.NewRegistry(ownershipStore, ownershipOpts...)
+	app.notifier = ownership.NewNotifier(app.ownership, nil)
+
+	rules := approvalRules(cfg.Approvals)
+	serviceOpts := []services.Option{
+		services.WithLinter(linter),
+		services.WithAuditLog(auditStore),
+		services.WithAuthorizer(app.ownership),
+		// only approved proposals register the schemas of the subjects requiring approval
+		services.WithRegistrationGate(proposals.NewGate(rules)),
+	}
+	if app.eventPublisher != nil && cfg.OutboxDir != "" {
+		if app.outboxStore, err = adapters.NewFileOutboxStore(cfg.OutboxDir); err != nil {
+			panic(err)
+		}
+		// events are stored before they are published so they survive a crash, the changes left by a crash are
+		// looked up in the registry
+		app.outbox = outbox.NewOutbox(app.outboxStore, app.eventPublisher,
+			outbox.WithRecoverer(services.NewChangeRecoverer(schemaRegistryAdapter)))
+		serviceOpts = append(serviceOpts, services.WithEventPublisher(app.outbox))
+	} else if app.eventPublisher != nil {
+		serviceOpts = append(serviceOpts, services.WithEventPublisher(app.eventPublisher))
+	}
+
+	app.schemaService = services.NewSchemaService(schemaRegistryAdapter, serviceOpts...)
+
+	// the index reads every subject, searches are filtered by the permissions of the caller
+	var searchOpts []search.Option
+	if cfg.SearchAllVersions {
+		searchOpts = append(searchOpts, search.WithAllVersions())
+	}
+	app.search = search.NewIndex(app.schemaService, searchOpts...)
+
+	if app.authenticator != nil {
+		app.schemaService = auth.NewSchemaService(app.schemaService)
+	}
+
+	var webhookStore webhooks.Store = webhooks.NewMemoryStore()
+	if cfg.WebhookStorePath != "" {
+		if webhookStore, err = adapters.NewFileWebhookStore(cfg.WebhookStorePath); err != nil {
+			panic(err)
+		}
+	}
+	app.webhooks = webhooks.NewDispatcher(webhookStore, webhooks.WithAllowedHosts(cfg.WebhookAllowedHosts...))
+
+	var proposalStore proposals.Store = proposals.NewMemoryStore()
+	if cfg.ProposalStorePath != "" {
+		if proposalStore, err = adapters.NewFileProposalStore(cfg.ProposalStorePath); err != nil {
+			panic(err)
+		}
+	}
+	app.proposals = proposals.NewService(app.schemaService, proposalStore, rules, proposals.WithAuthorizer(app))
+
+	return app
+}
+
+// Start starts the background workers of the application
+func (a *Application) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	a.stop = cancel
+
+	workers := []func(ctx context.Context){a.webhooks.Run, a.notifier.Run, a.search.Run}
+	if a.outbox != nil {
+		workers = append(workers, a.outbox.Relay)
+	}
+
+	for _, run := range workers {
+		a.stopped.Add(1)
+		go func(run func(ctx context.Context)) {
+			defer a.stopped.Done()
+			run(ctx)
+		}(run)
+	}
+}
+
+// Stop stops the background workers and waits for them
+func (a *Application) Stop() {
+	if a.stop == nil {
+		return
+	}
+
+	a.stop()
+	a.stopped.Wait()
+	a.stop = nil
+
+	if a.outboxStore != nil {
+		if err := a.outboxStore.Close(); err != nil {
+			log.Printf("outbox store could not be closed, trace: %v", err)
+		}
+	}
+	if a.auditStore != nil {
+		if err := a.auditStore.Close(); err != nil {
+			log.Printf("audit store could not be closed, trace: %v", err)
+		}
+	}
+}
+
+func (a *Application) SchemaService() services.SchemaService {
+	return a.schemaService
+}
+
+// Webhooks returns the dispatcher of webhook subscriptions, it handles the events of the event bus
+func (a *Application) Webhooks() *webhooks.Dispatcher {
+	return a.webhooks
+}
+
+// Ownership returns the registry of subject owners
+func (a *Application) Ownership() *ownership.Registry {
+	return a.ownership
+}
+
+// OwnerNotifier returns the notifier routing events to the owners of their subjects, it handles the events of the event bus
+func (a *Application) OwnerNotifier() *ownership.Notifier {
+	return a.notifier
+}
+
+// Search returns the search index of schemas, it handles the events of the event bus
+func (a *Application) Search() *search.Index {
+	return a.search
+}
+
+// Authenticator returns the authenticator of requests, nil if authentication is not enabled
+func (a *Application) Authenticator() auth.Authenticator {
+	return a.authenticator
+}
+
+// Authorize checks the permission of the identity of ctx on subject if authentication is enabled
+func (a *Application) Authorize(ctx context.Context, permission auth.Permission, subject schema.Subject) error {
+	if a.authenticator == nil {
+		return nil
+	}
+
+	return auth.Authorize(ctx, permission, subject)
+}
+
+// Proposals returns the service of schema proposals
+func (a *Application) Proposals() *proposals.Service {
+	return a.proposals
+}
+
+// authenticatorOf returns the chain of the configured authenticators, nil if none is configured
+func authenticatorOf(cfg config.AuthConfig) (auth.Authenticator, error) {
+	var authenticators []auth.Authenticator
+
+	if len(cfg.APIKeys) > 0 {
+		keys := make([]auth.APIKey, len(cfg.APIKeys))
+		for i, k := range cfg.APIKeys {
+			grants, err := auth.ParseGrants(k.Roles)
+			if err != nil {
+				return nil, fmt.Errorf("api key %s: %w", k.Name, err)
+			}
+			keys[i] = auth.APIKey{Name: k.Name, SHA256: k.SHA256, Teams: k.Teams, Grants: grants}
+		}
+
+		a, err := auth.NewAPIKeyAuthenticator(keys...)
+		if err != nil {
+			return nil, err
+		}
+		authenticators = append(authenticators, a)
+	}
+
+	if cfg.JWT.HMACSecret != "" || len(cfg.JWT.RSAPublicKeyFiles) > 0 {
+		jwtOpts := []auth.JWTOption{auth.WithIssuer(cfg.JWT.Issuer), auth.WithAudience(cfg.JWT.Audience)}
+		if cfg.JWT.HMACSecret != "" {
+			jwtOpts = append(jwtOpts, auth.WithHMACSecret([]byte(cfg.JWT.HMACSecret)))
+		}
+		for kid, path := range cfg.JWT.RSAPublicKeyFiles {
+			b, err := ioutil.ReadFile(path)
+			if err != nil {
+				return nil, err
+			}
+			key, err := auth.ParseRSAPublicKey(b)
+			if err != nil {
+				return nil, fmt.Errorf("jwt key %s: %w", kid, err)
+			}
+			jwtOpts = append(jwtOpts, auth.WithRSAPublicKey(kid, key))
+		}
+		authenticators = append(authenticators, auth.NewJWTAuthenticator(jwtOpts...))
+	}
+
+	if len(authenticators) == 0 {
+		return nil, nil
+	}
+
+	return auth.Chain(authenticators...), nil
+}
+
+// logStaleResponse logs the responses served from the disk cache while the registry can not be reached
+func logStaleResponse(resource string, fetchedAt time.Time, cause error) {
+	log.Printf("schema registry is unavailable, %s is served from the disk cache fetched at %s, trace: %v",
+		resource, fetchedAt.Format(time.RFC3339), cause)
+}
+
+func approvalRules(cfgs []config.ApprovalConfig) []proposals.Rule {
+	rules := make([]proposals.Rule, len(cfgs))
+	for i, c := range cfgs {
+		rules[i] = proposals.Rule{
+			SubjectPrefix:     c.SubjectPrefix,
+			RequiredApprovals: c.RequiredApprovals,
+			Reviewers:         c.Reviewers,
+		}
+	}
+
+	return rules
+}
+
+func lintConfigs(cfgs []config.LintConfig) []linting.Config {
+	configs := make([]linting.Config, len(cfgs))
+	for i, c := range cfgs {
+		configs[i] = linting.Config{SubjectPrefix: c.SubjectPrefix}
+		for _, r := range c.Rules {
+			configs[i].Rules = append(configs[i].Rules, linting.RuleConfig{
+				Name:     r.Name,
+				Severity: linting.Severity(r.Severity),
+				Params:   r.Params,
+			})
+		}
+	}
+
+	return configs
 }
@@ -0,0 +1,158 @@
+package audit
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ybalcin/event-schema-manager/internal/core/domain/schema"
+	"github.com/ybalcin/event-schema-manager/internal/shared/identity"
+)
+
+type (
+	Operation string
+
+	// Entry records a mutation of schemas, Diff is the change of the schema content or of the compatibility level
+	Entry struct {
+		ID        string               `json:"id"`
+		Time      time.Time            `json:"time"`
+		Actor     string               `json:"actor"`
+		Operation Operation            `json:"operation"`
+		Subject   schema.Subject       `json:"subject"`
+		Version   schema.SchemaVersion `json:"version,omitempty"`
+		SchemaID  schema.SchemaID      `json:"schemaId,omitempty"`
+		Reason    string               `json:"reason,omitempty"`
+		Ticket    string               `json:"ticket,omitempty"`
+		Diff      string               `json:"diff,omitempty"`
+	}
+
+	// Query filters entries, zero fields match every entry, From is inclusive and To is exclusive
+	Query struct {
+		Subject schema.Subject
+		Actor   string
+		From    time.Time
+		To      time.Time
+		// Limit is the maximum number of entries, the latest ones are returned
+		Limit int
+	}
+
+	// Store appends and queries audit entries, entries are returned in the order they are appended
+	Store interface {
+		Append(entry Entry) error
+		Query(query Query) ([]Entry, error)
+	}
+
+	// MemoryStore keeps entries in memory
+	MemoryStore struct {
+		mu      sync.RWMutex
+		entries []Entry
+	}
+
+	// Change is the reason of the mutations of a request
+	Change struct {
+		Reason string
+		Ticket string
+	}
+
+	changeKey struct{}
+)
+
+// ErrNotRecorded is returned when a change is made but its audit entry could not be appended
+var ErrNotRecorded = errors.New("change is made but its audit entry could not be recorded")
+
+const (
+	OperationRegister              Operation = "register"
+	OperationDeleteSubject         Operation = "delete-subject"
+	OperationDeleteVersion         Operation = "delete-version"
+	OperationSetCompatibilityLevel Operation = "set-compatibility-level"
+)
+
+// NewEntry returns an entry of operation by the actor of ctx with the reason of ctx
+func NewEntry(ctx context.Context, operation Operation, subject schema.Subject) Entry {
+	change := ChangeFromContext(ctx)
+
+	return Entry{
+		ID:        newEntryID(),
+		Time:      time.Now().UTC(),
+		Actor:     identity.FromContext(ctx).Actor,
+		Operation: operation,
+		Subject:   subject,
+		Reason:    change.Reason,
+		Ticket:    change.Ticket,
+	}
+}
+
+func newEntryID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+
+	return hex.EncodeToString(b)
+}
+
+// WithChange returns a copy of ctx carrying the reason of its mutations
+func WithChange(ctx context.Context, change Change) context.Context {
+	return context.WithValue(ctx, changeKey{}, change)
+}
+
+func ChangeFromContext(ctx context.Context) Change {
+	change, _ := ctx.Value(changeKey{}).(Change)
+	return change
+}
+
+// Matches returns true if entry passes the filters of q
+func (q Query) Matches(entry Entry) bool {
+	if q.Subject != "" && entry.Subject != q.Subject {
+		return false
+	}
+	if q.Actor != "" && entry.Actor != q.Actor {
+		return false
+	}
+	if !q.From.IsZero() && entry.Time.Before(q.From) {
+		return false
+	}
+	if !q.To.IsZero() && !entry.Time.Before(q.To) {
+		return false
+	}
+
+	return true
+}
+
+// Latest returns the last Limit entries, all of them if there is no limit
+func (q Query) Latest(entries []Entry) []Entry {
+	if q.Limit > 0 && len(entries) > q.Limit {
+		return entries[len(entries)-q.Limit:]
+	}
+
+	return entries
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+func (s *MemoryStore) Append(entry Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries = append(s.entries, entry)
+	return nil
+}
+
+func (s *MemoryStore) Query(query Query) ([]Entry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entries := []Entry{}
+	for _, e := range s.entries {
+		if query.Matches(e) {
+			entries = append(entries, e)
+		}
+	}
+
+	return query.Latest(entries), nil
+}
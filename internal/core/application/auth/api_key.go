@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/ybalcin/event-schema-manager/internal/shared/identity"
+)
+
+type (
+	// APIKey is a key known by its sha256 hash, so keys are not kept in the config
+	APIKey struct {
+		Name   string
+		SHA256 string
+		Teams  []string
+		Grants []identity.Grant
+	}
+
+	apiKeyAuthenticator struct {
+		keys []apiKey
+	}
+
+	apiKey struct {
+		APIKey
+		hash []byte
+	}
+)
+
+// HashAPIKey returns the hex sha256 hash of key as it is configured
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// NewAPIKeyAuthenticator authenticates the api key of requests as the name of the matching key
+func NewAPIKeyAuthenticator(keys ...APIKey) (Authenticator, error) {
+	a := &apiKeyAuthenticator{}
+	for _, k := range keys {
+		hash, err := hex.DecodeString(k.SHA256)
+		if err != nil || len(hash) != sha256.Size {
+			return nil, fmt.Errorf("api key %s: sha256 must be a hex sha256 hash", k.Name)
+		}
+		if k.Name == "" {
+			return nil, fmt.Errorf("api key %s: name is required", k.SHA256)
+		}
+
+		a.keys = append(a.keys, apiKey{APIKey: k, hash: hash})
+	}
+
+	return a, nil
+}
+
+func (a *apiKeyAuthenticator) Authenticate(ctx context.Context, credentials Credentials) (identity.Identity, error) {
+	if credentials.APIKey == "" {
+		return identity.Identity{}, ErrNoCredentials
+	}
+
+	sum := sha256.Sum256([]byte(credentials.APIKey))
+	for _, k := range a.keys {
+		if subtle.ConstantTimeCompare(sum[:], k.hash) == 1 {
+			return identity.Identity{Actor: k.Name, Teams: k.Teams, Grants: k.Grants, Authenticated: true}, nil
+		}
+	}
+
+	return identity.Identity{}, fmt.Errorf("%w: api key is not valid", ErrUnauthenticated)
+}
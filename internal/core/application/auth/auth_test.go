@@ -0,0 +1,217 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ybalcin/event-schema-manager/internal/shared/identity"
+)
+
+var ctx = context.Background()
+
+func mustEqual(t *testing.T, actual, expected interface{}) {
+	t.Helper()
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %#v, but got %#v", expected, actual)
+	}
+}
+
+func segment(t *testing.T, v interface{}) string {
+	t.Helper()
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func hsToken(t *testing.T, secret string, claims map[string]interface{}) string {
+	signed := segment(t, map[string]string{"alg": "HS256", "typ": "JWT"}) + "." + segment(t, claims)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(signed))
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func rsToken(t *testing.T, key *rsa.PrivateKey, kid string, claims map[string]interface{}) string {
+	signed := segment(t, map[string]string{"alg": "RS256", "kid": kid}) + "." + segment(t, claims)
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func claimsOf(sub string, roles ...string) map[string]interface{} {
+	return map[string]interface{}{
+		"sub":   sub,
+		"iss":   "https://idp.example.com",
+		"aud":   []string{"schemas"},
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"roles": roles,
+		"teams": []string{"payments"},
+	}
+}
+
+func TestParseGrants(t *testing.T) {
+	grants, err := ParseGrants([]string{"viewer", "producer:orders-*"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustEqual(t, grants, []identity.Grant{{Role: "viewer", Subjects: "*"}, {Role: "producer", Subjects: "orders-*"}})
+
+	for _, invalid := range []string{"owner", "admin:", "admin:[orders"} {
+		if _, err = ParseGrants([]string{invalid}); !errors.Is(err, ErrInvalidGrant) {
+			t.Errorf("expected invalid grant for %s, but got %v", invalid, err)
+		}
+	}
+}
+
+func TestAllowed(t *testing.T) {
+	grants, _ := ParseGrants([]string{"viewer", "producer:orders-*", "admin:orders-audit-*"})
+	id := identity.Identity{Actor: "alice", Grants: grants}
+
+	mustEqual(t, Allowed(id, PermissionRead, "payments-value"), true)
+	mustEqual(t, Allowed(id, PermissionWrite, "payments-value"), false)
+	mustEqual(t, Allowed(id, PermissionWrite, "orders-value"), true)
+	mustEqual(t, Allowed(id, PermissionAdmin, "orders-value"), false)
+	mustEqual(t, Allowed(id, PermissionAdmin, "orders-audit-value"), true)
+	// the global config needs a grant on every subject
+	mustEqual(t, Allowed(id, PermissionRead, ""), true)
+	mustEqual(t, Allowed(id, PermissionAdmin, ""), false)
+
+	forbidden := Authorize(identity.NewContext(ctx, id), PermissionAdmin, "payments-value")
+	if !errors.Is(forbidden, ErrForbidden) {
+		t.Fatalf("expected forbidden, but got %v", forbidden)
+	}
+}
+
+func TestAPIKeyAuthenticator(t *testing.T) {
+	grants, _ := ParseGrants([]string{"producer"})
+	a, err := NewAPIKeyAuthenticator(APIKey{Name: "ci", SHA256: HashAPIKey("s3cret"), Grants: grants})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := a.Authenticate(ctx, Credentials{APIKey: "s3cret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustEqual(t, id.Actor, "ci")
+	mustEqual(t, id.Authenticated, true)
+	mustEqual(t, id.Grants, grants)
+
+	if _, err = a.Authenticate(ctx, Credentials{APIKey: "wrong"}); !errors.Is(err, ErrUnauthenticated) {
+		t.Fatalf("expected unauthenticated, but got %v", err)
+	}
+	if _, err = a.Authenticate(ctx, Credentials{}); !errors.Is(err, ErrNoCredentials) {
+		t.Fatalf("expected no credentials, but got %v", err)
+	}
+}
+
+func TestJWTAuthenticator_HMAC(t *testing.T) {
+	a := NewJWTAuthenticator(WithHMACSecret([]byte("shared")), WithIssuer("https://idp.example.com"), WithAudience("schemas"))
+
+	id, err := a.Authenticate(ctx, Credentials{BearerToken: hsToken(t, "shared", claimsOf("alice", "admin:orders-*"))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustEqual(t, id, identity.Identity{
+		Actor:         "alice",
+		Teams:         []string{"payments"},
+		Grants:        []identity.Grant{{Role: "admin", Subjects: "orders-*"}},
+		Authenticated: true,
+	})
+
+	expired := claimsOf("alice")
+	expired["exp"] = time.Now().Add(-time.Hour).Unix()
+	otherAudience := claimsOf("alice")
+	otherAudience["aud"] = "billing"
+	unknownRole := claimsOf("alice", "root")
+
+	for name, token := range map[string]string{
+		"wrong secret":   hsToken(t, "other", claimsOf("alice")),
+		"expired":        hsToken(t, "shared", expired),
+		"other audience": hsToken(t, "shared", otherAudience),
+		"unknown role":   hsToken(t, "shared", unknownRole),
+		"not a jwt":      "abc",
+		"alg none":       segment(t, map[string]string{"alg": "none"}) + "." + segment(t, claimsOf("alice")) + ".",
+	} {
+		if _, err = a.Authenticate(ctx, Credentials{BearerToken: token}); !errors.Is(err, ErrUnauthenticated) {
+			t.Errorf("%s: expected unauthenticated, but got %v", name, err)
+		}
+	}
+}
+
+func TestJWTAuthenticator_RSA(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	public, err := ParseRSAPublicKey(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a := NewJWTAuthenticator(WithRSAPublicKey("k1", public))
+
+	id, err := a.Authenticate(ctx, Credentials{BearerToken: rsToken(t, key, "k1", claimsOf("bob", "viewer"))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustEqual(t, id.Actor, "bob")
+
+	if _, err = a.Authenticate(ctx, Credentials{BearerToken: rsToken(t, key, "k2", claimsOf("bob"))}); !errors.Is(err, ErrUnauthenticated) {
+		t.Fatalf("expected unknown key to be rejected, but got %v", err)
+	}
+
+	// an RS key is not used as an HS secret
+	pemKey := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	if _, err = a.Authenticate(ctx, Credentials{BearerToken: hsToken(t, pemKey, claimsOf("bob"))}); !errors.Is(err, ErrUnauthenticated) {
+		t.Fatalf("expected HS token to be rejected, but got %v", err)
+	}
+
+	tampered := rsToken(t, key, "k1", claimsOf("bob", "viewer"))
+	parts := strings.Split(tampered, ".")
+	parts[1] = segment(t, claimsOf("bob", "admin"))
+	if _, err = a.Authenticate(ctx, Credentials{BearerToken: strings.Join(parts, ".")}); !errors.Is(err, ErrUnauthenticated) {
+		t.Fatalf("expected tampered token to be rejected, but got %v", err)
+	}
+}
+
+func TestChain(t *testing.T) {
+	keys, _ := NewAPIKeyAuthenticator(APIKey{Name: "ci", SHA256: HashAPIKey("s3cret")})
+	a := Chain(keys, NewJWTAuthenticator(WithHMACSecret([]byte("shared"))))
+
+	id, err := a.Authenticate(ctx, Credentials{BearerToken: hsToken(t, "shared", claimsOf("alice"))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustEqual(t, id.Actor, "alice")
+
+	if _, err = a.Authenticate(ctx, Credentials{}); !errors.Is(err, ErrUnauthenticated) {
+		t.Fatalf("expected unauthenticated, but got %v", err)
+	}
+}
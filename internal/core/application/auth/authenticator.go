@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ybalcin/event-schema-manager/internal/shared/identity"
+)
+
+type (
+	// Credentials are the secrets a request is authenticated with
+	Credentials struct {
+		APIKey      string
+		BearerToken string
+	}
+
+	// Authenticator verifies credentials and returns the identity they belong to, it returns ErrNoCredentials
+	// if the credentials it verifies are not present so the next authenticator of a chain is tried
+	Authenticator interface {
+		Authenticate(ctx context.Context, credentials Credentials) (identity.Identity, error)
+	}
+
+	chain []Authenticator
+)
+
+var (
+	ErrUnauthenticated = errors.New("unauthenticated")
+	ErrNoCredentials   = fmt.Errorf("%w: credentials are required", ErrUnauthenticated)
+)
+
+// Chain returns an authenticator trying authenticators in order until one finds its credentials
+func Chain(authenticators ...Authenticator) Authenticator {
+	return chain(authenticators)
+}
+
+func (c chain) Authenticate(ctx context.Context, credentials Credentials) (identity.Identity, error) {
+	for _, a := range c {
+		id, err := a.Authenticate(ctx, credentials)
+		if errors.Is(err, ErrNoCredentials) {
+			continue
+		}
+
+		return id, err
+	}
+
+	return identity.Identity{}, ErrNoCredentials
+}
@@ -0,0 +1,285 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	_ "crypto/sha512" // HS384, HS512, RS384 and RS512
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ybalcin/event-schema-manager/internal/shared/identity"
+)
+
+type (
+	// JWTAuthenticator verifies HS256/384/512 tokens with a shared secret and RS256/384/512 tokens with
+	// locally configured public keys, the roles claim holds grants like producer:orders-*
+	JWTAuthenticator struct {
+		hmacSecret []byte
+		rsaKeys    map[string]*rsa.PublicKey
+		issuer     string
+		audience   string
+		leeway     time.Duration
+		now        func() time.Time
+	}
+
+	JWTOption func(*JWTAuthenticator)
+
+	jwtHeader struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+
+	jwtClaims struct {
+		Subject   string      `json:"sub"`
+		Issuer    string      `json:"iss"`
+		Audience  jwtAudience `json:"aud"`
+		ExpiresAt *float64    `json:"exp"`
+		NotBefore *float64    `json:"nbf"`
+		Roles     []string    `json:"roles"`
+		Teams     []string    `json:"teams"`
+	}
+
+	// jwtAudience is a string or an array of strings
+	jwtAudience []string
+)
+
+const defaultLeeway = time.Minute
+
+var (
+	hashes = map[string]crypto.Hash{
+		"HS256": crypto.SHA256, "HS384": crypto.SHA384, "HS512": crypto.SHA512,
+		"RS256": crypto.SHA256, "RS384": crypto.SHA384, "RS512": crypto.SHA512,
+	}
+	errInvalidToken = func(format string, args ...interface{}) error {
+		return fmt.Errorf("%w: token "+format, append([]interface{}{ErrUnauthenticated}, args...)...)
+	}
+)
+
+// WithHMACSecret verifies HS tokens with secret
+func WithHMACSecret(secret []byte) JWTOption {
+	return func(a *JWTAuthenticator) {
+		a.hmacSecret = secret
+	}
+}
+
+// WithRSAPublicKey verifies RS tokens whose kid is kid with key, tokens without a kid are tried with every key
+func WithRSAPublicKey(kid string, key *rsa.PublicKey) JWTOption {
+	return func(a *JWTAuthenticator) {
+		a.rsaKeys[kid] = key
+	}
+}
+
+// WithIssuer requires the iss claim to be issuer
+func WithIssuer(issuer string) JWTOption {
+	return func(a *JWTAuthenticator) {
+		a.issuer = issuer
+	}
+}
+
+// WithAudience requires the aud claim to contain audience
+func WithAudience(audience string) JWTOption {
+	return func(a *JWTAuthenticator) {
+		a.audience = audience
+	}
+}
+
+func NewJWTAuthenticator(opts ...JWTOption) *JWTAuthenticator {
+	a := &JWTAuthenticator{
+		rsaKeys: make(map[string]*rsa.PublicKey),
+		leeway:  defaultLeeway,
+		now:     time.Now,
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	return a
+}
+
+// ParseRSAPublicKey parses a PEM encoded PKIX or PKCS #1 public key or a certificate
+func ParseRSAPublicKey(b []byte) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, errors.New("no PEM block is found")
+	}
+
+	var key interface{}
+	var err error
+	switch block.Type {
+	case "RSA PUBLIC KEY":
+		key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "CERTIFICATE":
+		var cert *x509.Certificate
+		if cert, err = x509.ParseCertificate(block.Bytes); err == nil {
+			key = cert.PublicKey
+		}
+	default:
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%T is not an RSA public key", key)
+	}
+
+	return rsaKey, nil
+}
+
+func (a *JWTAuthenticator) Authenticate(ctx context.Context, credentials Credentials) (identity.Identity, error) {
+	// api keys are not tokens
+	if credentials.BearerToken == "" {
+		return identity.Identity{}, ErrNoCredentials
+	}
+
+	claims, err := a.verify(credentials.BearerToken)
+	if err != nil {
+		return identity.Identity{}, err
+	}
+
+	grants, err := ParseGrants(claims.Roles)
+	if err != nil {
+		return identity.Identity{}, errInvalidToken("roles are not valid, %v", err)
+	}
+
+	return identity.Identity{Actor: claims.Subject, Teams: claims.Teams, Grants: grants, Authenticated: true}, nil
+}
+
+func (a *JWTAuthenticator) verify(token string) (jwtClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return jwtClaims{}, errInvalidToken("is not a JWT")
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return jwtClaims{}, errInvalidToken("header is not valid")
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return jwtClaims{}, errInvalidToken("signature is not valid")
+	}
+
+	if err = a.verifySignature(header, parts[0]+"."+parts[1], signature); err != nil {
+		return jwtClaims{}, err
+	}
+
+	var claims jwtClaims
+	if err = decodeSegment(parts[1], &claims); err != nil {
+		return jwtClaims{}, errInvalidToken("claims are not valid")
+	}
+
+	return claims, a.validate(claims)
+}
+
+// verifySignature verifies the signature with the key type of the algorithm, so an RS key is never used as an HS secret
+func (a *JWTAuthenticator) verifySignature(header jwtHeader, signed string, signature []byte) error {
+	hash, ok := hashes[header.Alg]
+	if !ok {
+		return errInvalidToken("algorithm %q is not supported", header.Alg)
+	}
+
+	h := hash.New()
+	h.Write([]byte(signed))
+	digest := h.Sum(nil)
+
+	if strings.HasPrefix(header.Alg, "HS") {
+		if len(a.hmacSecret) == 0 {
+			return errInvalidToken("algorithm %s is not configured", header.Alg)
+		}
+
+		mac := hmac.New(hash.New, a.hmacSecret)
+		mac.Write([]byte(signed))
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return errInvalidToken("signature is not valid")
+		}
+		return nil
+	}
+
+	keys := a.rsaKeys
+	if key, ok := a.rsaKeys[header.Kid]; ok {
+		keys = map[string]*rsa.PublicKey{header.Kid: key}
+	} else if header.Kid != "" {
+		return errInvalidToken("key %q is not known", header.Kid)
+	}
+	for _, key := range keys {
+		if rsa.VerifyPKCS1v15(key, hash, digest, signature) == nil {
+			return nil
+		}
+	}
+
+	return errInvalidToken("signature is not valid")
+}
+
+func (a *JWTAuthenticator) validate(claims jwtClaims) error {
+	now := a.now()
+	if claims.ExpiresAt == nil {
+		return errInvalidToken("has no expiry")
+	}
+	if now.After(unixTime(*claims.ExpiresAt).Add(a.leeway)) {
+		return errInvalidToken("is expired")
+	}
+	if claims.NotBefore != nil && now.Add(a.leeway).Before(unixTime(*claims.NotBefore)) {
+		return errInvalidToken("is not valid yet")
+	}
+	if a.issuer != "" && claims.Issuer != a.issuer {
+		return errInvalidToken("issuer %q is not trusted", claims.Issuer)
+	}
+	if a.audience != "" && !contains(claims.Audience, a.audience) {
+		return errInvalidToken("is not issued for %s", a.audience)
+	}
+	if claims.Subject == "" {
+		return errInvalidToken("has no subject")
+	}
+
+	return nil
+}
+
+func (aud *jwtAudience) UnmarshalJSON(b []byte) error {
+	var single string
+	if err := json.Unmarshal(b, &single); err == nil {
+		*aud = jwtAudience{single}
+		return nil
+	}
+
+	var many []string
+	if err := json.Unmarshal(b, &many); err != nil {
+		return err
+	}
+	*aud = many
+	return nil
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(b, v)
+}
+
+func unixTime(seconds float64) time.Time {
+	return time.Unix(0, int64(seconds*float64(time.Second)))
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
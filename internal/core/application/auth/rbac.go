@@ -0,0 +1,109 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/ybalcin/event-schema-manager/internal/core/domain/schema"
+	"github.com/ybalcin/event-schema-manager/internal/shared/identity"
+)
+
+type (
+	Role string
+
+	// Permission is what a role allows on the subjects of its grant
+	Permission string
+)
+
+const (
+	RoleViewer   Role = "viewer"
+	RoleProducer Role = "producer"
+	RoleAdmin    Role = "admin"
+
+	// PermissionRead reads schemas, configs and the audit log, checks compatibility and lints
+	PermissionRead Permission = "read"
+	// PermissionWrite registers schemas
+	PermissionWrite Permission = "write"
+	// PermissionAdmin deletes schemas, changes configs and manages webhooks and owners
+	PermissionAdmin Permission = "admin"
+
+	allSubjects = "*"
+)
+
+var (
+	ErrForbidden    = errors.New("forbidden")
+	ErrInvalidGrant = errors.New("invalid grant")
+
+	permissions = map[Role][]Permission{
+		RoleViewer:   {PermissionRead},
+		RoleProducer: {PermissionRead, PermissionWrite},
+		RoleAdmin:    {PermissionRead, PermissionWrite, PermissionAdmin},
+	}
+)
+
+// ParseGrants parses grants written as role or role:glob, e.g. producer:orders-*, a role without a glob is
+// granted on every subject
+func ParseGrants(values []string) ([]identity.Grant, error) {
+	grants := make([]identity.Grant, 0, len(values))
+	for _, v := range values {
+		role, subjects := v, allSubjects
+		if i := strings.Index(v, ":"); i >= 0 {
+			role, subjects = v[:i], v[i+1:]
+		}
+
+		if _, ok := permissions[Role(role)]; !ok {
+			return nil, fmt.Errorf("%w: unknown role %q", ErrInvalidGrant, role)
+		}
+		if _, err := path.Match(subjects, ""); err != nil || subjects == "" {
+			return nil, fmt.Errorf("%w: subject glob %q is not valid", ErrInvalidGrant, subjects)
+		}
+
+		grants = append(grants, identity.Grant{Role: role, Subjects: subjects})
+	}
+
+	return grants, nil
+}
+
+// Allowed returns true if a grant of id allows permission on subject, the empty subject is the global config
+// and only grants on every subject allow it
+func Allowed(id identity.Identity, permission Permission, subject string) bool {
+	for _, g := range id.Grants {
+		if !grants(Role(g.Role), permission) {
+			continue
+		}
+		if g.Subjects == allSubjects {
+			return true
+		}
+		if matched, _ := path.Match(g.Subjects, subject); matched && subject != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Authorize returns ErrForbidden if the identity of ctx is not allowed permission on subject
+func Authorize(ctx context.Context, permission Permission, subject schema.Subject) error {
+	id := identity.FromContext(ctx)
+	if Allowed(id, permission, subject.String()) {
+		return nil
+	}
+
+	if subject == "" {
+		return fmt.Errorf("%w: %s has no %s permission on every subject", ErrForbidden, id.Actor, permission)
+	}
+	return fmt.Errorf("%w: %s has no %s permission on %s", ErrForbidden, id.Actor, permission, subject)
+}
+
+func grants(role Role, permission Permission) bool {
+	for _, p := range permissions[role] {
+		if p == permission {
+			return true
+		}
+	}
+
+	return false
+}
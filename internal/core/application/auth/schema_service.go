@@ -0,0 +1,188 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ybalcin/event-schema-manager/internal/core/application/audit"
+	"github.com/ybalcin/event-schema-manager/internal/core/application/changelog"
+	"github.com/ybalcin/event-schema-manager/internal/core/application/linting"
+	"github.com/ybalcin/event-schema-manager/internal/core/application/services"
+	"github.com/ybalcin/event-schema-manager/internal/core/domain/schema"
+	"github.com/ybalcin/event-schema-manager/internal/shared/identity"
+	"github.com/ybalcin/event-schema-manager/pkg/schemadiff"
+)
+
+// schemaService checks the permission of the identity of ctx before every operation of the wrapped service
+type schemaService struct {
+	next services.SchemaService
+}
+
+// NewSchemaService returns next authorizing every operation by the grants of the identity of ctx,
+// lists are filtered to the subjects the identity can read
+func NewSchemaService(next services.SchemaService) services.SchemaService {
+	return &schemaService{next: next}
+}
+
+func (s *schemaService) Add(ctx context.Context, sc *schema.Schema) (*services.AddResult, error) {
+	if err := Authorize(ctx, PermissionWrite, sc.Subject()); err != nil {
+		return nil, err
+	}
+
+	return s.next.Add(ctx, sc)
+}
+
+func (s *schemaService) Subjects(ctx context.Context) ([]schema.Subject, error) {
+	subjects, err := s.next.Subjects(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	id := identity.FromContext(ctx)
+	readable := []schema.Subject{}
+	for _, subject := range subjects {
+		if Allowed(id, PermissionRead, subject.String()) {
+			readable = append(readable, subject)
+		}
+	}
+
+	return readable, nil
+}
+
+func (s *schemaService) Versions(ctx context.Context, subject schema.Subject) ([]schema.SchemaVersion, error) {
+	if err := Authorize(ctx, PermissionRead, subject); err != nil {
+		return nil, err
+	}
+
+	return s.next.Versions(ctx, subject)
+}
+
+func (s *schemaService) Get(ctx context.Context, subject schema.Subject, version schema.SchemaVersion) (*schema.Schema, error) {
+	if err := Authorize(ctx, PermissionRead, subject); err != nil {
+		return nil, err
+	}
+
+	return s.next.Get(ctx, subject, version)
+}
+
+// GetByID authorizes by the subjects registered with the schema, since the registry returns a schema by id
+// without its subject. The schema can be read with a read permission on any of them.
+func (s *schemaService) GetByID(ctx context.Context, id schema.SchemaID) (*schema.Schema, error) {
+	subjects, err := s.SubjectsOf(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(subjects) == 0 {
+		return nil, fmt.Errorf("%w: %s has no read permission on a subject of schema %d", ErrForbidden,
+			identity.FromContext(ctx).Actor, id)
+	}
+
+	return s.next.GetByID(ctx, id)
+}
+
+// SubjectsOf returns the subjects of the schema the identity can read
+func (s *schemaService) SubjectsOf(ctx context.Context, id schema.SchemaID) ([]schema.Subject, error) {
+	subjects, err := s.next.SubjectsOf(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	caller := identity.FromContext(ctx)
+	readable := []schema.Subject{}
+	for _, subject := range subjects {
+		if Allowed(caller, PermissionRead, subject.String()) {
+			readable = append(readable, subject)
+		}
+	}
+
+	return readable, nil
+}
+
+func (s *schemaService) CheckCompatibility(ctx context.Context, sc *schema.Schema, version schema.SchemaVersion) (schema.Compatibility, error) {
+	if err := Authorize(ctx, PermissionRead, sc.Subject()); err != nil {
+		return schema.Compatibility{}, err
+	}
+
+	return s.next.CheckCompatibility(ctx, sc, version)
+}
+
+func (s *schemaService) Delete(ctx context.Context, subject schema.Subject) ([]schema.SchemaVersion, error) {
+	if err := Authorize(ctx, PermissionAdmin, subject); err != nil {
+		return nil, err
+	}
+
+	return s.next.Delete(ctx, subject)
+}
+
+func (s *schemaService) DeleteVersion(ctx context.Context, subject schema.Subject, version schema.SchemaVersion) (schema.SchemaVersion, error) {
+	if err := Authorize(ctx, PermissionAdmin, subject); err != nil {
+		return 0, err
+	}
+
+	return s.next.DeleteVersion(ctx, subject, version)
+}
+
+func (s *schemaService) CompatibilityLevel(ctx context.Context, subject schema.Subject) (schema.CompatibilityLevel, error) {
+	if err := Authorize(ctx, PermissionRead, subject); err != nil {
+		return "", err
+	}
+
+	return s.next.CompatibilityLevel(ctx, subject)
+}
+
+func (s *schemaService) SetCompatibilityLevel(ctx context.Context, subject schema.Subject, level schema.CompatibilityLevel) (schema.CompatibilityLevel, error) {
+	if err := Authorize(ctx, PermissionAdmin, subject); err != nil {
+		return "", err
+	}
+
+	return s.next.SetCompatibilityLevel(ctx, subject, level)
+}
+
+func (s *schemaService) Lint(ctx context.Context, sc *schema.Schema) (linting.Report, error) {
+	if err := Authorize(ctx, PermissionRead, sc.Subject()); err != nil {
+		return linting.Report{}, err
+	}
+
+	return s.next.Lint(ctx, sc)
+}
+
+// AuditLog returns the entries of the subjects the identity can read, entries of the global config need
+// a grant on every subject
+func (s *schemaService) AuditLog(ctx context.Context, query audit.Query) ([]audit.Entry, error) {
+	if query.Subject != "" {
+		if err := Authorize(ctx, PermissionRead, query.Subject); err != nil {
+			return nil, err
+		}
+	}
+
+	entries, err := s.next.AuditLog(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	id := identity.FromContext(ctx)
+	readable := []audit.Entry{}
+	for _, e := range entries {
+		if Allowed(id, PermissionRead, e.Subject.String()) {
+			readable = append(readable, e)
+		}
+	}
+
+	return readable, nil
+}
+
+func (s *schemaService) Diff(ctx context.Context, subject schema.Subject, from, to schema.SchemaVersion) (*schemadiff.Diff, error) {
+	if err := Authorize(ctx, PermissionRead, subject); err != nil {
+		return nil, err
+	}
+
+	return s.next.Diff(ctx, subject, from, to)
+}
+
+func (s *schemaService) Changelog(ctx context.Context, subject schema.Subject) (*changelog.Changelog, error) {
+	if err := Authorize(ctx, PermissionRead, subject); err != nil {
+		return nil, err
+	}
+
+	return s.next.Changelog(ctx, subject)
+}
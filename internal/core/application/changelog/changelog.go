@@ -0,0 +1,133 @@
+package changelog
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ybalcin/event-schema-manager/internal/core/application/audit"
+	"github.com/ybalcin/event-schema-manager/internal/core/domain/schema"
+	"github.com/ybalcin/event-schema-manager/pkg/schemadiff"
+)
+
+type (
+	// Changelog is the history of the versions of a subject, oldest first
+	Changelog struct {
+		Subject schema.Subject `json:"subject"`
+		Entries []Entry        `json:"entries"`
+		// Undated is the number of versions left out by a date range since their registration date is unknown
+		Undated int `json:"undated,omitempty"`
+	}
+
+	// Entry is a version and its changes from the previous version, the audit fields are empty if the registration
+	// of the version is not in the audit log
+	Entry struct {
+		Version    schema.SchemaVersion `json:"version"`
+		SchemaID   schema.SchemaID      `json:"schemaId"`
+		SchemaType schema.SchemaType    `json:"schemaType"`
+		Date       *time.Time           `json:"date,omitempty"`
+		Author     string               `json:"author,omitempty"`
+		Reason     string               `json:"reason,omitempty"`
+		Ticket     string               `json:"ticket,omitempty"`
+		// Initial is true for the first version, it has no changes
+		Initial bool                `json:"initial,omitempty"`
+		Changes []schemadiff.Change `json:"changes"`
+		// Diff is the line diff from the previous version when the schema type has no structural diff
+		Diff string `json:"diff,omitempty"`
+	}
+)
+
+// Build returns the changelog of subject from its versions in ascending order and its audit entries,
+// consecutive versions are diffed structurally, by lines if their schema type is not supported by schemadiff
+func Build(subject schema.Subject, versions []*schema.Schema, entries []audit.Entry) (*Changelog, error) {
+	byVersion, byID := registrationsOf(subject, entries)
+
+	changelog := &Changelog{Subject: subject, Entries: make([]Entry, 0, len(versions))}
+	for i, sc := range versions {
+		entry := Entry{
+			Version:    sc.Version(),
+			SchemaID:   sc.ID(),
+			SchemaType: sc.Type(),
+			Initial:    i == 0,
+			Changes:    []schemadiff.Change{},
+		}
+
+		registration, ok := byVersion[sc.Version()]
+		if !ok {
+			registration, ok = byID[sc.ID()]
+		}
+		if ok {
+			date := registration.Time
+			entry.Date = &date
+			entry.Author, entry.Reason, entry.Ticket = registration.Actor, registration.Reason, registration.Ticket
+		}
+
+		if i > 0 {
+			if err := entry.diff(versions[i-1], sc); err != nil {
+				return nil, err
+			}
+		}
+
+		changelog.Entries = append(changelog.Entries, entry)
+	}
+
+	return changelog, nil
+}
+
+// registrationsOf returns the latest registration entries of subject by version and by schema id,
+// the version of an entry is zero if the registry did not return it
+func registrationsOf(subject schema.Subject, entries []audit.Entry) (map[schema.SchemaVersion]audit.Entry, map[schema.SchemaID]audit.Entry) {
+	byVersion := make(map[schema.SchemaVersion]audit.Entry)
+	byID := make(map[schema.SchemaID]audit.Entry)
+	for _, e := range entries {
+		if e.Subject != subject || e.Operation != audit.OperationRegister {
+			continue
+		}
+		if e.Version > 0 {
+			byVersion[e.Version] = e
+		}
+		if e.SchemaID > 0 {
+			byID[e.SchemaID] = e
+		}
+	}
+
+	return byVersion, byID
+}
+
+func (e *Entry) diff(previous, current *schema.Schema) error {
+	if previous.Type() == current.Type() {
+		diff, err := schemadiff.Compare(string(current.Type()), previous.Content(), current.Content())
+		if err == nil {
+			e.Changes = diff.Changes
+			return nil
+		}
+		if !errors.Is(err, schemadiff.ErrUnsupportedSchemaType) {
+			return fmt.Errorf("%w: version %d: %v", schema.ErrInvalidSchema, current.Version(), err)
+		}
+	}
+
+	e.Diff = schemadiff.LineDiff(previous.Content(), current.Content())
+	return nil
+}
+
+// Between returns the entries dated from from, inclusive, to to, exclusive, zero times are not bounds.
+// Entries without a date are left out when there is a bound and counted in Undated.
+func (c *Changelog) Between(from, to time.Time) *Changelog {
+	if from.IsZero() && to.IsZero() {
+		return c
+	}
+
+	between := &Changelog{Subject: c.Subject, Entries: []Entry{}}
+	for _, e := range c.Entries {
+		if e.Date == nil {
+			between.Undated++
+			continue
+		}
+		if !from.IsZero() && e.Date.Before(from) || !to.IsZero() && !e.Date.Before(to) {
+			continue
+		}
+		between.Entries = append(between.Entries, e)
+	}
+
+	return between
+}
@@ -0,0 +1,117 @@
+package changelog
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ybalcin/event-schema-manager/internal/core/application/audit"
+	"github.com/ybalcin/event-schema-manager/internal/core/domain/schema"
+	"github.com/ybalcin/event-schema-manager/pkg/schemadiff"
+)
+
+func mustEqual(t *testing.T, actual, expected interface{}) {
+	t.Helper()
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %#v, but got %#v", expected, actual)
+	}
+}
+
+func registered(t *testing.T, schemaType schema.SchemaType, id schema.SchemaID, version schema.SchemaVersion, content string) *schema.Schema {
+	t.Helper()
+
+	sc, err := schema.NewSchema("orders-value", schemaType, content)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return sc.Registered(id, version)
+}
+
+func TestBuild(t *testing.T) {
+	versions := []*schema.Schema{
+		registered(t, schema.SchemaTypeAvro, 1, 1, `{"type":"record","name":"Order","fields":[{"name":"id","type":"string"}]}`),
+		registered(t, schema.SchemaTypeAvro, 4, 2, `{"type":"record","name":"Order","fields":[{"name":"id","type":"long"}]}`),
+		registered(t, schema.SchemaTypeJSON, 9, 3, `{"type":"object"}`),
+	}
+	march := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	entries := []audit.Entry{
+		{Time: march, Actor: "alice", Operation: audit.OperationRegister, Subject: "orders-value", SchemaID: 4, Reason: "ids are numbers"},
+		{Time: march, Actor: "bob", Operation: audit.OperationRegister, Subject: "payments-value", Version: 1, SchemaID: 1},
+		{Time: march, Actor: "bob", Operation: audit.OperationSetCompatibilityLevel, Subject: "orders-value"},
+	}
+
+	changelog, err := Build("orders-value", versions, entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustEqual(t, len(changelog.Entries), 3)
+
+	initial := changelog.Entries[0]
+	mustEqual(t, initial.Initial, true)
+	mustEqual(t, initial.Date, (*time.Time)(nil))
+	mustEqual(t, initial.Author, "")
+
+	// the version of the audit entry is unknown, it is matched by the schema id
+	second := changelog.Entries[1]
+	mustEqual(t, *second.Date, march)
+	mustEqual(t, second.Author, "alice")
+	mustEqual(t, second.Reason, "ids are numbers")
+	mustEqual(t, second.Changes, []schemadiff.Change{{Kind: schemadiff.TypeChanged, Path: "$.id", Before: "string", After: "long"}})
+
+	third := changelog.Entries[2]
+	mustEqual(t, third.Changes, []schemadiff.Change{})
+	if !strings.Contains(third.Diff, `+  "type": "object"`) || !strings.Contains(third.Diff, `-  "name": "Order",`) {
+		t.Errorf("expected a line diff, but got %q", third.Diff)
+	}
+}
+
+func TestChangelog_Between(t *testing.T) {
+	jan, feb := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+	changelog := &Changelog{Subject: "orders-value", Entries: []Entry{{Version: 1}, {Version: 2, Date: &jan}, {Version: 3, Date: &feb}}}
+
+	mustEqual(t, changelog.Between(time.Time{}, time.Time{}), changelog)
+	mustEqual(t, changelog.Between(jan, feb), &Changelog{Subject: "orders-value", Entries: []Entry{{Version: 2, Date: &jan}}, Undated: 1})
+	mustEqual(t, changelog.Between(feb, time.Time{}).Entries, []Entry{{Version: 3, Date: &feb}})
+}
+
+func TestChangelog_WriteMarkdown(t *testing.T) {
+	date := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	changelog := &Changelog{Subject: "orders-value", Entries: []Entry{
+		{Version: 1, SchemaID: 1, SchemaType: schema.SchemaTypeAvro, Initial: true, Changes: []schemadiff.Change{}},
+		{
+			Version: 2, SchemaID: 4, SchemaType: schema.SchemaTypeAvro, Date: &date, Author: "alice", Reason: "add total", Ticket: "ORD-7",
+			Changes: []schemadiff.Change{{Kind: schemadiff.FieldAdded, Path: "$.total", After: "long"}},
+		},
+	}}
+
+	var b bytes.Buffer
+	if err := changelog.Write(&b, FormatMarkdown); err != nil {
+		t.Fatal(err)
+	}
+	mustEqual(t, b.String(), "# Changelog of orders-value\n"+
+		"\n## Version 1\n\nSchema 1 (AVRO).\n\nInitial version.\n"+
+		"\n## Version 2\n\nSchema 4 (AVRO), registered on 2026-03-02 10:00 UTC by alice.\n\nReason: add total (ORD-7)\n\n"+
+		"| Change | Path | Before | After |\n| --- | --- | --- | --- |\n| field-added | `$.total` |  | `long` |\n")
+
+	b.Reset()
+	if err := (&Changelog{Subject: "orders-value", Entries: []Entry{}, Undated: 2}).Write(&b, FormatMarkdown); err != nil {
+		t.Fatal(err)
+	}
+	mustEqual(t, b.String(), "# Changelog of orders-value\n\nNo versions.\n\n2 versions without a registration date are left out.\n")
+}
+
+func TestParseFormat(t *testing.T) {
+	format, err := ParseFormat("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustEqual(t, format, FormatMarkdown)
+
+	if _, err = ParseFormat("html"); err == nil {
+		t.Fatal("expected an unknown format error")
+	}
+}
@@ -0,0 +1,105 @@
+package changelog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ybalcin/event-schema-manager/pkg/schemadiff"
+)
+
+// Format is an output format of a changelog
+type Format string
+
+const (
+	FormatMarkdown Format = "markdown"
+	FormatJSON     Format = "json"
+)
+
+const dateLayout = "2006-01-02 15:04 MST"
+
+var ErrUnknownFormat = errors.New("unknown changelog format")
+
+// ParseFormat parses a format, the empty string is markdown
+func ParseFormat(format string) (Format, error) {
+	switch f := Format(strings.ToLower(format)); f {
+	case "":
+		return FormatMarkdown, nil
+	case FormatMarkdown, FormatJSON:
+		return f, nil
+	}
+
+	return "", fmt.Errorf("%w: %s", ErrUnknownFormat, format)
+}
+
+// Write writes the changelog in format
+func (c *Changelog) Write(w io.Writer, format Format) error {
+	if format == FormatJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(c)
+	}
+
+	return c.writeMarkdown(w)
+}
+
+// writeMarkdown writes a section for every version with its audit metadata and its changes
+func (c *Changelog) writeMarkdown(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Changelog of %s\n", c.Subject)
+	if len(c.Entries) == 0 {
+		b.WriteString("\nNo versions.\n")
+	}
+	if c.Undated > 0 {
+		fmt.Fprintf(&b, "\n%d versions without a registration date are left out.\n", c.Undated)
+	}
+
+	for _, e := range c.Entries {
+		fmt.Fprintf(&b, "\n## Version %d\n\n", e.Version)
+		b.WriteString(e.registration() + "\n")
+		if e.Reason != "" || e.Ticket != "" {
+			b.WriteString("\n" + e.reason() + "\n")
+		}
+		b.WriteString("\n")
+
+		switch {
+		case e.Initial:
+			b.WriteString("Initial version.\n")
+		case e.Diff != "":
+			b.WriteString("```diff\n" + strings.TrimSuffix(e.Diff, "\n") + "\n```\n")
+		default:
+			if err := (&schemadiff.Diff{Changes: e.Changes}).Write(&b, schemadiff.FormatMarkdown); err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// registration describes the schema of the entry and who registered it when
+func (e Entry) registration() string {
+	s := fmt.Sprintf("Schema %d (%s)", e.SchemaID, e.SchemaType)
+	if e.Date != nil {
+		s += ", registered on " + e.Date.UTC().Format(dateLayout)
+	}
+	if e.Author != "" {
+		s += " by " + e.Author
+	}
+
+	return s + "."
+}
+
+func (e Entry) reason() string {
+	switch {
+	case e.Ticket == "":
+		return "Reason: " + e.Reason
+	case e.Reason == "":
+		return "Ticket: " + e.Ticket
+	}
+
+	return fmt.Sprintf("Reason: %s (%s)", e.Reason, e.Ticket)
+}
@@ -0,0 +1,197 @@
+package linting
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ybalcin/event-schema-manager/internal/core/domain/schema"
+	"github.com/ybalcin/event-schema-manager/pkg/avro"
+)
+
+type (
+	Severity string
+
+	// LintRule checks a parsed schema and reports its violations
+	LintRule interface {
+		Name() string
+		Check(subject schema.Subject, sc *avro.Schema) []Violation
+	}
+
+	// RuleFactory creates a rule from its parameters
+	RuleFactory func(params map[string]string) (LintRule, error)
+
+	Violation struct {
+		Rule     string   `json:"rule"`
+		Severity Severity `json:"severity"`
+		Path     string   `json:"path"`
+		Message  string   `json:"message"`
+	}
+
+	Report struct {
+		Violations []Violation `json:"violations"`
+	}
+
+	// RuleConfig enables a rule with a severity
+	RuleConfig struct {
+		Name     string
+		Severity Severity
+		Params   map[string]string
+	}
+
+	// Config is the rule set of the subjects starting with SubjectPrefix, the empty prefix matches every subject
+	Config struct {
+		SubjectPrefix string
+		Rules         []RuleConfig
+	}
+
+	// Linter runs the rule set of the longest matching subject prefix
+	Linter struct {
+		ruleSets []ruleSet
+	}
+
+	ruleSet struct {
+		subjectPrefix string
+		rules         []configuredRule
+	}
+
+	configuredRule struct {
+		rule     LintRule
+		severity Severity
+	}
+
+	// Error is returned when a schema has violations with error severity
+	Error struct {
+		Subject schema.Subject
+		Report  Report
+	}
+)
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+	SeverityInfo    Severity = "info"
+)
+
+var ErrLintFailed = errors.New("schema lint failed")
+
+var factories = map[string]RuleFactory{}
+
+// Register makes a rule available to configurations by its name
+func Register(name string, factory RuleFactory) {
+	factories[name] = factory
+}
+
+// NewLinter creates a linter from rule configurations, unknown rules and severities are errors
+func NewLinter(configs []Config) (*Linter, error) {
+	l := &Linter{}
+
+	for _, cfg := range configs {
+		rs := ruleSet{subjectPrefix: cfg.SubjectPrefix}
+
+		for _, rc := range cfg.Rules {
+			factory, ok := factories[rc.Name]
+			if !ok {
+				return nil, fmt.Errorf("linting: unknown rule %s", rc.Name)
+			}
+
+			severity, err := parseSeverity(rc.Severity)
+			if err != nil {
+				return nil, err
+			}
+
+			rule, err := factory(rc.Params)
+			if err != nil {
+				return nil, fmt.Errorf("linting: rule %s is not valid, trace: %v", rc.Name, err)
+			}
+
+			rs.rules = append(rs.rules, configuredRule{rule: rule, severity: severity})
+		}
+
+		l.ruleSets = append(l.ruleSets, rs)
+	}
+
+	// longest prefixes first
+	sort.SliceStable(l.ruleSets, func(i, j int) bool {
+		return len(l.ruleSets[i].subjectPrefix) > len(l.ruleSets[j].subjectPrefix)
+	})
+
+	return l, nil
+}
+
+func parseSeverity(severity Severity) (Severity, error) {
+	switch s := Severity(strings.ToLower(string(severity))); s {
+	case "":
+		return SeverityError, nil
+	case SeverityError, SeverityWarning, SeverityInfo:
+		return s, nil
+	}
+
+	return "", fmt.Errorf("linting: unknown severity %s", severity)
+}
+
+// Lint checks sc against the rule set of its subject, schemas other than avro are not linted
+func (l *Linter) Lint(sc *schema.Schema) (Report, error) {
+	report := Report{Violations: []Violation{}}
+	if l == nil || sc.Type() != schema.SchemaTypeAvro {
+		return report, nil
+	}
+
+	rs := l.ruleSetOf(sc.Subject())
+	if rs == nil {
+		return report, nil
+	}
+
+	parsed, err := avro.Parse(sc.Content())
+	if err != nil {
+		return report, fmt.Errorf("%w: %v", schema.ErrInvalidSchema, err)
+	}
+
+	for _, cr := range rs.rules {
+		for _, v := range cr.rule.Check(sc.Subject(), parsed) {
+			v.Rule = cr.rule.Name()
+			v.Severity = cr.severity
+			report.Violations = append(report.Violations, v)
+		}
+	}
+
+	return report, nil
+}
+
+func (l *Linter) ruleSetOf(subject schema.Subject) *ruleSet {
+	for i, rs := range l.ruleSets {
+		if strings.HasPrefix(subject.String(), rs.subjectPrefix) {
+			return &l.ruleSets[i]
+		}
+	}
+
+	return nil
+}
+
+// HasErrors returns true if the report has a violation with error severity
+func (r Report) HasErrors() bool {
+	for _, v := range r.Violations {
+		if v.Severity == SeverityError {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (err *Error) Error() string {
+	messages := make([]string, 0, len(err.Report.Violations))
+	for _, v := range err.Report.Violations {
+		if v.Severity == SeverityError {
+			messages = append(messages, fmt.Sprintf("%s: %s %s", v.Rule, v.Path, v.Message))
+		}
+	}
+
+	return fmt.Sprintf("%v: %s, %s", ErrLintFailed, err.Subject, strings.Join(messages, "; "))
+}
+
+// Is makes errors.Is(err, ErrLintFailed) true
+func (err *Error) Is(target error) bool {
+	return target == ErrLintFailed
+}
@@ -0,0 +1,112 @@
+package linting
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/ybalcin/event-schema-manager/internal/core/domain/schema"
+)
+
+const lintSchema = `{
+	"type": "record",
+	"name": "order_created",
+	"namespace": "com.acme.orders",
+	"fields": [
+		{"name": "id", "type": "string", "doc": "Order id"},
+		{"name": "note", "type": ["null", "string"]},
+		{"name": "status", "type": {"type": "enum", "name": "Status", "namespace": "com.other", "symbols": ["NEW", "inProgress"]}, "doc": "Status"}
+	]
+}`
+
+func mustSchema(t *testing.T, subject, content string) *schema.Schema {
+	t.Helper()
+
+	sc, err := schema.NewSchema(schema.Subject(subject), schema.SchemaTypeAvro, content)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return sc
+}
+
+func TestLinter_Lint(t *testing.T) {
+	linter, err := NewLinter([]Config{
+		{SubjectPrefix: "", Rules: []RuleConfig{{Name: RuleFieldDoc, Severity: SeverityWarning}}},
+		{SubjectPrefix: "orders-", Rules: []RuleConfig{
+			{Name: RuleFieldDoc, Severity: SeverityWarning},
+			{Name: RuleNullableDefault},
+			{Name: RuleEnumSymbolsUpperSnake, Severity: SeverityError},
+			{Name: RuleRecordNamePascalCase, Severity: SeverityInfo},
+			{Name: RuleNamespacePrefix, Severity: SeverityError, Params: map[string]string{"prefix": "com.acme"}},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := linter.Lint(mustSchema(t, "orders-value", lintSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Violation{
+		{RuleFieldDoc, SeverityWarning, "$.note", "field has no doc"},
+		{RuleNullableDefault, SeverityError, "$.note", "nullable field has no default"},
+		{RuleEnumSymbolsUpperSnake, SeverityError, "$.status", "symbol inProgress of enum com.other.Status is not UPPER_SNAKE_CASE"},
+		{RuleRecordNamePascalCase, SeverityInfo, "$", "record name order_created is not PascalCase"},
+		{RuleNamespacePrefix, SeverityError, "$.status", `namespace "com.other" of Status does not start with com.acme`},
+	}
+	if !reflect.DeepEqual(report.Violations, expected) {
+		t.Errorf("expected %#v, but got %#v", expected, report.Violations)
+	}
+	if !report.HasErrors() {
+		t.Error("report must have errors")
+	}
+
+	// other subjects use the default rule set
+	report, err = linter.Lint(mustSchema(t, "payments-value", lintSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Violations) != 1 || report.HasErrors() {
+		t.Errorf("unexpected violations %#v", report.Violations)
+	}
+
+	// only avro schemas are linted
+	json, err := schema.NewSchema("orders-value", schema.SchemaTypeJSON, `{"type": "object"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report, err = linter.Lint(json); err != nil || len(report.Violations) != 0 {
+		t.Errorf("unexpected lint result %#v, %v", report, err)
+	}
+}
+
+func TestNewLinter_InvalidConfig(t *testing.T) {
+	invalid := [][]Config{
+		{{Rules: []RuleConfig{{Name: "unknown"}}}},
+		{{Rules: []RuleConfig{{Name: RuleFieldDoc, Severity: "fatal"}}}},
+		{{Rules: []RuleConfig{{Name: RuleNamespacePrefix}}}},
+	}
+
+	for _, configs := range invalid {
+		if _, err := NewLinter(configs); err == nil {
+			t.Errorf("%#v must be invalid", configs)
+		}
+	}
+}
+
+func TestError(t *testing.T) {
+	err := error(&Error{Subject: "orders-value", Report: Report{Violations: []Violation{
+		{RuleFieldDoc, SeverityError, "$.id", "field has no doc"},
+		{RuleRecordNamePascalCase, SeverityWarning, "$", "record name x is not PascalCase"},
+	}}})
+
+	if !errors.Is(err, ErrLintFailed) {
+		t.Error("error must be ErrLintFailed")
+	}
+	if err.Error() != "schema lint failed: orders-value, field-doc: $.id field has no doc" {
+		t.Errorf("unexpected message %s", err.Error())
+	}
+}
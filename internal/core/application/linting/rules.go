@@ -0,0 +1,163 @@
+package linting
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/ybalcin/event-schema-manager/internal/core/domain/schema"
+	"github.com/ybalcin/event-schema-manager/pkg/avro"
+)
+
+type (
+	// rule is a LintRule implemented by a function
+	rule struct {
+		name  string
+		check func(subject schema.Subject, sc *avro.Schema) []Violation
+	}
+)
+
+const (
+	RuleFieldDoc              = "field-doc"
+	RuleNamespacePrefix       = "namespace-prefix"
+	RuleNullableDefault       = "nullable-default"
+	RuleEnumSymbolsUpperSnake = "enum-symbols-upper-snake"
+	RuleRecordNamePascalCase  = "record-name-pascal-case"
+
+	namespacePrefixParam = "prefix"
+)
+
+var (
+	upperSnakeCase = regexp.MustCompile(`^[A-Z][A-Z0-9]*(_[A-Z0-9]+)*$`)
+	pascalCase     = regexp.MustCompile(`^[A-Z][a-zA-Z0-9]*$`)
+)
+
+func init() {
+	Register(RuleFieldDoc, noParams(RuleFieldDoc, checkFieldDoc))
+	Register(RuleNullableDefault, noParams(RuleNullableDefault, checkNullableDefault))
+	Register(RuleEnumSymbolsUpperSnake, noParams(RuleEnumSymbolsUpperSnake, checkEnumSymbols))
+	Register(RuleRecordNamePascalCase, noParams(RuleRecordNamePascalCase, checkRecordNames))
+	Register(RuleNamespacePrefix, newNamespacePrefixRule)
+}
+
+func (r rule) Name() string {
+	return r.name
+}
+
+func (r rule) Check(subject schema.Subject, sc *avro.Schema) []Violation {
+	return r.check(subject, sc)
+}
+
+func noParams(name string, check func(subject schema.Subject, sc *avro.Schema) []Violation) RuleFactory {
+	return func(params map[string]string) (LintRule, error) {
+		return rule{name: name, check: check}, nil
+	}
+}
+
+// eachField calls fn with every field of every record of sc and the path of the field
+func eachField(sc *avro.Schema, fn func(path string, field *avro.Field)) {
+	sc.Walk(func(path string, node *avro.Schema, field *avro.Field) bool {
+		if node.Type == avro.Record || node.Type == avro.Error {
+			for _, f := range node.Fields {
+				fn(path+"."+f.Name, f)
+			}
+		}
+		return true
+	})
+}
+
+// eachNamed calls fn with every named type defined in sc
+func eachNamed(sc *avro.Schema, fn func(path string, node *avro.Schema)) {
+	sc.Walk(func(path string, node *avro.Schema, field *avro.Field) bool {
+		if node.Type.IsNamed() {
+			fn(path, node)
+		}
+		return true
+	})
+}
+
+// checkFieldDoc reports fields without a doc
+func checkFieldDoc(subject schema.Subject, sc *avro.Schema) []Violation {
+	var violations []Violation
+	eachField(sc, func(path string, field *avro.Field) {
+		if strings.TrimSpace(field.Doc) == "" {
+			violations = append(violations, Violation{Path: path, Message: "field has no doc"})
+		}
+	})
+
+	return violations
+}
+
+// checkNullableDefault reports nullable fields without a default
+func checkNullableDefault(subject schema.Subject, sc *avro.Schema) []Violation {
+	var violations []Violation
+	eachField(sc, func(path string, field *avro.Field) {
+		if field.Type.IsNullable() && !field.HasDefault {
+			violations = append(violations, Violation{Path: path, Message: "nullable field has no default"})
+		}
+	})
+
+	return violations
+}
+
+// checkEnumSymbols reports enum symbols that are not UPPER_SNAKE_CASE
+func checkEnumSymbols(subject schema.Subject, sc *avro.Schema) []Violation {
+	var violations []Violation
+	eachNamed(sc, func(path string, node *avro.Schema) {
+		if node.Type != avro.Enum {
+			return
+		}
+		for _, symbol := range node.Symbols {
+			if !upperSnakeCase.MatchString(symbol) {
+				violations = append(violations, Violation{
+					Path:    path,
+					Message: fmt.Sprintf("symbol %s of enum %s is not UPPER_SNAKE_CASE", symbol, node.Name),
+				})
+			}
+		}
+	})
+
+	return violations
+}
+
+// checkRecordNames reports record names that are not PascalCase
+func checkRecordNames(subject schema.Subject, sc *avro.Schema) []Violation {
+	var violations []Violation
+	eachNamed(sc, func(path string, node *avro.Schema) {
+		if (node.Type == avro.Record || node.Type == avro.Error) && !pascalCase.MatchString(node.ShortName()) {
+			violations = append(violations, Violation{
+				Path:    path,
+				Message: fmt.Sprintf("record name %s is not PascalCase", node.ShortName()),
+			})
+		}
+	})
+
+	return violations
+}
+
+// newNamespacePrefixRule creates the rule reporting named types whose namespace does not start with the prefix param,
+// e.g. the namespace of the team
+func newNamespacePrefixRule(params map[string]string) (LintRule, error) {
+	prefix := params[namespacePrefixParam]
+	if prefix == "" {
+		return nil, fmt.Errorf("%s param is required", namespacePrefixParam)
+	}
+
+	return rule{
+		name: RuleNamespacePrefix,
+		check: func(subject schema.Subject, sc *avro.Schema) []Violation {
+			var violations []Violation
+			eachNamed(sc, func(path string, node *avro.Schema) {
+				ns := node.Namespace()
+				if ns != prefix && !strings.HasPrefix(ns, prefix+".") {
+					violations = append(violations, Violation{
+						Path:    path,
+						Message: fmt.Sprintf("namespace %q of %s does not start with %s", ns, node.ShortName(), prefix),
+					})
+				}
+			})
+
+			return violations
+		},
+	}, nil
+}
@@ -0,0 +1,357 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/ybalcin/event-schema-manager/internal/core/domain/schema"
+)
+
+type (
+	// Record is an event waiting in the outbox, ID is the id of the event which consumers use to deduplicate
+	// since a record may be delivered more than once
+	Record struct {
+		ID         string           `json:"id"`
+		Type       schema.EventType `json:"type"`
+		Subject    schema.Subject   `json:"subject"`
+		OccurredAt time.Time        `json:"occurredAt"`
+		Payload    json.RawMessage  `json:"payload"`
+		Attempts   int              `json:"attempts"`
+		LastError  string           `json:"lastError,omitempty"`
+		// Staged is set on the record of a change that is about to be made, it is not delivered and is replaced
+		// by the events of the change once it is made. Its payload describes the change for a Recoverer.
+		Staged bool `json:"staged,omitempty"`
+		// Parked is set on a record that is not delivered anymore, after its attempts are exhausted
+		// or if its staged change is never confirmed
+		Parked bool `json:"parked,omitempty"`
+	}
+
+	// Store persists records durably, Append must not return before the records survive a crash
+	Store interface {
+		// Append adds records in order, records with an id appended before are ignored
+		Append(records ...Record) error
+		// Confirm replaces the staged record id by records in its place, none of them removes it
+		Confirm(id string, records ...Record) error
+		// Pending returns at most limit undelivered records which are not parked and not of the subjects in skip,
+		// in append order
+		Pending(limit int, skip map[schema.Subject]bool) ([]Record, error)
+		// Parked returns the parked records in append order
+		Parked() ([]Record, error)
+		MarkDelivered(id string) error
+		MarkFailed(id string, cause error) error
+		MarkParked(id string, cause error) error
+	}
+
+	// Recoverer finds out if a change staged before a crash was made, it returns the events of the change if it
+	// was made and none if it was not
+	Recoverer interface {
+		Recover(eventType schema.EventType, subject schema.Subject, change []byte) ([]schema.Event, error)
+	}
+
+	// Outbox records events durably and relays them to a publisher, e.g. the event bus, with at-least-once delivery
+	Outbox struct {
+		store       Store
+		publisher   schema.EventPublisher
+		interval    time.Duration
+		batchSize   int
+		maxAttempts int
+		recoverer   Recoverer
+		wake        chan struct{}
+	}
+
+	Option func(*Outbox)
+)
+
+const (
+	defaultRelayInterval = time.Second
+	defaultBatchSize     = 100
+	defaultMaxAttempts   = 20
+
+	// stageTimeout is how long a staged change may take, a staged record older than that is left by a crash
+	// between the change and its confirmation and is recovered, or parked without a recoverer
+	stageTimeout = time.Minute
+)
+
+var errUnconfirmed = errors.New("staged change is not confirmed and there is no recoverer to find out if it was made")
+
+// WithRelayInterval sets how often the relay retries pending records when it is not woken up by new records
+func WithRelayInterval(interval time.Duration) Option {
+	return func(o *Outbox) {
+		o.interval = interval
+	}
+}
+
+// WithMaxAttempts sets the number of failed deliveries after which a record is parked
+func WithMaxAttempts(attempts int) Option {
+	return func(o *Outbox) {
+		o.maxAttempts = attempts
+	}
+}
+
+// WithRecoverer makes the outbox recover the staged changes left by a crash with recoverer, which checks if they
+// were made, instead of parking them
+func WithRecoverer(recoverer Recoverer) Option {
+	return func(o *Outbox) {
+		o.recoverer = recoverer
+	}
+}
+
+// WithBatchSize sets the number of records the relay reads from the store at once
+func WithBatchSize(size int) Option {
+	return func(o *Outbox) {
+		o.batchSize = size
+	}
+}
+
+func NewOutbox(store Store, publisher schema.EventPublisher, opts ...Option) *Outbox {
+	o := &Outbox{
+		store:       store,
+		publisher:   publisher,
+		interval:    defaultRelayInterval,
+		batchSize:   defaultBatchSize,
+		maxAttempts: defaultMaxAttempts,
+		wake:        make(chan struct{}, 1),
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// NewRecord encodes event as a record
+func NewRecord(event schema.Event) (Record, error) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return Record{}, fmt.Errorf("event %s could not be encoded, trace: %v", event.EventID(), err)
+	}
+
+	return Record{
+		ID:         event.EventID(),
+		Type:       event.EventType(),
+		Subject:    event.EventSubject(),
+		OccurredAt: event.OccurredAt(),
+		Payload:    payload,
+	}, nil
+}
+
+func recordsOf(events []schema.Event) ([]Record, error) {
+	records := make([]Record, len(events))
+	for i, event := range events {
+		record, err := NewRecord(event)
+		if err != nil {
+			return nil, err
+		}
+		records[i] = record
+	}
+
+	return records, nil
+}
+
+// Publish stores events in the outbox and wakes the relay up, the events are delivered by Relay
+func (o *Outbox) Publish(events ...schema.Event) error {
+	records, err := recordsOf(events)
+	if err != nil {
+		return err
+	}
+
+	if err = o.store.Append(records...); err != nil {
+		return fmt.Errorf("outbox: events could not be stored, trace: %v", err)
+	}
+	o.wakeUp()
+
+	return nil
+}
+
+// Stage stores a record of a change of subject before it is made, so the events of the change are not lost
+// if the process stops after the change. The events of the subject wait until the returned id is confirmed.
+// change describes the change for the recoverer in case it is never confirmed.
+func (o *Outbox) Stage(eventType schema.EventType, subject schema.Subject, change []byte) (string, error) {
+	record := Record{
+		ID:         schema.NewEventMetadata().ID,
+		Type:       eventType,
+		Subject:    subject,
+		OccurredAt: time.Now(),
+		Payload:    change,
+		Staged:     true,
+	}
+	if err := o.store.Append(record); err != nil {
+		return "", fmt.Errorf("outbox: change could not be staged, trace: %v", err)
+	}
+
+	return record.ID, nil
+}
+
+// Confirm replaces the staged record id by events once the change is made, without events the change
+// is discarded because it failed
+func (o *Outbox) Confirm(id string, events ...schema.Event) error {
+	records, err := recordsOf(events)
+	if err != nil {
+		return err
+	}
+
+	if err = o.store.Confirm(id, records...); err != nil {
+		return fmt.Errorf("outbox: staged change %s could not be confirmed, trace: %v", id, err)
+	}
+	o.wakeUp()
+
+	return nil
+}
+
+// Parked returns the records which are not delivered anymore
+func (o *Outbox) Parked() ([]Record, error) {
+	return o.store.Parked()
+}
+
+func (o *Outbox) wakeUp() {
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Relay delivers pending records until ctx is done, records left by a crash are delivered on start
+func (o *Outbox) Relay(ctx context.Context) {
+	ticker := time.NewTicker(o.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := o.Flush(); err != nil {
+			log.Printf("outbox: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-o.wake:
+		}
+	}
+}
+
+// Flush delivers pending records in order until none of them can be delivered. A record that fails or waits
+// for its change to be confirmed holds the later records of its subject back, so events of a subject are never
+// delivered out of order, and the records of other subjects are delivered. A failed record is retried by the next
+// flush, it is parked after maxAttempts so it does not hold its subject back forever. The records of blocked
+// subjects are skipped by the store, so a batch full of them does not hide the records after it. A staged change
+// left by a crash is replaced by the events the recoverer finds for it, it is retried like a delivery if that fails.
+func (o *Outbox) Flush() error {
+	// blocked are the subjects having a record which can not be delivered by this flush
+	blocked := make(map[schema.Subject]bool)
+	var failure error
+	for {
+		records, err := o.store.Pending(o.batchSize, blocked)
+		if err != nil {
+			return fmt.Errorf("pending records could not be read, trace: %v", err)
+		}
+
+		// the next batch is read when records are delivered or subjects are blocked, both change it
+		progress := false
+	batch:
+		for _, record := range records {
+			if blocked[record.Subject] {
+				continue
+			}
+
+			var cause error
+			switch {
+			case record.Staged && time.Since(record.OccurredAt) < stageTimeout:
+				blocked[record.Subject] = true
+				progress = true
+				continue
+			case record.Staged && o.recoverer == nil:
+				if err = o.park(record, errUnconfirmed); err != nil {
+					return err
+				}
+				progress = true
+				continue
+			case record.Staged:
+				if cause = o.recover(record); cause == nil {
+					// the events of the change took the place of the record, they are read again to keep the order
+					progress = true
+					break batch
+				}
+			default:
+				if cause = o.deliver(record); cause == nil {
+					// a crash before this point delivers the record again, consumers deduplicate by its id
+					if err = o.store.MarkDelivered(record.ID); err != nil {
+						return fmt.Errorf("record %s could not be marked as delivered, trace: %v", record.ID, err)
+					}
+					progress = true
+					continue
+				}
+			}
+
+			record.Attempts++
+			if markErr := o.store.MarkFailed(record.ID, cause); markErr != nil {
+				log.Printf("outbox: failure of record %s could not be stored, trace: %v", record.ID, markErr)
+			}
+
+			if record.Attempts >= o.maxAttempts {
+				if err = o.park(record, cause); err != nil {
+					return err
+				}
+				progress = true
+				continue
+			}
+
+			blocked[record.Subject] = true
+			progress = true
+			if failure == nil {
+				failure = fmt.Errorf("record %s failed, attempt %d, trace: %v", record.ID, record.Attempts, cause)
+			}
+		}
+
+		if !progress {
+			return failure
+		}
+	}
+}
+
+// park stops delivering record, it is kept in the store to be inspected
+func (o *Outbox) park(record Record, cause error) error {
+	if err := o.store.MarkParked(record.ID, cause); err != nil {
+		return fmt.Errorf("record %s could not be parked, trace: %v", record.ID, err)
+	}
+	if record.Attempts == 0 {
+		log.Printf("outbox: %s %s of subject %s is parked, trace: %v", record.Type, record.ID, record.Subject, cause)
+	} else {
+		log.Printf("outbox: %s %s of subject %s is parked after %d attempts, trace: %v",
+			record.Type, record.ID, record.Subject, record.Attempts, cause)
+	}
+
+	return nil
+}
+
+// recover replaces a staged change left by a crash by the events the recoverer finds for it, none if the change
+// was not made
+func (o *Outbox) recover(record Record) error {
+	events, err := o.recoverer.Recover(record.Type, record.Subject, record.Payload)
+	if err != nil {
+		return err
+	}
+
+	records, err := recordsOf(events)
+	if err != nil {
+		return err
+	}
+	if err = o.store.Confirm(record.ID, records...); err != nil {
+		return err
+	}
+	log.Printf("outbox: staged %s %s of subject %s is recovered with %d events", record.Type, record.ID, record.Subject, len(events))
+
+	return nil
+}
+
+func (o *Outbox) deliver(record Record) error {
+	event, err := schema.UnmarshalEvent(record.Type, record.Payload)
+	if err != nil {
+		return err
+	}
+
+	return o.publisher.Publish(event)
+}
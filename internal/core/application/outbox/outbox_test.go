@@ -0,0 +1,367 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ybalcin/event-schema-manager/internal/core/domain/schema"
+)
+
+type (
+	memoryStore struct {
+		mu      sync.Mutex
+		records []Record
+	}
+
+	publisherFunc func(events ...schema.Event) error
+)
+
+func (s *memoryStore) Append(records ...Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.records = append(s.records, records...)
+	return nil
+}
+
+func (s *memoryStore) Confirm(id string, records ...Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, r := range s.records {
+		if r.ID == id {
+			s.records = append(s.records[:i], append(records, s.records[i+1:]...)...)
+			return nil
+		}
+	}
+	return errors.New("record not found")
+}
+
+func (s *memoryStore) Pending(limit int, skip map[schema.Subject]bool) ([]Record, error) {
+	return s.filter(false, limit, skip), nil
+}
+
+func (s *memoryStore) Parked() ([]Record, error) {
+	return s.filter(true, 0, nil), nil
+}
+
+func (s *memoryStore) filter(parked bool, limit int, skip map[schema.Subject]bool) []Record {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var records []Record
+	for _, r := range s.records {
+		if r.Parked == parked && !skip[r.Subject] && (limit <= 0 || len(records) < limit) {
+			records = append(records, r)
+		}
+	}
+	return records
+}
+
+func (s *memoryStore) MarkDelivered(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, r := range s.records {
+		if r.ID == id {
+			s.records = append(s.records[:i], s.records[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (s *memoryStore) MarkFailed(id string, cause error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, r := range s.records {
+		if r.ID == id {
+			s.records[i].Attempts++
+			s.records[i].LastError = cause.Error()
+		}
+	}
+	return nil
+}
+
+func (s *memoryStore) MarkParked(id string, cause error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, r := range s.records {
+		if r.ID == id {
+			s.records[i].Parked = true
+			s.records[i].LastError = cause.Error()
+		}
+	}
+	return nil
+}
+
+func (f publisherFunc) Publish(events ...schema.Event) error {
+	return f(events...)
+}
+
+type recovererFunc func(eventType schema.EventType, subject schema.Subject, change []byte) ([]schema.Event, error)
+
+func (f recovererFunc) Recover(eventType schema.EventType, subject schema.Subject, change []byte) ([]schema.Event, error) {
+	return f(eventType, subject, change)
+}
+
+func mustEqual(t *testing.T, actual, expected interface{}) {
+	t.Helper()
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %#v, but got %#v", expected, actual)
+	}
+}
+
+func registered(subject schema.Subject, version schema.SchemaVersion) schema.SchemaRegistered {
+	return schema.SchemaRegistered{
+		EventMetadata: schema.NewEventMetadata(),
+		Subject:       subject,
+		Version:       version,
+		SchemaID:      schema.SchemaID(version),
+		SchemaType:    schema.SchemaTypeAvro,
+	}
+}
+
+func TestOutbox_Flush_RetriesInOrder(t *testing.T) {
+	store := &memoryStore{}
+	fail := true
+	var delivered []schema.Event
+	o := NewOutbox(store, publisherFunc(func(events ...schema.Event) error {
+		if fail {
+			fail = false
+			return errors.New("bus is down")
+		}
+		delivered = append(delivered, events...)
+		return nil
+	}))
+
+	first, second := registered("orders-value", 1), registered("orders-value", 2)
+	if err := o.Publish(first, second); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := o.Flush(); err == nil {
+		t.Fatal("first flush must fail")
+	}
+	mustEqual(t, len(delivered), 0)
+	mustEqual(t, store.records[0].Attempts, 1)
+	mustEqual(t, store.records[0].LastError, "bus is down")
+
+	if err := o.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	// events are decoded to the values published
+	mustEqual(t, delivered, []schema.Event{first, second})
+	mustEqual(t, len(store.records), 0)
+}
+
+func TestOutbox_Relay(t *testing.T) {
+	store := &memoryStore{}
+	delivered := make(chan schema.Event, 2)
+	o := NewOutbox(store, publisherFunc(func(events ...schema.Event) error {
+		for _, e := range events {
+			delivered <- e
+		}
+		return nil
+	}), WithRelayInterval(time.Hour))
+
+	// a record left by a crash is delivered on start
+	left := registered("orders-value", 1)
+	record, err := NewRecord(left)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = store.Append(record); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		o.Relay(ctx)
+	}()
+
+	// a new record wakes the relay up before the interval
+	published := registered("orders-value", 2)
+	if err = o.Publish(published); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []schema.Event{left, published} {
+		select {
+		case e := <-delivered:
+			mustEqual(t, e, expected)
+		case <-time.After(5 * time.Second):
+			t.Fatal("event is not delivered")
+		}
+	}
+
+	cancel()
+	<-done
+}
+
+func TestOutbox_Flush_StagedChanges(t *testing.T) {
+	store := &memoryStore{}
+	var delivered []schema.Event
+	o := NewOutbox(store, publisherFunc(func(events ...schema.Event) error {
+		delivered = append(delivered, events...)
+		return nil
+	}))
+
+	id, err := o.Stage(schema.EventSchemaRegistered, "orders-value", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	discarded, err := o.Stage(schema.EventSchemaRegistered, "orders-value", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	later, other := registered("orders-value", 2), registered("payments-value", 1)
+	if err = o.Publish(later, other); err != nil {
+		t.Fatal(err)
+	}
+
+	// the staged change holds the later events of its subject back
+	if err = o.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	mustEqual(t, delivered, []schema.Event{other})
+
+	// a failed change is discarded, a confirmed one is delivered in its place
+	if err = o.Confirm(discarded); err != nil {
+		t.Fatal(err)
+	}
+	made := registered("orders-value", 1)
+	if err = o.Confirm(id, made); err != nil {
+		t.Fatal(err)
+	}
+	if err = o.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	mustEqual(t, delivered, []schema.Event{other, made, later})
+	mustEqual(t, len(store.records), 0)
+}
+
+func TestOutbox_Flush_ReadsPastBlockedSubjects(t *testing.T) {
+	store := &memoryStore{}
+	var delivered []schema.Event
+	o := NewOutbox(store, publisherFunc(func(events ...schema.Event) error {
+		delivered = append(delivered, events...)
+		return nil
+	}), WithBatchSize(2))
+
+	// the first batch holds only records of a subject waiting for its staged change
+	if _, err := o.Stage(schema.EventSchemaRegistered, "orders-value", nil); err != nil {
+		t.Fatal(err)
+	}
+	held, other := registered("orders-value", 2), registered("payments-value", 1)
+	if err := o.Publish(held, other); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := o.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	mustEqual(t, delivered, []schema.Event{other})
+}
+
+func TestOutbox_Flush_RecoversStagedChanges(t *testing.T) {
+	store := &memoryStore{}
+	var delivered []schema.Event
+	made := registered("orders-value", 1)
+	unavailable := true
+	o := NewOutbox(store, publisherFunc(func(events ...schema.Event) error {
+		delivered = append(delivered, events...)
+		return nil
+	}), WithRecoverer(recovererFunc(func(eventType schema.EventType, subject schema.Subject, change []byte) ([]schema.Event, error) {
+		if unavailable {
+			return nil, errors.New("registry is unavailable")
+		}
+		if string(change) == "made" {
+			return []schema.Event{made}, nil
+		}
+		return nil, nil
+	})))
+
+	// staged changes left by a crash, one of them was made
+	crashed := time.Now().Add(-2 * stageTimeout)
+	if err := store.Append(
+		Record{ID: "made", Type: schema.EventSchemaRegistered, Subject: "orders-value", OccurredAt: crashed, Payload: []byte("made"), Staged: true},
+		Record{ID: "failed", Type: schema.EventSchemaRegistered, Subject: "payments-value", OccurredAt: crashed, Payload: []byte("failed"), Staged: true},
+	); err != nil {
+		t.Fatal(err)
+	}
+	later := registered("orders-value", 2)
+	if err := o.Publish(later); err != nil {
+		t.Fatal(err)
+	}
+
+	// a failed recovery is retried like a delivery and holds the subject back
+	if err := o.Flush(); err == nil {
+		t.Fatal("first flush must fail")
+	}
+	mustEqual(t, len(delivered), 0)
+	mustEqual(t, store.records[0].Attempts, 1)
+
+	unavailable = false
+	if err := o.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	mustEqual(t, delivered, []schema.Event{made, later})
+	mustEqual(t, len(store.records), 0)
+}
+
+func TestOutbox_Flush_ParksRecords(t *testing.T) {
+	store := &memoryStore{}
+	var delivered []schema.Event
+	o := NewOutbox(store, publisherFunc(func(events ...schema.Event) error {
+		for _, e := range events {
+			if e.EventSubject() == "orders-value" {
+				return errors.New("broker rejected the event")
+			}
+		}
+		delivered = append(delivered, events...)
+		return nil
+	}), WithMaxAttempts(2))
+
+	// a staged change left by a crash is parked since it is unknown if it was made
+	if err := store.Append(Record{ID: "left", Type: schema.EventSchemaRegistered, Subject: "payments-value",
+		OccurredAt: time.Now().Add(-2 * stageTimeout), Staged: true}); err != nil {
+		t.Fatal(err)
+	}
+	failing, next := registered("orders-value", 1), registered("payments-value", 1)
+	if err := o.Publish(failing, next); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := o.Flush(); err == nil {
+		t.Fatal("first flush must fail")
+	}
+	// records of other subjects are not held back by the failure
+	mustEqual(t, delivered, []schema.Event{next})
+
+	if err := o.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	pending, _ := store.Pending(0, nil)
+	mustEqual(t, len(pending), 0)
+
+	parked, err := o.Parked()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustEqual(t, len(parked), 2)
+	mustEqual(t, parked[0].ID, "left")
+	mustEqual(t, parked[0].LastError, errUnconfirmed.Error())
+	mustEqual(t, parked[1].ID, failing.ID)
+	mustEqual(t, parked[1].Attempts, 2)
+	mustEqual(t, parked[1].LastError, "broker rejected the event")
+}
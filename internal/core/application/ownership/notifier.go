@@ -0,0 +1,134 @@
+package ownership
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/ybalcin/event-schema-manager/internal/core/domain/schema"
+)
+
+type (
+	// Notification is posted to the escalation channel of the owner of the subject of an event,
+	// Text makes it readable by chat webhooks
+	Notification struct {
+		Text      string           `json:"text"`
+		Team      string           `json:"team"`
+		Subject   schema.Subject   `json:"subject"`
+		EventID   string           `json:"eventId"`
+		EventType schema.EventType `json:"eventType"`
+		Contacts  []string         `json:"contacts"`
+	}
+
+	// Notifier routes the events of owned subjects to the escalation channels of their owners,
+	// the events of unowned subjects are reported in the log
+	Notifier struct {
+		registry   *Registry
+		httpClient *http.Client
+		queue      chan notification
+	}
+
+	notification struct {
+		channel string
+		body    Notification
+	}
+)
+
+const (
+	notificationTimeout   = 10 * time.Second
+	notificationQueueSize = 1000
+)
+
+func NewNotifier(registry *Registry, httpClient *http.Client) *Notifier {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: notificationTimeout}
+	}
+
+	return &Notifier{
+		registry:   registry,
+		httpClient: httpClient,
+		queue:      make(chan notification, notificationQueueSize),
+	}
+}
+
+// Handle queues a notification to the owner of the subject of event, it is subscribed to the event bus
+func (n *Notifier) Handle(event schema.Event) error {
+	subject := event.EventSubject()
+	if subject == "" {
+		return nil
+	}
+
+	owner, err := n.registry.OwnerOf(context.Background(), subject)
+	if errors.Is(err, ErrOwnerNotFound) {
+		log.Printf("ownership: %s of subject %s is not routed, the subject has no owner", event.EventType(), subject)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !isHTTPURL(owner.EscalationChannel) {
+		return nil
+	}
+
+	select {
+	case n.queue <- notification{channel: owner.EscalationChannel, body: Notification{
+		Text:      fmt.Sprintf("%s of %s, owned by %s", event.EventType(), subject, owner.Team),
+		Team:      owner.Team,
+		Subject:   subject,
+		EventID:   event.EventID(),
+		EventType: event.EventType(),
+		Contacts:  owner.Contacts,
+	}}:
+		return nil
+	default:
+		log.Printf("ownership: notification queue is full, %s %s of subject %s is not routed to %s", event.EventType(), event.EventID(), subject, owner.Team)
+		return nil
+	}
+}
+
+// Run posts queued notifications until ctx is done, failed notifications are logged and not retried
+func (n *Notifier) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case nt := <-n.queue:
+			if err := n.post(ctx, nt); err != nil {
+				log.Printf("ownership: notification of event %s could not be posted to %s, trace: %v", nt.body.EventID, nt.channel, err)
+			}
+		}
+	}
+}
+
+func (n *Notifier) post(ctx context.Context, nt notification) error {
+	b, err := json.Marshal(nt.body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, nt.channel, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s responded %d", nt.channel, resp.StatusCode)
+	}
+
+	return nil
+}
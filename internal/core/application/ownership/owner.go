@@ -0,0 +1,145 @@
+package ownership
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ybalcin/event-schema-manager/internal/core/domain/schema"
+)
+
+type (
+	// Owner is the team owning a subject or, if Subject ends with *, the subjects starting with the prefix before it
+	Owner struct {
+		Subject           string    `json:"subject"`
+		Team              string    `json:"team"`
+		Contacts          []string  `json:"contacts"`
+		EscalationChannel string    `json:"escalationChannel,omitempty"`
+		Repository        string    `json:"repository,omitempty"`
+		UpdatedAt         time.Time `json:"updatedAt"`
+	}
+
+	// Store persists owners by their subject
+	Store interface {
+		Save(owner Owner) error
+		Delete(subject string) error
+		List() ([]Owner, error)
+	}
+
+	// MemoryStore keeps owners in memory
+	MemoryStore struct {
+		mu     sync.RWMutex
+		owners map[string]Owner
+	}
+)
+
+const prefixWildcard = "*"
+
+var (
+	ErrOwnerNotFound = errors.New("owner not found")
+	ErrInvalidOwner  = errors.New("invalid owner")
+	ErrNotOwner      = errors.New("subject is owned by another team")
+	errInvalidOwner  = func(format string, args ...interface{}) error {
+		return fmt.Errorf("%w: "+format, append([]interface{}{ErrInvalidOwner}, args...)...)
+	}
+)
+
+// Validate checks the subject pattern, the team and the links of o
+func (o Owner) Validate() error {
+	if o.Subject == "" {
+		return errInvalidOwner("subject is required")
+	}
+	if strings.Contains(strings.TrimSuffix(o.Subject, prefixWildcard), prefixWildcard) {
+		return errInvalidOwner("subject %q can only end with %s", o.Subject, prefixWildcard)
+	}
+	if strings.TrimSpace(o.Team) == "" {
+		return errInvalidOwner("team is required")
+	}
+	if o.Repository != "" && !isHTTPURL(o.Repository) {
+		return errInvalidOwner("repository %q must be an absolute http or https url", o.Repository)
+	}
+
+	return nil
+}
+
+// IsPrefix returns true if o owns the subjects starting with a prefix
+func (o Owner) IsPrefix() bool {
+	return strings.HasSuffix(o.Subject, prefixWildcard)
+}
+
+// Owns returns true if o owns subject
+func (o Owner) Owns(subject schema.Subject) bool {
+	if o.IsPrefix() {
+		return strings.HasPrefix(subject.String(), strings.TrimSuffix(o.Subject, prefixWildcard))
+	}
+
+	return subject.String() == o.Subject
+}
+
+// ownerOf returns the owner of subject, an exact owner wins over prefixes and the longest prefix wins
+func ownerOf(owners []Owner, subject schema.Subject) (Owner, bool) {
+	var found Owner
+	ok := false
+	for _, o := range owners {
+		if !o.Owns(subject) {
+			continue
+		}
+		if !o.IsPrefix() {
+			return o, true
+		}
+		if !ok || len(o.Subject) > len(found.Subject) {
+			found, ok = o, true
+		}
+	}
+
+	return found, ok
+}
+
+func isHTTPURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{owners: make(map[string]Owner)}
+}
+
+func (s *MemoryStore) Save(owner Owner) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	owner.Contacts = append([]string{}, owner.Contacts...)
+	s.owners[owner.Subject] = owner
+	return nil
+}
+
+func (s *MemoryStore) Delete(subject string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.owners[subject]; !ok {
+		return fmt.Errorf("%w: %s", ErrOwnerNotFound, subject)
+	}
+
+	delete(s.owners, subject)
+	return nil
+}
+
+// List returns the owners sorted by subject
+func (s *MemoryStore) List() ([]Owner, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	owners := make([]Owner, 0, len(s.owners))
+	for _, o := range s.owners {
+		o.Contacts = append([]string{}, o.Contacts...)
+		owners = append(owners, o)
+	}
+	sort.Slice(owners, func(i, j int) bool { return owners[i].Subject < owners[j].Subject })
+
+	return owners, nil
+}
@@ -0,0 +1,175 @@
+package ownership
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ybalcin/event-schema-manager/internal/core/domain/schema"
+	"github.com/ybalcin/event-schema-manager/internal/shared/identity"
+)
+
+type (
+	// Registry resolves the owners of subjects and authorizes the changes of owned subjects
+	Registry struct {
+		store Store
+		// enforced rejects changes of owned subjects by other teams
+		enforced bool
+		mu       sync.Mutex
+		now      func() time.Time
+	}
+
+	Option func(*Registry)
+)
+
+// WithEnforcement makes the registry authorize only the owning team to change an owned subject
+// and its owner, unowned subjects can be changed by anyone. The teams of unauthenticated callers are not trusted
+func WithEnforcement() Option {
+	return func(r *Registry) {
+		r.enforced = true
+	}
+}
+
+func NewRegistry(store Store, opts ...Option) *Registry {
+	r := &Registry{store: store, now: func() time.Time { return time.Now().UTC() }}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+// Put creates or replaces the owner of owner.Subject
+func (r *Registry) Put(ctx context.Context, owner Owner) (Owner, error) {
+	if err := owner.Validate(); err != nil {
+		return Owner{}, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	owners, err := r.store.List()
+	if err != nil {
+		return Owner{}, err
+	}
+	for _, o := range owners {
+		if o.Subject == owner.Subject {
+			if err = r.authorizeTeam(ctx, o); err != nil {
+				return Owner{}, err
+			}
+		}
+	}
+
+	if owner.Contacts == nil {
+		owner.Contacts = []string{}
+	}
+	owner.UpdatedAt = r.now()
+	if err = r.store.Save(owner); err != nil {
+		return Owner{}, err
+	}
+
+	return owner, nil
+}
+
+// Get returns the owner registered for the subject pattern
+func (r *Registry) Get(ctx context.Context, subject string) (Owner, error) {
+	owners, err := r.store.List()
+	if err != nil {
+		return Owner{}, err
+	}
+
+	for _, o := range owners {
+		if o.Subject == subject {
+			return o, nil
+		}
+	}
+
+	return Owner{}, fmt.Errorf("%w: %s", ErrOwnerNotFound, subject)
+}
+
+func (r *Registry) Delete(ctx context.Context, subject string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	owner, err := r.Get(ctx, subject)
+	if err != nil {
+		return err
+	}
+	if err = r.authorizeTeam(ctx, owner); err != nil {
+		return err
+	}
+
+	return r.store.Delete(subject)
+}
+
+func (r *Registry) List(ctx context.Context) ([]Owner, error) {
+	return r.store.List()
+}
+
+// OwnerOf resolves the owner of subject, an exact owner wins over prefixes and the longest prefix wins
+func (r *Registry) OwnerOf(ctx context.Context, subject schema.Subject) (Owner, error) {
+	owners, err := r.store.List()
+	if err != nil {
+		return Owner{}, err
+	}
+
+	owner, ok := ownerOf(owners, subject)
+	if !ok {
+		return Owner{}, fmt.Errorf("%w: %s", ErrOwnerNotFound, subject)
+	}
+
+	return owner, nil
+}
+
+// Unowned returns the subjects without an owner
+func (r *Registry) Unowned(ctx context.Context, subjects []schema.Subject) ([]schema.Subject, error) {
+	owners, err := r.store.List()
+	if err != nil {
+		return nil, err
+	}
+
+	unowned := []schema.Subject{}
+	for _, subject := range subjects {
+		if _, ok := ownerOf(owners, subject); !ok {
+			unowned = append(unowned, subject)
+		}
+	}
+
+	return unowned, nil
+}
+
+// Authorize returns ErrNotOwner if ownership is enforced and the identity of ctx is not in the team owning subject
+func (r *Registry) Authorize(ctx context.Context, subject schema.Subject) error {
+	if !r.enforced || subject == "" {
+		return nil
+	}
+
+	owners, err := r.store.List()
+	if err != nil {
+		return err
+	}
+
+	owner, ok := ownerOf(owners, subject)
+	if !ok {
+		return nil
+	}
+
+	return r.authorizeTeam(ctx, owner)
+}
+
+func (r *Registry) authorizeTeam(ctx context.Context, owner Owner) error {
+	if !r.enforced {
+		return nil
+	}
+
+	id := identity.FromContext(ctx)
+	if !id.Authenticated {
+		return fmt.Errorf("%w: %s is owned by %s, %s is not authenticated", ErrNotOwner, owner.Subject, owner.Team, id.Actor)
+	}
+	if !id.InTeam(owner.Team) {
+		return fmt.Errorf("%w: %s is owned by %s, %s is not in the team", ErrNotOwner, owner.Subject, owner.Team, id.Actor)
+	}
+
+	return nil
+}
@@ -0,0 +1,156 @@
+package ownership
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ybalcin/event-schema-manager/internal/core/domain/schema"
+	"github.com/ybalcin/event-schema-manager/internal/shared/identity"
+)
+
+func mustEqual(t *testing.T, actual, expected interface{}) {
+	t.Helper()
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %#v, but got %#v", expected, actual)
+	}
+}
+
+func inTeam(team string) context.Context {
+	return identity.NewContext(context.Background(), identity.Identity{Actor: "alice", Teams: []string{team}, Authenticated: true})
+}
+
+func TestRegistry_OwnerOf(t *testing.T) {
+	registry := NewRegistry(NewMemoryStore())
+	for _, o := range []Owner{
+		{Subject: "*", Team: "platform"},
+		{Subject: "payments-*", Team: "payments"},
+		{Subject: "payments-refunds-*", Team: "refunds"},
+		{Subject: "payments-value", Team: "billing"},
+	} {
+		if _, err := registry.Put(context.Background(), o); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for subject, team := range map[schema.Subject]string{
+		"payments-value":         "billing",
+		"payments-key":           "payments",
+		"payments-refunds-value": "refunds",
+		"orders-value":           "platform",
+	} {
+		owner, err := registry.OwnerOf(context.Background(), subject)
+		if err != nil {
+			t.Fatal(err)
+		}
+		mustEqual(t, owner.Team, team)
+	}
+}
+
+func TestRegistry_Put_Invalid(t *testing.T) {
+	registry := NewRegistry(NewMemoryStore())
+
+	for _, o := range []Owner{
+		{Subject: "payments-value"},
+		{Subject: "pay*ments", Team: "payments"},
+		{Subject: "payments-value", Team: "payments", Repository: "github.com/payments"},
+	} {
+		if _, err := registry.Put(context.Background(), o); !errors.Is(err, ErrInvalidOwner) {
+			t.Errorf("expected invalid owner for %+v, but got %v", o, err)
+		}
+	}
+}
+
+func TestRegistry_Authorize(t *testing.T) {
+	registry := NewRegistry(NewMemoryStore(), WithEnforcement())
+	if _, err := registry.Put(inTeam("payments"), Owner{Subject: "payments-*", Team: "payments"}); err != nil {
+		t.Fatal(err)
+	}
+
+	mustEqual(t, registry.Authorize(inTeam("payments"), "payments-value"), nil)
+	if err := registry.Authorize(inTeam("orders"), "payments-value"); !errors.Is(err, ErrNotOwner) {
+		t.Fatalf("expected not owner, but got %v", err)
+	}
+	// the teams of unauthenticated callers are only claimed
+	claimed := identity.NewContext(context.Background(), identity.Identity{Actor: "alice", Teams: []string{"payments"}})
+	if err := registry.Authorize(claimed, "payments-value"); !errors.Is(err, ErrNotOwner) {
+		t.Fatalf("expected not owner, but got %v", err)
+	}
+	// unowned subjects and the global config are not owned
+	mustEqual(t, registry.Authorize(inTeam("orders"), "orders-value"), nil)
+	mustEqual(t, registry.Authorize(inTeam("orders"), ""), nil)
+
+	// only the owning team can hand over the subject
+	if _, err := registry.Put(inTeam("orders"), Owner{Subject: "payments-*", Team: "orders"}); !errors.Is(err, ErrNotOwner) {
+		t.Fatalf("expected not owner, but got %v", err)
+	}
+	if err := registry.Delete(inTeam("orders"), "payments-*"); !errors.Is(err, ErrNotOwner) {
+		t.Fatalf("expected not owner, but got %v", err)
+	}
+	mustEqual(t, registry.Delete(inTeam("payments"), "payments-*"), nil)
+}
+
+func TestRegistry_Unowned(t *testing.T) {
+	registry := NewRegistry(NewMemoryStore())
+	if _, err := registry.Put(context.Background(), Owner{Subject: "payments-*", Team: "payments"}); err != nil {
+		t.Fatal(err)
+	}
+
+	unowned, err := registry.Unowned(context.Background(), []schema.Subject{"payments-value", "orders-value", "users-value"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustEqual(t, unowned, []schema.Subject{"orders-value", "users-value"})
+}
+
+func TestNotifier_RoutesToEscalationChannel(t *testing.T) {
+	received := make(chan Notification, 1)
+	channel := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var n Notification
+		if err := json.NewDecoder(r.Body).Decode(&n); err != nil {
+			t.Error(err)
+		}
+		received <- n
+	}))
+	defer channel.Close()
+
+	registry := NewRegistry(NewMemoryStore())
+	if _, err := registry.Put(context.Background(), Owner{
+		Subject:           "payments-*",
+		Team:              "payments",
+		Contacts:          []string{"payments@example.com"},
+		EscalationChannel: channel.URL,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	notifier := NewNotifier(registry, channel.Client())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go notifier.Run(ctx)
+
+	event := schema.SchemaRegistered{EventMetadata: schema.NewEventMetadata(), Subject: "payments-value", Version: 1}
+	if err := notifier.Handle(event); err != nil {
+		t.Fatal(err)
+	}
+	// unowned subjects are only logged
+	if err := notifier.Handle(schema.SchemaRegistered{EventMetadata: schema.NewEventMetadata(), Subject: "orders-value"}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case n := <-received:
+		mustEqual(t, n.Team, "payments")
+		mustEqual(t, n.Subject, schema.Subject("payments-value"))
+		mustEqual(t, n.EventID, event.EventID())
+		mustEqual(t, n.Contacts, []string{"payments@example.com"})
+	case <-time.After(5 * time.Second):
+		t.Fatal("notification is not posted")
+	}
+}
@@ -1 +1,15 @@
 package ports
+
+import (
+	"github.com/ybalcin/event-schema-manager/internal/core/domain/schema"
+)
+
+// EventBus output port the schema service publishes domain events to
+type (
+	EventBus interface {
+		schema.EventPublisher
+		// Subscribe registers handler for events of eventType, every event if eventType is empty,
+		// the returned func removes the subscription
+		Subscribe(eventType schema.EventType, handler schema.EventHandler) (unsubscribe func())
+	}
+)
@@ -1,18 +1,456 @@
 package ports
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
 	"github.com/ybalcin/event-schema-manager/internal/core/application"
+	"github.com/ybalcin/event-schema-manager/internal/core/application/services"
+	"github.com/ybalcin/event-schema-manager/internal/core/domain/schema"
+	"github.com/ybalcin/event-schema-manager/internal/infrastructure/adapters"
 	"github.com/ybalcin/event-schema-manager/internal/shared/config"
 )
 
 // HttpServer input http port
 type (
 	HttpServer struct {
-		app *application.Application
+		app      *application.Application
+		eventBus EventBus
+		server   *http.Server
+		sessions *portalSessions
+		// secureCookies marks the portal session cookie to be sent over https only
+		secureCookies bool
+	}
+)
+
+const (
+	defaultHttpAddress = ":8080"
+	readHeaderTimeout  = 10 * time.Second
+	maxRequestBodySize = 4 << 20
+
+	contentTypeHeaderKey = "Content-Type"
+	contentTypeJSON      = "application/json"
+)
+
+var (
+	errMethodNotAllowed = func(method string) error {
+		return fmt.Errorf("method %s is not allowed", method)
+	}
+	errRouteNotFound = func(path string) error {
+		return fmt.Errorf("%s is not found", path)
+	}
+	errRequired = func(field string) error {
+		return fmt.Errorf("%s is required", field)
 	}
 )
 
-func NewHttpServer(cfg *config.AppConfig) *HttpServer {
-	app := application.NewApplication(cfg)
-	return &HttpServer{app: app}
+func NewHttpServer(cfg *config.AppConfig, opts ...application.Option) *HttpServer {
+	eventBus := adapters.NewInMemoryEventBus()
+	app := application.NewApplication(cfg, append(opts, application.WithEventPublisher(eventBus))...)
+	eventBus.Subscribe("", app.Webhooks().Handle)
+	eventBus.Subscribe("", app.OwnerNotifier().Handle)
+	eventBus.Subscribe("", app.Search().Handle)
+
+	address := cfg.HttpAddress
+	if address == "" {
+		address = defaultHttpAddress
+	}
+
+	s := &HttpServer{app: app, eventBus: eventBus, sessions: newPortalSessions(), secureCookies: !cfg.PortalInsecureCookie}
+	s.server = &http.Server{
+		Addr:              address,
+		Handler:           s.routes(),
+		ReadHeaderTimeout: readHeaderTimeout,
+	}
+
+	return s
+}
+
+// Address returns the address the server listens on
+func (s *HttpServer) Address() string {
+	return s.server.Addr
+}
+
+// ListenAndServe starts the background workers of the application, listens on the configured address
+// and serves requests until Shutdown is called
+func (s *HttpServer) ListenAndServe() error {
+	s.app.Start()
+
+	if err := s.server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
+
+// Shutdown stops the server gracefully, waiting for active requests until ctx is done,
+// then it stops the background workers of the application
+func (s *HttpServer) Shutdown(ctx context.Context) error {
+	err := s.server.Shutdown(ctx)
+	s.app.Stop()
+
+	return err
+}
+
+// EventBus returns the bus the domain events of the application are published to
+func (s *HttpServer) EventBus() EventBus {
+	return s.eventBus
+}
+
+func (s *HttpServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.server.Handler.ServeHTTP(w, r)
+}
+
+func (s *HttpServer) routes() http.Handler {
+	rt := &router{}
+
+	rt.handle(http.MethodGet, "/subjects", s.listSubjects)
+	rt.handle(http.MethodDelete, "/subjects/{subject}", s.deleteSubject)
+	rt.handle(http.MethodGet, "/subjects/{subject}/versions", s.listVersions)
+	rt.handle(http.MethodPost, "/subjects/{subject}/versions", s.registerSchema)
+	rt.handle(http.MethodGet, "/subjects/{subject}/versions/{version}", s.getSchemaByVersion)
+	rt.handle(http.MethodDelete, "/subjects/{subject}/versions/{version}", s.deleteSchemaVersion)
+	rt.handle(http.MethodGet, "/subjects/{subject}/diff", s.diffVersions)
+	rt.handle(http.MethodGet, "/subjects/{subject}/changelog", s.getChangelog)
+	rt.handle(http.MethodGet, "/schemas/ids/{id}", s.getSchemaByID)
+	rt.handle(http.MethodPost, "/compatibility/subjects/{subject}/versions/{version}", s.checkCompatibility)
+	rt.handle(http.MethodPost, "/lint/subjects/{subject}", s.lintSchema)
+	rt.handle(http.MethodGet, "/config", s.getCompatibilityLevel)
+	rt.handle(http.MethodPut, "/config", s.setCompatibilityLevel)
+	rt.handle(http.MethodGet, "/config/{subject}", s.getCompatibilityLevel)
+	rt.handle(http.MethodPut, "/config/{subject}", s.setCompatibilityLevel)
+	rt.handle(http.MethodGet, "/audit", s.queryAuditLog)
+	rt.handle(http.MethodGet, "/search", s.search)
+	rt.handle(http.MethodGet, "/ui", s.portalSubjects)
+	rt.handle(http.MethodGet, "/ui/subjects/{subject}", s.portalSubject)
+	rt.handle(http.MethodGet, "/ui/subjects/{subject}/history", s.portalHistory)
+	rt.handle(http.MethodGet, "/ui/subjects/{subject}/diff", s.portalDiff)
+	rt.handle(http.MethodGet, "/ui/login", s.portalLoginForm)
+	rt.handle(http.MethodPost, "/ui/login", s.portalLogin)
+	rt.handle(http.MethodGet, "/ui/assets/{name}", s.portalAsset)
+	rt.handle(http.MethodGet, "/owners", s.listOwners)
+	rt.handle(http.MethodGet, "/owners/{subject}", s.getOwner)
+	rt.handle(http.MethodPut, "/owners/{subject}", s.putOwner)
+	rt.handle(http.MethodDelete, "/owners/{subject}", s.deleteOwner)
+	rt.handle(http.MethodGet, "/subjects/{subject}/owner", s.getSubjectOwner)
+	rt.handle(http.MethodGet, "/unowned-subjects", s.listUnownedSubjects)
+	rt.handle(http.MethodGet, "/proposals", s.listProposals)
+	rt.handle(http.MethodPost, "/proposals", s.createProposal)
+	rt.handle(http.MethodGet, "/proposals/{id}", s.getProposal)
+	rt.handle(http.MethodPut, "/proposals/{id}", s.editProposal)
+	rt.handle(http.MethodPost, "/proposals/{id}/submit", s.submitProposal)
+	rt.handle(http.MethodPost, "/proposals/{id}/reviews", s.reviewProposal)
+	rt.handle(http.MethodPost, "/proposals/{id}/comments", s.commentProposal)
+	rt.handle(http.MethodPost, "/proposals/{id}/apply", s.applyProposal)
+	rt.handle(http.MethodGet, "/webhooks", s.listWebhooks)
+	rt.handle(http.MethodPost, "/webhooks", s.createWebhook)
+	rt.handle(http.MethodGet, "/webhooks/dead-letters", s.listDeadLetters)
+	rt.handle(http.MethodPost, "/webhooks/dead-letters/{id}/redeliver", s.redeliverDeadLetter)
+	rt.handle(http.MethodGet, "/webhooks/{id}", s.getWebhook)
+	rt.handle(http.MethodDelete, "/webhooks/{id}", s.deleteWebhook)
+
+	return s.withRequestIdentity(rt)
+}
+
+// POST /subjects/{subject}/versions
+func (s *HttpServer) registerSchema(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	sc, ok := s.schemaOfRequest(w, r, params)
+	if !ok {
+		return
+	}
+
+	result, err := s.app.SchemaService().Add(r.Context(), sc)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	status := http.StatusCreated
+	if result.Status == services.AddStatusUnchanged {
+		status = http.StatusOK
+	}
+	writeJSON(w, status, newRegisterResponse(result))
+}
+
+// GET /subjects
+func (s *HttpServer) listSubjects(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	subjects, err := s.app.SchemaService().Subjects(r.Context())
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	if subjects == nil {
+		subjects = []schema.Subject{}
+	}
+	writeJSON(w, http.StatusOK, subjects)
+}
+
+// GET /subjects/{subject}/versions
+func (s *HttpServer) listVersions(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	subject, ok := subjectParam(w, r, params)
+	if !ok {
+		return
+	}
+
+	versions, err := s.app.SchemaService().Versions(r.Context(), subject)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	if versions == nil {
+		versions = []schema.SchemaVersion{}
+	}
+	writeJSON(w, http.StatusOK, versions)
+}
+
+// GET /subjects/{subject}/versions/{version}
+func (s *HttpServer) getSchemaByVersion(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	subject, ok := subjectParam(w, r, params)
+	if !ok {
+		return
+	}
+	version, ok := versionParam(w, r, params)
+	if !ok {
+		return
+	}
+
+	sc, err := s.app.SchemaService().Get(r.Context(), subject, version)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newSchemaResponse(sc))
+}
+
+// GET /schemas/ids/{id}
+func (s *HttpServer) getSchemaByID(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	id, err := schema.ParseSchemaID(params["id"])
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	sc, err := s.app.SchemaService().GetByID(r.Context(), id)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, schemaOnlyResponse{Schema: sc.Content(), SchemaType: sc.Type()})
+}
+
+// POST /compatibility/subjects/{subject}/versions/{version}
+func (s *HttpServer) checkCompatibility(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	version, ok := versionParam(w, r, params)
+	if !ok {
+		return
+	}
+	sc, ok := s.schemaOfRequest(w, r, params)
+	if !ok {
+		return
+	}
+
+	compatibility, err := s.app.SchemaService().CheckCompatibility(r.Context(), sc, version)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, compatibilityResponse{IsCompatible: compatibility.IsCompatible, Messages: compatibility.Reasons})
+}
+
+// POST /lint/subjects/{subject}
+func (s *HttpServer) lintSchema(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	sc, ok := s.schemaOfRequest(w, r, params)
+	if !ok {
+		return
+	}
+
+	report, err := s.app.SchemaService().Lint(r.Context(), sc)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, lintResponse{Passed: !report.HasErrors(), Violations: report.Violations})
+}
+
+// DELETE /subjects/{subject}
+func (s *HttpServer) deleteSubject(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	subject, ok := subjectParam(w, r, params)
+	if !ok {
+		return
+	}
+
+	versions, err := s.app.SchemaService().Delete(r.Context(), subject)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, versions)
+}
+
+// DELETE /subjects/{subject}/versions/{version}
+func (s *HttpServer) deleteSchemaVersion(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	subject, ok := subjectParam(w, r, params)
+	if !ok {
+		return
+	}
+	version, ok := versionParam(w, r, params)
+	if !ok {
+		return
+	}
+
+	deleted, err := s.app.SchemaService().DeleteVersion(r.Context(), subject, version)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, deleted)
+}
+
+// GET /config and GET /config/{subject}
+func (s *HttpServer) getCompatibilityLevel(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	subject, ok := optionalSubjectParam(w, r, params)
+	if !ok {
+		return
+	}
+
+	level, err := s.app.SchemaService().CompatibilityLevel(r.Context(), subject)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, compatibilityLevelResponse{CompatibilityLevel: level})
+}
+
+// PUT /config and PUT /config/{subject}
+func (s *HttpServer) setCompatibilityLevel(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	subject, ok := optionalSubjectParam(w, r, params)
+	if !ok {
+		return
+	}
+
+	var req compatibilityLevelRequest
+	if err := readJSON(w, r, &req); err != nil {
+		writeProblem(w, r, problemBadRequest, err)
+		return
+	}
+	if req.Compatibility == "" {
+		writeProblem(w, r, problemBadRequest, errRequired("compatibility"))
+		return
+	}
+
+	level, err := schema.ParseCompatibilityLevel(req.Compatibility)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	level, err = s.app.SchemaService().SetCompatibilityLevel(r.Context(), subject, level)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, compatibilityLevelRequest{Compatibility: string(level)})
+}
+
+// GET /audit?subject=&actor=&from=&to=&limit=, from and to are RFC 3339 times
+func (s *HttpServer) queryAuditLog(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	query, err := auditQueryOf(r.URL.Query())
+	if err != nil {
+		writeProblem(w, r, problemBadRequest, err)
+		return
+	}
+
+	entries, err := s.app.SchemaService().AuditLog(r.Context(), query)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, entries)
+}
+
+// schemaOfRequest reads the schema of the subject path parameter from the request body,
+// it writes the problem and returns false if the request is not valid
+func (s *HttpServer) schemaOfRequest(w http.ResponseWriter, r *http.Request, params map[string]string) (*schema.Schema, bool) {
+	subject, ok := subjectParam(w, r, params)
+	if !ok {
+		return nil, false
+	}
+
+	var req schemaRequest
+	if err := readJSON(w, r, &req); err != nil {
+		writeProblem(w, r, problemBadRequest, err)
+		return nil, false
+	}
+	if req.Schema == "" {
+		writeProblem(w, r, problemBadRequest, errRequired("schema"))
+		return nil, false
+	}
+
+	sc, err := schema.NewSchema(subject, req.SchemaType, req.Schema)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return nil, false
+	}
+
+	return sc, true
+}
+
+func subjectParam(w http.ResponseWriter, r *http.Request, params map[string]string) (schema.Subject, bool) {
+	subject, err := schema.NewSubject(params["subject"])
+	if err != nil {
+		writeServiceError(w, r, err)
+		return "", false
+	}
+
+	return subject, true
+}
+
+// optionalSubjectParam returns the subject path parameter, the empty subject of the global config if there is none
+func optionalSubjectParam(w http.ResponseWriter, r *http.Request, params map[string]string) (schema.Subject, bool) {
+	if _, ok := params["subject"]; !ok {
+		return "", true
+	}
+
+	return subjectParam(w, r, params)
+}
+
+// versionParam returns the version path parameter, a positive number or "latest"
+func versionParam(w http.ResponseWriter, r *http.Request, params map[string]string) (schema.SchemaVersion, bool) {
+	version, err := schema.ParseSchemaVersion(params["version"])
+	if err != nil {
+		writeServiceError(w, r, err)
+		return 0, false
+	}
+
+	return version, true
+}
+
+func readJSON(w http.ResponseWriter, r *http.Request, val interface{}) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBodySize))
+	if err := decoder.Decode(val); err != nil {
+		return fmt.Errorf("request body is not valid, trace: %v", err)
+	}
+
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, val interface{}) {
+	w.Header().Set(contentTypeHeaderKey, contentTypeJSON)
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(val)
 }
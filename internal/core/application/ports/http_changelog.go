@@ -0,0 +1,53 @@
+package ports
+
+import (
+	"net/http"
+
+	"github.com/ybalcin/event-schema-manager/internal/core/application/changelog"
+	"github.com/ybalcin/event-schema-manager/pkg/schemadiff"
+)
+
+// GET /subjects/{subject}/changelog?from=&to=&format=, from and to are RFC 3339 times bounding the registration
+// dates of the versions and format is json, the default, or markdown
+func (s *HttpServer) getChangelog(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	subject, ok := subjectParam(w, r, params)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	from, err := timeParam(query, "from")
+	if err != nil {
+		writeProblem(w, r, problemBadRequest, err)
+		return
+	}
+	to, err := timeParam(query, "to")
+	if err != nil {
+		writeProblem(w, r, problemBadRequest, err)
+		return
+	}
+
+	format := changelog.FormatJSON
+	if query.Get("format") != "" {
+		if format, err = changelog.ParseFormat(query.Get("format")); err != nil {
+			writeProblem(w, r, problemBadRequest, err)
+			return
+		}
+	}
+
+	history, err := s.app.SchemaService().Changelog(r.Context(), subject)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	history = history.Between(from, to)
+
+	if format == changelog.FormatJSON {
+		writeJSON(w, http.StatusOK, history)
+		return
+	}
+
+	w.Header().Set(contentTypeHeaderKey, diffContentTypes[schemadiff.FormatMarkdown])
+	w.WriteHeader(http.StatusOK)
+	_ = history.Write(w, format)
+}
@@ -0,0 +1,64 @@
+package ports
+
+import (
+	"net/http"
+
+	"github.com/ybalcin/event-schema-manager/internal/core/domain/schema"
+	"github.com/ybalcin/event-schema-manager/pkg/schemadiff"
+)
+
+// diffContentTypes are the content types of the diff formats that are not json
+var diffContentTypes = map[schemadiff.Format]string{
+	schemadiff.FormatText:     "text/plain; charset=utf-8",
+	schemadiff.FormatMarkdown: "text/markdown; charset=utf-8",
+}
+
+// GET /subjects/{subject}/diff?from=&to=&format=, to is the latest version by default and format is json, text
+// or markdown
+func (s *HttpServer) diffVersions(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	subject, ok := subjectParam(w, r, params)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	if query.Get("from") == "" {
+		writeProblem(w, r, problemBadRequest, errRequired("from"))
+		return
+	}
+	from, err := schema.ParseSchemaVersion(query.Get("from"))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	to := schema.Latest
+	if query.Get("to") != "" {
+		if to, err = schema.ParseSchemaVersion(query.Get("to")); err != nil {
+			writeServiceError(w, r, err)
+			return
+		}
+	}
+
+	format := schemadiff.FormatJSON
+	if query.Get("format") != "" {
+		if format, err = schemadiff.ParseFormat(query.Get("format")); err != nil {
+			writeProblem(w, r, problemBadRequest, err)
+			return
+		}
+	}
+
+	diff, err := s.app.SchemaService().Diff(r.Context(), subject, from, to)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	if format == schemadiff.FormatJSON {
+		writeJSON(w, http.StatusOK, diff)
+		return
+	}
+
+	w.Header().Set(contentTypeHeaderKey, diffContentTypes[format])
+	w.WriteHeader(http.StatusOK)
+	_ = diff.Write(w, format)
+}
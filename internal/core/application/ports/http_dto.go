@@ -0,0 +1,103 @@
+package ports
+
+import (
+	"github.com/ybalcin/event-schema-manager/internal/core/application/linting"
+	"github.com/ybalcin/event-schema-manager/internal/core/application/proposals"
+	"github.com/ybalcin/event-schema-manager/internal/core/application/services"
+	"github.com/ybalcin/event-schema-manager/internal/core/domain/schema"
+)
+
+type (
+	schemaRequest struct {
+		Schema     string            `json:"schema"`
+		SchemaType schema.SchemaType `json:"schemaType,omitempty"`
+	}
+
+	registerResponse struct {
+		ID         schema.SchemaID      `json:"id"`
+		Version    schema.SchemaVersion `json:"version"`
+		Status     services.AddStatus   `json:"status"`
+		Violations []linting.Violation  `json:"violations,omitempty"`
+	}
+
+	lintResponse struct {
+		Passed     bool                `json:"passed"`
+		Violations []linting.Violation `json:"violations"`
+	}
+
+	schemaOnlyResponse struct {
+		Schema     string            `json:"schema"`
+		SchemaType schema.SchemaType `json:"schemaType"`
+	}
+
+	schemaResponse struct {
+		Subject    schema.Subject       `json:"subject"`
+		Version    schema.SchemaVersion `json:"version"`
+		ID         schema.SchemaID      `json:"id"`
+		SchemaType schema.SchemaType    `json:"schemaType"`
+		Schema     string               `json:"schema"`
+	}
+
+	compatibilityResponse struct {
+		IsCompatible bool     `json:"is_compatible"`
+		Messages     []string `json:"messages,omitempty"`
+	}
+
+	// compatibilityLevelRequest is the response of PUT /config too, like the confluent api
+	compatibilityLevelRequest struct {
+		Compatibility string `json:"compatibility"`
+	}
+
+	compatibilityLevelResponse struct {
+		CompatibilityLevel schema.CompatibilityLevel `json:"compatibilityLevel"`
+	}
+
+	proposalRequest struct {
+		Subject     string            `json:"subject"`
+		Schema      string            `json:"schema"`
+		SchemaType  schema.SchemaType `json:"schemaType,omitempty"`
+		Description string            `json:"description"`
+	}
+
+	reviewRequest struct {
+		Decision proposals.Decision `json:"decision"`
+		Comment  string             `json:"comment"`
+	}
+
+	commentRequest struct {
+		Text string `json:"text"`
+	}
+
+	ownerRequest struct {
+		Team              string   `json:"team"`
+		Contacts          []string `json:"contacts"`
+		EscalationChannel string   `json:"escalationChannel"`
+		Repository        string   `json:"repository"`
+	}
+
+	webhookRequest struct {
+		URL        string             `json:"url"`
+		Subjects   string             `json:"subjects"`
+		EventTypes []schema.EventType `json:"eventTypes"`
+		Secret     string             `json:"secret"`
+	}
+)
+
+func newSchemaResponse(sc *schema.Schema) schemaResponse {
+	return schemaResponse{
+		Subject:    sc.Subject(),
+		Version:    sc.Version(),
+		ID:         sc.ID(),
+		SchemaType: sc.Type(),
+		Schema:     sc.Content(),
+	}
+}
+
+func newRegisterResponse(result *services.AddResult) registerResponse {
+	return registerResponse{
+		ID:         result.Schema.ID(),
+		Version:    result.Schema.Version(),
+		Status:     result.Status,
+		Violations: result.Violations,
+	}
+}
@@ -0,0 +1,166 @@
+package ports
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ybalcin/event-schema-manager/internal/core/application/audit"
+	"github.com/ybalcin/event-schema-manager/internal/core/application/auth"
+	"github.com/ybalcin/event-schema-manager/internal/core/domain/schema"
+	"github.com/ybalcin/event-schema-manager/internal/shared/identity"
+)
+
+const (
+	actorHeaderKey        = "X-Actor"
+	teamsHeaderKey        = "X-Teams"
+	changeReasonHeaderKey = "X-Change-Reason"
+	changeTicketHeaderKey = "X-Change-Ticket"
+
+	apiKeyHeaderKey          = "X-API-Key"
+	authorizationHeaderKey   = "Authorization"
+	wwwAuthenticateHeaderKey = "WWW-Authenticate"
+	bearerPrefix             = "Bearer "
+
+	defaultAuditLimit = 100
+)
+
+// withRequestIdentity puts the identity and the change reason of the request into its context. The identity is
+// authenticated if authentication is enabled, otherwise the actor and its teams are taken from the headers.
+// Unauthenticated requests of portal pages are redirected to the sign in form, which is public like the assets.
+func (s *HttpServer) withRequestIdentity(next http.Handler) http.Handler {
+	authenticator := s.app.Authenticator()
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := identity.Identity{
+			Actor: r.Header.Get(actorHeaderKey),
+			Teams: listHeader(r.Header.Get(teamsHeaderKey)),
+		}
+
+		if authenticator != nil && !isPublicPortalPath(r.URL.Path) {
+			var err error
+			if id, err = authenticator.Authenticate(r.Context(), s.credentialsOf(r)); err != nil {
+				if isPortalPath(r.URL.Path) {
+					http.Redirect(w, r, "/ui/login", http.StatusSeeOther)
+					return
+				}
+				w.Header().Set(wwwAuthenticateHeaderKey, `Bearer realm="event-schema-manager"`)
+				writeServiceError(w, r, err)
+				return
+			}
+		}
+
+		ctx := identity.NewContext(r.Context(), id)
+		ctx = audit.WithChange(ctx, audit.Change{
+			Reason: r.Header.Get(changeReasonHeaderKey),
+			Ticket: r.Header.Get(changeTicketHeaderKey),
+		})
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// credentialsOf returns the api key of the X-API-Key header and the bearer token of the Authorization header.
+// Browsers can not send them, portal pages without them are read with the credentials of the session of the
+// portal cookie. The session is accepted for reading the portal only so it can not be used to change the registry
+// from another site.
+func (s *HttpServer) credentialsOf(r *http.Request) auth.Credentials {
+	credentials := auth.Credentials{APIKey: r.Header.Get(apiKeyHeaderKey)}
+
+	authorization := r.Header.Get(authorizationHeaderKey)
+	if len(authorization) > len(bearerPrefix) && strings.EqualFold(authorization[:len(bearerPrefix)], bearerPrefix) {
+		credentials.BearerToken = strings.TrimSpace(authorization[len(bearerPrefix):])
+	}
+
+	if credentials == (auth.Credentials{}) && r.Method == http.MethodGet && isPortalPath(r.URL.Path) {
+		if cookie, err := r.Cookie(portalCookieName); err == nil {
+			if sessionCredentials, ok := s.sessions.credentials(cookie.Value); ok {
+				credentials = sessionCredentials
+			}
+		}
+	}
+
+	return credentials
+}
+
+// portalCredentials returns the credentials of the sign in form, a jwt is a bearer token, anything else an api key
+func portalCredentials(credential string) auth.Credentials {
+	if strings.Count(credential, ".") == 2 {
+		return auth.Credentials{BearerToken: credential}
+	}
+
+	return auth.Credentials{APIKey: credential}
+}
+
+func isPortalPath(path string) bool {
+	return path == "/ui" || strings.HasPrefix(path, "/ui/")
+}
+
+// isPublicPortalPath returns true for the sign in form and the assets, they are served without authentication
+func isPublicPortalPath(path string) bool {
+	return path == "/ui/login" || strings.HasPrefix(path, "/ui/assets/")
+}
+
+// authorize writes a forbidden problem and returns false if the identity of r is not allowed permission on subject
+func (s *HttpServer) authorize(w http.ResponseWriter, r *http.Request, permission auth.Permission, subject schema.Subject) bool {
+	if err := s.app.Authorize(r.Context(), permission, subject); err != nil {
+		writeServiceError(w, r, err)
+		return false
+	}
+
+	return true
+}
+
+// listHeader splits a comma separated header value
+func listHeader(value string) []string {
+	var values []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+
+	return values
+}
+
+// auditQueryOf parses the audit query parameters
+func auditQueryOf(values url.Values) (audit.Query, error) {
+	query := audit.Query{
+		Subject: schema.Subject(values.Get("subject")),
+		Actor:   values.Get("actor"),
+		Limit:   defaultAuditLimit,
+	}
+
+	var err error
+	if query.From, err = timeParam(values, "from"); err != nil {
+		return audit.Query{}, err
+	}
+	if query.To, err = timeParam(values, "to"); err != nil {
+		return audit.Query{}, err
+	}
+
+	if limit := values.Get("limit"); limit != "" {
+		if query.Limit, err = strconv.Atoi(limit); err != nil || query.Limit <= 0 {
+			return audit.Query{}, fmt.Errorf("limit %s must be a positive number", limit)
+		}
+	}
+
+	return query, nil
+}
+
+func timeParam(values url.Values, name string) (time.Time, error) {
+	value := values.Get(name)
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s %s must be an RFC 3339 time", name, value)
+	}
+
+	return t, nil
+}
@@ -0,0 +1,106 @@
+package ports
+
+import (
+	"net/http"
+
+	"github.com/ybalcin/event-schema-manager/internal/core/application/auth"
+	"github.com/ybalcin/event-schema-manager/internal/core/application/ownership"
+	"github.com/ybalcin/event-schema-manager/internal/core/domain/schema"
+)
+
+// GET /owners
+func (s *HttpServer) listOwners(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	owners, err := s.app.Ownership().List(r.Context())
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, owners)
+}
+
+// GET /owners/{subject}
+func (s *HttpServer) getOwner(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	owner, err := s.app.Ownership().Get(r.Context(), params["subject"])
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, owner)
+}
+
+// PUT /owners/{subject}
+func (s *HttpServer) putOwner(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	if !s.authorize(w, r, auth.PermissionAdmin, schema.Subject(params["subject"])) {
+		return
+	}
+
+	var req ownerRequest
+	if err := readJSON(w, r, &req); err != nil {
+		writeProblem(w, r, problemBadRequest, err)
+		return
+	}
+
+	owner, err := s.app.Ownership().Put(r.Context(), ownership.Owner{
+		Subject:           params["subject"],
+		Team:              req.Team,
+		Contacts:          req.Contacts,
+		EscalationChannel: req.EscalationChannel,
+		Repository:        req.Repository,
+	})
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, owner)
+}
+
+// DELETE /owners/{subject}
+func (s *HttpServer) deleteOwner(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	if !s.authorize(w, r, auth.PermissionAdmin, schema.Subject(params["subject"])) {
+		return
+	}
+
+	if err := s.app.Ownership().Delete(r.Context(), params["subject"]); err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GET /subjects/{subject}/owner
+func (s *HttpServer) getSubjectOwner(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	subject, err := schema.NewSubject(params["subject"])
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	owner, err := s.app.Ownership().OwnerOf(r.Context(), subject)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, owner)
+}
+
+// GET /unowned-subjects
+func (s *HttpServer) listUnownedSubjects(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	subjects, err := s.app.SchemaService().Subjects(r.Context())
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	unowned, err := s.app.Ownership().Unowned(r.Context(), subjects)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, unowned)
+}
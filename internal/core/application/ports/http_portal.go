@@ -0,0 +1,459 @@
+package ports
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ybalcin/event-schema-manager/internal/core/application/changelog"
+	"github.com/ybalcin/event-schema-manager/internal/core/domain/schema"
+	"github.com/ybalcin/event-schema-manager/pkg/avro"
+	"github.com/ybalcin/event-schema-manager/pkg/schemadiff"
+)
+
+type (
+	portalSubjectsPage struct {
+		Query    string
+		Subjects []schema.Subject
+		Total    int
+	}
+
+	portalSubjectPage struct {
+		Subject    schema.Subject
+		Schema     *schema.Schema
+		Previous   schema.SchemaVersion
+		Fields     []portalField
+		References []schema.Reference
+		Content    string
+		Versions   []schema.SchemaVersion
+	}
+
+	portalHistoryPage struct {
+		Subject schema.Subject
+		// History is the changelog of the subject, latest version first
+		History []portalVersion
+	}
+
+	// portalField is a row of the field table, Links are the referenced schemas its type uses
+	portalField struct {
+		Path        string
+		Type        string
+		LogicalType string
+		Default     string
+		HasDefault  bool
+		Doc         string
+		Links       []schema.Reference
+	}
+
+	portalVersion struct {
+		changelog.Entry
+		Previous schema.SchemaVersion
+	}
+
+	portalDiffPage struct {
+		Subject    schema.Subject
+		Versions   []schema.SchemaVersion
+		From       schema.SchemaVersion
+		To         schema.SchemaVersion
+		SchemaType schema.SchemaType
+		Changes    []schemadiff.Change
+		// Lines is the line diff of schema types without a structural diff
+		Lines []string
+	}
+
+	portalLoginPage struct {
+		Error string
+	}
+
+	portalErrorPage struct {
+		Title  string
+		Detail string
+	}
+)
+
+const (
+	contentTypeHTML = "text/html; charset=utf-8"
+
+	// portalCookieName is the cookie keeping the session id of the sign in form for the portal pages
+	portalCookieName = "esm_session"
+	// maxLoginFormSize is the size limit of the sign in form
+	maxLoginFormSize = 64 << 10
+)
+
+//go:embed portal
+var portalFiles embed.FS
+
+var portalFuncs = template.FuncMap{
+	"subjectURL": portalSubjectURL,
+	"versionURL": func(subject schema.Subject, version schema.SchemaVersion) string {
+		return fmt.Sprintf("%s?version=%d", portalSubjectURL(subject), version)
+	},
+	"historyURL": func(subject schema.Subject) string {
+		return portalSubjectURL(subject) + "/history"
+	},
+	"diffURL": func(subject schema.Subject, from, to schema.SchemaVersion) string {
+		return fmt.Sprintf("%s/diff?from=%d&to=%d", portalSubjectURL(subject), from, to)
+	},
+	"date": func(t *time.Time) string {
+		return t.UTC().Format("2006-01-02 15:04 MST")
+	},
+	"lineClass": func(line string) string {
+		if strings.HasPrefix(line, "+") {
+			return "added"
+		}
+		return "removed"
+	},
+}
+
+// portalTemplates are the pages of the portal by name, each page is rendered into the layout
+var portalTemplates = parsePortalTemplates("subjects", "subject", "history", "diff", "login", "error")
+
+func parsePortalTemplates(pages ...string) map[string]*template.Template {
+	templates := make(map[string]*template.Template, len(pages))
+	for _, page := range pages {
+		templates[page] = template.Must(template.New(page).Funcs(portalFuncs).
+			ParseFS(portalFiles, "portal/layout.html", "portal/"+page+".html"))
+	}
+
+	return templates
+}
+
+func portalSubjectURL(subject schema.Subject) string {
+	return "/ui/subjects/" + url.PathEscape(subject.String())
+}
+
+// GET /ui?q=, lists the subjects containing q, case insensitive
+func (s *HttpServer) portalSubjects(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	subjects, err := s.app.SchemaService().Subjects(r.Context())
+	if err != nil {
+		writePortalError(w, err)
+		return
+	}
+
+	page := portalSubjectsPage{Query: strings.TrimSpace(r.URL.Query().Get("q")), Total: len(subjects), Subjects: []schema.Subject{}}
+	query := strings.ToLower(page.Query)
+	for _, subject := range subjects {
+		if strings.Contains(strings.ToLower(subject.String()), query) {
+			page.Subjects = append(page.Subjects, subject)
+		}
+	}
+	sort.Slice(page.Subjects, func(i, j int) bool { return page.Subjects[i] < page.Subjects[j] })
+
+	renderPortal(w, http.StatusOK, "subjects", page)
+}
+
+// GET /ui/subjects/{subject}?version=, shows a version of a subject, the latest by default, and links to the
+// other versions. The history is a page of its own since building the changelog reads every version.
+func (s *HttpServer) portalSubject(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	subject := schema.Subject(params["subject"])
+	version := schema.Latest
+	if v := r.URL.Query().Get("version"); v != "" {
+		var err error
+		if version, err = schema.ParseSchemaVersion(v); err != nil {
+			writePortalError(w, err)
+			return
+		}
+	}
+
+	sc, err := s.app.SchemaService().Get(r.Context(), subject, version)
+	if err != nil {
+		writePortalError(w, err)
+		return
+	}
+	versions, err := s.app.SchemaService().Versions(r.Context(), subject)
+	if err != nil {
+		writePortalError(w, err)
+		return
+	}
+
+	page := portalSubjectPage{Subject: subject, Schema: sc, References: sc.References(), Content: indentContent(sc.Content()), Versions: versions}
+	for i, v := range versions {
+		if v == sc.Version() && i > 0 {
+			page.Previous = versions[i-1]
+		}
+	}
+
+	if sc.Type() == schema.SchemaTypeAvro {
+		if page.Fields, err = portalFieldsOf(sc); err != nil {
+			writePortalError(w, fmt.Errorf("%w: %v", schema.ErrInvalidSchema, err))
+			return
+		}
+	}
+
+	renderPortal(w, http.StatusOK, "subject", page)
+}
+
+// GET /ui/subjects/{subject}/history, lists the versions of a subject with their audit metadata and changes
+func (s *HttpServer) portalHistory(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	subject := schema.Subject(params["subject"])
+	history, err := s.app.SchemaService().Changelog(r.Context(), subject)
+	if err != nil {
+		writePortalError(w, err)
+		return
+	}
+
+	page := portalHistoryPage{Subject: subject}
+	for i, e := range history.Entries {
+		v := portalVersion{Entry: e}
+		if i > 0 {
+			v.Previous = history.Entries[i-1].Version
+		}
+		page.History = append([]portalVersion{v}, page.History...)
+	}
+
+	renderPortal(w, http.StatusOK, "history", page)
+}
+
+// GET /ui/subjects/{subject}/diff?from=&to=, compares two versions, the latest one with its previous by default
+func (s *HttpServer) portalDiff(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	subject := schema.Subject(params["subject"])
+	versions, err := s.app.SchemaService().Versions(r.Context(), subject)
+	if err != nil {
+		writePortalError(w, err)
+		return
+	}
+	if len(versions) == 0 {
+		writePortalError(w, schema.ErrSubjectNotFound)
+		return
+	}
+
+	page := portalDiffPage{Subject: subject, Versions: versions, To: versions[len(versions)-1], From: versions[0]}
+	if len(versions) > 1 {
+		page.From = versions[len(versions)-2]
+	}
+	query := r.URL.Query()
+	for name, v := range map[string]*schema.SchemaVersion{"from": &page.From, "to": &page.To} {
+		if query.Get(name) == "" {
+			continue
+		}
+		parsed, err := schema.ParseSchemaVersion(query.Get(name))
+		if err != nil {
+			writePortalError(w, err)
+			return
+		}
+		if !parsed.IsLatest() {
+			*v = parsed
+		} else {
+			*v = versions[len(versions)-1]
+		}
+	}
+
+	diff, err := s.app.SchemaService().Diff(r.Context(), subject, page.From, page.To)
+	switch {
+	case err == nil:
+		page.SchemaType, page.Changes = schema.SchemaType(diff.SchemaType), diff.Changes
+	case errors.Is(err, schemadiff.ErrUnsupportedSchemaType):
+		if page.Lines, page.SchemaType, err = s.portalLineDiff(r, subject, page.From, page.To); err != nil {
+			writePortalError(w, err)
+			return
+		}
+	default:
+		writePortalError(w, err)
+		return
+	}
+
+	renderPortal(w, http.StatusOK, "diff", page)
+}
+
+// portalLineDiff returns the line diff of two versions and the schema type of the later one
+func (s *HttpServer) portalLineDiff(r *http.Request, subject schema.Subject, from, to schema.SchemaVersion) ([]string, schema.SchemaType, error) {
+	previous, err := s.app.SchemaService().Get(r.Context(), subject, from)
+	if err != nil {
+		return nil, "", err
+	}
+	current, err := s.app.SchemaService().Get(r.Context(), subject, to)
+	if err != nil {
+		return nil, "", err
+	}
+
+	diff := schemadiff.LineDiff(previous.Content(), current.Content())
+	if diff == "" {
+		return nil, current.Type(), nil
+	}
+
+	return strings.Split(diff, "\n"), current.Type(), nil
+}
+
+// GET /ui/login, the sign in form of the portal when authentication is enabled
+func (s *HttpServer) portalLoginForm(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	if s.app.Authenticator() == nil {
+		http.Redirect(w, r, "/ui", http.StatusSeeOther)
+		return
+	}
+
+	renderPortal(w, http.StatusOK, "login", portalLoginPage{})
+}
+
+// POST /ui/login, checks the api key or bearer token of the form and starts a session for the portal pages.
+// The cookie holds the opaque session id only, it lives until the browser is closed or the session expires.
+func (s *HttpServer) portalLogin(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	authenticator := s.app.Authenticator()
+	if authenticator == nil {
+		http.Redirect(w, r, "/ui", http.StatusSeeOther)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxLoginFormSize)
+	credentials := portalCredentials(strings.TrimSpace(r.PostFormValue("credential")))
+	if _, err := authenticator.Authenticate(r.Context(), credentials); err != nil {
+		renderPortal(w, http.StatusUnauthorized, "login", portalLoginPage{Error: "The api key or token is not valid."})
+		return
+	}
+
+	id, err := s.sessions.create(credentials)
+	if err != nil {
+		writePortalError(w, err)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     portalCookieName,
+		Value:    id,
+		Path:     "/ui",
+		HttpOnly: true,
+		Secure:   s.secureCookies,
+		SameSite: http.SameSiteStrictMode,
+	})
+	http.Redirect(w, r, "/ui", http.StatusSeeOther)
+}
+
+// GET /ui/assets/{name}
+func (s *HttpServer) portalAsset(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	if !strings.HasSuffix(params["name"], ".css") {
+		writePortalProblem(w, problemRouteNotFound, errRouteNotFound(r.URL.Path))
+		return
+	}
+
+	b, err := portalFiles.ReadFile("portal/" + params["name"])
+	if err != nil {
+		writePortalProblem(w, problemRouteNotFound, errRouteNotFound(r.URL.Path))
+		return
+	}
+
+	w.Header().Set(contentTypeHeaderKey, "text/css; charset=utf-8")
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	_, _ = w.Write(b)
+}
+
+// portalFieldsOf returns the fields of an avro schema depth first, the types of references of the schema are
+// linked to their subjects
+func portalFieldsOf(sc *schema.Schema) ([]portalField, error) {
+	root, err := avro.Parse(sc.Content())
+	if err != nil {
+		return nil, err
+	}
+
+	references := make(map[string]schema.Reference, len(sc.References()))
+	for _, ref := range sc.References() {
+		references[ref.Name] = ref
+	}
+
+	var fields []portalField
+	root.Walk(func(path string, node *avro.Schema, field *avro.Field) bool {
+		// union branches are visited with the field of the union
+		if field == nil || node != field.Type {
+			return true
+		}
+
+		f := portalField{Path: path, Type: node.String(), LogicalType: logicalTypeOf(node), Doc: field.Doc, HasDefault: field.HasDefault}
+		if field.HasDefault {
+			b, _ := json.Marshal(field.Default)
+			f.Default = string(b)
+		}
+		for _, name := range referencedNames(root, node) {
+			if ref, ok := references[name]; ok {
+				f.Links = append(f.Links, ref)
+			}
+		}
+		fields = append(fields, f)
+
+		return true
+	})
+
+	return fields, nil
+}
+
+// logicalTypeOf returns the logical type of a type or of the branches of a union
+func logicalTypeOf(node *avro.Schema) string {
+	if node.Type != avro.Union {
+		return node.LogicalType
+	}
+
+	var logical []string
+	for _, b := range node.Branches {
+		if b.LogicalType != "" {
+			logical = append(logical, b.LogicalType)
+		}
+	}
+
+	return strings.Join(logical, ", ")
+}
+
+// referencedNames returns the names used by a type that are not defined in root
+func referencedNames(root, node *avro.Schema) []string {
+	switch node.Type {
+	case avro.Reference:
+		if root.Lookup(node.Name) == nil {
+			return []string{node.Name}
+		}
+	case avro.Array:
+		return referencedNames(root, node.Items)
+	case avro.Map:
+		return referencedNames(root, node.Values)
+	case avro.Union:
+		var names []string
+		for _, b := range node.Branches {
+			names = append(names, referencedNames(root, b)...)
+		}
+		return names
+	}
+
+	return nil
+}
+
+// indentContent indents json schemas, other contents are returned as they are
+func indentContent(content string) string {
+	var b bytes.Buffer
+	if err := json.Indent(&b, []byte(content), "", "  "); err != nil {
+		return content
+	}
+
+	return b.String()
+}
+
+func renderPortal(w http.ResponseWriter, status int, page string, data interface{}) {
+	var b bytes.Buffer
+	if err := portalTemplates[page].ExecuteTemplate(&b, "layout", data); err != nil {
+		log.Printf("portal: page %s could not be rendered, trace: %v", page, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set(contentTypeHeaderKey, contentTypeHTML)
+	w.WriteHeader(status)
+	_, _ = w.Write(b.Bytes())
+}
+
+// writePortalProblem renders a problem as an html page, details of internal errors are not exposed
+func writePortalProblem(w http.ResponseWriter, pt problemType, err error) {
+	page := portalErrorPage{Title: pt.title}
+	if err != nil && pt != problemInternal {
+		page.Detail = err.Error()
+	}
+
+	renderPortal(w, pt.status, "error", page)
+}
+
+// writePortalError renders err returned by a service as an html page
+func writePortalError(w http.ResponseWriter, err error) {
+	writePortalProblem(w, problemOf(err), err)
+}
@@ -0,0 +1,68 @@
+package ports
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"sync"
+	"time"
+
+	"github.com/ybalcin/event-schema-manager/internal/core/application/auth"
+)
+
+type (
+	// portalSessions are the sessions of the portal sign in form by their opaque ids. The credentials of a session
+	// stay on the server and are authenticated on every request, so revoked keys and expired tokens end it.
+	portalSessions struct {
+		mu       sync.Mutex
+		sessions map[string]portalSession
+		ttl      time.Duration
+		now      func() time.Time
+	}
+
+	portalSession struct {
+		credentials auth.Credentials
+		expires     time.Time
+	}
+)
+
+// portalSessionTTL is how long a session lasts after signing in
+const portalSessionTTL = 8 * time.Hour
+
+func newPortalSessions() *portalSessions {
+	return &portalSessions{sessions: make(map[string]portalSession), ttl: portalSessionTTL, now: time.Now}
+}
+
+// create keeps credentials in a new session and returns its id, expired sessions are removed
+func (s *portalSessions) create(credentials auth.Credentials) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	id := base64.RawURLEncoding.EncodeToString(b)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	for sid, session := range s.sessions {
+		if !now.Before(session.expires) {
+			delete(s.sessions, sid)
+		}
+	}
+	s.sessions[id] = portalSession{credentials: credentials, expires: now.Add(s.ttl)}
+
+	return id, nil
+}
+
+// credentials returns the credentials of the session id, false if it is unknown or expired
+func (s *portalSessions) credentials(id string) (auth.Credentials, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[id]
+	if !ok || !s.now().Before(session.expires) {
+		return auth.Credentials{}, false
+	}
+
+	return session.credentials, true
+}
@@ -0,0 +1,185 @@
+package ports
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ybalcin/event-schema-manager/internal/core/application/auth"
+	"github.com/ybalcin/event-schema-manager/internal/core/application/changelog"
+	"github.com/ybalcin/event-schema-manager/internal/core/domain/schema"
+	"github.com/ybalcin/event-schema-manager/internal/shared/config"
+	"github.com/ybalcin/event-schema-manager/pkg/schemadiff"
+)
+
+func TestPortalFieldsOf(t *testing.T) {
+	sc, err := schema.NewSchema("orders-value", schema.SchemaTypeAvro, `{
+		"type": "record", "name": "Order", "namespace": "com.acme",
+		"fields": [
+			{"name": "id", "type": "string", "doc": "order id"},
+			{"name": "total", "type": {"type": "bytes", "logicalType": "decimal", "precision": 10, "scale": 2}},
+			{"name": "placedAt", "type": ["null", {"type": "long", "logicalType": "timestamp-millis"}], "default": null},
+			{"name": "customer", "type": "com.acme.Customer"},
+			{"name": "lines", "type": {"type": "array", "items": {"type": "record", "name": "Line", "fields": [
+				{"name": "sku", "type": "string", "default": "unknown"}
+			]}}}
+		]
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	customer := schema.Reference{Name: "com.acme.Customer", Subject: "customers-value", Version: 2}
+	sc = sc.WithReferences([]schema.Reference{customer})
+
+	fields, err := portalFieldsOf(sc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustEqual(t, fields, []portalField{
+		{Path: "$.id", Type: "string", Doc: "order id"},
+		{Path: "$.total", Type: "bytes(decimal)", LogicalType: "decimal"},
+		{Path: "$.placedAt", Type: "union<null, long(timestamp-millis)>", LogicalType: "timestamp-millis", Default: "null", HasDefault: true},
+		{Path: "$.customer", Type: "com.acme.Customer", Links: []schema.Reference{customer}},
+		{Path: "$.lines", Type: "array<com.acme.Line>"},
+		{Path: "$.lines[].sku", Type: "string", Default: `"unknown"`, HasDefault: true},
+	})
+}
+
+func TestRenderPortal(t *testing.T) {
+	sc := schema.RestoreSchema("orders/value", 2, 7, schema.SchemaTypeAvro, `{"type":"record","name":"Order","fields":[{"name":"id","type":"long"}]}`)
+	first := portalVersion{Entry: changelog.Entry{Version: 1, SchemaID: 3, Initial: true}}
+	second := portalVersion{Entry: changelog.Entry{Version: 2, SchemaID: 7, Author: "<alice>", Changes: []schemadiff.Change{{Kind: schemadiff.TypeChanged}}}, Previous: 1}
+
+	for _, tc := range []struct {
+		page     string
+		data     interface{}
+		contains []string
+	}{
+		{
+			page:     "subjects",
+			data:     portalSubjectsPage{Query: "orders", Subjects: []schema.Subject{"orders/value"}, Total: 3},
+			contains: []string{`<a href="/ui/subjects/orders%2Fvalue">orders/value</a>`, "1 of 3 subjects"},
+		},
+		{
+			page: "subject",
+			data: portalSubjectPage{
+				Subject: "orders/value", Schema: sc, Previous: 1, Content: sc.Content(),
+				Fields:   []portalField{{Path: "$.id", Type: "long"}},
+				Versions: []schema.SchemaVersion{1, 2},
+			},
+			contains: []string{
+				"Version 2 of 2", `<code>$.id</code>`, `<li class="current"><a href="/ui/subjects/orders%2Fvalue?version=2">2</a></li>`,
+				`href="/ui/subjects/orders%2Fvalue/diff?from=1&amp;to=2"`, `href="/ui/subjects/orders%2Fvalue/history"`,
+			},
+		},
+		{
+			page: "history",
+			data: portalHistoryPage{Subject: "orders/value", History: []portalVersion{second, first}},
+			contains: []string{
+				"&lt;alice&gt;", "1 changes", "initial version", `href="/ui/subjects/orders%2Fvalue/diff?from=1&amp;to=2"`,
+			},
+		},
+		{
+			page:     "login",
+			data:     portalLoginPage{Error: "The api key or token is not valid."},
+			contains: []string{`<form class="search" action="/ui/login" method="post">`, "The api key or token is not valid."},
+		},
+		{
+			page:     "diff",
+			data:     portalDiffPage{Subject: "orders-value", Versions: []schema.SchemaVersion{1, 2}, From: 1, To: 2, Lines: []string{"-a", "+b"}},
+			contains: []string{`<option selected>1</option>`, `<span class="removed">-a</span>`, `<span class="added">&#43;b</span>`},
+		},
+		{
+			page:     "error",
+			data:     portalErrorPage{Title: "Subject not found", Detail: "subject not found"},
+			contains: []string{"<h1>Subject not found</h1>"},
+		},
+	} {
+		rec := httptest.NewRecorder()
+		renderPortal(rec, http.StatusOK, tc.page, tc.data)
+
+		mustEqual(t, rec.Code, http.StatusOK)
+		mustEqual(t, rec.Header().Get(contentTypeHeaderKey), contentTypeHTML)
+		for _, s := range tc.contains {
+			if !strings.Contains(rec.Body.String(), s) {
+				t.Errorf("page %s does not contain %s:\n%s", tc.page, s, rec.Body.String())
+			}
+		}
+	}
+}
+
+func TestHttpServer_PortalLogin(t *testing.T) {
+	s, _ := newTestServer(t, &config.AppConfig{Auth: config.AuthConfig{
+		APIKeys: []config.APIKeyConfig{{Name: "ci", SHA256: auth.HashAPIKey("s3cret"), Roles: []string{"viewer"}}},
+	}})
+
+	// pages without credentials lead to the sign in form, the form and the assets are public
+	rec := serve(s, http.MethodGet, "/ui", "")
+	mustEqual(t, rec.Code, http.StatusSeeOther)
+	mustEqual(t, rec.Header().Get("Location"), "/ui/login")
+	mustEqual(t, serve(s, http.MethodGet, "/ui/login", "").Code, http.StatusOK)
+	mustEqual(t, serve(s, http.MethodGet, "/ui/assets/portal.css", "").Code, http.StatusOK)
+
+	login := func(credential string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/ui/login", strings.NewReader(url.Values{"credential": {credential}}.Encode()))
+		req.Header.Set(contentTypeHeaderKey, "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		return rec
+	}
+	mustEqual(t, login("wrong").Code, http.StatusUnauthorized)
+
+	rec = login("s3cret")
+	mustEqual(t, rec.Code, http.StatusSeeOther)
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || !cookies[0].HttpOnly || !cookies[0].Secure || cookies[0].SameSite != http.SameSiteStrictMode {
+		t.Fatalf("expected a secure, http only, same site cookie, but got %+v", cookies)
+	}
+	// the cookie holds an opaque session id, not the api key
+	if strings.Contains(cookies[0].Value, "s3cret") || cookies[0].Value == base64.RawURLEncoding.EncodeToString([]byte("s3cret")) {
+		t.Fatalf("expected the cookie not to hold the api key, but got %s", cookies[0].Value)
+	}
+
+	withCookie := func(method, path string) int {
+		req := httptest.NewRequest(method, path, nil)
+		req.AddCookie(cookies[0])
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	mustEqual(t, withCookie(http.MethodGet, "/ui"), http.StatusOK)
+	// the cookie is not accepted by the api
+	mustEqual(t, withCookie(http.MethodGet, "/subjects"), http.StatusUnauthorized)
+
+	// unknown session ids lead to the sign in form
+	cookies[0].Value = "forged"
+	mustEqual(t, withCookie(http.MethodGet, "/ui"), http.StatusSeeOther)
+}
+
+func TestPortalSessions(t *testing.T) {
+	sessions := newPortalSessions()
+	now := time.Now()
+	sessions.now = func() time.Time { return now }
+
+	id, err := sessions.create(auth.Credentials{APIKey: "s3cret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	credentials, ok := sessions.credentials(id)
+	mustEqual(t, ok, true)
+	mustEqual(t, credentials, auth.Credentials{APIKey: "s3cret"})
+
+	now = now.Add(portalSessionTTL)
+	_, ok = sessions.credentials(id)
+	mustEqual(t, ok, false)
+
+	// expired sessions are removed when a session is created
+	if _, err = sessions.create(auth.Credentials{APIKey: "other"}); err != nil {
+		t.Fatal(err)
+	}
+	mustEqual(t, len(sessions.sessions), 1)
+}
@@ -0,0 +1,157 @@
+package ports
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/ybalcin/event-schema-manager/internal/core/application/audit"
+	"github.com/ybalcin/event-schema-manager/internal/core/application/auth"
+	"github.com/ybalcin/event-schema-manager/internal/core/application/linting"
+	"github.com/ybalcin/event-schema-manager/internal/core/application/ownership"
+	"github.com/ybalcin/event-schema-manager/internal/core/application/proposals"
+	"github.com/ybalcin/event-schema-manager/internal/core/application/search"
+	"github.com/ybalcin/event-schema-manager/internal/core/application/webhooks"
+	"github.com/ybalcin/event-schema-manager/internal/core/domain/schema"
+	"github.com/ybalcin/event-schema-manager/pkg/schemadiff"
+)
+
+// problem is an RFC 7807 problem details response
+type (
+	problem struct {
+		Type     string `json:"type"`
+		Title    string `json:"title"`
+		Status   int    `json:"status"`
+		Detail   string `json:"detail,omitempty"`
+		Instance string `json:"instance,omitempty"`
+		// Reasons of incompatible schemas
+		Reasons []string `json:"reasons,omitempty"`
+		// Violations of rejected schemas
+		Violations []linting.Violation `json:"violations,omitempty"`
+	}
+
+	problemType struct {
+		uri    string
+		title  string
+		status int
+	}
+)
+
+const (
+	contentTypeProblemJSON = "application/problem+json"
+
+	// problemTypeBaseUri is the base of problem type uris, they are stable and must not be changed
+	problemTypeBaseUri = "https://github.com/ybalcin/event-schema-manager/problems/"
+)
+
+var (
+	problemInvalidSubject            = problemType{problemTypeBaseUri + "invalid-subject", "Invalid subject", http.StatusUnprocessableEntity}
+	problemInvalidSchemaID           = problemType{problemTypeBaseUri + "invalid-schema-id", "Invalid schema id", http.StatusUnprocessableEntity}
+	problemSubjectNotFound           = problemType{problemTypeBaseUri + "subject-not-found", "Subject not found", http.StatusNotFound}
+	problemVersionNotFound           = problemType{problemTypeBaseUri + "version-not-found", "Version not found", http.StatusNotFound}
+	problemSchemaNotFound            = problemType{problemTypeBaseUri + "schema-not-found", "Schema not found", http.StatusNotFound}
+	problemInvalidSchema             = problemType{problemTypeBaseUri + "invalid-schema", "Invalid schema", http.StatusUnprocessableEntity}
+	problemInvalidVersion            = problemType{problemTypeBaseUri + "invalid-version", "Invalid version", http.StatusUnprocessableEntity}
+	problemInvalidCompatibilityLevel = problemType{problemTypeBaseUri + "invalid-compatibility-level", "Invalid compatibility level", http.StatusUnprocessableEntity}
+	problemIncompatibleSchema        = problemType{problemTypeBaseUri + "incompatible-schema", "Incompatible schema", http.StatusConflict}
+	problemLintFailed                = problemType{problemTypeBaseUri + "lint-failed", "Schema lint failed", http.StatusUnprocessableEntity}
+	problemRegistryUnavailable       = problemType{problemTypeBaseUri + "registry-unavailable", "Schema registry unavailable", http.StatusServiceUnavailable}
+	problemInvalidSubscription       = problemType{problemTypeBaseUri + "invalid-subscription", "Invalid webhook subscription", http.StatusUnprocessableEntity}
+	problemSubscriptionNotFound      = problemType{problemTypeBaseUri + "subscription-not-found", "Webhook subscription not found", http.StatusNotFound}
+	problemDeadLetterNotFound        = problemType{problemTypeBaseUri + "dead-letter-not-found", "Dead letter not found", http.StatusNotFound}
+	problemOwnerNotFound             = problemType{problemTypeBaseUri + "owner-not-found", "Owner not found", http.StatusNotFound}
+	problemInvalidOwner              = problemType{problemTypeBaseUri + "invalid-owner", "Invalid owner", http.StatusUnprocessableEntity}
+	problemNotOwner                  = problemType{problemTypeBaseUri + "not-owner", "Not owner", http.StatusForbidden}
+	problemProposalNotFound          = problemType{problemTypeBaseUri + "proposal-not-found", "Proposal not found", http.StatusNotFound}
+	problemInvalidProposal           = problemType{problemTypeBaseUri + "invalid-proposal", "Invalid proposal", http.StatusUnprocessableEntity}
+	problemInvalidProposalState      = problemType{problemTypeBaseUri + "invalid-proposal-state", "Invalid proposal state", http.StatusConflict}
+	problemProposalNotAllowed        = problemType{problemTypeBaseUri + "proposal-action-not-allowed", "Proposal action not allowed", http.StatusForbidden}
+	problemApprovalRequired          = problemType{problemTypeBaseUri + "approval-required", "Approval required", http.StatusConflict}
+	problemUnauthenticated           = problemType{problemTypeBaseUri + "unauthenticated", "Unauthenticated", http.StatusUnauthorized}
+	problemForbidden                 = problemType{problemTypeBaseUri + "forbidden", "Forbidden", http.StatusForbidden}
+	problemInvalidSearchQuery        = problemType{problemTypeBaseUri + "invalid-search-query", "Invalid search query", http.StatusUnprocessableEntity}
+	problemDiffNotSupported          = problemType{problemTypeBaseUri + "diff-not-supported", "Diff not supported", http.StatusUnprocessableEntity}
+	problemBadRequest                = problemType{problemTypeBaseUri + "bad-request", "Bad request", http.StatusBadRequest}
+	problemRouteNotFound             = problemType{problemTypeBaseUri + "route-not-found", "Route not found", http.StatusNotFound}
+	problemMethodNotAllowed          = problemType{problemTypeBaseUri + "method-not-allowed", "Method not allowed", http.StatusMethodNotAllowed}
+	problemAuditNotRecorded          = problemType{problemTypeBaseUri + "audit-not-recorded", "Change not audited", http.StatusInternalServerError}
+	problemInternal                  = problemType{problemTypeBaseUri + "internal-error", "Internal server error", http.StatusInternalServerError}
+)
+
+// domainProblems maps domain errors to the problem types they are reported as
+var domainProblems = []struct {
+	err         error
+	problemType problemType
+}{
+	{schema.ErrInvalidSubject, problemInvalidSubject},
+	{schema.ErrInvalidSchemaID, problemInvalidSchemaID},
+	{schema.ErrSubjectNotFound, problemSubjectNotFound},
+	{schema.ErrVersionNotFound, problemVersionNotFound},
+	{schema.ErrSchemaNotFound, problemSchemaNotFound},
+	{schema.ErrInvalidSchema, problemInvalidSchema},
+	{schema.ErrInvalidVersion, problemInvalidVersion},
+	{schema.ErrInvalidCompatibilityLevel, problemInvalidCompatibilityLevel},
+	{schema.ErrIncompatibleSchema, problemIncompatibleSchema},
+	{schema.ErrRegistryUnavailable, problemRegistryUnavailable},
+	{linting.ErrLintFailed, problemLintFailed},
+	{auth.ErrUnauthenticated, problemUnauthenticated},
+	{auth.ErrForbidden, problemForbidden},
+	{ownership.ErrOwnerNotFound, problemOwnerNotFound},
+	{ownership.ErrInvalidOwner, problemInvalidOwner},
+	{ownership.ErrNotOwner, problemNotOwner},
+	{proposals.ErrProposalNotFound, problemProposalNotFound},
+	{proposals.ErrInvalidProposal, problemInvalidProposal},
+	{proposals.ErrInvalidTransition, problemInvalidProposalState},
+	{proposals.ErrNotAllowed, problemProposalNotAllowed},
+	{proposals.ErrApprovalRequired, problemApprovalRequired},
+	{schemadiff.ErrUnsupportedSchemaType, problemDiffNotSupported},
+	{search.ErrInvalidQuery, problemInvalidSearchQuery},
+	{webhooks.ErrInvalidSubscription, problemInvalidSubscription},
+	{webhooks.ErrSubscriptionNotFound, problemSubscriptionNotFound},
+	{webhooks.ErrDeadLetterNotFound, problemDeadLetterNotFound},
+	{audit.ErrNotRecorded, problemAuditNotRecorded},
+}
+
+// problemOf returns the problem type of err, errors unknown to the api are internal errors
+func problemOf(err error) problemType {
+	for _, dp := range domainProblems {
+		if errors.Is(err, dp.err) {
+			return dp.problemType
+		}
+	}
+
+	return problemInternal
+}
+
+func writeProblem(w http.ResponseWriter, r *http.Request, pt problemType, err error) {
+	p := problem{
+		Type:     pt.uri,
+		Title:    pt.title,
+		Status:   pt.status,
+		Instance: r.URL.Path,
+	}
+
+	// details of internal errors are not exposed
+	if err != nil && pt != problemInternal {
+		p.Detail = err.Error()
+	}
+
+	var incompatibleErr *schema.IncompatibleError
+	if errors.As(err, &incompatibleErr) {
+		p.Reasons = incompatibleErr.Reasons
+	}
+
+	var lintErr *linting.Error
+	if errors.As(err, &lintErr) {
+		p.Violations = lintErr.Report.Violations
+	}
+
+	w.Header().Set(contentTypeHeaderKey, contentTypeProblemJSON)
+	w.WriteHeader(pt.status)
+	_ = json.NewEncoder(w).Encode(p)
+}
+
+// writeServiceError writes err returned by a service as a problem
+func writeServiceError(w http.ResponseWriter, r *http.Request, err error) {
+	writeProblem(w, r, problemOf(err), err)
+}
@@ -0,0 +1,73 @@
+package ports
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ybalcin/event-schema-manager/internal/core/application/auth"
+	"github.com/ybalcin/event-schema-manager/internal/core/application/linting"
+	"github.com/ybalcin/event-schema-manager/internal/core/application/proposals"
+	"github.com/ybalcin/event-schema-manager/internal/core/domain/schema"
+)
+
+func TestProblemOf(t *testing.T) {
+	for _, tc := range []struct {
+		err      error
+		expected problemType
+	}{
+		{fmt.Errorf("%w: orders", schema.ErrSubjectNotFound), problemSubjectNotFound},
+		{fmt.Errorf("%w: 0", schema.ErrInvalidSchemaID), problemInvalidSchemaID},
+		{fmt.Errorf("%w: bad", schema.ErrInvalidSubject), problemInvalidSubject},
+		{&schema.IncompatibleError{Subject: "orders"}, problemIncompatibleSchema},
+		{&linting.Error{}, problemLintFailed},
+		{fmt.Errorf("%w: down", schema.ErrRegistryUnavailable), problemRegistryUnavailable},
+		{auth.ErrForbidden, problemForbidden},
+		{proposals.ErrApprovalRequired, problemApprovalRequired},
+		{errors.New("disk is full"), problemInternal},
+	} {
+		mustEqual(t, problemOf(tc.err), tc.expected)
+	}
+}
+
+func TestWriteProblem(t *testing.T) {
+	violations := []linting.Violation{{Rule: "doc-required", Severity: linting.SeverityError, Path: "$.id", Message: "field has no doc"}}
+	lintErr := &linting.Error{Report: linting.Report{Violations: violations}}
+
+	for _, tc := range []struct {
+		err      error
+		expected problem
+	}{
+		{
+			err: &schema.IncompatibleError{Subject: "orders", Reasons: []string{"field id removed"}},
+			expected: problem{
+				Type: problemIncompatibleSchema.uri, Title: "Incompatible schema", Status: http.StatusConflict,
+				Detail: "incompatible schema: orders, field id removed", Instance: "/subjects/orders/versions",
+				Reasons: []string{"field id removed"},
+			},
+		},
+		{
+			err: lintErr,
+			expected: problem{
+				Type: problemLintFailed.uri, Title: "Schema lint failed", Status: http.StatusUnprocessableEntity,
+				Detail: lintErr.Error(), Instance: "/subjects/orders/versions", Violations: violations,
+			},
+		},
+		{
+			// details of internal errors are not exposed
+			err: errors.New("open /var/lib/outbox: permission denied"),
+			expected: problem{
+				Type: problemInternal.uri, Title: "Internal server error", Status: http.StatusInternalServerError,
+				Instance: "/subjects/orders/versions",
+			},
+		},
+	} {
+		rec := httptest.NewRecorder()
+		writeServiceError(rec, httptest.NewRequest(http.MethodPost, "/subjects/orders/versions", nil), tc.err)
+
+		mustEqual(t, rec.Code, tc.expected.Status)
+		mustEqual(t, problemOfResponse(t, rec), tc.expected)
+	}
+}
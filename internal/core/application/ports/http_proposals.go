@@ -0,0 +1,145 @@
+package ports
+
+import (
+	"net/http"
+
+	"github.com/ybalcin/event-schema-manager/internal/core/application/proposals"
+	"github.com/ybalcin/event-schema-manager/internal/core/domain/schema"
+)
+
+// POST /proposals
+func (s *HttpServer) createProposal(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	var req proposalRequest
+	if err := readJSON(w, r, &req); err != nil {
+		writeProblem(w, r, problemBadRequest, err)
+		return
+	}
+	if req.Schema == "" {
+		writeProblem(w, r, problemBadRequest, errRequired("schema"))
+		return
+	}
+
+	subject, err := schema.NewSubject(req.Subject)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	sc, err := schema.NewSchema(subject, req.SchemaType, req.Schema)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	proposal, err := s.app.Proposals().Create(r.Context(), sc, req.Description)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, proposal)
+}
+
+// GET /proposals?subject=&state=
+func (s *HttpServer) listProposals(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	filter := proposals.Filter{
+		Subject: schema.Subject(r.URL.Query().Get("subject")),
+		State:   proposals.State(r.URL.Query().Get("state")),
+	}
+
+	list, err := s.app.Proposals().List(r.Context(), filter)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, list)
+}
+
+// GET /proposals/{id}
+func (s *HttpServer) getProposal(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	proposal, err := s.app.Proposals().Get(r.Context(), params["id"])
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, proposal)
+}
+
+// PUT /proposals/{id}
+func (s *HttpServer) editProposal(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	var req proposalRequest
+	if err := readJSON(w, r, &req); err != nil {
+		writeProblem(w, r, problemBadRequest, err)
+		return
+	}
+	if req.Schema == "" {
+		writeProblem(w, r, problemBadRequest, errRequired("schema"))
+		return
+	}
+
+	proposal, err := s.app.Proposals().Edit(r.Context(), params["id"], req.SchemaType, req.Schema, req.Description)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, proposal)
+}
+
+// POST /proposals/{id}/submit
+func (s *HttpServer) submitProposal(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	proposal, err := s.app.Proposals().Submit(r.Context(), params["id"])
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, proposal)
+}
+
+// POST /proposals/{id}/reviews
+func (s *HttpServer) reviewProposal(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	var req reviewRequest
+	if err := readJSON(w, r, &req); err != nil {
+		writeProblem(w, r, problemBadRequest, err)
+		return
+	}
+
+	proposal, err := s.app.Proposals().Review(r.Context(), params["id"], req.Decision, req.Comment)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, proposal)
+}
+
+// POST /proposals/{id}/comments
+func (s *HttpServer) commentProposal(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	var req commentRequest
+	if err := readJSON(w, r, &req); err != nil {
+		writeProblem(w, r, problemBadRequest, err)
+		return
+	}
+
+	proposal, err := s.app.Proposals().Comment(r.Context(), params["id"], req.Text)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, proposal)
+}
+
+// POST /proposals/{id}/apply
+func (s *HttpServer) applyProposal(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	proposal, err := s.app.Proposals().Apply(r.Context(), params["id"])
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, proposal)
+}
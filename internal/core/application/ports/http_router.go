@@ -0,0 +1,92 @@
+package ports
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type (
+	// handlerFunc handles a request matched by a route, params holds the unescaped path parameters
+	handlerFunc func(w http.ResponseWriter, r *http.Request, params map[string]string)
+
+	route struct {
+		method   string
+		segments []string
+		handler  handlerFunc
+	}
+
+	// router matches requests by method and path patterns like /subjects/{subject}/versions
+	router struct {
+		routes []route
+	}
+)
+
+func (rt *router) handle(method, pattern string, handler handlerFunc) {
+	rt.routes = append(rt.routes, route{
+		method:   method,
+		segments: splitPath(pattern),
+		handler:  handler,
+	})
+}
+
+func (rt *router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	segments := splitPath(r.URL.EscapedPath())
+
+	var allowed []string
+	for _, rte := range rt.routes {
+		params, ok := rte.match(segments)
+		if !ok {
+			continue
+		}
+
+		if rte.method != r.Method {
+			allowed = append(allowed, rte.method)
+			continue
+		}
+
+		rte.handler(w, r, params)
+		return
+	}
+
+	if len(allowed) > 0 {
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		writeProblem(w, r, problemMethodNotAllowed, errMethodNotAllowed(r.Method))
+		return
+	}
+
+	writeProblem(w, r, problemRouteNotFound, errRouteNotFound(r.URL.Path))
+}
+
+func (rte route) match(segments []string) (map[string]string, bool) {
+	if len(segments) != len(rte.segments) {
+		return nil, false
+	}
+
+	params := make(map[string]string)
+	for i, s := range rte.segments {
+		if strings.HasPrefix(s, "{") && strings.HasSuffix(s, "}") {
+			value, err := url.PathUnescape(segments[i])
+			if err != nil || value == "" {
+				return nil, false
+			}
+			params[s[1:len(s)-1]] = value
+			continue
+		}
+
+		if s != segments[i] {
+			return nil, false
+		}
+	}
+
+	return params, true
+}
+
+func splitPath(path string) []string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil
+	}
+
+	return strings.Split(path, "/")
+}
@@ -0,0 +1,56 @@
+package ports
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/ybalcin/event-schema-manager/internal/core/application/auth"
+	"github.com/ybalcin/event-schema-manager/internal/core/application/search"
+	"github.com/ybalcin/event-schema-manager/internal/core/domain/schema"
+)
+
+// GET /search?q=&field=&type=&logicalType=&namespace=&subject=&schemaType=&allVersions=&limit=, the results are
+// limited to the subjects the caller can read
+func (s *HttpServer) search(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	values := r.URL.Query()
+	query := search.Query{
+		Text:        values.Get("q"),
+		Field:       values.Get("field"),
+		Type:        values.Get("type"),
+		LogicalType: values.Get("logicalType"),
+		Namespace:   values.Get("namespace"),
+		Subject:     values.Get("subject"),
+		Readable: func(subject schema.Subject) bool {
+			return s.app.Authorize(r.Context(), auth.PermissionRead, subject) == nil
+		},
+	}
+
+	var err error
+	if schemaType := values.Get("schemaType"); schemaType != "" {
+		if query.SchemaType, err = schema.ParseSchemaType(schemaType); err != nil {
+			writeProblem(w, r, problemBadRequest, err)
+			return
+		}
+	}
+	if allVersions := values.Get("allVersions"); allVersions != "" {
+		if query.AllVersions, err = strconv.ParseBool(allVersions); err != nil {
+			writeProblem(w, r, problemBadRequest, fmt.Errorf("allVersions %s must be true or false", allVersions))
+			return
+		}
+	}
+	if limit := values.Get("limit"); limit != "" {
+		if query.Limit, err = strconv.Atoi(limit); err != nil || query.Limit <= 0 {
+			writeProblem(w, r, problemBadRequest, fmt.Errorf("limit %s must be a positive number", limit))
+			return
+		}
+	}
+
+	results, err := s.app.Search().Search(query)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, results)
+}
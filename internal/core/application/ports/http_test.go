@@ -0,0 +1,237 @@
+package ports
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/ybalcin/event-schema-manager/internal/core/application"
+	"github.com/ybalcin/event-schema-manager/internal/core/application/auth"
+	"github.com/ybalcin/event-schema-manager/internal/shared/config"
+	"github.com/ybalcin/event-schema-manager/pkg/schemaregistry/schemaregistrytest"
+)
+
+const (
+	orderSchema   = `{"type":"record","name":"Order","fields":[{"name":"id","type":"string"}]}`
+	orderSchemaV2 = `{"type":"record","name":"Order","fields":[{"name":"id","type":"string"},{"name":"note","type":["null","string"],"default":null}]}`
+)
+
+func mustEqual(t *testing.T, actual, expected interface{}) {
+	t.Helper()
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %#v, but got %#v", expected, actual)
+	}
+}
+
+func newTestServer(t *testing.T, cfg *config.AppConfig) (*HttpServer, *schemaregistrytest.Registry) {
+	t.Helper()
+
+	if cfg == nil {
+		cfg = &config.AppConfig{}
+	}
+	registry := schemaregistrytest.NewRegistry()
+
+	return NewHttpServer(cfg, application.WithSchemaRegistryClient(registry)), registry
+}
+
+func serve(s *HttpServer, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+
+	return rec
+}
+
+func schemaBody(content string) string {
+	b, _ := json.Marshal(schemaRequest{Schema: content})
+	return string(b)
+}
+
+func problemOfResponse(t *testing.T, rec *httptest.ResponseRecorder) problem {
+	t.Helper()
+
+	mustEqual(t, rec.Header().Get(contentTypeHeaderKey), contentTypeProblemJSON)
+	var p problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &p); err != nil {
+		t.Fatalf("response is not a problem: %v, %s", err, rec.Body.String())
+	}
+
+	return p
+}
+
+func TestHttpServer_Routes(t *testing.T) {
+	s, _ := newTestServer(t, nil)
+
+	for _, tc := range []struct {
+		method string
+		path   string
+		body   string
+		status int
+		json   string
+	}{
+		{http.MethodGet, "/subjects", "", http.StatusOK, `[]`},
+		{http.MethodPost, "/subjects/orders%2Fvalue/versions", schemaBody(orderSchema), http.StatusCreated, `{"id":1,"version":1,"status":"created"}`},
+		{http.MethodPost, "/subjects/orders%2Fvalue/versions", schemaBody(orderSchema), http.StatusOK, `{"id":1,"version":1,"status":"unchanged"}`},
+		{http.MethodPost, "/subjects/orders%2Fvalue/versions", schemaBody(orderSchemaV2), http.StatusCreated, `{"id":2,"version":2,"status":"created"}`},
+		{http.MethodGet, "/subjects", "", http.StatusOK, `["orders/value"]`},
+		{http.MethodGet, "/subjects/orders%2Fvalue/versions", "", http.StatusOK, `[1,2]`},
+		{http.MethodGet, "/subjects/orders%2Fvalue/versions/1", "", http.StatusOK,
+			`{"subject":"orders/value","version":1,"id":1,"schemaType":"AVRO","schema":` + jsonString(orderSchema) + `}`},
+		{http.MethodGet, "/schemas/ids/2", "", http.StatusOK, `{"schema":` + jsonString(orderSchemaV2) + `,"schemaType":"AVRO"}`},
+		{http.MethodPost, "/compatibility/subjects/orders%2Fvalue/versions/latest", schemaBody(orderSchema), http.StatusOK, `{"is_compatible":true}`},
+		{http.MethodPut, "/config/orders%2Fvalue", `{"compatibility":"FULL"}`, http.StatusOK, `{"compatibility":"FULL"}`},
+		{http.MethodGet, "/config/orders%2Fvalue", "", http.StatusOK, `{"compatibilityLevel":"FULL"}`},
+		{http.MethodDelete, "/subjects/orders%2Fvalue/versions/2", "", http.StatusOK, `2`},
+		{http.MethodDelete, "/subjects/orders%2Fvalue", "", http.StatusOK, `[1]`},
+		{http.MethodGet, "/subjects", "", http.StatusOK, `[]`},
+	} {
+		rec := serve(s, tc.method, tc.path, tc.body)
+		if rec.Code != tc.status {
+			t.Fatalf("%s %s: expected status %d, but got %d: %s", tc.method, tc.path, tc.status, rec.Code, rec.Body.String())
+		}
+		mustEqual(t, rec.Header().Get(contentTypeHeaderKey), contentTypeJSON)
+		if actual := strings.TrimSpace(rec.Body.String()); actual != tc.json {
+			t.Errorf("%s %s: expected %s, but got %s", tc.method, tc.path, tc.json, actual)
+		}
+	}
+}
+
+func TestHttpServer_Problems(t *testing.T) {
+	s, _ := newTestServer(t, nil)
+	if rec := serve(s, http.MethodPost, "/subjects/orders-value/versions", schemaBody(orderSchema)); rec.Code != http.StatusCreated {
+		t.Fatalf("schema could not be registered: %s", rec.Body.String())
+	}
+
+	for _, tc := range []struct {
+		method string
+		path   string
+		body   string
+		// problem is the problem type after the base uri
+		problem string
+		status  int
+		detail  string
+	}{
+		{http.MethodGet, "/subjects/payments-value/versions", "", "subject-not-found", http.StatusNotFound, ""},
+		{http.MethodGet, "/subjects/orders-value/versions/9", "", "version-not-found", http.StatusNotFound, ""},
+		{http.MethodGet, "/schemas/ids/9", "", "schema-not-found", http.StatusNotFound, ""},
+		{http.MethodPost, "/subjects/orders-value/versions", schemaBody("{"), "invalid-schema", http.StatusUnprocessableEntity, ""},
+		{http.MethodPost, "/subjects/orders-value/versions", `{"schema":`, "bad-request", http.StatusBadRequest, ""},
+		{http.MethodPost, "/subjects/orders-value/versions", `{}`, "bad-request", http.StatusBadRequest, "schema is required"},
+		{http.MethodPut, "/config", `{"compatibility":"SOMETIMES"}`, "invalid-compatibility-level", http.StatusUnprocessableEntity,
+			"invalid compatibility level: SOMETIMES"},
+		{http.MethodGet, "/subjects/%20orders/versions", "", "invalid-subject", http.StatusUnprocessableEntity, ""},
+		{http.MethodGet, "/subjects/orders-value/versions/0", "", "invalid-version", http.StatusUnprocessableEntity, "invalid version: 0"},
+		{http.MethodGet, "/subjects/orders-value/diff?from=x", "", "invalid-version", http.StatusUnprocessableEntity, "invalid version: x"},
+		{http.MethodGet, "/schemas/ids/abc", "", "invalid-schema-id", http.StatusUnprocessableEntity, "invalid schema id: abc"},
+		{http.MethodPatch, "/subjects", "", "method-not-allowed", http.StatusMethodNotAllowed, "method PATCH is not allowed"},
+		{http.MethodGet, "/unknown", "", "route-not-found", http.StatusNotFound, "/unknown is not found"},
+	} {
+		rec := serve(s, tc.method, tc.path, tc.body)
+		mustEqual(t, rec.Code, tc.status)
+
+		p := problemOfResponse(t, rec)
+		mustEqual(t, p.Type, problemTypeBaseUri+tc.problem)
+		mustEqual(t, p.Status, tc.status)
+		u, _ := url.Parse(tc.path)
+		mustEqual(t, p.Instance, u.Path)
+		if tc.detail != "" {
+			mustEqual(t, p.Detail, tc.detail)
+		}
+	}
+
+	rec := serve(s, http.MethodPatch, "/subjects", "")
+	mustEqual(t, rec.Header().Get("Allow"), "GET")
+}
+
+func TestHttpServer_RegistersNonAvroSchemas(t *testing.T) {
+	s, registry := newTestServer(t, nil)
+
+	for _, tc := range []struct {
+		subject    string
+		schemaType string
+		schema     string
+	}{
+		{"signups-value", "JSON", `{"type":"object","properties":{"email":{"type":"string"}}}`},
+		{"shipments-value", "PROTOBUF", `syntax = "proto3"; message Shipment { string id = 1; }`},
+	} {
+		b, _ := json.Marshal(map[string]string{"schema": tc.schema, "schemaType": tc.schemaType})
+		path := "/subjects/" + tc.subject + "/versions"
+
+		rec := serve(s, http.MethodPost, path, string(b))
+		mustEqual(t, rec.Code, http.StatusCreated)
+		// the schema is looked up with its type, it is not parsed as avro
+		rec = serve(s, http.MethodPost, path, string(b))
+		if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"status":"unchanged"`) {
+			t.Errorf("%s: expected the registered schema to be unchanged, but got %d: %s", tc.schemaType, rec.Code, rec.Body.String())
+		}
+		rec = serve(s, http.MethodPost, "/compatibility/subjects/"+tc.subject+"/versions/latest", string(b))
+		mustEqual(t, rec.Code, http.StatusOK)
+
+		versions, err := registry.Versions(tc.subject)
+		mustEqual(t, err, nil)
+		mustEqual(t, versions, []int{1})
+	}
+}
+
+func TestHttpServer_GetSchemaByID_ScopedGrant(t *testing.T) {
+	s, registry := newTestServer(t, &config.AppConfig{Auth: config.AuthConfig{
+		APIKeys: []config.APIKeyConfig{{Name: "orders", SHA256: auth.HashAPIKey("s3cret"), Roles: []string{"viewer:orders-*"}}},
+	}})
+
+	orders, err := registry.RegisterNewSchema("orders-value", orderSchema)
+	mustEqual(t, err, nil)
+	payments, err := registry.RegisterNewSchema("payments-value", `"string"`)
+	mustEqual(t, err, nil)
+
+	get := func(id int) int {
+		req := httptest.NewRequest(http.MethodGet, "/schemas/ids/"+strconv.Itoa(id), nil)
+		req.Header.Set("X-API-Key", "s3cret")
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	// the schema is authorized by the subjects registered with it
+	mustEqual(t, get(orders), http.StatusOK)
+	mustEqual(t, get(payments), http.StatusForbidden)
+	mustEqual(t, get(99), http.StatusNotFound)
+}
+
+func jsonString(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
+
+func TestHttpServer_RequiresApproval(t *testing.T) {
+	s, registry := newTestServer(t, &config.AppConfig{Approvals: []config.ApprovalConfig{{SubjectPrefix: "payments-"}}})
+
+	rec := serve(s, http.MethodPost, "/subjects/payments-value/versions", schemaBody(orderSchema))
+	mustEqual(t, rec.Code, http.StatusConflict)
+	mustEqual(t, problemOfResponse(t, rec).Type, problemApprovalRequired.uri)
+
+	// proposals can not be approved without authentication, the actor of the header is only claimed
+	b, _ := json.Marshal(proposalRequest{Subject: "payments-value", Schema: orderSchema})
+	rec = serve(s, http.MethodPost, "/proposals", string(b))
+	mustEqual(t, rec.Code, http.StatusCreated)
+	var p struct{ ID string }
+	if err := json.Unmarshal(rec.Body.Bytes(), &p); err != nil {
+		t.Fatal(err)
+	}
+	mustEqual(t, serve(s, http.MethodPost, "/proposals/"+p.ID+"/submit", "").Code, http.StatusOK)
+
+	req := httptest.NewRequest(http.MethodPost, "/proposals/"+p.ID+"/reviews", strings.NewReader(`{"decision":"approve"}`))
+	req.Header.Set(actorHeaderKey, "bob")
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	mustEqual(t, rec.Code, http.StatusForbidden)
+	mustEqual(t, problemOfResponse(t, rec).Type, problemProposalNotAllowed.uri)
+
+	subjects, err := registry.Subjects()
+	mustEqual(t, err, nil)
+	mustEqual(t, len(subjects), 0)
+}
@@ -0,0 +1,110 @@
+package ports
+
+import (
+	"net/http"
+
+	"github.com/ybalcin/event-schema-manager/internal/core/application/auth"
+	"github.com/ybalcin/event-schema-manager/internal/core/application/webhooks"
+)
+
+// POST /webhooks
+func (s *HttpServer) createWebhook(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	if !s.authorize(w, r, auth.PermissionAdmin, "") {
+		return
+	}
+
+	var req webhookRequest
+	if err := readJSON(w, r, &req); err != nil {
+		writeProblem(w, r, problemBadRequest, err)
+		return
+	}
+	if req.URL == "" {
+		writeProblem(w, r, problemBadRequest, errRequired("url"))
+		return
+	}
+
+	// the secret is returned only here
+	subscription, err := s.app.Webhooks().Subscribe(req.URL, req.Subjects, req.EventTypes, req.Secret)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, subscription)
+}
+
+// GET /webhooks
+func (s *HttpServer) listWebhooks(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	if !s.authorize(w, r, auth.PermissionAdmin, "") {
+		return
+	}
+
+	subscriptions, err := s.app.Webhooks().Subscriptions()
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, subscriptions)
+}
+
+// GET /webhooks/{id}
+func (s *HttpServer) getWebhook(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	if !s.authorize(w, r, auth.PermissionAdmin, "") {
+		return
+	}
+
+	subscription, err := s.app.Webhooks().Subscription(params["id"])
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, subscription)
+}
+
+// DELETE /webhooks/{id}
+func (s *HttpServer) deleteWebhook(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	if !s.authorize(w, r, auth.PermissionAdmin, "") {
+		return
+	}
+
+	if err := s.app.Webhooks().Unsubscribe(params["id"]); err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GET /webhooks/dead-letters
+func (s *HttpServer) listDeadLetters(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	if !s.authorize(w, r, auth.PermissionAdmin, "") {
+		return
+	}
+
+	deadLetters, err := s.app.Webhooks().DeadLetters()
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	if deadLetters == nil {
+		deadLetters = []webhooks.DeadLetter{}
+	}
+	writeJSON(w, http.StatusOK, deadLetters)
+}
+
+// POST /webhooks/dead-letters/{id}/redeliver
+func (s *HttpServer) redeliverDeadLetter(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	if !s.authorize(w, r, auth.PermissionAdmin, "") {
+		return
+	}
+
+	if err := s.app.Webhooks().Redeliver(params["id"]); err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
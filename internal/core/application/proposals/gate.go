@@ -0,0 +1,66 @@
+package proposals
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ybalcin/event-schema-manager/internal/core/domain/schema"
+)
+
+type (
+	// Gate lets only approved proposals register the schemas of the subjects requiring approval.
+	// It is the registration gate of the schema service, so every caller of the service is gated.
+	Gate struct {
+		// rules are sorted by the longest prefix first
+		rules []Rule
+	}
+
+	approvedKey struct{}
+)
+
+func NewGate(rules []Rule) *Gate {
+	sorted := append([]Rule(nil), rules...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return len(sorted[i].SubjectPrefix) > len(sorted[j].SubjectPrefix)
+	})
+
+	return &Gate{rules: sorted}
+}
+
+// RequiresApproval returns true if changes of subject must be made through proposals
+func (g *Gate) RequiresApproval(subject schema.Subject) bool {
+	_, ok := g.ruleOf(subject)
+	return ok
+}
+
+func (g *Gate) ruleOf(subject schema.Subject) (Rule, bool) {
+	for _, r := range g.rules {
+		if strings.HasPrefix(subject.String(), r.SubjectPrefix) {
+			return r, true
+		}
+	}
+
+	return Rule{}, false
+}
+
+// AllowRegistration returns ErrApprovalRequired if sc requires approval and ctx is not the application
+// of an approved proposal of sc
+func (g *Gate) AllowRegistration(ctx context.Context, sc *schema.Schema) error {
+	if !g.RequiresApproval(sc.Subject()) {
+		return nil
+	}
+
+	if p, ok := ctx.Value(approvedKey{}).(*Proposal); ok && p.State == StateApproved &&
+		p.Subject == sc.Subject() && p.SchemaType == sc.Type() && p.Schema == sc.Content() {
+		return nil
+	}
+
+	return fmt.Errorf("%w: %s", ErrApprovalRequired, sc.Subject())
+}
+
+// withApproved returns a copy of ctx applying the approved proposal p
+func withApproved(ctx context.Context, p *Proposal) context.Context {
+	return context.WithValue(ctx, approvedKey{}, p)
+}
@@ -0,0 +1,186 @@
+package proposals
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ybalcin/event-schema-manager/internal/core/application/linting"
+	"github.com/ybalcin/event-schema-manager/internal/core/domain/schema"
+)
+
+type (
+	State string
+
+	Decision string
+
+	// Proposal is a schema change waiting for review, the schema is registered only when the approved proposal
+	// is applied
+	Proposal struct {
+		ID                string               `json:"id"`
+		Subject           schema.Subject       `json:"subject"`
+		SchemaType        schema.SchemaType    `json:"schemaType"`
+		Schema            string               `json:"schema"`
+		Description       string               `json:"description,omitempty"`
+		Author            string               `json:"author"`
+		State             State                `json:"state"`
+		Reviewers         []string             `json:"reviewers"`
+		RequiredApprovals int                  `json:"requiredApprovals"`
+		Reviews           []Review             `json:"reviews"`
+		Comments          []Comment            `json:"comments"`
+		Checks            *Checks              `json:"checks,omitempty"`
+		AppliedVersion    schema.SchemaVersion `json:"appliedVersion,omitempty"`
+		AppliedID         schema.SchemaID      `json:"appliedId,omitempty"`
+		CreatedAt         time.Time            `json:"createdAt"`
+		UpdatedAt         time.Time            `json:"updatedAt"`
+	}
+
+	Review struct {
+		Reviewer string    `json:"reviewer"`
+		Decision Decision  `json:"decision"`
+		Comment  string    `json:"comment,omitempty"`
+		Time     time.Time `json:"time"`
+	}
+
+	Comment struct {
+		Author string    `json:"author"`
+		Text   string    `json:"text"`
+		Time   time.Time `json:"time"`
+	}
+
+	// Checks are the compatibility and lint results of the schema when the proposal is submitted
+	Checks struct {
+		Compatible bool                `json:"compatible"`
+		Reasons    []string            `json:"reasons,omitempty"`
+		Violations []linting.Violation `json:"violations"`
+		LintPassed bool                `json:"lintPassed"`
+		CheckedAt  time.Time           `json:"checkedAt"`
+	}
+)
+
+const (
+	StateDraft     State = "draft"
+	StateSubmitted State = "submitted"
+	StateApproved  State = "approved"
+	StateRejected  State = "rejected"
+	StateApplied   State = "applied"
+
+	DecisionApprove Decision = "approve"
+	DecisionReject  Decision = "reject"
+)
+
+var (
+	ErrProposalNotFound  = errors.New("proposal not found")
+	ErrInvalidProposal   = errors.New("invalid proposal")
+	ErrInvalidTransition = errors.New("invalid proposal state transition")
+	ErrNotAllowed        = errors.New("proposal action not allowed")
+	ErrApprovalRequired  = errors.New("schema changes of the subject require an approved proposal")
+	errInvalidTransition = func(p *Proposal, action string) error {
+		return fmt.Errorf("%w: %s proposal %s can not be %s", ErrInvalidTransition, p.State, p.ID, action)
+	}
+	errNotAllowed = func(format string, args ...interface{}) error {
+		return fmt.Errorf("%w: "+format, append([]interface{}{ErrNotAllowed}, args...)...)
+	}
+)
+
+// Edit replaces the schema and the description of a draft
+func (p *Proposal) Edit(sc *schema.Schema, description string, now time.Time) error {
+	if p.State != StateDraft {
+		return errInvalidTransition(p, "edited")
+	}
+
+	p.SchemaType, p.Schema, p.Description = sc.Type(), sc.Content(), description
+	p.UpdatedAt = now
+	return nil
+}
+
+// Submit sends a draft to review with the results of its checks
+func (p *Proposal) Submit(checks Checks, now time.Time) error {
+	if p.State != StateDraft {
+		return errInvalidTransition(p, "submitted")
+	}
+
+	p.State = StateSubmitted
+	p.Checks = &checks
+	p.UpdatedAt = now
+	return nil
+}
+
+// AddReview records the decision of reviewer, a rejection rejects the proposal and it is approved
+// when it has the required approvals. Authors can not review their own proposals.
+func (p *Proposal) AddReview(review Review) error {
+	if p.State != StateSubmitted {
+		return errInvalidTransition(p, "reviewed")
+	}
+
+	if review.Decision != DecisionApprove && review.Decision != DecisionReject {
+		return fmt.Errorf("%w: unknown decision %s", ErrInvalidProposal, review.Decision)
+	}
+	if review.Reviewer == p.Author {
+		return errNotAllowed("%s is the author of proposal %s", review.Reviewer, p.ID)
+	}
+	if len(p.Reviewers) > 0 && !contains(p.Reviewers, review.Reviewer) {
+		return errNotAllowed("%s is not a reviewer of proposal %s", review.Reviewer, p.ID)
+	}
+	for _, r := range p.Reviews {
+		if r.Reviewer == review.Reviewer {
+			return errNotAllowed("%s reviewed proposal %s already", review.Reviewer, p.ID)
+		}
+	}
+
+	p.Reviews = append(p.Reviews, review)
+	p.UpdatedAt = review.Time
+
+	switch {
+	case review.Decision == DecisionReject:
+		p.State = StateRejected
+	case p.approvals() >= p.RequiredApprovals:
+		p.State = StateApproved
+	}
+
+	return nil
+}
+
+// AddComment adds a comment to a proposal that is not applied
+func (p *Proposal) AddComment(comment Comment) error {
+	if p.State == StateApplied {
+		return errInvalidTransition(p, "commented")
+	}
+
+	p.Comments = append(p.Comments, comment)
+	p.UpdatedAt = comment.Time
+	return nil
+}
+
+// Applied marks an approved proposal as registered with id and version
+func (p *Proposal) Applied(id schema.SchemaID, version schema.SchemaVersion, now time.Time) error {
+	if p.State != StateApproved {
+		return errInvalidTransition(p, "applied")
+	}
+
+	p.State = StateApplied
+	p.AppliedID, p.AppliedVersion = id, version
+	p.UpdatedAt = now
+	return nil
+}
+
+func (p *Proposal) approvals() int {
+	approvals := 0
+	for _, r := range p.Reviews {
+		if r.Decision == DecisionApprove {
+			approvals++
+		}
+	}
+
+	return approvals
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,320 @@
+package proposals
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ybalcin/event-schema-manager/internal/core/application/audit"
+	"github.com/ybalcin/event-schema-manager/internal/core/application/auth"
+	"github.com/ybalcin/event-schema-manager/internal/core/application/services"
+	"github.com/ybalcin/event-schema-manager/internal/core/domain/schema"
+	"github.com/ybalcin/event-schema-manager/internal/shared/identity"
+)
+
+type (
+	// Rule requires approvals of the proposals of subjects starting with SubjectPrefix,
+	// the rule of the longest matching prefix is used and the empty prefix matches every subject
+	Rule struct {
+		SubjectPrefix     string
+		RequiredApprovals int
+		Reviewers         []string
+	}
+
+	// Authorizer decides if the caller of ctx has permission on subject
+	Authorizer interface {
+		Authorize(ctx context.Context, permission auth.Permission, subject schema.Subject) error
+	}
+
+	// Service runs the lifecycle of proposals: draft, submitted, approved or rejected, applied
+	Service struct {
+		schemaService services.SchemaService
+		store         Store
+		gate          *Gate
+		authorizer    Authorizer
+		// mu guards locks, the changes of a proposal are serialized by its lock so concurrent reviews are not lost
+		// and the changes of other proposals do not wait for a registration
+		mu    sync.Mutex
+		locks map[string]*proposalLock
+		now   func() time.Time
+	}
+
+	// proposalLock is the lock of a proposal, it is dropped when no change holds or waits for it
+	proposalLock struct {
+		sync.Mutex
+		holders int
+	}
+
+	Option func(*Service)
+)
+
+const defaultRequiredApprovals = 1
+
+// WithAuthorizer makes the service check the read permission on the subjects of the proposals read and
+// the write permission on the subjects of the proposals created and reviewed, everything is allowed by default
+func WithAuthorizer(authorizer Authorizer) Option {
+	return func(s *Service) {
+		s.authorizer = authorizer
+	}
+}
+
+// NewService returns the proposals of rules, schemaService must be gated by NewGate(rules) so schemas requiring
+// approval are registered only by Apply
+func NewService(schemaService services.SchemaService, store Store, rules []Rule, opts ...Option) *Service {
+	s := &Service{
+		schemaService: schemaService,
+		store:         store,
+		gate:          NewGate(rules),
+		locks:         make(map[string]*proposalLock),
+		now:           func() time.Time { return time.Now().UTC() },
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+func (s *Service) authorize(ctx context.Context, permission auth.Permission, subject schema.Subject) error {
+	if s.authorizer == nil {
+		return nil
+	}
+
+	return s.authorizer.Authorize(ctx, permission, subject)
+}
+
+// RequiresApproval returns true if changes of subject must be made through proposals
+func (s *Service) RequiresApproval(subject schema.Subject) bool {
+	return s.gate.RequiresApproval(subject)
+}
+
+// Create creates a draft of sc authored by the actor of ctx, who must have the write permission on its subject
+func (s *Service) Create(ctx context.Context, sc *schema.Schema, description string) (*Proposal, error) {
+	if err := s.authorize(ctx, auth.PermissionWrite, sc.Subject()); err != nil {
+		return nil, err
+	}
+
+	rule, ok := s.gate.ruleOf(sc.Subject())
+	if !ok {
+		rule = Rule{RequiredApprovals: defaultRequiredApprovals}
+	}
+	if rule.RequiredApprovals <= 0 {
+		rule.RequiredApprovals = defaultRequiredApprovals
+	}
+
+	now := s.now()
+	p := &Proposal{
+		ID:                newProposalID(),
+		Subject:           sc.Subject(),
+		SchemaType:        sc.Type(),
+		Schema:            sc.Content(),
+		Description:       description,
+		Author:            identity.FromContext(ctx).Actor,
+		State:             StateDraft,
+		Reviewers:         append([]string{}, rule.Reviewers...),
+		RequiredApprovals: rule.RequiredApprovals,
+		Reviews:           []Review{},
+		Comments:          []Comment{},
+		CreatedAt:         now,
+		UpdatedAt:         now,
+	}
+
+	if err := s.store.Save(p); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// Get returns the proposal id if the caller of ctx can read its subject
+func (s *Service) Get(ctx context.Context, id string) (*Proposal, error) {
+	p, err := s.store.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = s.authorize(ctx, auth.PermissionRead, p.Subject); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// List returns the proposals matching filter of the subjects the caller of ctx can read
+func (s *Service) List(ctx context.Context, filter Filter) ([]*Proposal, error) {
+	list, err := s.store.List(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	readable := make([]*Proposal, 0, len(list))
+	for _, p := range list {
+		if s.authorize(ctx, auth.PermissionRead, p.Subject) == nil {
+			readable = append(readable, p)
+		}
+	}
+
+	return readable, nil
+}
+
+// Edit replaces the schema of a draft, only its author can edit it
+func (s *Service) Edit(ctx context.Context, id string, schemaType schema.SchemaType, content, description string) (*Proposal, error) {
+	return s.change(id, func(p *Proposal) error {
+		if actor := identity.FromContext(ctx).Actor; actor != p.Author {
+			return errNotAllowed("%s is not the author of proposal %s", actor, p.ID)
+		}
+
+		sc, err := schema.NewSchema(p.Subject, schemaType, content)
+		if err != nil {
+			return err
+		}
+
+		return p.Edit(sc, description, s.now())
+	})
+}
+
+// Submit checks the compatibility and the lint rules of the schema and sends the draft to review,
+// the results are attached for the reviewers and do not block the submission. Only its author can submit it.
+func (s *Service) Submit(ctx context.Context, id string) (*Proposal, error) {
+	return s.change(id, func(p *Proposal) error {
+		if actor := identity.FromContext(ctx).Actor; actor != p.Author {
+			return errNotAllowed("%s is not the author of proposal %s", actor, p.ID)
+		}
+
+		sc, err := schema.NewSchema(p.Subject, p.SchemaType, p.Schema)
+		if err != nil {
+			return err
+		}
+
+		checks := Checks{Compatible: true, CheckedAt: s.now()}
+
+		compatibility, err := s.schemaService.CheckCompatibility(ctx, sc, schema.Latest)
+		switch {
+		case errors.Is(err, schema.ErrSubjectNotFound) || errors.Is(err, schema.ErrVersionNotFound):
+			// the first version of a subject
+		case err != nil:
+			return err
+		default:
+			checks.Compatible, checks.Reasons = compatibility.IsCompatible, compatibility.Reasons
+		}
+
+		report, err := s.schemaService.Lint(ctx, sc)
+		if err != nil {
+			return err
+		}
+		checks.Violations, checks.LintPassed = report.Violations, !report.HasErrors()
+
+		return p.Submit(checks, s.now())
+	})
+}
+
+// Review records the decision of the actor of ctx, reviewers must be authenticated since a claimed actor could
+// approve its own proposal, and they must have the write permission on the subject
+func (s *Service) Review(ctx context.Context, id string, decision Decision, comment string) (*Proposal, error) {
+	caller := identity.FromContext(ctx)
+	if !caller.Authenticated {
+		return nil, errNotAllowed("reviewers must be authenticated")
+	}
+
+	return s.change(id, func(p *Proposal) error {
+		if err := s.authorize(ctx, auth.PermissionWrite, p.Subject); err != nil {
+			return err
+		}
+
+		return p.AddReview(Review{Reviewer: caller.Actor, Decision: decision, Comment: comment, Time: s.now()})
+	})
+}
+
+// Comment adds a comment of the actor of ctx
+func (s *Service) Comment(ctx context.Context, id string, text string) (*Proposal, error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, fmt.Errorf("%w: comment is empty", ErrInvalidProposal)
+	}
+
+	return s.change(id, func(p *Proposal) error {
+		return p.AddComment(Comment{Author: identity.FromContext(ctx).Actor, Text: text, Time: s.now()})
+	})
+}
+
+// Apply registers the schema of an approved proposal, the proposal stays approved if the registration fails
+func (s *Service) Apply(ctx context.Context, id string) (*Proposal, error) {
+	return s.change(id, func(p *Proposal) error {
+		if p.State != StateApproved {
+			return errInvalidTransition(p, "applied")
+		}
+
+		sc, err := schema.NewSchema(p.Subject, p.SchemaType, p.Schema)
+		if err != nil {
+			return err
+		}
+
+		change := audit.ChangeFromContext(ctx)
+		if change.Reason == "" {
+			change.Reason = fmt.Sprintf("proposal %s: %s", p.ID, p.Description)
+		}
+		result, err := s.schemaService.Add(withApproved(audit.WithChange(ctx, change), p), sc)
+		if err != nil {
+			return err
+		}
+
+		return p.Applied(result.Schema.ID(), result.Schema.Version(), s.now())
+	})
+}
+
+// change applies fn to the proposal with id and saves it if fn succeeds
+func (s *Service) change(id string, fn func(p *Proposal) error) (*Proposal, error) {
+	unlock := s.lock(id)
+	defer unlock()
+
+	p, err := s.store.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = fn(p); err != nil {
+		return nil, err
+	}
+
+	if err = s.store.Save(p); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// lock locks the proposal with id and returns the func unlocking it
+func (s *Service) lock(id string) func() {
+	s.mu.Lock()
+	l, ok := s.locks[id]
+	if !ok {
+		l = &proposalLock{}
+		s.locks[id] = l
+	}
+	l.holders++
+	s.mu.Unlock()
+
+	l.Lock()
+	return func() {
+		l.Unlock()
+
+		s.mu.Lock()
+		if l.holders--; l.holders == 0 {
+			delete(s.locks, id)
+		}
+		s.mu.Unlock()
+	}
+}
+
+func newProposalID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,355 @@
+package proposals_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/ybalcin/event-schema-manager/internal/core/application/auth"
+	"github.com/ybalcin/event-schema-manager/internal/core/application/linting"
+	"github.com/ybalcin/event-schema-manager/internal/core/application/proposals"
+	"github.com/ybalcin/event-schema-manager/internal/core/application/services"
+	"github.com/ybalcin/event-schema-manager/internal/core/domain/schema"
+	"github.com/ybalcin/event-schema-manager/internal/shared/identity"
+)
+
+type (
+	// fakeSchemaService registers schemas in memory, the methods proposals do not use panic
+	fakeSchemaService struct {
+		services.SchemaService
+		added        []*schema.Schema
+		incompatible []string
+	}
+)
+
+func (s *fakeSchemaService) Add(ctx context.Context, sc *schema.Schema) (*services.AddResult, error) {
+	s.added = append(s.added, sc)
+	id := schema.SchemaID(len(s.added))
+
+	return &services.AddResult{Status: services.AddStatusCreated, Schema: sc.Registered(id, schema.SchemaVersion(id))}, nil
+}
+
+func (s *fakeSchemaService) CheckCompatibility(ctx context.Context, sc *schema.Schema, version schema.SchemaVersion) (schema.Compatibility, error) {
+	if len(s.added) == 0 {
+		return schema.Compatibility{}, schema.ErrSubjectNotFound
+	}
+
+	return schema.Compatibility{IsCompatible: len(s.incompatible) == 0, Reasons: s.incompatible}, nil
+}
+
+func (s *fakeSchemaService) Lint(ctx context.Context, sc *schema.Schema) (linting.Report, error) {
+	return linting.Report{Violations: []linting.Violation{}}, nil
+}
+
+func mustEqual(t *testing.T, actual, expected interface{}) {
+	t.Helper()
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %#v, but got %#v", expected, actual)
+	}
+}
+
+// as returns the context of the authenticated actor
+func as(actor string) context.Context {
+	return identity.NewContext(context.Background(), identity.Identity{Actor: actor, Authenticated: true})
+}
+
+func newTestProposal(t *testing.T, service *proposals.Service, subject schema.Subject) *proposals.Proposal {
+	t.Helper()
+
+	sc, err := schema.NewSchema(subject, schema.SchemaTypeAvro, `"string"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := service.Create(as("alice"), sc, "add orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return p
+}
+
+func TestService_Lifecycle(t *testing.T) {
+	registry := &fakeSchemaService{}
+	service := proposals.NewService(registry, proposals.NewMemoryStore(), []proposals.Rule{
+		{SubjectPrefix: "orders-", RequiredApprovals: 2},
+	})
+
+	p := newTestProposal(t, service, "orders-value")
+	mustEqual(t, p.State, proposals.StateDraft)
+	mustEqual(t, p.Author, "alice")
+	mustEqual(t, p.RequiredApprovals, 2)
+
+	if _, err := service.Edit(as("bob"), p.ID, schema.SchemaTypeAvro, `"int"`, ""); !errors.Is(err, proposals.ErrNotAllowed) {
+		t.Fatalf("expected not allowed, but got %v", err)
+	}
+	if _, err := service.Apply(as("alice"), p.ID); !errors.Is(err, proposals.ErrInvalidTransition) {
+		t.Fatalf("expected invalid transition, but got %v", err)
+	}
+	if _, err := service.Submit(as("bob"), p.ID); !errors.Is(err, proposals.ErrNotAllowed) {
+		t.Fatalf("expected not allowed, but got %v", err)
+	}
+
+	p, err := service.Submit(as("alice"), p.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustEqual(t, p.State, proposals.StateSubmitted)
+	mustEqual(t, p.Checks.Compatible, true)
+	mustEqual(t, p.Checks.LintPassed, true)
+
+	if _, err = service.Review(as("alice"), p.ID, proposals.DecisionApprove, ""); !errors.Is(err, proposals.ErrNotAllowed) {
+		t.Fatalf("expected self review to be forbidden, but got %v", err)
+	}
+	if _, err = service.Review(context.Background(), p.ID, proposals.DecisionApprove, ""); !errors.Is(err, proposals.ErrNotAllowed) {
+		t.Fatalf("expected anonymous review to be forbidden, but got %v", err)
+	}
+	// an actor claimed by a header without authentication
+	claimed := identity.NewContext(context.Background(), identity.Identity{Actor: "bob"})
+	if _, err = service.Review(claimed, p.ID, proposals.DecisionApprove, ""); !errors.Is(err, proposals.ErrNotAllowed) {
+		t.Fatalf("expected unauthenticated review to be forbidden, but got %v", err)
+	}
+
+	if p, err = service.Review(as("bob"), p.ID, proposals.DecisionApprove, "looks good"); err != nil {
+		t.Fatal(err)
+	}
+	mustEqual(t, p.State, proposals.StateSubmitted)
+	if _, err = service.Review(as("bob"), p.ID, proposals.DecisionApprove, ""); !errors.Is(err, proposals.ErrNotAllowed) {
+		t.Fatalf("expected duplicate review to be forbidden, but got %v", err)
+	}
+
+	if p, err = service.Review(as("carol"), p.ID, proposals.DecisionApprove, ""); err != nil {
+		t.Fatal(err)
+	}
+	mustEqual(t, p.State, proposals.StateApproved)
+	mustEqual(t, len(registry.added), 0)
+
+	if p, err = service.Apply(as("alice"), p.ID); err != nil {
+		t.Fatal(err)
+	}
+	mustEqual(t, p.State, proposals.StateApplied)
+	mustEqual(t, p.AppliedVersion, schema.SchemaVersion(1))
+	mustEqual(t, len(registry.added), 1)
+
+	stored, err := service.Get(context.Background(), p.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustEqual(t, stored, p)
+}
+
+// blockingSchemaService registers schemas once release is closed
+type blockingSchemaService struct {
+	*fakeSchemaService
+	adding  chan struct{}
+	release chan struct{}
+}
+
+func (s *blockingSchemaService) Add(ctx context.Context, sc *schema.Schema) (*services.AddResult, error) {
+	close(s.adding)
+	<-s.release
+	return s.fakeSchemaService.Add(ctx, sc)
+}
+
+func TestService_Apply_DoesNotBlockOtherProposals(t *testing.T) {
+	registry := &blockingSchemaService{fakeSchemaService: &fakeSchemaService{}, adding: make(chan struct{}), release: make(chan struct{})}
+	service := proposals.NewService(registry, proposals.NewMemoryStore(), nil)
+
+	applied, other := newTestProposal(t, service, "orders-value"), newTestProposal(t, service, "payments-value")
+	for _, p := range []*proposals.Proposal{applied, other} {
+		if _, err := service.Submit(as("alice"), p.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := service.Review(as("bob"), applied.ID, proposals.DecisionApprove, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error)
+	go func() {
+		_, err := service.Apply(as("alice"), applied.ID)
+		done <- err
+	}()
+	<-registry.adding
+
+	// the other proposal is reviewed while the registration is in progress
+	p, err := service.Review(as("bob"), other.ID, proposals.DecisionApprove, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustEqual(t, p.State, proposals.StateApproved)
+
+	close(registry.release)
+	if err = <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestService_Review_Rejects(t *testing.T) {
+	registry := &fakeSchemaService{}
+	service := proposals.NewService(registry, proposals.NewMemoryStore(), []proposals.Rule{
+		{SubjectPrefix: "orders-", Reviewers: []string{"bob"}},
+	})
+
+	p := newTestProposal(t, service, "orders-value")
+	if _, err := service.Submit(as("alice"), p.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := service.Review(as("carol"), p.ID, proposals.DecisionApprove, ""); !errors.Is(err, proposals.ErrNotAllowed) {
+		t.Fatalf("expected carol not to be a reviewer, but got %v", err)
+	}
+
+	p, err := service.Review(as("bob"), p.ID, proposals.DecisionReject, "use a record")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustEqual(t, p.State, proposals.StateRejected)
+
+	if _, err = service.Apply(as("alice"), p.ID); !errors.Is(err, proposals.ErrInvalidTransition) {
+		t.Fatalf("expected invalid transition, but got %v", err)
+	}
+	mustEqual(t, len(registry.added), 0)
+}
+
+func TestService_RequiresApproval(t *testing.T) {
+	service := proposals.NewService(&fakeSchemaService{}, proposals.NewMemoryStore(), []proposals.Rule{
+		{SubjectPrefix: "payments-", RequiredApprovals: 2},
+		{SubjectPrefix: "payments-audit-", RequiredApprovals: 3},
+	})
+
+	mustEqual(t, service.RequiresApproval("payments-value"), true)
+	mustEqual(t, service.RequiresApproval("orders-value"), false)
+
+	// the longest prefix wins
+	p := newTestProposal(t, service, "payments-audit-value")
+	mustEqual(t, p.RequiredApprovals, 3)
+
+	list, err := service.List(context.Background(), proposals.Filter{State: proposals.StateDraft})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustEqual(t, len(list), 1)
+}
+
+// authorizerFunc authorizes with the grants of the identity of ctx
+type authorizerFunc func(ctx context.Context, permission auth.Permission, subject schema.Subject) error
+
+func (f authorizerFunc) Authorize(ctx context.Context, permission auth.Permission, subject schema.Subject) error {
+	return f(ctx, permission, subject)
+}
+
+func TestService_WithAuthorizer(t *testing.T) {
+	service := proposals.NewService(&fakeSchemaService{}, proposals.NewMemoryStore(), nil,
+		proposals.WithAuthorizer(authorizerFunc(auth.Authorize)))
+
+	grants, err := auth.ParseGrants([]string{"producer:orders-*", "viewer:payments-*"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bob := identity.NewContext(context.Background(), identity.Identity{Actor: "bob", Grants: grants, Authenticated: true})
+	if grants, err = auth.ParseGrants([]string{"producer:*"}); err != nil {
+		t.Fatal(err)
+	}
+	alice := identity.NewContext(context.Background(), identity.Identity{Actor: "alice", Grants: grants, Authenticated: true})
+
+	// proposals need the write permission on their subject
+	sc, err := schema.NewSchema("payments-value", schema.SchemaTypeAvro, `"string"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = service.Create(bob, sc, ""); !errors.Is(err, auth.ErrForbidden) {
+		t.Fatalf("expected forbidden, but got %v", err)
+	}
+
+	var orders, payments, billing *proposals.Proposal
+	for _, p := range []struct {
+		subject  schema.Subject
+		proposal **proposals.Proposal
+	}{{"orders-value", &orders}, {"payments-value", &payments}, {"billing-value", &billing}} {
+		sc, err := schema.NewSchema(p.subject, schema.SchemaTypeAvro, `"string"`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if *p.proposal, err = service.Create(alice, sc, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, p := range []*proposals.Proposal{orders, payments} {
+		if _, err = service.Submit(alice, p.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// proposals of subjects bob can not read are not listed nor returned
+	list, err := service.List(bob, proposals.Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustEqual(t, len(list), 2)
+	if _, err = service.Get(bob, billing.ID); !errors.Is(err, auth.ErrForbidden) {
+		t.Fatalf("expected forbidden, but got %v", err)
+	}
+
+	// reviews need the write permission on the subject
+	if _, err = service.Review(bob, payments.ID, proposals.DecisionApprove, ""); !errors.Is(err, auth.ErrForbidden) {
+		t.Fatalf("expected forbidden, but got %v", err)
+	}
+	p, err := service.Review(bob, orders.ID, proposals.DecisionApprove, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustEqual(t, p.State, proposals.StateApproved)
+}
+
+func TestGate(t *testing.T) {
+	store := proposals.NewMemoryStore()
+	rules := []proposals.Rule{{SubjectPrefix: "orders-"}}
+	gate := proposals.NewGate(rules)
+
+	// the schema service gated like the application gates it
+	var registered []*schema.Schema
+	gated := &gatedSchemaService{fakeSchemaService: &fakeSchemaService{}, gate: gate, registered: &registered}
+	service := proposals.NewService(gated, store, rules)
+
+	sc, err := schema.NewSchema("orders-value", schema.SchemaTypeAvro, `"int"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = gate.AllowRegistration(context.Background(), sc); !errors.Is(err, proposals.ErrApprovalRequired) {
+		t.Fatalf("expected approval required, but got %v", err)
+	}
+	other, _ := schema.NewSchema("payments-value", schema.SchemaTypeAvro, `"int"`)
+	mustEqual(t, gate.AllowRegistration(context.Background(), other), nil)
+
+	p := newTestProposal(t, service, "orders-value")
+	if _, err = service.Submit(as("alice"), p.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = service.Review(as("bob"), p.ID, proposals.DecisionApprove, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = service.Apply(as("alice"), p.ID); err != nil {
+		t.Fatal(err)
+	}
+	mustEqual(t, len(registered), 1)
+	mustEqual(t, registered[0].Content(), `"string"`)
+}
+
+// gatedSchemaService registers the schemas its gate allows
+type gatedSchemaService struct {
+	*fakeSchemaService
+	gate       *proposals.Gate
+	registered *[]*schema.Schema
+}
+
+func (s *gatedSchemaService) Add(ctx context.Context, sc *schema.Schema) (*services.AddResult, error) {
+	if err := s.gate.AllowRegistration(ctx, sc); err != nil {
+		return nil, err
+	}
+	*s.registered = append(*s.registered, sc)
+
+	return s.fakeSchemaService.Add(ctx, sc)
+}
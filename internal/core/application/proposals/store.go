@@ -0,0 +1,91 @@
+package proposals
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/ybalcin/event-schema-manager/internal/core/domain/schema"
+)
+
+type (
+	// Filter selects proposals, zero fields match every proposal
+	Filter struct {
+		Subject schema.Subject
+		State   State
+	}
+
+	Store interface {
+		Save(proposal *Proposal) error
+		Get(id string) (*Proposal, error)
+		List(filter Filter) ([]*Proposal, error)
+	}
+
+	// MemoryStore keeps proposals in memory, it stores copies so callers can not change stored proposals
+	MemoryStore struct {
+		mu        sync.RWMutex
+		proposals map[string]Proposal
+	}
+)
+
+// Matches returns true if p passes the filter
+func (f Filter) Matches(p *Proposal) bool {
+	return (f.Subject == "" || p.Subject == f.Subject) && (f.State == "" || p.State == f.State)
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{proposals: make(map[string]Proposal)}
+}
+
+func (s *MemoryStore) Save(proposal *Proposal) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.proposals[proposal.ID] = proposal.clone()
+	return nil
+}
+
+func (s *MemoryStore) Get(id string) (*Proposal, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	p, ok := s.proposals[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrProposalNotFound, id)
+	}
+
+	clone := p.clone()
+	return &clone, nil
+}
+
+// List returns the proposals passing filter in creation order
+func (s *MemoryStore) List(filter Filter) ([]*Proposal, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	proposals := []*Proposal{}
+	for _, p := range s.proposals {
+		if filter.Matches(&p) {
+			clone := p.clone()
+			proposals = append(proposals, &clone)
+		}
+	}
+	sort.Slice(proposals, func(i, j int) bool {
+		return proposals[i].CreatedAt.Before(proposals[j].CreatedAt)
+	})
+
+	return proposals, nil
+}
+
+func (p *Proposal) clone() Proposal {
+	c := *p
+	c.Reviewers = append([]string(nil), p.Reviewers...)
+	c.Reviews = append([]Review(nil), p.Reviews...)
+	c.Comments = append([]Comment(nil), p.Comments...)
+	if p.Checks != nil {
+		checks := *p.Checks
+		c.Checks = &checks
+	}
+
+	return c
+}
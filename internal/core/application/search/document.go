@@ -0,0 +1,362 @@
+package search
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/ybalcin/event-schema-manager/internal/core/domain/schema"
+	"github.com/ybalcin/event-schema-manager/pkg/avro"
+)
+
+type (
+	// Document is an indexed version of a subject
+	Document struct {
+		Subject    schema.Subject       `json:"subject"`
+		Version    schema.SchemaVersion `json:"version"`
+		SchemaID   schema.SchemaID      `json:"schemaId"`
+		SchemaType schema.SchemaType    `json:"schemaType"`
+		Latest     bool                 `json:"latest"`
+		// Name is the full name of the root type, Namespace is its namespace or the package of protobuf schemas
+		Name      string            `json:"name,omitempty"`
+		Namespace string            `json:"namespace,omitempty"`
+		Doc       string            `json:"doc,omitempty"`
+		Metadata  map[string]string `json:"metadata,omitempty"`
+		Fields    []Field           `json:"fields"`
+
+		// hits are the attributes of the document by the terms of their values
+		hits map[string][]hit
+	}
+
+	// Field is a field of a schema, Path is the json path of the field, e.g. $.customer.email
+	Field struct {
+		Path        string            `json:"path"`
+		Name        string            `json:"name"`
+		Type        string            `json:"type"`
+		LogicalType string            `json:"logicalType,omitempty"`
+		Doc         string            `json:"doc,omitempty"`
+		Metadata    map[string]string `json:"metadata,omitempty"`
+
+		// types are the types the field can have, the branches of unions, and logicalTypes their logical types
+		types        []string
+		logicalTypes []string
+	}
+
+	// hit is an attribute of a document whose value contains a term, path is the path of the field if any
+	hit struct {
+		attribute Attribute
+		path      string
+		value     string
+	}
+)
+
+var (
+	protobufPackage = regexp.MustCompile(`^\s*package\s+([\w.]+)\s*;`)
+	protobufBlock   = regexp.MustCompile(`^\s*(message|enum|oneof|service)\s+(\w+)\s*\{`)
+	protobufField   = regexp.MustCompile(`^\s*(?:repeated\s+|optional\s+|required\s+)?([\w.]+(?:<[\w.,\s]+>)?)\s+(\w+)\s*=\s*\d+`)
+)
+
+// newDocument extracts the indexed attributes of sc
+func newDocument(sc *schema.Schema, latest bool) (*Document, error) {
+	doc := &Document{
+		Subject:    sc.Subject(),
+		Version:    sc.Version(),
+		SchemaID:   sc.ID(),
+		SchemaType: sc.Type(),
+		Latest:     latest,
+		Fields:     []Field{},
+	}
+
+	var err error
+	switch sc.Type() {
+	case schema.SchemaTypeAvro:
+		err = doc.addAvro(sc.Content())
+	case schema.SchemaTypeJSON:
+		err = doc.addJSONSchema(sc.Content())
+	case schema.SchemaTypeProtobuf:
+		doc.addProtobuf(sc.Content())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s version %d: %w", sc.Subject(), sc.Version(), err)
+	}
+
+	doc.indexHits()
+	return doc, nil
+}
+
+func (d *Document) addAvro(content string) error {
+	root, err := avro.Parse(content)
+	if err != nil {
+		return err
+	}
+
+	if root.Type.IsNamed() {
+		d.Name, d.Namespace, d.Doc = root.Name, root.Namespace(), root.Doc
+	}
+	d.Metadata = metadataOf(root.Props)
+
+	root.Walk(func(path string, node *avro.Schema, field *avro.Field) bool {
+		// union branches are visited with the field of the union
+		if field == nil || node != field.Type {
+			return true
+		}
+
+		f := Field{Path: path, Name: field.Name, Type: node.String(), Doc: field.Doc, Metadata: metadataOf(field.Props)}
+		branches := []*avro.Schema{node}
+		if node.Type == avro.Union {
+			branches = node.Branches
+		}
+		for _, b := range branches {
+			f.types = append(f.types, b.String(), string(b.Type))
+			if b.LogicalType != "" {
+				f.logicalTypes = append(f.logicalTypes, b.LogicalType)
+			}
+		}
+		f.LogicalType = strings.Join(f.logicalTypes, ", ")
+		d.Fields = append(d.Fields, f)
+
+		return true
+	})
+
+	return nil
+}
+
+func (d *Document) addJSONSchema(content string) error {
+	var root interface{}
+	if err := json.Unmarshal([]byte(content), &root); err != nil {
+		return err
+	}
+
+	obj, ok := root.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	d.Name, _ = obj["title"].(string)
+	d.Namespace, _ = obj["$id"].(string)
+	d.Doc, _ = obj["description"].(string)
+	d.Metadata = jsonSchemaMetadata(obj)
+
+	d.addJSONProperties("$", obj)
+	for _, key := range []string{"$defs", "definitions"} {
+		defs, _ := obj[key].(map[string]interface{})
+		for _, name := range sortedKeys(defs) {
+			if def, ok := defs[name].(map[string]interface{}); ok {
+				d.addJSONProperties(key+"."+name, def)
+			}
+		}
+	}
+
+	return nil
+}
+
+// addJSONProperties adds the properties of a json schema object and of its items, nested objects are added with
+// their path
+func (d *Document) addJSONProperties(path string, obj map[string]interface{}) {
+	if items, ok := obj["items"].(map[string]interface{}); ok {
+		d.addJSONProperties(path+"[]", items)
+	}
+
+	properties, _ := obj["properties"].(map[string]interface{})
+	for _, name := range sortedKeys(properties) {
+		property, ok := properties[name].(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		f := Field{Path: path + "." + name, Name: name, Metadata: jsonSchemaMetadata(property)}
+		f.Doc, _ = property["description"].(string)
+		switch t := property["type"].(type) {
+		case string:
+			f.types = []string{t}
+		case []interface{}:
+			for _, v := range t {
+				if s, ok := v.(string); ok {
+					f.types = append(f.types, s)
+				}
+			}
+		}
+		if ref, ok := property["$ref"].(string); ok {
+			f.types = append(f.types, ref)
+		}
+		f.Type = strings.Join(f.types, " | ")
+		if format, ok := property["format"].(string); ok {
+			f.LogicalType, f.logicalTypes = format, []string{format}
+		}
+		d.Fields = append(d.Fields, f)
+
+		d.addJSONProperties(f.Path, property)
+	}
+}
+
+// addProtobuf adds the package and the fields of the messages of a protobuf schema line by line, fields of nested
+// messages are added with the path of their messages, the fields of oneofs with the path of their message
+func (d *Document) addProtobuf(content string) {
+	var blocks []string
+	for _, line := range strings.Split(content, "\n") {
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+
+		if m := protobufPackage.FindStringSubmatch(line); m != nil {
+			d.Namespace = m[1]
+		}
+		if m := protobufBlock.FindStringSubmatch(line); m != nil {
+			name := m[2]
+			if m[1] != "message" {
+				name = ""
+			}
+			if d.Name == "" && name != "" {
+				d.Name = qualified(d.Namespace, name)
+			}
+			blocks = append(blocks, name)
+		} else if m := protobufField.FindStringSubmatch(line); m != nil && len(blocks) > 0 {
+			path := "$"
+			for _, b := range blocks[1:] {
+				if b != "" {
+					path += "." + b
+				}
+			}
+			d.Fields = append(d.Fields, Field{Path: path + "." + m[2], Name: m[2], Type: m[1], types: []string{m[1]}})
+		}
+
+		for i := strings.Count(line, "}"); i > 0 && len(blocks) > 0; i-- {
+			blocks = blocks[:len(blocks)-1]
+		}
+	}
+}
+
+// indexHits collects the attributes of the document by the terms of their values
+func (d *Document) indexHits() {
+	d.hits = make(map[string][]hit)
+	add := func(attribute Attribute, path, value string) {
+		for _, term := range terms(value) {
+			d.hits[term] = append(d.hits[term], hit{attribute: attribute, path: path, value: value})
+		}
+	}
+
+	add(AttributeSubject, "", d.Subject.String())
+	add(AttributeName, "", d.Name)
+	add(AttributeNamespace, "", d.Namespace)
+	add(AttributeDoc, "", d.Doc)
+	for _, key := range sortedKeys(d.Metadata) {
+		add(AttributeMetadata, "", key+"="+d.Metadata[key])
+	}
+
+	for _, f := range d.Fields {
+		add(AttributeField, f.Path, f.Name)
+		add(AttributePath, f.Path, f.Path)
+		add(AttributeType, f.Path, f.Type)
+		add(AttributeLogicalType, f.Path, f.LogicalType)
+		add(AttributeDoc, f.Path, f.Doc)
+		for _, key := range sortedKeys(f.Metadata) {
+			add(AttributeMetadata, f.Path, key+"="+f.Metadata[key])
+		}
+	}
+}
+
+// terms returns the lower case words of s and the parts of camel case words, e.g. customer, email and
+// customeremail for customerEmail
+func terms(s string) []string {
+	seen := make(map[string]bool)
+	var result []string
+	add := func(term string) {
+		term = strings.ToLower(term)
+		if term != "" && !seen[term] {
+			seen[term] = true
+			result = append(result, term)
+		}
+	}
+
+	words := strings.FieldsFunc(s, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+	for _, word := range words {
+		add(word)
+
+		start := 0
+		runes := []rune(word)
+		for i := 1; i < len(runes); i++ {
+			if unicode.IsUpper(runes[i]) && !unicode.IsUpper(runes[i-1]) {
+				add(string(runes[start:i]))
+				start = i
+			}
+		}
+		if start > 0 {
+			add(string(runes[start:]))
+		}
+	}
+
+	return result
+}
+
+// queryTerms returns the terms a text must match, camel case words are matched by their parts so customerEmail
+// matches a doc about a customer email too
+func queryTerms(text string) []string {
+	var result []string
+	for _, word := range strings.FieldsFunc(text, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }) {
+		parts := terms(word)
+		if len(parts) > 1 {
+			parts = parts[1:]
+		}
+		result = append(result, parts...)
+	}
+
+	return result
+}
+
+// metadataOf returns the custom attributes of an avro type or field, values that are not strings are kept as json
+func metadataOf(props map[string]interface{}) map[string]string {
+	if len(props) == 0 {
+		return nil
+	}
+
+	metadata := make(map[string]string, len(props))
+	for key, value := range props {
+		if s, ok := value.(string); ok {
+			metadata[key] = s
+			continue
+		}
+		b, _ := json.Marshal(value)
+		metadata[key] = string(b)
+	}
+
+	return metadata
+}
+
+// jsonSchemaMetadata returns the extension keywords of a json schema object, the ones starting with x-
+func jsonSchemaMetadata(obj map[string]interface{}) map[string]string {
+	props := make(map[string]interface{})
+	for key, value := range obj {
+		if strings.HasPrefix(key, "x-") {
+			props[key] = value
+		}
+	}
+
+	return metadataOf(props)
+}
+
+func qualified(namespace, name string) string {
+	if namespace == "" {
+		return name
+	}
+
+	return namespace + "." + name
+}
+
+func sortedKeys(m interface{}) []string {
+	var keys []string
+	switch m := m.(type) {
+	case map[string]interface{}:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	case map[string]string:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	return keys
+}
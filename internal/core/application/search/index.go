@@ -0,0 +1,299 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ybalcin/event-schema-manager/internal/core/domain/schema"
+)
+
+type (
+	// Source is where the indexed schemas are read from, the schema service satisfies it
+	Source interface {
+		Subjects(ctx context.Context) ([]schema.Subject, error)
+		Versions(ctx context.Context, subject schema.Subject) ([]schema.SchemaVersion, error)
+		Get(ctx context.Context, subject schema.Subject, version schema.SchemaVersion) (*schema.Schema, error)
+	}
+
+	// Index is an in-memory search index over the latest versions of the subjects, or all of them with
+	// WithAllVersions. It is built by Run and refreshed by subject on the events of the event bus.
+	Index struct {
+		source      Source
+		allVersions bool
+
+		mu sync.RWMutex
+		// documents are the indexed versions by subject, oldest first
+		documents map[schema.Subject][]*Document
+		// postings are the subjects having a document with a term
+		postings map[string]map[schema.Subject]bool
+
+		queue chan schema.Subject
+		// overflowed is set when a refresh could not be queued, Run rebuilds the index then
+		overflowed int32
+	}
+
+	Option func(*Index)
+)
+
+const refreshQueueSize = 1000
+
+// WithAllVersions indexes every version of the subjects, only the latest ones are indexed by default
+func WithAllVersions() Option {
+	return func(i *Index) {
+		i.allVersions = true
+	}
+}
+
+func NewIndex(source Source, opts ...Option) *Index {
+	i := &Index{
+		source:    source,
+		documents: make(map[schema.Subject][]*Document),
+		postings:  make(map[string]map[schema.Subject]bool),
+		queue:     make(chan schema.Subject, refreshQueueSize),
+	}
+	for _, opt := range opts {
+		opt(i)
+	}
+
+	return i
+}
+
+// Handle queues the refresh of the subject of event, it is subscribed to the event bus.
+// When the queue is full the refresh is dropped and the whole index is rebuilt by Run instead
+func (i *Index) Handle(event schema.Event) error {
+	subject := event.EventSubject()
+	if subject == "" {
+		return nil
+	}
+
+	select {
+	case i.queue <- subject:
+		return nil
+	default:
+		if atomic.CompareAndSwapInt32(&i.overflowed, 0, 1) {
+			log.Printf("search: refresh queue is full, refresh of subject %s is dropped and the index will be rebuilt", subject)
+		}
+		return nil
+	}
+}
+
+// Run builds the index, then refreshes the subjects queued by Handle until ctx is done,
+// failures are logged and the subject is refreshed again by its next event
+func (i *Index) Run(ctx context.Context) {
+	if err := i.Rebuild(ctx); err != nil {
+		log.Printf("search: index could not be built, trace: %v", err)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case subject := <-i.queue:
+			if err := i.Refresh(ctx, subject); err != nil {
+				log.Printf("search: subject %s could not be indexed, trace: %v", subject, err)
+			}
+			if atomic.CompareAndSwapInt32(&i.overflowed, 1, 0) {
+				if err := i.Rebuild(ctx); err != nil {
+					log.Printf("search: index could not be rebuilt, trace: %v", err)
+				}
+			}
+		}
+	}
+}
+
+// Rebuild indexes every subject of the source, subjects that can not be indexed are logged and skipped
+func (i *Index) Rebuild(ctx context.Context) error {
+	subjects, err := i.source.Subjects(ctx)
+	if err != nil {
+		return err
+	}
+
+	existing := make(map[schema.Subject]bool, len(subjects))
+	for _, subject := range subjects {
+		existing[subject] = true
+		if err = i.Refresh(ctx, subject); err != nil {
+			log.Printf("search: subject %s could not be indexed, trace: %v", subject, err)
+		}
+	}
+
+	for _, subject := range i.Subjects() {
+		if !existing[subject] {
+			i.replace(subject, nil)
+		}
+	}
+
+	return nil
+}
+
+// Refresh indexes the current versions of subject, versions indexed already are not read again
+// and a deleted subject is removed from the index
+func (i *Index) Refresh(ctx context.Context, subject schema.Subject) error {
+	versions, err := i.source.Versions(ctx, subject)
+	if errors.Is(err, schema.ErrSubjectNotFound) {
+		i.replace(subject, nil)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(versions) == 0 {
+		i.replace(subject, nil)
+		return nil
+	}
+	if !i.allVersions {
+		versions = versions[len(versions)-1:]
+	}
+
+	indexed := make(map[schema.SchemaVersion]*Document)
+	i.mu.RLock()
+	for _, doc := range i.documents[subject] {
+		indexed[doc.Version] = doc
+	}
+	i.mu.RUnlock()
+
+	documents := make([]*Document, 0, len(versions))
+	for n, v := range versions {
+		latest := n == len(versions)-1
+		if doc, ok := indexed[v]; ok {
+			// documents are not changed once they are indexed, searches may be reading them
+			if doc.Latest != latest {
+				updated := *doc
+				updated.Latest = latest
+				doc = &updated
+			}
+			documents = append(documents, doc)
+			continue
+		}
+
+		sc, err := i.source.Get(ctx, subject, v)
+		if errors.Is(err, schema.ErrVersionNotFound) {
+			// the version is deleted since the versions are listed, its event refreshes the subject again
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		doc, err := newDocument(sc, latest)
+		if err != nil {
+			return err
+		}
+		documents = append(documents, doc)
+	}
+
+	i.replace(subject, documents)
+	return nil
+}
+
+// replace replaces the documents of subject, it is removed from the index if documents is empty
+func (i *Index) replace(subject schema.Subject, documents []*Document) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	for _, doc := range i.documents[subject] {
+		for term := range doc.hits {
+			delete(i.postings[term], subject)
+			if len(i.postings[term]) == 0 {
+				delete(i.postings, term)
+			}
+		}
+	}
+
+	if len(documents) == 0 {
+		delete(i.documents, subject)
+		return
+	}
+
+	i.documents[subject] = documents
+	for _, doc := range documents {
+		for term := range doc.hits {
+			if i.postings[term] == nil {
+				i.postings[term] = make(map[schema.Subject]bool)
+			}
+			i.postings[term][subject] = true
+		}
+	}
+}
+
+// Subjects returns the indexed subjects in order
+func (i *Index) Subjects() []schema.Subject {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	subjects := make([]schema.Subject, 0, len(i.documents))
+	for subject := range i.documents {
+		subjects = append(subjects, subject)
+	}
+	sort.Slice(subjects, func(a, b int) bool { return subjects[a] < subjects[b] })
+
+	return subjects
+}
+
+// Search returns the documents matching q, the best matches first
+func (i *Index) Search(q Query) ([]Result, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+	textTerms := queryTerms(q.Text)
+
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	results := []Result{}
+	for _, subject := range i.candidates(textTerms) {
+		for _, doc := range i.documents[subject] {
+			if !q.AllVersions && !doc.Latest {
+				continue
+			}
+			if result, ok := q.match(doc, textTerms); ok {
+				results = append(results, result)
+			}
+		}
+	}
+
+	sort.SliceStable(results, func(a, b int) bool {
+		ra, rb := results[a], results[b]
+		if ra.Score != rb.Score {
+			return ra.Score > rb.Score
+		}
+		if ra.Subject != rb.Subject {
+			return ra.Subject < rb.Subject
+		}
+		return ra.Version > rb.Version
+	})
+
+	if len(results) > q.limit() {
+		results = results[:q.limit()]
+	}
+
+	return results, nil
+}
+
+// candidates returns the subjects having documents with every term, all subjects if there is no term
+func (i *Index) candidates(textTerms []string) []schema.Subject {
+	var subjects []schema.Subject
+	if len(textTerms) == 0 {
+		for subject := range i.documents {
+			subjects = append(subjects, subject)
+		}
+		return subjects
+	}
+
+	for subject := range i.postings[textTerms[0]] {
+		all := true
+		for _, term := range textTerms[1:] {
+			if !i.postings[term][subject] {
+				all = false
+				break
+			}
+		}
+		if all {
+			subjects = append(subjects, subject)
+		}
+	}
+
+	return subjects
+}
@@ -0,0 +1,238 @@
+package search
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/ybalcin/event-schema-manager/internal/core/domain/schema"
+)
+
+type (
+	// Attribute is an indexed attribute of a document
+	Attribute string
+
+	// Query finds documents by text and filters, zero fields match every document. Field, Type and LogicalType
+	// must be matched by the same field of a document.
+	Query struct {
+		// Text matches documents having every word of it in an attribute, camel case words match their parts
+		Text string
+		// Field is the name of a field, case insensitive, it may be a glob like customer*
+		Field string
+		// Type is a type of a field, e.g. string, a named type or a type of a union branch
+		Type string
+		// LogicalType is a logical type of a field, e.g. decimal, or the format of json schema properties
+		LogicalType string
+		// Namespace matches documents in the namespace or in the namespaces under it
+		Namespace string
+		// Subject is a subject name or a glob like orders-*
+		Subject    string
+		SchemaType schema.SchemaType
+		// AllVersions matches the older indexed versions too, the latest versions are matched by default
+		AllVersions bool
+		// Limit is the maximum number of results, defaultLimit by default
+		Limit int
+		// Readable filters the subjects the caller can read, every subject is readable if it is nil
+		Readable func(subject schema.Subject) bool
+	}
+
+	// Result is a matching document with the attributes it is matched by
+	Result struct {
+		Subject    schema.Subject       `json:"subject"`
+		Version    schema.SchemaVersion `json:"version"`
+		SchemaID   schema.SchemaID      `json:"schemaId"`
+		SchemaType schema.SchemaType    `json:"schemaType"`
+		Latest     bool                 `json:"latest"`
+		Name       string               `json:"name,omitempty"`
+		Namespace  string               `json:"namespace,omitempty"`
+		Score      float64              `json:"score"`
+		Matches    []Match              `json:"matches"`
+	}
+
+	// Match is an attribute of a result matching the query, Path is the path of the field if any
+	Match struct {
+		Attribute Attribute `json:"attribute"`
+		Path      string    `json:"path,omitempty"`
+		Value     string    `json:"value"`
+	}
+)
+
+const (
+	AttributeSubject     Attribute = "subject"
+	AttributeName        Attribute = "name"
+	AttributeNamespace   Attribute = "namespace"
+	AttributeField       Attribute = "field"
+	AttributePath        Attribute = "path"
+	AttributeType        Attribute = "type"
+	AttributeLogicalType Attribute = "logicalType"
+	AttributeDoc         Attribute = "doc"
+	AttributeMetadata    Attribute = "metadata"
+)
+
+const (
+	defaultLimit = 20
+	maxLimit     = 1000
+
+	// exactFieldWeight is added when the whole text is the name of a field
+	exactFieldWeight = 10
+	// filterFieldWeight is added for every field matching the field filters
+	filterFieldWeight = 5
+	// latestWeight ranks the latest version of a subject above its older versions
+	latestWeight = 0.5
+)
+
+// weights rank the attributes a term is found in, names rank above types and docs
+var weights = map[Attribute]float64{
+	AttributeSubject:     8,
+	AttributeName:        6,
+	AttributeField:       6,
+	AttributeNamespace:   3,
+	AttributePath:        3,
+	AttributeType:        3,
+	AttributeLogicalType: 3,
+	AttributeMetadata:    2,
+	AttributeDoc:         1,
+}
+
+var ErrInvalidQuery = errors.New("invalid search query")
+
+func (q Query) Validate() error {
+	for name, pattern := range map[string]string{"subject": q.Subject, "field": q.Field} {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("%w: %s %s is not a valid glob", ErrInvalidQuery, name, pattern)
+		}
+	}
+	if q.Limit < 0 || q.Limit > maxLimit {
+		return fmt.Errorf("%w: limit %d is out of range, the maximum is %d", ErrInvalidQuery, q.Limit, maxLimit)
+	}
+
+	return nil
+}
+
+func (q Query) limit() int {
+	if q.Limit == 0 {
+		return defaultLimit
+	}
+
+	return q.Limit
+}
+
+// match returns the result of doc if it matches q, textTerms are the terms of the text of q
+func (q Query) match(doc *Document, textTerms []string) (Result, bool) {
+	if q.SchemaType != "" && doc.SchemaType != q.SchemaType {
+		return Result{}, false
+	}
+	if q.Subject != "" {
+		if ok, _ := path.Match(q.Subject, doc.Subject.String()); !ok {
+			return Result{}, false
+		}
+	}
+	if q.Namespace != "" && !inNamespace(doc.Namespace, q.Namespace) {
+		return Result{}, false
+	}
+	if q.Readable != nil && !q.Readable(doc.Subject) {
+		return Result{}, false
+	}
+
+	result := Result{
+		Subject:    doc.Subject,
+		Version:    doc.Version,
+		SchemaID:   doc.SchemaID,
+		SchemaType: doc.SchemaType,
+		Latest:     doc.Latest,
+		Name:       doc.Name,
+		Namespace:  doc.Namespace,
+		Matches:    []Match{},
+	}
+	matched := make(map[Match]bool)
+	addMatch := func(m Match) {
+		if !matched[m] {
+			matched[m] = true
+			result.Matches = append(result.Matches, m)
+		}
+	}
+
+	if q.hasFieldFilters() {
+		for _, f := range doc.Fields {
+			if q.matchesField(f) {
+				result.Score += filterFieldWeight
+				addMatch(Match{Attribute: AttributeField, Path: f.Path, Value: f.Name})
+			}
+		}
+		if len(result.Matches) == 0 {
+			return Result{}, false
+		}
+	}
+
+	for _, term := range textTerms {
+		hits := doc.hits[term]
+		if len(hits) == 0 {
+			return Result{}, false
+		}
+
+		best := 0.0
+		for _, h := range hits {
+			if weights[h.attribute] > best {
+				best = weights[h.attribute]
+			}
+			addMatch(Match{Attribute: h.attribute, Path: h.path, Value: h.value})
+		}
+		result.Score += best
+	}
+
+	text := strings.TrimSpace(q.Text)
+	for _, f := range doc.Fields {
+		if text != "" && strings.EqualFold(f.Name, text) {
+			result.Score += exactFieldWeight
+			break
+		}
+	}
+	if doc.Latest {
+		result.Score += latestWeight
+	}
+
+	sort.SliceStable(result.Matches, func(a, b int) bool {
+		return weights[result.Matches[a].Attribute] > weights[result.Matches[b].Attribute]
+	})
+
+	return result, true
+}
+
+func (q Query) hasFieldFilters() bool {
+	return q.Field != "" || q.Type != "" || q.LogicalType != ""
+}
+
+// matchesField returns true if f matches the field filters of q
+func (q Query) matchesField(f Field) bool {
+	if q.Field != "" {
+		if ok, _ := path.Match(strings.ToLower(q.Field), strings.ToLower(f.Name)); !ok {
+			return false
+		}
+	}
+	if q.Type != "" && !containsFold(f.types, q.Type) {
+		return false
+	}
+	if q.LogicalType != "" && !containsFold(f.logicalTypes, q.LogicalType) {
+		return false
+	}
+
+	return true
+}
+
+// inNamespace returns true if namespace is parent or a namespace under it
+func inNamespace(namespace, parent string) bool {
+	namespace, parent = strings.ToLower(namespace), strings.ToLower(parent)
+	return namespace == parent || strings.HasPrefix(namespace, parent+".")
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,293 @@
+package search
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ybalcin/event-schema-manager/internal/core/domain/schema"
+)
+
+type (
+	// fakeSource keeps the versions of subjects in memory, oldest first
+	fakeSource struct {
+		mu       sync.Mutex
+		subjects map[schema.Subject][]*schema.Schema
+		nextID   schema.SchemaID
+		gets     int
+	}
+)
+
+const (
+	orderV1 = `{"type":"record","name":"Order","namespace":"com.acme.orders","doc":"an order","owner":"orders-team",
+		"fields":[{"name":"id","type":"string"}]}`
+	orderV2 = `{"type":"record","name":"Order","namespace":"com.acme.orders","doc":"an order","owner":"orders-team",
+		"fields":[
+			{"name":"id","type":"string"},
+			{"name":"customerEmail","type":["null","string"],"doc":"where the receipt is sent","default":null},
+			{"name":"total","type":{"type":"bytes","logicalType":"decimal","precision":10,"scale":2}}
+		]}`
+	payment = `{"type":"record","name":"Payment","namespace":"com.acme.payments","fields":[
+		{"name":"amount","type":{"type":"bytes","logicalType":"decimal","precision":10,"scale":2}},
+		{"name":"payer","type":{"type":"record","name":"Payer","fields":[{"name":"email","type":"string","doc":"the customer email"}]}}
+	]}`
+	signup = `{"title":"Signup","$id":"https://acme.com/signup","type":"object","properties":{
+		"customerEmail":{"type":"string","format":"email","x-pii":true},
+		"address":{"type":"object","properties":{"city":{"type":"string"}}}
+	}}`
+	shipment = `syntax = "proto3";
+package acme.shipping;
+
+message Shipment {
+  string id = 1;
+  repeated Parcel parcels = 2;
+  message Parcel {
+    int64 weight_grams = 1;
+  }
+  oneof destination {
+    string customer_email = 3;
+  }
+}`
+)
+
+func mustEqual(t *testing.T, actual, expected interface{}) {
+	t.Helper()
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %#v, but got %#v", expected, actual)
+	}
+}
+
+func newFakeSource() *fakeSource {
+	return &fakeSource{subjects: make(map[schema.Subject][]*schema.Schema), nextID: 1}
+}
+
+func (s *fakeSource) add(t *testing.T, subject schema.Subject, schemaType schema.SchemaType, content string) {
+	t.Helper()
+
+	sc, err := schema.NewSchema(subject, schemaType, content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	versions := s.subjects[subject]
+	s.subjects[subject] = append(versions, sc.Registered(s.nextID, schema.SchemaVersion(len(versions)+1)))
+	s.nextID++
+}
+
+func (s *fakeSource) Subjects(ctx context.Context) ([]schema.Subject, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var subjects []schema.Subject
+	for subject := range s.subjects {
+		subjects = append(subjects, subject)
+	}
+
+	return subjects, nil
+}
+
+func (s *fakeSource) Versions(ctx context.Context, subject schema.Subject) ([]schema.SchemaVersion, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	schemas, ok := s.subjects[subject]
+	if !ok {
+		return nil, schema.ErrSubjectNotFound
+	}
+
+	versions := make([]schema.SchemaVersion, len(schemas))
+	for i, sc := range schemas {
+		versions[i] = sc.Version()
+	}
+
+	return versions, nil
+}
+
+func (s *fakeSource) Get(ctx context.Context, subject schema.Subject, version schema.SchemaVersion) (*schema.Schema, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.gets++
+	for _, sc := range s.subjects[subject] {
+		if sc.Version() == version {
+			return sc, nil
+		}
+	}
+
+	return nil, schema.ErrVersionNotFound
+}
+
+func newTestIndex(t *testing.T, opts ...Option) (*Index, *fakeSource) {
+	t.Helper()
+
+	source := newFakeSource()
+	source.add(t, "orders-value", schema.SchemaTypeAvro, orderV1)
+	source.add(t, "orders-value", schema.SchemaTypeAvro, orderV2)
+	source.add(t, "payments-value", schema.SchemaTypeAvro, payment)
+	source.add(t, "signups-value", schema.SchemaTypeJSON, signup)
+	source.add(t, "shipments-value", schema.SchemaTypeProtobuf, shipment)
+
+	index := NewIndex(source, opts...)
+	if err := index.Rebuild(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	return index, source
+}
+
+func mustSearch(t *testing.T, index *Index, q Query) []Result {
+	t.Helper()
+
+	results, err := index.Search(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return results
+}
+
+func subjectsOf(results []Result) []schema.Subject {
+	subjects := []schema.Subject{}
+	for _, r := range results {
+		subjects = append(subjects, r.Subject)
+	}
+
+	return subjects
+}
+
+func TestIndex_SearchText(t *testing.T) {
+	index, _ := newTestIndex(t)
+
+	// fields named customerEmail rank above customer_email and above the doc mentioning a customer email
+	results := mustSearch(t, index, Query{Text: "customerEmail"})
+	mustEqual(t, subjectsOf(results), []schema.Subject{"orders-value", "signups-value", "shipments-value", "payments-value"})
+	mustEqual(t, results[0].Version, schema.SchemaVersion(2))
+	mustEqual(t, results[0].Matches[0], Match{Attribute: AttributeField, Path: "$.customerEmail", Value: "customerEmail"})
+
+	mustEqual(t, subjectsOf(mustSearch(t, index, Query{Text: "orders team"})), []schema.Subject{"orders-value"})
+	mustEqual(t, subjectsOf(mustSearch(t, index, Query{Text: "weight grams"})), []schema.Subject{"shipments-value"})
+	mustEqual(t, subjectsOf(mustSearch(t, index, Query{Text: "unknown"})), []schema.Subject{})
+}
+
+func TestIndex_SearchFilters(t *testing.T) {
+	index, _ := newTestIndex(t)
+
+	results := mustSearch(t, index, Query{LogicalType: "decimal"})
+	mustEqual(t, subjectsOf(results), []schema.Subject{"orders-value", "payments-value"})
+	mustEqual(t, results[1].Matches, []Match{{Attribute: AttributeField, Path: "$.amount", Value: "amount"}})
+
+	mustEqual(t, subjectsOf(mustSearch(t, index, Query{Field: "*email", Type: "string"})),
+		[]schema.Subject{"orders-value", "payments-value", "shipments-value", "signups-value"})
+	mustEqual(t, subjectsOf(mustSearch(t, index, Query{Field: "customer_email"})), []schema.Subject{"shipments-value"})
+	mustEqual(t, subjectsOf(mustSearch(t, index, Query{LogicalType: "email"})), []schema.Subject{"signups-value"})
+	mustEqual(t, subjectsOf(mustSearch(t, index, Query{Namespace: "com.acme"})), []schema.Subject{"orders-value", "payments-value"})
+	mustEqual(t, subjectsOf(mustSearch(t, index, Query{Text: "email", Subject: "s*"})), []schema.Subject{"shipments-value", "signups-value"})
+	mustEqual(t, subjectsOf(mustSearch(t, index, Query{SchemaType: schema.SchemaTypeJSON})), []schema.Subject{"signups-value"})
+	mustEqual(t, subjectsOf(mustSearch(t, index, Query{Text: "email", Limit: 1})), []schema.Subject{"payments-value"})
+
+	readable := func(subject schema.Subject) bool { return subject != "orders-value" }
+	mustEqual(t, subjectsOf(mustSearch(t, index, Query{LogicalType: "decimal", Readable: readable})), []schema.Subject{"payments-value"})
+
+	if _, err := index.Search(Query{Subject: "["}); err == nil {
+		t.Fatal("expected an invalid query error")
+	}
+}
+
+func TestIndex_AllVersions(t *testing.T) {
+	latest, _ := newTestIndex(t)
+	mustEqual(t, len(mustSearch(t, latest, Query{Subject: "orders-value", AllVersions: true})), 1)
+
+	index, _ := newTestIndex(t, WithAllVersions())
+	results := mustSearch(t, index, Query{Text: "id", Subject: "orders-value", AllVersions: true})
+	mustEqual(t, len(results), 2)
+	mustEqual(t, []bool{results[0].Latest, results[1].Latest}, []bool{true, false})
+
+	mustEqual(t, len(mustSearch(t, index, Query{Text: "id", Subject: "orders-value"})), 1)
+}
+
+func TestIndex_Refresh(t *testing.T) {
+	index, source := newTestIndex(t, WithAllVersions())
+	ctx := context.Background()
+
+	gets := source.gets
+	source.add(t, "payments-value", schema.SchemaTypeAvro, `{"type":"record","name":"Payment","namespace":"com.acme.payments","fields":[
+		{"name":"amount","type":"long","doc":"in cents"}
+	]}`)
+	if err := index.Refresh(ctx, "payments-value"); err != nil {
+		t.Fatal(err)
+	}
+	// only the new version is read
+	mustEqual(t, source.gets, gets+1)
+
+	results := mustSearch(t, index, Query{LogicalType: "decimal", AllVersions: true})
+	mustEqual(t, len(results), 2)
+	mustEqual(t, results[1].Subject, schema.Subject("payments-value"))
+	mustEqual(t, results[1].Latest, false)
+	mustEqual(t, subjectsOf(mustSearch(t, index, Query{Text: "cents"})), []schema.Subject{"payments-value"})
+
+	delete(source.subjects, "payments-value")
+	if err := index.Refresh(ctx, "payments-value"); err != nil {
+		t.Fatal(err)
+	}
+	mustEqual(t, index.Subjects(), []schema.Subject{"orders-value", "shipments-value", "signups-value"})
+	mustEqual(t, subjectsOf(mustSearch(t, index, Query{Text: "cents"})), []schema.Subject{})
+	if _, ok := index.postings["cents"]; ok {
+		t.Error("expected the terms of the deleted subject to be removed")
+	}
+}
+
+func TestIndex_RunRefreshesOnEvents(t *testing.T) {
+	source := newFakeSource()
+	index := NewIndex(source)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		index.Run(ctx)
+		close(stopped)
+	}()
+	defer func() {
+		cancel()
+		<-stopped
+	}()
+
+	source.add(t, "orders-value", schema.SchemaTypeAvro, orderV2)
+	if err := index.Handle(schema.SchemaRegistered{EventMetadata: schema.NewEventMetadata(), Subject: "orders-value", Version: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for len(mustSearch(t, index, Query{Text: "customerEmail"})) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the registered subject to be indexed")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestNewDocument(t *testing.T) {
+	sc, err := schema.NewSchema("shipments-value", schema.SchemaTypeProtobuf, shipment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := newDocument(sc.Registered(1, 1), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustEqual(t, doc.Name, "acme.shipping.Shipment")
+	mustEqual(t, doc.Namespace, "acme.shipping")
+	mustEqual(t, doc.Fields, []Field{
+		{Path: "$.id", Name: "id", Type: "string", types: []string{"string"}},
+		{Path: "$.parcels", Name: "parcels", Type: "Parcel", types: []string{"Parcel"}},
+		{Path: "$.Parcel.weight_grams", Name: "weight_grams", Type: "int64", types: []string{"int64"}},
+		{Path: "$.customer_email", Name: "customer_email", Type: "string", types: []string{"string"}},
+	})
+
+	mustEqual(t, terms("customerEmail HTTPServer v2"), []string{"customeremail", "customer", "email", "httpserver", "v2"})
+	mustEqual(t, queryTerms("customerEmail v2"), []string{"customer", "email", "v2"})
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ybalcin/event-schema-manager/internal/core/domain/schema"
+)
+
+type (
+	// stagedChange describes a change staged before it is made, so it can be looked up in the registry if the
+	// process stops before it is confirmed
+	stagedChange struct {
+		SchemaType schema.SchemaType         `json:"schemaType,omitempty"`
+		Schema     string                    `json:"schema,omitempty"`
+		References []schema.Reference        `json:"references,omitempty"`
+		Version    schema.SchemaVersion      `json:"version,omitempty"`
+		Versions   []schema.SchemaVersion    `json:"versions,omitempty"`
+		Previous   schema.CompatibilityLevel `json:"previous,omitempty"`
+		Level      schema.CompatibilityLevel `json:"level,omitempty"`
+	}
+
+	// ChangeRecoverer finds out in the registry if a change staged by the schema service was made, the outbox uses
+	// it for the changes left by a crash between the registry call and publishing their events
+	ChangeRecoverer struct {
+		repository schema.Repository
+	}
+)
+
+func NewChangeRecoverer(repository schema.Repository) *ChangeRecoverer {
+	return &ChangeRecoverer{repository: repository}
+}
+
+// Recover returns the events of the staged change if the registry shows it was made and none if it was not.
+// The deletion of the latest version can not be told apart from an earlier deletion and is never recovered.
+func (r *ChangeRecoverer) Recover(eventType schema.EventType, subject schema.Subject, change []byte) ([]schema.Event, error) {
+	var staged stagedChange
+	if err := json.Unmarshal(change, &staged); err != nil {
+		return nil, fmt.Errorf("staged change could not be read, trace: %v", err)
+	}
+
+	switch eventType {
+	case schema.EventSchemaRegistered:
+		return r.recoverRegistration(subject, staged)
+	case schema.EventSubjectDeleted:
+		return r.recoverSubjectDeletion(subject, staged)
+	case schema.EventSchemaVersionDeleted:
+		return r.recoverVersionDeletion(subject, staged)
+	case schema.EventCompatibilityChanged:
+		return r.recoverCompatibilityChange(subject, staged)
+	}
+
+	return nil, fmt.Errorf("staged change of type %s can not be recovered", eventType)
+}
+
+func (r *ChangeRecoverer) recoverRegistration(subject schema.Subject, staged stagedChange) ([]schema.Event, error) {
+	sc, err := schema.NewSchema(subject, staged.SchemaType, staged.Schema)
+	if err != nil {
+		return nil, err
+	}
+
+	registered, err := r.repository.Find(sc.WithReferences(staged.References))
+	if err != nil || registered == nil {
+		return nil, err
+	}
+
+	return []schema.Event{schema.SchemaRegistered{
+		EventMetadata: schema.NewEventMetadata(),
+		Subject:       registered.Subject(),
+		Version:       registered.Version(),
+		SchemaID:      registered.ID(),
+		SchemaType:    registered.Type(),
+	}}, nil
+}
+
+func (r *ChangeRecoverer) recoverSubjectDeletion(subject schema.Subject, staged stagedChange) ([]schema.Event, error) {
+	if len(staged.Versions) == 0 {
+		// the subject did not exist when the deletion was staged
+		return nil, nil
+	}
+
+	_, err := r.repository.Versions(subject)
+	if !errors.Is(err, schema.ErrSubjectNotFound) {
+		return nil, err
+	}
+
+	return []schema.Event{schema.SubjectDeleted{
+		EventMetadata: schema.NewEventMetadata(),
+		Subject:       subject,
+		Versions:      staged.Versions,
+	}}, nil
+}
+
+func (r *ChangeRecoverer) recoverVersionDeletion(subject schema.Subject, staged stagedChange) ([]schema.Event, error) {
+	if staged.Version.IsLatest() || staged.Version == 0 {
+		return nil, nil
+	}
+
+	_, err := r.repository.Get(subject, staged.Version)
+	if !errors.Is(err, schema.ErrVersionNotFound) && !errors.Is(err, schema.ErrSubjectNotFound) {
+		return nil, err
+	}
+
+	return []schema.Event{schema.SchemaVersionDeleted{
+		EventMetadata: schema.NewEventMetadata(),
+		Subject:       subject,
+		Version:       staged.Version,
+	}}, nil
+}
+
+func (r *ChangeRecoverer) recoverCompatibilityChange(subject schema.Subject, staged stagedChange) ([]schema.Event, error) {
+	level, err := r.repository.CompatibilityLevel(subject)
+	if err != nil {
+		return nil, err
+	}
+	if level != staged.Level || level == staged.Previous {
+		return nil, nil
+	}
+
+	return []schema.Event{schema.CompatibilityChanged{
+		EventMetadata: schema.NewEventMetadata(),
+		Subject:       subject,
+		Previous:      staged.Previous,
+		Level:         level,
+	}}, nil
+}
@@ -0,0 +1,101 @@
+package services_test
+
+import (
+	"testing"
+
+	"github.com/ybalcin/event-schema-manager/internal/core/application/services"
+	"github.com/ybalcin/event-schema-manager/internal/core/domain/schema"
+)
+
+type (
+	// stagedChange is the last change staged by recordingStager
+	stagedChange struct {
+		eventType schema.EventType
+		subject   schema.Subject
+		change    []byte
+	}
+
+	// recordingStager keeps the last staged change and drops the events, as a crash before confirming does
+	recordingStager struct {
+		last stagedChange
+	}
+)
+
+func (s *recordingStager) Publish(events ...schema.Event) error {
+	return nil
+}
+
+func (s *recordingStager) Stage(eventType schema.EventType, subject schema.Subject, change []byte) (string, error) {
+	s.last = stagedChange{eventType: eventType, subject: subject, change: change}
+	return "staged", nil
+}
+
+func (s *recordingStager) Confirm(id string, events ...schema.Event) error {
+	return nil
+}
+
+func mustRecover(t *testing.T, recoverer *services.ChangeRecoverer, staged stagedChange) []schema.Event {
+	t.Helper()
+
+	events, err := recoverer.Recover(staged.eventType, staged.subject, staged.change)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return events
+}
+
+func TestChangeRecoverer_Recover(t *testing.T) {
+	repository := newFakeRepository()
+	stager := &recordingStager{}
+	service := services.NewSchemaService(repository, services.WithEventPublisher(stager))
+	recoverer := services.NewChangeRecoverer(repository)
+
+	if _, err := service.Add(ctx, mustSchema(t, "orders-value", `"string"`)); err != nil {
+		t.Fatal(err)
+	}
+	events := mustRecover(t, recoverer, stager.last)
+	if len(events) != 1 {
+		t.Fatalf("expected 1 event, but got %d", len(events))
+	}
+	registered := events[0].(schema.SchemaRegistered)
+	mustEqual(t, registered.Subject, schema.Subject("orders-value"))
+	mustEqual(t, registered.Version, schema.SchemaVersion(1))
+	mustEqual(t, registered.SchemaID, schema.SchemaID(1))
+
+	// a change which was not made has no events
+	mustEqual(t, len(mustRecover(t, services.NewChangeRecoverer(newFakeRepository()), stager.last)), 0)
+
+	if _, err := service.SetCompatibilityLevel(ctx, "orders-value", schema.CompatibilityFull); err != nil {
+		t.Fatal(err)
+	}
+	events = mustRecover(t, recoverer, stager.last)
+	if len(events) != 1 {
+		t.Fatalf("expected 1 event, but got %d", len(events))
+	}
+	changed := events[0].(schema.CompatibilityChanged)
+	mustEqual(t, changed.Previous, globalLevel)
+	mustEqual(t, changed.Level, schema.CompatibilityFull)
+
+	if _, err := service.Add(ctx, mustSchema(t, "orders-value", `"int"`)); err != nil {
+		t.Fatal(err)
+	}
+	// the latest version is staged as the concrete version it refers to
+	if _, err := service.DeleteVersion(ctx, "orders-value", schema.Latest); err != nil {
+		t.Fatal(err)
+	}
+	events = mustRecover(t, recoverer, stager.last)
+	if len(events) != 1 {
+		t.Fatalf("expected 1 event, but got %d", len(events))
+	}
+	mustEqual(t, events[0].(schema.SchemaVersionDeleted).Version, schema.SchemaVersion(2))
+
+	if _, err := service.Delete(ctx, "orders-value"); err != nil {
+		t.Fatal(err)
+	}
+	events = mustRecover(t, recoverer, stager.last)
+	if len(events) != 1 {
+		t.Fatalf("expected 1 event, but got %d", len(events))
+	}
+	mustEqual(t, events[0].(schema.SubjectDeleted).Versions, []schema.SchemaVersion{1})
+}
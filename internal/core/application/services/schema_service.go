@@ -1,29 +1,512 @@
 package services
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/ybalcin/event-schema-manager/internal/core/application/audit"
+	"github.com/ybalcin/event-schema-manager/internal/core/application/changelog"
+	"github.com/ybalcin/event-schema-manager/internal/core/application/linting"
 	"github.com/ybalcin/event-schema-manager/internal/core/domain/schema"
+	"github.com/ybalcin/event-schema-manager/pkg/schemadiff"
 )
 
 type (
 	SchemaService interface {
-		Add(subject string, schema string) error
+		Add(ctx context.Context, sc *schema.Schema) (*AddResult, error)
+		Subjects(ctx context.Context) ([]schema.Subject, error)
+		Versions(ctx context.Context, subject schema.Subject) ([]schema.SchemaVersion, error)
+		Get(ctx context.Context, subject schema.Subject, version schema.SchemaVersion) (*schema.Schema, error)
+		GetByID(ctx context.Context, id schema.SchemaID) (*schema.Schema, error)
+		SubjectsOf(ctx context.Context, id schema.SchemaID) ([]schema.Subject, error)
+		CheckCompatibility(ctx context.Context, sc *schema.Schema, version schema.SchemaVersion) (schema.Compatibility, error)
+		Delete(ctx context.Context, subject schema.Subject) ([]schema.SchemaVersion, error)
+		DeleteVersion(ctx context.Context, subject schema.Subject, version schema.SchemaVersion) (schema.SchemaVersion, error)
+		CompatibilityLevel(ctx context.Context, subject schema.Subject) (schema.CompatibilityLevel, error)
+		SetCompatibilityLevel(ctx context.Context, subject schema.Subject, level schema.CompatibilityLevel) (schema.CompatibilityLevel, error)
+		Lint(ctx context.Context, sc *schema.Schema) (linting.Report, error)
+		AuditLog(ctx context.Context, query audit.Query) ([]audit.Entry, error)
+		Diff(ctx context.Context, subject schema.Subject, from, to schema.SchemaVersion) (*schemadiff.Diff, error)
+		Changelog(ctx context.Context, subject schema.Subject) (*changelog.Changelog, error)
+	}
+
+	AddStatus string
+
+	// AddResult is the outcome of adding a schema, Schema is the registered schema unless it is rejected or incompatible
+	AddResult struct {
+		Status     AddStatus
+		Schema     *schema.Schema
+		Reasons    []string
+		Violations []linting.Violation
+	}
+
+	// Authorizer decides if the caller of ctx can change subject, the global config if subject is empty
+	Authorizer interface {
+		Authorize(ctx context.Context, subject schema.Subject) error
+	}
+
+	// RegistrationGate decides if sc can be registered by the caller of ctx, e.g. only through an approved proposal
+	RegistrationGate interface {
+		AllowRegistration(ctx context.Context, sc *schema.Schema) error
+	}
+
+	// EventStager is a publisher recording a change before it is made, so the events of the change are not lost if
+	// the process stops between the change and publishing them. The outbox implements it.
+	EventStager interface {
+		schema.EventPublisher
+		// Stage records a change of subject which is about to be made, change describes it to find out after a
+		// crash if it was made
+		Stage(eventType schema.EventType, subject schema.Subject, change []byte) (id string, err error)
+		// Confirm publishes the events of the staged change id, without events the change is discarded
+		Confirm(id string, events ...schema.Event) error
 	}
+
+	Option func(*schemaService)
+)
+
+const (
+	AddStatusCreated      AddStatus = "created"
+	AddStatusUnchanged    AddStatus = "unchanged"
+	AddStatusIncompatible AddStatus = "incompatible"
+	AddStatusRejected     AddStatus = "rejected"
 )
 
 type (
 	schemaService struct {
-		repository schema.Repository
+		repository  schema.Repository
+		linter      *linting.Linter
+		publisher   schema.EventPublisher
+		auditStore  audit.Store
+		authorizers []Authorizer
+		gates       []RegistrationGate
 	}
 )
 
-func NewSchemaService(repository schema.Repository) SchemaService {
-	return &schemaService{
+func NewSchemaService(repository schema.Repository, opts ...Option) SchemaService {
+	s := &schemaService{
 		repository: repository,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// WithLinter makes the service lint schemas before they are registered, violations with error severity reject them
+func WithLinter(linter *linting.Linter) Option {
+	return func(s *schemaService) {
+		s.linter = linter
+	}
+}
+
+// WithEventPublisher makes the service publish a domain event for every change, changes are staged before
+// they are made if publisher is an EventStager
+func WithEventPublisher(publisher schema.EventPublisher) Option {
+	return func(s *schemaService) {
+		s.publisher = publisher
+	}
+}
+
+// WithAuditLog makes the service append an audit entry for every change to store
+func WithAuditLog(store audit.Store) Option {
+	return func(s *schemaService) {
+		s.auditStore = store
+	}
+}
+
+// WithAuthorizer makes the service check changes with authorizer, every authorizer must allow a change
+func WithAuthorizer(authorizer Authorizer) Option {
+	return func(s *schemaService) {
+		s.authorizers = append(s.authorizers, authorizer)
+	}
+}
+
+// WithRegistrationGate makes the service check registrations with gate, every gate must allow a registration
+func WithRegistrationGate(gate RegistrationGate) Option {
+	return func(s *schemaService) {
+		s.gates = append(s.gates, gate)
+	}
+}
+
+func (s *schemaService) authorize(ctx context.Context, subject schema.Subject) error {
+	for _, a := range s.authorizers {
+		if err := a.Authorize(ctx, subject); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// record appends entry to the audit log. The change is done already but it fails with audit.ErrNotRecorded
+// if the entry can not be appended, so no change goes unaudited silently
+func (s *schemaService) record(entry audit.Entry) error {
+	if s.auditStore == nil {
+		return nil
+	}
+
+	if err := s.auditStore.Append(entry); err != nil {
+		log.Printf("schemaService: audit entry of %s %s could not be appended, trace: %v", entry.Operation, entry.Subject, err)
+		return fmt.Errorf("%w: %s of %s", audit.ErrNotRecorded, entry.Operation, entry.Subject)
+	}
+
+	return nil
+}
+
+// contentOf returns the content of a version for the audit diff, empty if it can not be read
+func (s *schemaService) contentOf(subject schema.Subject, version schema.SchemaVersion) string {
+	if s.auditStore == nil {
+		return ""
+	}
+
+	sc, err := s.repository.Get(subject, version)
+	if err != nil {
+		return ""
+	}
+
+	return sc.Content()
+}
+
+// publish publishes events of a change that is done already, so failures are logged and not returned
+func (s *schemaService) publish(events ...schema.Event) {
+	if s.publisher == nil || len(events) == 0 {
+		return
+	}
+
+	if err := s.publisher.Publish(events...); err != nil {
+		log.Printf("schemaService: events could not be published, trace: %v", err)
+	}
+}
+
+// stage records a change of subject before it is made if the publisher is an EventStager, the returned func
+// publishes the events of the change once it is made, or discards it when it is called without events.
+// A change which can not be staged is not made since its events could be lost.
+func (s *schemaService) stage(eventType schema.EventType, subject schema.Subject, change stagedChange) (func(events ...schema.Event), error) {
+	stager, ok := s.publisher.(EventStager)
+	if !ok {
+		return s.publish, nil
+	}
+
+	data, err := json.Marshal(change)
+	if err != nil {
+		return nil, err
+	}
+	id, err := stager.Stage(eventType, subject, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(events ...schema.Event) {
+		if err := stager.Confirm(id, events...); err != nil {
+			log.Printf("schemaService: events could not be published, trace: %v", err)
+		}
+	}, nil
 }
 
-func (s *schemaService) Add(subject string, schema string) error {
-	_, err := s.repository.Add(subject, schema)
-	// handle version, do something useful :)
-	return err
+// Add registers sc if it is not registered already, it passes the lint rules of its subject and it is compatible
+// with the latest version of its subject. A rejected schema is not registered and a *linting.Error is returned
+// with the result, an incompatible schema is not registered and a *schema.IncompatibleError is returned with the result.
+func (s *schemaService) Add(ctx context.Context, sc *schema.Schema) (*AddResult, error) {
+	if err := s.authorize(ctx, sc.Subject()); err != nil {
+		return nil, err
+	}
+	for _, g := range s.gates {
+		if err := g.AllowRegistration(ctx, sc); err != nil {
+			return nil, err
+		}
+	}
+
+	existing, err := s.repository.Find(sc)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return &AddResult{Status: AddStatusUnchanged, Schema: existing}, nil
+	}
+
+	report, err := s.Lint(ctx, sc)
+	if err != nil {
+		return nil, err
+	}
+	if report.HasErrors() {
+		result := &AddResult{Status: AddStatusRejected, Violations: report.Violations}
+		return result, &linting.Error{Subject: sc.Subject(), Report: report}
+	}
+
+	compatibility, err := s.repository.CheckCompatibility(sc, schema.Latest)
+	switch {
+	case isFirstVersion(err):
+		// nothing to be compatible with
+	case err != nil:
+		return nil, err
+	case !compatibility.IsCompatible:
+		result := &AddResult{Status: AddStatusIncompatible, Reasons: compatibility.Reasons, Violations: report.Violations}
+		return result, &schema.IncompatibleError{Subject: sc.Subject(), Reasons: compatibility.Reasons}
+	}
+
+	previous := s.contentOf(sc.Subject(), schema.Latest)
+
+	publish, err := s.stage(schema.EventSchemaRegistered, sc.Subject(), stagedChange{
+		SchemaType: sc.Type(),
+		Schema:     sc.Content(),
+		References: sc.References(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := s.repository.Add(sc)
+	if err != nil {
+		publish()
+		return nil, err
+	}
+
+	// the registry returns only the id, the version is looked up. The schema is registered even if its version
+	// could not be looked up, so the change is published, audited and returned without it.
+	registered, err := s.repository.Find(sc)
+	if err != nil {
+		log.Printf("schemaService: version of schema %d of subject %s could not be looked up, trace: %v", id, sc.Subject(), err)
+	}
+	if registered == nil {
+		registered = sc.Registered(id, 0)
+	}
+
+	publish(schema.SchemaRegistered{
+		EventMetadata: schema.NewEventMetadata(),
+		Subject:       registered.Subject(),
+		Version:       registered.Version(),
+		SchemaID:      registered.ID(),
+		SchemaType:    registered.Type(),
+	})
+
+	entry := audit.NewEntry(ctx, audit.OperationRegister, registered.Subject())
+	entry.Version, entry.SchemaID = registered.Version(), registered.ID()
+	entry.Diff = schemadiff.LineDiff(previous, registered.Content())
+	if err = s.record(entry); err != nil {
+		return nil, err
+	}
+
+	return &AddResult{Status: AddStatusCreated, Schema: registered, Violations: report.Violations}, nil
+}
+
+// isFirstVersion returns true if err means the subject has no version to check compatibility against
+func isFirstVersion(err error) bool {
+	return errors.Is(err, schema.ErrSubjectNotFound) || errors.Is(err, schema.ErrVersionNotFound)
+}
+
+func (s *schemaService) Subjects(ctx context.Context) ([]schema.Subject, error) {
+	return s.repository.Subjects()
+}
+
+func (s *schemaService) Versions(ctx context.Context, subject schema.Subject) ([]schema.SchemaVersion, error) {
+	return s.repository.Versions(subject)
+}
+
+func (s *schemaService) Get(ctx context.Context, subject schema.Subject, version schema.SchemaVersion) (*schema.Schema, error) {
+	return s.repository.Get(subject, version)
+}
+
+func (s *schemaService) GetByID(ctx context.Context, id schema.SchemaID) (*schema.Schema, error) {
+	return s.repository.GetByID(id)
+}
+
+func (s *schemaService) SubjectsOf(ctx context.Context, id schema.SchemaID) ([]schema.Subject, error) {
+	return s.repository.SubjectsOf(id)
+}
+
+func (s *schemaService) CheckCompatibility(ctx context.Context, sc *schema.Schema, version schema.SchemaVersion) (schema.Compatibility, error) {
+	return s.repository.CheckCompatibility(sc, version)
+}
+
+func (s *schemaService) Delete(ctx context.Context, subject schema.Subject) ([]schema.SchemaVersion, error) {
+	if err := s.authorize(ctx, subject); err != nil {
+		return nil, err
+	}
+
+	latest := s.contentOf(subject, schema.Latest)
+	// the versions are staged to publish them if the deletion is recovered, errors are left to the deletion
+	staged, _ := s.repository.Versions(subject)
+
+	publish, err := s.stage(schema.EventSubjectDeleted, subject, stagedChange{Versions: staged})
+	if err != nil {
+		return nil, err
+	}
+
+	versions, err := s.repository.Delete(subject)
+	if err != nil {
+		publish()
+		return nil, err
+	}
+
+	publish(schema.SubjectDeleted{
+		EventMetadata: schema.NewEventMetadata(),
+		Subject:       subject,
+		Versions:      versions,
+	})
+
+	entry := audit.NewEntry(ctx, audit.OperationDeleteSubject, subject)
+	entry.Diff = schemadiff.LineDiff(latest, "")
+	if err = s.record(entry); err != nil {
+		return nil, err
+	}
+
+	return versions, nil
+}
+
+func (s *schemaService) DeleteVersion(ctx context.Context, subject schema.Subject, version schema.SchemaVersion) (schema.SchemaVersion, error) {
+	if err := s.authorize(ctx, subject); err != nil {
+		return 0, err
+	}
+
+	// the deleted schema is recorded and its concrete version staged, errors are left to the deletion
+	existing, _ := s.repository.Get(subject, version)
+	staged := version
+	if existing != nil {
+		staged = existing.Version()
+	}
+
+	publish, err := s.stage(schema.EventSchemaVersionDeleted, subject, stagedChange{Version: staged})
+	if err != nil {
+		return 0, err
+	}
+
+	deleted, err := s.repository.DeleteVersion(subject, version)
+	if err != nil {
+		publish()
+		return 0, err
+	}
+
+	publish(schema.SchemaVersionDeleted{
+		EventMetadata: schema.NewEventMetadata(),
+		Subject:       subject,
+		Version:       deleted,
+	})
+
+	entry := audit.NewEntry(ctx, audit.OperationDeleteVersion, subject)
+	entry.Version = deleted
+	if existing != nil {
+		entry.SchemaID = existing.ID()
+		entry.Diff = schemadiff.LineDiff(existing.Content(), "")
+	}
+	if err = s.record(entry); err != nil {
+		return 0, err
+	}
+
+	return deleted, nil
+}
+
+func (s *schemaService) CompatibilityLevel(ctx context.Context, subject schema.Subject) (schema.CompatibilityLevel, error) {
+	return s.repository.CompatibilityLevel(subject)
+}
+
+// SetCompatibilityLevel sets the compatibility level of subject, the global level if subject is empty
+func (s *schemaService) SetCompatibilityLevel(ctx context.Context, subject schema.Subject, level schema.CompatibilityLevel) (schema.CompatibilityLevel, error) {
+	if err := s.authorize(ctx, subject); err != nil {
+		return "", err
+	}
+
+	// the previous level is only informative for the event
+	previous, err := s.repository.CompatibilityLevel(subject)
+	if err != nil && !errors.Is(err, schema.ErrSubjectNotFound) {
+		return "", err
+	}
+
+	publish, err := s.stage(schema.EventCompatibilityChanged, subject, stagedChange{Previous: previous, Level: level})
+	if err != nil {
+		return "", err
+	}
+
+	updated, err := s.repository.SetCompatibilityLevel(subject, level)
+	if err != nil {
+		publish()
+		return "", err
+	}
+
+	if updated != previous {
+		publish(schema.CompatibilityChanged{
+			EventMetadata: schema.NewEventMetadata(),
+			Subject:       subject,
+			Previous:      previous,
+			Level:         updated,
+		})
+	} else {
+		publish()
+	}
+
+	entry := audit.NewEntry(ctx, audit.OperationSetCompatibilityLevel, subject)
+	entry.Diff = schemadiff.LineDiff(string(previous), string(updated))
+	if err = s.record(entry); err != nil {
+		return "", err
+	}
+
+	return updated, nil
+}
+
+// Lint checks sc against the lint rules of its subject
+func (s *schemaService) Lint(ctx context.Context, sc *schema.Schema) (linting.Report, error) {
+	return s.linter.Lint(sc)
+}
+
+// AuditLog returns the audit entries matching query, no entries if the audit log is not enabled
+func (s *schemaService) AuditLog(ctx context.Context, query audit.Query) ([]audit.Entry, error) {
+	if s.auditStore == nil {
+		return []audit.Entry{}, nil
+	}
+
+	return s.auditStore.Query(query)
+}
+
+// Diff returns the structural difference from version from to version to of subject, the versions must have the
+// same schema type and it must be supported by schemadiff
+func (s *schemaService) Diff(ctx context.Context, subject schema.Subject, from, to schema.SchemaVersion) (*schemadiff.Diff, error) {
+	previous, err := s.repository.Get(subject, from)
+	if err != nil {
+		return nil, err
+	}
+	current, err := s.repository.Get(subject, to)
+	if err != nil {
+		return nil, err
+	}
+
+	if previous.Type() != current.Type() {
+		return nil, fmt.Errorf("%w: versions are %s and %s", schemadiff.ErrUnsupportedSchemaType, previous.Type(), current.Type())
+	}
+
+	diff, err := schemadiff.Compare(string(current.Type()), previous.Content(), current.Content())
+	if err != nil && !errors.Is(err, schemadiff.ErrUnsupportedSchemaType) {
+		return nil, fmt.Errorf("%w: %v", schema.ErrInvalidSchema, err)
+	}
+
+	return diff, err
+}
+
+// Changelog returns the changes between the consecutive versions of subject with the audit entries of their
+// registrations, the audit fields are empty if the audit log is not enabled
+func (s *schemaService) Changelog(ctx context.Context, subject schema.Subject) (*changelog.Changelog, error) {
+	versions, err := s.repository.Versions(subject)
+	if err != nil {
+		return nil, err
+	}
+
+	schemas := make([]*schema.Schema, 0, len(versions))
+	for _, v := range versions {
+		sc, err := s.repository.Get(subject, v)
+		if err != nil {
+			return nil, err
+		}
+		schemas = append(schemas, sc)
+	}
+
+	var entries []audit.Entry
+	if s.auditStore != nil {
+		if entries, err = s.auditStore.Query(audit.Query{Subject: subject}); err != nil {
+			return nil, err
+		}
+	}
+
+	return changelog.Build(subject, schemas, entries)
 }
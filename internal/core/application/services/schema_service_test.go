@@ -0,0 +1,592 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ybalcin/event-schema-manager/internal/core/application/audit"
+	"github.com/ybalcin/event-schema-manager/internal/core/application/outbox"
+	"github.com/ybalcin/event-schema-manager/internal/core/application/services"
+	"github.com/ybalcin/event-schema-manager/internal/core/domain/schema"
+	"github.com/ybalcin/event-schema-manager/internal/infrastructure/adapters"
+	"github.com/ybalcin/event-schema-manager/internal/shared/identity"
+	"github.com/ybalcin/event-schema-manager/pkg/schemadiff"
+)
+
+type (
+	// fakeRepository keeps schemas in memory, every schema is compatible unless incompatible is set
+	fakeRepository struct {
+		subjects     map[schema.Subject][]*schema.Schema
+		levels       map[schema.Subject]schema.CompatibilityLevel
+		nextID       schema.SchemaID
+		incompatible []string
+		// beforeAdd is called by Add before the schema is registered
+		beforeAdd func()
+	}
+)
+
+const globalLevel = schema.CompatibilityBackward
+
+var ctx = context.Background()
+
+func newFakeRepository() *fakeRepository {
+	return &fakeRepository{
+		subjects: make(map[schema.Subject][]*schema.Schema),
+		levels:   map[schema.Subject]schema.CompatibilityLevel{"": globalLevel},
+		nextID:   1,
+	}
+}
+
+func (r *fakeRepository) Add(sc *schema.Schema) (schema.SchemaID, error) {
+	if r.beforeAdd != nil {
+		r.beforeAdd()
+	}
+
+	id := r.nextID
+	r.nextID++
+
+	versions := r.subjects[sc.Subject()]
+	r.subjects[sc.Subject()] = append(versions, sc.Registered(id, schema.SchemaVersion(len(versions)+1)))
+
+	return id, nil
+}
+
+func (r *fakeRepository) Subjects() ([]schema.Subject, error) {
+	var subjects []schema.Subject
+	for subject := range r.subjects {
+		subjects = append(subjects, subject)
+	}
+
+	return subjects, nil
+}
+
+func (r *fakeRepository) Versions(subject schema.Subject) ([]schema.SchemaVersion, error) {
+	schemas, ok := r.subjects[subject]
+	if !ok {
+		return nil, schema.ErrSubjectNotFound
+	}
+
+	versions := make([]schema.SchemaVersion, len(schemas))
+	for i, sc := range schemas {
+		versions[i] = sc.Version()
+	}
+
+	return versions, nil
+}
+
+func (r *fakeRepository) Get(subject schema.Subject, version schema.SchemaVersion) (*schema.Schema, error) {
+	schemas, ok := r.subjects[subject]
+	if !ok {
+		return nil, schema.ErrSubjectNotFound
+	}
+
+	if version.IsLatest() && len(schemas) > 0 {
+		return schemas[len(schemas)-1], nil
+	}
+	for _, sc := range schemas {
+		if sc.Version() == version {
+			return sc, nil
+		}
+	}
+
+	return nil, schema.ErrVersionNotFound
+}
+
+func (r *fakeRepository) GetByID(id schema.SchemaID) (*schema.Schema, error) {
+	for _, schemas := range r.subjects {
+		for _, sc := range schemas {
+			if sc.ID() == id {
+				return sc, nil
+			}
+		}
+	}
+
+	return nil, schema.ErrSchemaNotFound
+}
+
+func (r *fakeRepository) SubjectsOf(id schema.SchemaID) ([]schema.Subject, error) {
+	var subjects []schema.Subject
+	for subject, schemas := range r.subjects {
+		for _, sc := range schemas {
+			if sc.ID() == id {
+				subjects = append(subjects, subject)
+				break
+			}
+		}
+	}
+	if len(subjects) == 0 {
+		return nil, schema.ErrSchemaNotFound
+	}
+
+	return subjects, nil
+}
+
+func (r *fakeRepository) Find(sc *schema.Schema) (*schema.Schema, error) {
+	for _, registered := range r.subjects[sc.Subject()] {
+		if registered.Content() == sc.Content() {
+			return registered, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (r *fakeRepository) CheckCompatibility(sc *schema.Schema, version schema.SchemaVersion) (schema.Compatibility, error) {
+	if _, err := r.Get(sc.Subject(), version); err != nil {
+		return schema.Compatibility{}, err
+	}
+
+	return schema.Compatibility{IsCompatible: len(r.incompatible) == 0, Reasons: r.incompatible}, nil
+}
+
+func (r *fakeRepository) Delete(subject schema.Subject) ([]schema.SchemaVersion, error) {
+	versions, err := r.Versions(subject)
+	if err != nil {
+		return nil, err
+	}
+	delete(r.subjects, subject)
+
+	return versions, nil
+}
+
+func (r *fakeRepository) DeleteVersion(subject schema.Subject, version schema.SchemaVersion) (schema.SchemaVersion, error) {
+	sc, err := r.Get(subject, version)
+	if err != nil {
+		return 0, err
+	}
+
+	var kept []*schema.Schema
+	for _, s := range r.subjects[subject] {
+		if s != sc {
+			kept = append(kept, s)
+		}
+	}
+	r.subjects[subject] = kept
+
+	return sc.Version(), nil
+}
+
+func (r *fakeRepository) CompatibilityLevel(subject schema.Subject) (schema.CompatibilityLevel, error) {
+	if level, ok := r.levels[subject]; ok {
+		return level, nil
+	}
+
+	return r.levels[""], nil
+}
+
+func (r *fakeRepository) SetCompatibilityLevel(subject schema.Subject, level schema.CompatibilityLevel) (schema.CompatibilityLevel, error) {
+	r.levels[subject] = level
+	return level, nil
+}
+
+func mustEqual(t *testing.T, actual, expected interface{}) {
+	t.Helper()
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %#v, but got %#v", expected, actual)
+	}
+}
+
+// newTestService returns a service publishing to an in-memory bus and the events it publishes
+func newTestService(repository schema.Repository, opts ...services.Option) (services.SchemaService, *[]schema.Event) {
+	events := &[]schema.Event{}
+	bus := adapters.NewInMemoryEventBus()
+	bus.Subscribe("", func(event schema.Event) error {
+		*events = append(*events, event)
+		return nil
+	})
+
+	return services.NewSchemaService(repository, append(opts, services.WithEventPublisher(bus))...), events
+}
+
+func mustSchema(t *testing.T, subject schema.Subject, content string) *schema.Schema {
+	t.Helper()
+
+	sc, err := schema.NewSchema(subject, schema.SchemaTypeAvro, content)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return sc
+}
+
+func TestSchemaService_Add_PublishesSchemaRegistered(t *testing.T) {
+	repository := newFakeRepository()
+	service, events := newTestService(repository)
+
+	sc := mustSchema(t, "orders-value", `"string"`)
+	result, err := service.Add(ctx, sc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustEqual(t, result.Status, services.AddStatusCreated)
+
+	// unchanged schemas are not registered again
+	if _, err = service.Add(ctx, sc); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(*events) != 1 {
+		t.Fatalf("expected 1 event, but got %d", len(*events))
+	}
+	registered, ok := (*events)[0].(schema.SchemaRegistered)
+	if !ok {
+		t.Fatalf("expected SchemaRegistered, but got %T", (*events)[0])
+	}
+	mustEqual(t, registered.Subject, schema.Subject("orders-value"))
+	mustEqual(t, registered.Version, schema.SchemaVersion(1))
+	mustEqual(t, registered.SchemaID, schema.SchemaID(1))
+	mustEqual(t, registered.SchemaType, schema.SchemaTypeAvro)
+	if registered.EventID() == "" {
+		t.Error("event id is required")
+	}
+}
+
+// lookupFailingRepository can not look schemas up once one is added
+type lookupFailingRepository struct {
+	*fakeRepository
+	added bool
+}
+
+func (r *lookupFailingRepository) Add(sc *schema.Schema) (schema.SchemaID, error) {
+	r.added = true
+	return r.fakeRepository.Add(sc)
+}
+
+func (r *lookupFailingRepository) Find(sc *schema.Schema) (*schema.Schema, error) {
+	if r.added {
+		return nil, errors.New("registry is unavailable")
+	}
+	return r.fakeRepository.Find(sc)
+}
+
+func TestSchemaService_Add_LookupFails(t *testing.T) {
+	store := audit.NewMemoryStore()
+	service, events := newTestService(&lookupFailingRepository{fakeRepository: newFakeRepository()}, services.WithAuditLog(store))
+
+	// the schema is registered, so it is returned, published and audited without its version
+	result, err := service.Add(ctx, mustSchema(t, "orders-value", `"string"`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustEqual(t, result.Status, services.AddStatusCreated)
+	mustEqual(t, result.Schema.ID(), schema.SchemaID(1))
+	mustEqual(t, len(*events), 1)
+
+	entries, err := service.AuditLog(ctx, audit.Query{Subject: "orders-value"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 entry, but got %d", len(entries))
+	}
+	mustEqual(t, entries[0].SchemaID, schema.SchemaID(1))
+}
+
+func TestSchemaService_Add_Incompatible(t *testing.T) {
+	repository := newFakeRepository()
+	service, events := newTestService(repository)
+
+	if _, err := service.Add(ctx, mustSchema(t, "orders-value", `"string"`)); err != nil {
+		t.Fatal(err)
+	}
+
+	repository.incompatible = []string{"reader type: INT not compatible with writer type: STRING"}
+	result, err := service.Add(ctx, mustSchema(t, "orders-value", `"int"`))
+	if !errors.Is(err, schema.ErrIncompatibleSchema) {
+		t.Fatalf("expected incompatible schema error, but got %v", err)
+	}
+	mustEqual(t, result.Status, services.AddStatusIncompatible)
+	mustEqual(t, len(*events), 1)
+}
+
+func TestSchemaService_Delete_PublishesEvents(t *testing.T) {
+	repository := newFakeRepository()
+	service, events := newTestService(repository)
+
+	for _, content := range []string{`"string"`, `"int"`} {
+		if _, err := service.Add(ctx, mustSchema(t, "orders-value", content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	*events = nil
+
+	deleted, err := service.DeleteVersion(ctx, "orders-value", schema.Latest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustEqual(t, deleted, schema.SchemaVersion(2))
+
+	versions, err := service.Delete(ctx, "orders-value")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustEqual(t, versions, []schema.SchemaVersion{1})
+
+	if _, err = service.Delete(ctx, "orders-value"); !errors.Is(err, schema.ErrSubjectNotFound) {
+		t.Fatalf("expected subject not found, but got %v", err)
+	}
+
+	if len(*events) != 2 {
+		t.Fatalf("expected 2 events, but got %d", len(*events))
+	}
+	mustEqual(t, (*events)[0].(schema.SchemaVersionDeleted).Version, schema.SchemaVersion(2))
+	mustEqual(t, (*events)[1].(schema.SubjectDeleted).Versions, []schema.SchemaVersion{1})
+}
+
+func TestSchemaService_SetCompatibilityLevel_PublishesCompatibilityChanged(t *testing.T) {
+	service, events := newTestService(newFakeRepository())
+
+	level, err := service.SetCompatibilityLevel(ctx, "orders-value", schema.CompatibilityFull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustEqual(t, level, schema.CompatibilityFull)
+
+	// setting the same level is not a change
+	if _, err = service.SetCompatibilityLevel(ctx, "orders-value", schema.CompatibilityFull); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(*events) != 1 {
+		t.Fatalf("expected 1 event, but got %d", len(*events))
+	}
+	changed := (*events)[0].(schema.CompatibilityChanged)
+	mustEqual(t, changed.Subject, schema.Subject("orders-value"))
+	mustEqual(t, changed.Previous, globalLevel)
+	mustEqual(t, changed.Level, schema.CompatibilityFull)
+}
+
+func TestSchemaService_StagesChangesInOutbox(t *testing.T) {
+	store, err := adapters.NewFileOutboxStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	box := outbox.NewOutbox(store, nil)
+	repository := newFakeRepository()
+	service := services.NewSchemaService(repository, services.WithEventPublisher(box))
+
+	// the change is staged before the registry is called
+	repository.beforeAdd = func() {
+		records, err := store.Pending(0, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		mustEqual(t, len(records), 1)
+		mustEqual(t, records[0].Staged, true)
+		mustEqual(t, records[0].Subject, schema.Subject("orders-value"))
+	}
+
+	if _, err = service.Add(ctx, mustSchema(t, "orders-value", `"string"`)); err != nil {
+		t.Fatal(err)
+	}
+	// the failed change is discarded
+	if _, err = service.Delete(ctx, "payments-value"); !errors.Is(err, schema.ErrSubjectNotFound) {
+		t.Fatalf("expected subject not found, but got %v", err)
+	}
+
+	records, err := store.Pending(0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("expected 1 record, but got %d", len(records))
+	}
+	mustEqual(t, records[0].Type, schema.EventSchemaRegistered)
+	mustEqual(t, records[0].Staged, false)
+}
+
+// failingAuditStore can not append entries
+type failingAuditStore struct {
+	*audit.MemoryStore
+}
+
+func (failingAuditStore) Append(entry audit.Entry) error {
+	return errors.New("disk is full")
+}
+
+func TestSchemaService_AuditNotRecorded(t *testing.T) {
+	service, events := newTestService(newFakeRepository(), services.WithAuditLog(failingAuditStore{audit.NewMemoryStore()}))
+
+	_, err := service.Add(ctx, mustSchema(t, "orders-value", `"string"`))
+	if !errors.Is(err, audit.ErrNotRecorded) {
+		t.Fatalf("expected %v, but got %v", audit.ErrNotRecorded, err)
+	}
+
+	// the change is made and its event is published anyway
+	versions, err := service.Versions(ctx, "orders-value")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustEqual(t, versions, []schema.SchemaVersion{1})
+	mustEqual(t, len(*events), 1)
+}
+
+func TestSchemaService_AuditLog(t *testing.T) {
+	store := audit.NewMemoryStore()
+	service := services.NewSchemaService(newFakeRepository(), services.WithAuditLog(store))
+
+	ctx := identity.NewContext(context.Background(), identity.Identity{Actor: "alice"})
+	ctx = audit.WithChange(ctx, audit.Change{Reason: "add customer", Ticket: "ORD-42"})
+
+	first := `{"type":"record","name":"Order","fields":[{"name":"id","type":"string"}]}`
+	second := `{"type":"record","name":"Order","fields":[{"name":"id","type":"string"},{"name":"customer","type":"string","default":""}]}`
+	for _, content := range []string{first, second} {
+		if _, err := service.Add(ctx, mustSchema(t, "orders-value", content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	bob := identity.NewContext(context.Background(), identity.Identity{Actor: "bob"})
+	if _, err := service.DeleteVersion(bob, "orders-value", 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.SetCompatibilityLevel(context.Background(), "orders-value", schema.CompatibilityFull); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := service.AuditLog(ctx, audit.Query{Subject: "orders-value"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 4 {
+		t.Fatalf("expected 4 entries, but got %d", len(entries))
+	}
+
+	registered := entries[1]
+	mustEqual(t, registered.Actor, "alice")
+	mustEqual(t, registered.Operation, audit.OperationRegister)
+	mustEqual(t, registered.Version, schema.SchemaVersion(2))
+	mustEqual(t, registered.SchemaID, schema.SchemaID(2))
+	mustEqual(t, registered.Reason, "add customer")
+	mustEqual(t, registered.Ticket, "ORD-42")
+	mustEqual(t, registered.Diff, `+    },
++    {
++      "name": "customer",
++      "type": "string",
++      "default": ""`)
+
+	deleted := entries[2]
+	mustEqual(t, deleted.Actor, "bob")
+	mustEqual(t, deleted.Operation, audit.OperationDeleteVersion)
+	mustEqual(t, deleted.SchemaID, schema.SchemaID(1))
+
+	mustEqual(t, entries[3].Actor, identity.Anonymous)
+	mustEqual(t, entries[3].Diff, "-BACKWARD\n+FULL")
+
+	byActor, err := service.AuditLog(ctx, audit.Query{Actor: "bob"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustEqual(t, len(byActor), 1)
+
+	latest, err := service.AuditLog(ctx, audit.Query{Limit: 1, To: entries[3].Time.Add(time.Second)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustEqual(t, latest, entries[3:])
+}
+
+type authorizerFunc func(ctx context.Context, subject schema.Subject) error
+
+func (f authorizerFunc) Authorize(ctx context.Context, subject schema.Subject) error {
+	return f(ctx, subject)
+}
+
+func TestSchemaService_WithAuthorizer(t *testing.T) {
+	errForbidden := errors.New("forbidden")
+	repository := newFakeRepository()
+	service := services.NewSchemaService(repository, services.WithAuthorizer(authorizerFunc(
+		func(ctx context.Context, subject schema.Subject) error {
+			if subject == "payments-value" {
+				return errForbidden
+			}
+			return nil
+		})))
+
+	if _, err := service.Add(ctx, mustSchema(t, "payments-value", `"string"`)); !errors.Is(err, errForbidden) {
+		t.Fatalf("expected forbidden, but got %v", err)
+	}
+	if _, err := service.SetCompatibilityLevel(ctx, "payments-value", schema.CompatibilityFull); !errors.Is(err, errForbidden) {
+		t.Fatalf("expected forbidden, but got %v", err)
+	}
+	if _, err := service.Add(ctx, mustSchema(t, "orders-value", `"string"`)); err != nil {
+		t.Fatal(err)
+	}
+	mustEqual(t, len(repository.subjects), 1)
+}
+
+func TestSchemaService_Diff(t *testing.T) {
+	repository := newFakeRepository()
+	service, _ := newTestService(repository)
+
+	for _, content := range []string{
+		`{"type":"record","name":"Order","fields":[{"name":"id","type":"string"}]}`,
+		`{"type":"record","name":"Order","fields":[{"name":"id","type":"string"},{"name":"total","type":"long"}]}`,
+	} {
+		if _, err := service.Add(ctx, mustSchema(t, "orders-value", content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	diff, err := service.Diff(ctx, "orders-value", 1, schema.Latest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustEqual(t, diff.Changes, []schemadiff.Change{{Kind: schemadiff.FieldAdded, Path: "$.total", After: "long"}})
+
+	if _, err = service.Diff(ctx, "orders-value", 1, 3); !errors.Is(err, schema.ErrVersionNotFound) {
+		t.Fatalf("expected version not found, but got %v", err)
+	}
+
+	events, err := schema.NewSchema("events-value", schema.SchemaTypeJSON, `{"type":"object"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = service.Add(ctx, events); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = service.Diff(ctx, "events-value", 1, 1); !errors.Is(err, schemadiff.ErrUnsupportedSchemaType) {
+		t.Fatalf("expected unsupported schema type, but got %v", err)
+	}
+}
+
+func TestSchemaService_Changelog(t *testing.T) {
+	store := audit.NewMemoryStore()
+	service := services.NewSchemaService(newFakeRepository(), services.WithAuditLog(store))
+
+	alice := identity.NewContext(context.Background(), identity.Identity{Actor: "alice"})
+	if _, err := service.Add(alice, mustSchema(t, "orders-value", `{"type":"record","name":"Order","fields":[{"name":"id","type":"string"}]}`)); err != nil {
+		t.Fatal(err)
+	}
+	bob := audit.WithChange(identity.NewContext(context.Background(), identity.Identity{Actor: "bob"}), audit.Change{Reason: "add total", Ticket: "ORD-7"})
+	if _, err := service.Add(bob, mustSchema(t, "orders-value", `{"type":"record","name":"Order","fields":[{"name":"id","type":"string"},{"name":"total","type":"long","default":0}]}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	changelog, err := service.Changelog(ctx, "orders-value")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustEqual(t, changelog.Subject, schema.Subject("orders-value"))
+	mustEqual(t, len(changelog.Entries), 2)
+
+	first, second := changelog.Entries[0], changelog.Entries[1]
+	mustEqual(t, first.Initial, true)
+	mustEqual(t, first.Author, "alice")
+	mustEqual(t, second.Version, schema.SchemaVersion(2))
+	mustEqual(t, second.Author, "bob")
+	mustEqual(t, second.Reason, "add total")
+	mustEqual(t, second.Ticket, "ORD-7")
+	mustEqual(t, second.Date != nil, true)
+	mustEqual(t, second.Changes, []schemadiff.Change{{Kind: schemadiff.FieldAdded, Path: "$.total", After: "long"}})
+
+	if _, err = service.Changelog(ctx, "payments-value"); !errors.Is(err, schema.ErrSubjectNotFound) {
+		t.Fatalf("expected subject not found, but got %v", err)
+	}
+}
@@ -0,0 +1,448 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ybalcin/event-schema-manager/internal/core/domain/schema"
+)
+
+type (
+	// Payload is the body posted to subscribers
+	Payload struct {
+		ID         string           `json:"id"`
+		Type       schema.EventType `json:"type"`
+		Subject    schema.Subject   `json:"subject"`
+		OccurredAt time.Time        `json:"occurredAt"`
+		Data       schema.Event     `json:"data"`
+	}
+
+	// Dispatcher manages subscriptions and delivers events to them, failed deliveries are retried
+	// with exponential backoff and moved to the dead letters after the last attempt
+	Dispatcher struct {
+		store       Store
+		httpClient  *http.Client
+		maxAttempts int
+		minBackoff  time.Duration
+		maxBackoff  time.Duration
+		workers     int
+		queue       chan delivery
+		now         func() time.Time
+		// allowedHosts may resolve to internal addresses
+		allowedHosts map[string]bool
+
+		// mu guards queueing against stopped, deliveries queued after Run is stopped would never be delivered
+		mu      sync.Mutex
+		stopped bool
+	}
+
+	Option func(*Dispatcher)
+
+	delivery struct {
+		subscription Subscription
+		eventID      string
+		eventType    schema.EventType
+		payload      []byte
+	}
+
+	// permanentError is a failure retrying does not fix, e.g. a 4xx response
+	permanentError struct {
+		err error
+	}
+)
+
+const (
+	defaultMaxAttempts = 5
+	defaultMinBackoff  = 500 * time.Millisecond
+	defaultMaxBackoff  = time.Minute
+	defaultWorkers     = 4
+	defaultQueueSize   = 1000
+	deliveryTimeout    = 10 * time.Second
+
+	// maxErrorBodySize is the size of the response body kept in the error of a failed delivery
+	maxErrorBodySize = 256
+)
+
+var (
+	errQueueFull = errors.New("webhook delivery queue is full")
+	errShutdown  = errors.New("webhook delivery is cancelled by shutdown")
+)
+
+// WithHTTPClient sets the client deliveries are posted with
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(d *Dispatcher) {
+		d.httpClient = httpClient
+	}
+}
+
+// WithAllowedHosts allows webhooks to hosts resolving to internal addresses, which are refused otherwise.
+// It applies to the default http client only, a client set by WithHTTPClient must guard its connections itself
+func WithAllowedHosts(hosts ...string) Option {
+	return func(d *Dispatcher) {
+		for _, host := range hosts {
+			d.allowedHosts[strings.ToLower(host)] = true
+		}
+	}
+}
+
+// WithRetry sets the number of attempts of a delivery and the backoff between them, the backoff doubles
+// after every attempt from min up to max
+func WithRetry(maxAttempts int, minBackoff, maxBackoff time.Duration) Option {
+	return func(d *Dispatcher) {
+		d.maxAttempts = maxAttempts
+		d.minBackoff = minBackoff
+		d.maxBackoff = maxBackoff
+	}
+}
+
+// WithWorkers sets the number of concurrent deliveries
+func WithWorkers(workers int) Option {
+	return func(d *Dispatcher) {
+		d.workers = workers
+	}
+}
+
+func NewDispatcher(store Store, opts ...Option) *Dispatcher {
+	d := &Dispatcher{
+		store:        store,
+		allowedHosts: make(map[string]bool),
+		maxAttempts:  defaultMaxAttempts,
+		minBackoff:   defaultMinBackoff,
+		maxBackoff:   defaultMaxBackoff,
+		workers:      defaultWorkers,
+		queue:        make(chan delivery, defaultQueueSize),
+		now:          time.Now,
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+
+	if d.httpClient == nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.DialContext = d.dialContext(&net.Dialer{Timeout: deliveryTimeout, KeepAlive: 30 * time.Second})
+		d.httpClient = &http.Client{Timeout: deliveryTimeout, Transport: transport}
+	}
+
+	return d
+}
+
+// Subscribe creates a subscription, the returned subscription holds its secret.
+// Urls on internal addresses are refused unless their host is allowed by WithAllowedHosts.
+func (d *Dispatcher) Subscribe(url, subjectGlob string, eventTypes []schema.EventType, secret string) (Subscription, error) {
+	subscription, err := NewSubscription(url, subjectGlob, eventTypes, secret)
+	if err != nil {
+		return Subscription{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), deliveryTimeout)
+	defer cancel()
+	if err = d.checkTarget(ctx, subscription.URL); err != nil {
+		return Subscription{}, errInvalidSubscription("url %q is not allowed, trace: %v", url, err)
+	}
+
+	if err = d.store.SaveSubscription(subscription); err != nil {
+		return Subscription{}, err
+	}
+
+	return subscription, nil
+}
+
+// Subscriptions returns the subscriptions without their secrets
+func (d *Dispatcher) Subscriptions() ([]Subscription, error) {
+	subscriptions, err := d.store.Subscriptions()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range subscriptions {
+		subscriptions[i] = subscriptions[i].WithoutSecret()
+	}
+
+	return subscriptions, nil
+}
+
+// Subscription returns the subscription with id without its secret
+func (d *Dispatcher) Subscription(id string) (Subscription, error) {
+	subscriptions, err := d.store.Subscriptions()
+	if err != nil {
+		return Subscription{}, err
+	}
+
+	for _, s := range subscriptions {
+		if s.ID == id {
+			return s.WithoutSecret(), nil
+		}
+	}
+
+	return Subscription{}, fmt.Errorf("%w: %s", ErrSubscriptionNotFound, id)
+}
+
+func (d *Dispatcher) Unsubscribe(id string) error {
+	return d.store.DeleteSubscription(id)
+}
+
+func (d *Dispatcher) DeadLetters() ([]DeadLetter, error) {
+	return d.store.DeadLetters()
+}
+
+// Redeliver queues a dead letter again, it becomes a dead letter again if it fails
+func (d *Dispatcher) Redeliver(id string) error {
+	deadLetters, err := d.store.DeadLetters()
+	if err != nil {
+		return err
+	}
+
+	for _, dl := range deadLetters {
+		if dl.ID != id {
+			continue
+		}
+
+		subscription, err := d.subscriptionWithSecret(dl.SubscriptionID)
+		if err != nil {
+			return err
+		}
+		// the dead letter is kept if it can not be queued
+		if err = d.enqueue(delivery{subscription: subscription, eventID: dl.EventID, eventType: dl.EventType, payload: dl.Payload}); err != nil {
+			return err
+		}
+
+		return d.store.DeleteDeadLetter(id)
+	}
+
+	return fmt.Errorf("%w: %s", ErrDeadLetterNotFound, id)
+}
+
+func (d *Dispatcher) subscriptionWithSecret(id string) (Subscription, error) {
+	subscriptions, err := d.store.Subscriptions()
+	if err != nil {
+		return Subscription{}, err
+	}
+
+	for _, s := range subscriptions {
+		if s.ID == id {
+			return s, nil
+		}
+	}
+
+	return Subscription{}, fmt.Errorf("%w: %s", ErrSubscriptionNotFound, id)
+}
+
+// Handle queues event for the matching subscriptions, it is subscribed to the event bus.
+// It is all or nothing: an error is returned before any delivery is queued, and a delivery which can not be
+// queued becomes a dead letter, so a redelivered event is not posted twice to some subscriptions.
+func (d *Dispatcher) Handle(event schema.Event) error {
+	subscriptions, err := d.store.Subscriptions()
+	if err != nil {
+		return err
+	}
+
+	var deliveries []delivery
+	var payload []byte
+	for _, s := range subscriptions {
+		if !s.Matches(event) {
+			continue
+		}
+
+		if payload == nil {
+			if payload, err = json.Marshal(Payload{
+				ID:         event.EventID(),
+				Type:       event.EventType(),
+				Subject:    event.EventSubject(),
+				OccurredAt: event.OccurredAt(),
+				Data:       event,
+			}); err != nil {
+				return err
+			}
+		}
+
+		deliveries = append(deliveries, delivery{subscription: s, eventID: event.EventID(), eventType: event.EventType(), payload: payload})
+	}
+
+	for _, dl := range deliveries {
+		if err = d.enqueue(dl); err != nil {
+			d.deadLetter(dl, 0, err)
+		}
+	}
+
+	return nil
+}
+
+func (d *Dispatcher) enqueue(dl delivery) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.stopped {
+		return errShutdown
+	}
+
+	select {
+	case d.queue <- dl:
+		return nil
+	default:
+		return errQueueFull
+	}
+}
+
+// stop keeps the deliveries still queued as dead letters, so they can be redelivered after a restart,
+// and makes the later deliveries dead letters too
+func (d *Dispatcher) stop() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.stopped = true
+	for {
+		select {
+		case dl := <-d.queue:
+			d.deadLetter(dl, 0, errShutdown)
+		default:
+			return
+		}
+	}
+}
+
+// Run delivers queued events until ctx is done, deliveries queued or waiting for a retry when ctx is done
+// become dead letters
+func (d *Dispatcher) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < d.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case dl := <-d.queue:
+					d.deliverWithRetry(ctx, dl)
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+	d.stop()
+}
+
+func (d *Dispatcher) deliverWithRetry(ctx context.Context, dl delivery) {
+	var err error
+	attempt := 0
+	for attempt < d.maxAttempts {
+		attempt++
+
+		if err = d.deliver(ctx, dl); err == nil {
+			return
+		}
+
+		var permanent *permanentError
+		if errors.As(err, &permanent) || attempt == d.maxAttempts {
+			break
+		}
+
+		timer := time.NewTimer(d.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			err = fmt.Errorf("%v, retry is cancelled by shutdown", err)
+			d.deadLetter(dl, attempt, err)
+			return
+		case <-timer.C:
+		}
+	}
+
+	d.deadLetter(dl, attempt, err)
+}
+
+// backoff returns the delay after attempt, it doubles every attempt from minBackoff up to maxBackoff
+func (d *Dispatcher) backoff(attempt int) time.Duration {
+	delay := d.minBackoff
+	for i := 1; i < attempt && delay < d.maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > d.maxBackoff {
+		delay = d.maxBackoff
+	}
+
+	return delay
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, dl delivery) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, dl.subscription.URL, bytes.NewReader(dl.payload))
+	if err != nil {
+		return &permanentError{err: err}
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventIDHeader, dl.eventID)
+	req.Header.Set(EventTypeHeader, string(dl.eventType))
+
+	now := d.now()
+	req.Header.Set(TimestampHeader, fmt.Sprint(now.Unix()))
+	req.Header.Set(SignatureHeader, Sign(dl.subscription.Secret, now, dl.payload))
+
+	resp, err := d.httpClient.Do(req)
+	if errors.Is(err, errInternalTarget) {
+		return &permanentError{err: err}
+	}
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		_, _ = io.Copy(ioutil.Discard, resp.Body)
+		return nil
+	}
+
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+	err = fmt.Errorf("%s responded %d %s", dl.subscription.URL, resp.StatusCode, bytes.TrimSpace(body))
+
+	// client errors are not retried but timeouts and rate limits are
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 &&
+		resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests {
+		return &permanentError{err: err}
+	}
+
+	return err
+}
+
+func (d *Dispatcher) deadLetter(dl delivery, attempts int, cause error) {
+	deadLetter := DeadLetter{
+		ID:             randomHex(16),
+		SubscriptionID: dl.subscription.ID,
+		URL:            dl.subscription.URL,
+		EventID:        dl.eventID,
+		EventType:      dl.eventType,
+		Payload:        dl.payload,
+		Attempts:       attempts,
+		LastError:      cause.Error(),
+		FailedAt:       d.now().UTC(),
+	}
+
+	if err := d.store.SaveDeadLetter(deadLetter); err != nil {
+		log.Printf("webhooks: dead letter of event %s could not be saved, trace: %v", dl.eventID, err)
+		return
+	}
+
+	log.Printf("webhooks: event %s could not be delivered to %s after %d attempts, trace: %v",
+		dl.eventID, dl.subscription.URL, attempts, cause)
+}
+
+func (err *permanentError) Error() string {
+	return err.err.Error()
+}
+
+func (err *permanentError) Unwrap() error {
+	return err.err
+}
@@ -0,0 +1,353 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ybalcin/event-schema-manager/internal/core/domain/schema"
+)
+
+type (
+	// receiver is a webhook endpoint answering with the queued statuses, then 200
+	receiver struct {
+		mu       sync.Mutex
+		statuses []int
+		requests []*http.Request
+		bodies   [][]byte
+		received chan struct{}
+	}
+)
+
+func newReceiver(statuses ...int) *receiver {
+	return &receiver{statuses: statuses, received: make(chan struct{}, 100)}
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+
+	rc.mu.Lock()
+	rc.requests = append(rc.requests, r)
+	rc.bodies = append(rc.bodies, body)
+	status := http.StatusOK
+	if len(rc.statuses) > 0 {
+		status, rc.statuses = rc.statuses[0], rc.statuses[1:]
+	}
+	rc.mu.Unlock()
+
+	w.WriteHeader(status)
+	rc.received <- struct{}{}
+}
+
+func (rc *receiver) wait(t *testing.T, n int) {
+	t.Helper()
+
+	for i := 0; i < n; i++ {
+		select {
+		case <-rc.received:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("expected %d requests, but got %d", n, i)
+		}
+	}
+}
+
+func mustEqual(t *testing.T, actual, expected interface{}) {
+	t.Helper()
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %#v, but got %#v", expected, actual)
+	}
+}
+
+func startDispatcher(t *testing.T, opts ...Option) *Dispatcher {
+	t.Helper()
+
+	// the receivers of the tests listen on the loopback
+	opts = append([]Option{WithRetry(3, time.Millisecond, 4*time.Millisecond), WithWorkers(1), WithAllowedHosts("127.0.0.1")}, opts...)
+	d := NewDispatcher(NewMemoryStore(), opts...)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		d.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	return d
+}
+
+func registeredEvent(subject schema.Subject) schema.SchemaRegistered {
+	return schema.SchemaRegistered{
+		EventMetadata: schema.NewEventMetadata(),
+		Subject:       subject,
+		Version:       1,
+		SchemaID:      1,
+		SchemaType:    schema.SchemaTypeAvro,
+	}
+}
+
+// waitDeadLetters polls the dead letters since they are saved after the last response
+func waitDeadLetters(t *testing.T, d *Dispatcher, n int) []DeadLetter {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		deadLetters, err := d.DeadLetters()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(deadLetters) == n || time.Now().After(deadline) {
+			mustEqual(t, len(deadLetters), n)
+			return deadLetters
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestDispatcher_DeliversSignedPayload(t *testing.T) {
+	rc := newReceiver()
+	server := httptest.NewServer(rc)
+	defer server.Close()
+
+	d := startDispatcher(t)
+	subscription, err := d.Subscribe(server.URL, "orders-*", []schema.EventType{schema.EventSchemaRegistered}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	event := registeredEvent("orders-value")
+	for _, e := range []schema.Event{
+		event,
+		registeredEvent("payments-value"),
+		schema.SubjectDeleted{EventMetadata: schema.NewEventMetadata(), Subject: "orders-value"},
+	} {
+		if err = d.Handle(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rc.wait(t, 1)
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	mustEqual(t, len(rc.requests), 1)
+	req, body := rc.requests[0], rc.bodies[0]
+	mustEqual(t, req.Header.Get(EventIDHeader), event.ID)
+	mustEqual(t, req.Header.Get(EventTypeHeader), string(schema.EventSchemaRegistered))
+	if !Verify(subscription.Secret, req.Header.Get(TimestampHeader), req.Header.Get(SignatureHeader), body) {
+		t.Error("signature must be valid")
+	}
+	if Verify("another secret", req.Header.Get(TimestampHeader), req.Header.Get(SignatureHeader), body) {
+		t.Error("signature must not be valid with another secret")
+	}
+
+	var payload map[string]interface{}
+	if err = json.Unmarshal(body, &payload); err != nil {
+		t.Fatal(err)
+	}
+	mustEqual(t, payload["id"], event.ID)
+	mustEqual(t, payload["subject"], "orders-value")
+	mustEqual(t, payload["data"].(map[string]interface{})["version"], float64(1))
+}
+
+func TestDispatcher_RetriesWithBackoff(t *testing.T) {
+	rc := newReceiver(http.StatusInternalServerError, http.StatusTooManyRequests)
+	server := httptest.NewServer(rc)
+	defer server.Close()
+
+	d := startDispatcher(t)
+	if _, err := d.Subscribe(server.URL, "", nil, "secret"); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Handle(registeredEvent("orders-value")); err != nil {
+		t.Fatal(err)
+	}
+
+	rc.wait(t, 3)
+	waitDeadLetters(t, d, 0)
+}
+
+func TestDispatcher_DeadLetters(t *testing.T) {
+	rc := newReceiver(http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway, http.StatusBadRequest)
+	server := httptest.NewServer(rc)
+	defer server.Close()
+
+	d := startDispatcher(t)
+	subscription, err := d.Subscribe(server.URL, "", nil, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// retries are exhausted
+	event := registeredEvent("orders-value")
+	if err = d.Handle(event); err != nil {
+		t.Fatal(err)
+	}
+	rc.wait(t, 3)
+	deadLetters := waitDeadLetters(t, d, 1)
+	mustEqual(t, deadLetters[0].SubscriptionID, subscription.ID)
+	mustEqual(t, deadLetters[0].EventID, event.ID)
+	mustEqual(t, deadLetters[0].Attempts, 3)
+
+	// client errors are not retried
+	if err = d.Redeliver(deadLetters[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	rc.wait(t, 1)
+	deadLetters = waitDeadLetters(t, d, 1)
+	mustEqual(t, deadLetters[0].Attempts, 1)
+
+	// the receiver accepts it now
+	if err = d.Redeliver(deadLetters[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	rc.wait(t, 1)
+	waitDeadLetters(t, d, 0)
+}
+
+func TestDispatcher_Backoff(t *testing.T) {
+	d := NewDispatcher(NewMemoryStore(), WithRetry(10, time.Second, 10*time.Second))
+
+	var delays []time.Duration
+	for attempt := 1; attempt <= 6; attempt++ {
+		delays = append(delays, d.backoff(attempt))
+	}
+
+	mustEqual(t, delays, []time.Duration{
+		time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second,
+	})
+}
+
+func TestNewSubscription_Invalid(t *testing.T) {
+	invalid := []struct {
+		url        string
+		glob       string
+		eventTypes []schema.EventType
+	}{
+		{url: "ftp://example.com"},
+		{url: "/hooks"},
+		{url: "https://example.com", glob: "orders-["},
+		{url: "https://example.com", eventTypes: []schema.EventType{"SchemaUpdated"}},
+	}
+
+	for _, s := range invalid {
+		if _, err := NewSubscription(s.url, s.glob, s.eventTypes, ""); err == nil {
+			t.Errorf("%+v must be invalid", s)
+		}
+	}
+}
+
+func TestDispatcher_QueueFull(t *testing.T) {
+	// the dispatcher is not running so nothing is taken from the queue
+	d := NewDispatcher(NewMemoryStore(), WithAllowedHosts("receiver.example.com"))
+	subscription, err := d.Subscribe("http://receiver.example.com/events", "", nil, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i <= defaultQueueSize; i++ {
+		if err = d.Handle(registeredEvent("orders-value")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	deadLetters := waitDeadLetters(t, d, 1)
+	mustEqual(t, deadLetters[0].SubscriptionID, subscription.ID)
+	mustEqual(t, deadLetters[0].Attempts, 0)
+	mustEqual(t, deadLetters[0].LastError, errQueueFull.Error())
+
+	// the dead letter is kept while it can not be queued
+	if err = d.Redeliver(deadLetters[0].ID); !errors.Is(err, errQueueFull) {
+		t.Fatalf("expected %v, but got %v", errQueueFull, err)
+	}
+	waitDeadLetters(t, d, 1)
+}
+
+func TestDispatcher_Shutdown(t *testing.T) {
+	d := NewDispatcher(NewMemoryStore(), WithWorkers(1), WithAllowedHosts("receiver.example.com"))
+	if _, err := d.Subscribe("http://receiver.example.com/events", "", nil, "secret"); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := d.Handle(registeredEvent("orders-value")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the queued deliveries are kept as dead letters instead of being dropped
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	d.Run(ctx)
+	waitDeadLetters(t, d, 2)
+
+	// and so are the deliveries of the events handled during the shutdown
+	if err := d.Handle(registeredEvent("orders-value")); err != nil {
+		t.Fatal(err)
+	}
+	deadLetters := waitDeadLetters(t, d, 3)
+	mustEqual(t, deadLetters[2].LastError, errShutdown.Error())
+}
+
+func TestDispatcher_RefusesInternalTargets(t *testing.T) {
+	d := NewDispatcher(NewMemoryStore(), WithRetry(3, time.Millisecond, time.Millisecond))
+
+	for _, url := range []string{
+		"http://127.0.0.1:8081/subjects",
+		"http://localhost/hooks",
+		"http://10.0.0.7/hooks",
+		"http://192.168.1.1/hooks",
+		"http://169.254.169.254/latest/meta-data",
+		"http://[::1]/hooks",
+		"http://0.0.0.0/hooks",
+	} {
+		if _, err := d.Subscribe(url, "", nil, ""); !errors.Is(err, ErrInvalidSubscription) {
+			t.Errorf("%s: expected %v, but got %v", url, ErrInvalidSubscription, err)
+		}
+	}
+
+	// a subscription which became internal is refused when it is delivered, without retries
+	rc := newReceiver()
+	server := httptest.NewServer(rc)
+	defer server.Close()
+
+	subscription, err := NewSubscription(server.URL, "", nil, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = d.store.SaveSubscription(subscription); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		d.Run(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	if err = d.Handle(registeredEvent("orders-value")); err != nil {
+		t.Fatal(err)
+	}
+	deadLetters := waitDeadLetters(t, d, 1)
+	mustEqual(t, deadLetters[0].Attempts, 1)
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	mustEqual(t, len(rc.requests), 0)
+}
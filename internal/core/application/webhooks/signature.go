@@ -0,0 +1,49 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// SignatureHeader holds "sha256=" and the hex HMAC-SHA256 of the timestamp, a dot and the body, keyed by the secret
+	SignatureHeader = "X-Schema-Signature"
+	// TimestampHeader holds the unix time the payload was signed at, receivers should reject old timestamps
+	TimestampHeader = "X-Schema-Timestamp"
+	EventIDHeader   = "X-Schema-Event-Id"
+	EventTypeHeader = "X-Schema-Event-Type"
+
+	signaturePrefix = "sha256="
+)
+
+// Sign returns the signature header value of body signed at timestamp
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	return signaturePrefix + hex.EncodeToString(mac(secret, strconv.FormatInt(timestamp.Unix(), 10), body))
+}
+
+// Verify returns true if signature is the signature of body sent with the timestamp header value
+func Verify(secret, timestamp, signature string, body []byte) bool {
+	if !strings.HasPrefix(signature, signaturePrefix) {
+		return false
+	}
+
+	expected, err := hex.DecodeString(strings.TrimPrefix(signature, signaturePrefix))
+	if err != nil {
+		return false
+	}
+
+	return hmac.Equal(expected, mac(secret, timestamp, body))
+}
+
+func mac(secret, timestamp string, body []byte) []byte {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(timestamp))
+	h.Write([]byte("."))
+	h.Write(body)
+
+	return h.Sum(nil)
+}
@@ -0,0 +1,215 @@
+package webhooks
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ybalcin/event-schema-manager/internal/core/domain/schema"
+)
+
+type (
+	// Subscription delivers the events of subjects matching SubjectGlob to URL, every event type if EventTypes is empty
+	Subscription struct {
+		ID          string             `json:"id"`
+		URL         string             `json:"url"`
+		SubjectGlob string             `json:"subjects"`
+		EventTypes  []schema.EventType `json:"eventTypes"`
+		Secret      string             `json:"secret,omitempty"`
+		CreatedAt   time.Time          `json:"createdAt"`
+	}
+
+	// DeadLetter is a delivery that failed after all attempts, it can be redelivered by its id
+	DeadLetter struct {
+		ID             string           `json:"id"`
+		SubscriptionID string           `json:"subscriptionId"`
+		URL            string           `json:"url"`
+		EventID        string           `json:"eventId"`
+		EventType      schema.EventType `json:"eventType"`
+		Payload        json.RawMessage  `json:"payload"`
+		Attempts       int              `json:"attempts"`
+		LastError      string           `json:"lastError"`
+		FailedAt       time.Time        `json:"failedAt"`
+	}
+
+	// Store persists subscriptions and dead letters
+	Store interface {
+		SaveSubscription(subscription Subscription) error
+		Subscriptions() ([]Subscription, error)
+		DeleteSubscription(id string) error
+		SaveDeadLetter(deadLetter DeadLetter) error
+		DeadLetters() ([]DeadLetter, error)
+		DeleteDeadLetter(id string) error
+	}
+
+	// MemoryStore keeps subscriptions and dead letters in memory
+	MemoryStore struct {
+		mu            sync.RWMutex
+		subscriptions map[string]Subscription
+		deadLetters   map[string]DeadLetter
+	}
+)
+
+var (
+	ErrInvalidSubscription  = errors.New("invalid webhook subscription")
+	ErrSubscriptionNotFound = errors.New("webhook subscription not found")
+	ErrDeadLetterNotFound   = errors.New("dead letter not found")
+	errInvalidSubscription  = func(format string, args ...interface{}) error {
+		return fmt.Errorf("%w: "+format, append([]interface{}{ErrInvalidSubscription}, args...)...)
+	}
+)
+
+// NewSubscription validates a subscription and gives it an id, a secret is generated if it is empty
+func NewSubscription(rawURL, subjectGlob string, eventTypes []schema.EventType, secret string) (Subscription, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return Subscription{}, errInvalidSubscription("url %q must be an absolute http or https url", rawURL)
+	}
+
+	if subjectGlob == "" {
+		subjectGlob = "*"
+	}
+	if _, err = path.Match(subjectGlob, ""); err != nil {
+		return Subscription{}, errInvalidSubscription("subject glob %q is not valid", subjectGlob)
+	}
+
+	for _, t := range eventTypes {
+		switch t {
+		case schema.EventSchemaRegistered, schema.EventSchemaVersionDeleted, schema.EventSubjectDeleted, schema.EventCompatibilityChanged:
+		default:
+			return Subscription{}, errInvalidSubscription("unknown event type %s", t)
+		}
+	}
+
+	if secret == "" {
+		secret = randomHex(32)
+	}
+
+	return Subscription{
+		ID:          randomHex(16),
+		URL:         rawURL,
+		SubjectGlob: subjectGlob,
+		EventTypes:  eventTypes,
+		Secret:      secret,
+		CreatedAt:   time.Now().UTC(),
+	}, nil
+}
+
+// Matches returns true if event should be delivered to the subscription
+func (s Subscription) Matches(event schema.Event) bool {
+	if ok, _ := path.Match(s.SubjectGlob, event.EventSubject().String()); !ok {
+		return false
+	}
+
+	if len(s.EventTypes) == 0 {
+		return true
+	}
+	for _, t := range s.EventTypes {
+		if t == event.EventType() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// WithoutSecret returns the subscription to be listed, the secret is shown only when it is created
+func (s Subscription) WithoutSecret() Subscription {
+	s.Secret = ""
+	return s
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+
+	return hex.EncodeToString(b)
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		subscriptions: make(map[string]Subscription),
+		deadLetters:   make(map[string]DeadLetter),
+	}
+}
+
+func (s *MemoryStore) SaveSubscription(subscription Subscription) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.subscriptions[subscription.ID] = subscription
+	return nil
+}
+
+// Subscriptions returns the subscriptions in creation order
+func (s *MemoryStore) Subscriptions() ([]Subscription, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	subscriptions := make([]Subscription, 0, len(s.subscriptions))
+	for _, sub := range s.subscriptions {
+		subscriptions = append(subscriptions, sub)
+	}
+	sort.Slice(subscriptions, func(i, j int) bool {
+		return subscriptions[i].CreatedAt.Before(subscriptions[j].CreatedAt)
+	})
+
+	return subscriptions, nil
+}
+
+func (s *MemoryStore) DeleteSubscription(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.subscriptions[id]; !ok {
+		return fmt.Errorf("%w: %s", ErrSubscriptionNotFound, id)
+	}
+	delete(s.subscriptions, id)
+
+	return nil
+}
+
+func (s *MemoryStore) SaveDeadLetter(deadLetter DeadLetter) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.deadLetters[deadLetter.ID] = deadLetter
+	return nil
+}
+
+// DeadLetters returns the dead letters in the order they failed
+func (s *MemoryStore) DeadLetters() ([]DeadLetter, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	deadLetters := make([]DeadLetter, 0, len(s.deadLetters))
+	for _, dl := range s.deadLetters {
+		deadLetters = append(deadLetters, dl)
+	}
+	sort.Slice(deadLetters, func(i, j int) bool {
+		return deadLetters[i].FailedAt.Before(deadLetters[j].FailedAt)
+	})
+
+	return deadLetters, nil
+}
+
+func (s *MemoryStore) DeleteDeadLetter(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.deadLetters[id]; !ok {
+		return fmt.Errorf("%w: %s", ErrDeadLetterNotFound, id)
+	}
+	delete(s.deadLetters, id)
+
+	return nil
+}
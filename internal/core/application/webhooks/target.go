@@ -0,0 +1,85 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// errInternalTarget is returned for webhook urls on internal addresses, e.g. the loopback, private networks or
+// the cloud metadata service, so subscribers can not make the manager call its neighbours
+var errInternalTarget = errors.New("webhook target is an internal address")
+
+// isInternal returns true if ip is not reachable from the internet
+func isInternal(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast()
+}
+
+// isAllowedHost returns true if host may be an internal address
+func (d *Dispatcher) isAllowedHost(host string) bool {
+	return d.allowedHosts[strings.ToLower(host)]
+}
+
+// resolve returns the addresses of host, every one of them must be public unless host is allowed
+func (d *Dispatcher) resolve(ctx context.Context, host string) ([]net.IP, error) {
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	ips := make([]net.IP, len(addrs))
+	for i, addr := range addrs {
+		if isInternal(addr.IP) {
+			return nil, fmt.Errorf("%w: %s resolves to %s", errInternalTarget, host, addr.IP)
+		}
+		ips[i] = addr.IP
+	}
+
+	return ips, nil
+}
+
+// checkTarget refuses rawURL if its host resolves to an internal address and it is not allowed
+func (d *Dispatcher) checkTarget(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	if d.isAllowedHost(u.Hostname()) {
+		return nil
+	}
+
+	_, err = d.resolve(ctx, u.Hostname())
+	return err
+}
+
+// dialContext dials the checked addresses of the host of address, so a host resolving to an internal address
+// after it is subscribed or a redirect to an internal address is refused too
+func (d *Dispatcher) dialContext(dialer *net.Dialer) func(ctx context.Context, network, address string) (net.Conn, error) {
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+		if d.isAllowedHost(host) {
+			return dialer.DialContext(ctx, network, address)
+		}
+
+		ips, err := d.resolve(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, ip := range ips {
+			var conn net.Conn
+			if conn, err = dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port)); err == nil {
+				return conn, nil
+			}
+		}
+
+		return nil, err
+	}
+}
@@ -0,0 +1,57 @@
+package schema
+
+import (
+	"fmt"
+	"strings"
+)
+
+type (
+	// CompatibilityLevel is the compatibility rule new versions of a subject are checked with
+	CompatibilityLevel string
+
+	// Compatibility is the result of checking a schema against a version of its subject
+	Compatibility struct {
+		IsCompatible bool
+		Reasons      []string
+	}
+
+	// IncompatibleError is returned when a schema is rejected because it is not compatible with its subject
+	IncompatibleError struct {
+		Subject Subject
+		Reasons []string
+	}
+)
+
+const (
+	CompatibilityNone               CompatibilityLevel = "NONE"
+	CompatibilityBackward           CompatibilityLevel = "BACKWARD"
+	CompatibilityBackwardTransitive CompatibilityLevel = "BACKWARD_TRANSITIVE"
+	CompatibilityForward            CompatibilityLevel = "FORWARD"
+	CompatibilityForwardTransitive  CompatibilityLevel = "FORWARD_TRANSITIVE"
+	CompatibilityFull               CompatibilityLevel = "FULL"
+	CompatibilityFullTransitive     CompatibilityLevel = "FULL_TRANSITIVE"
+)
+
+// ParseCompatibilityLevel parses a compatibility level, case insensitive
+func ParseCompatibilityLevel(level string) (CompatibilityLevel, error) {
+	switch l := CompatibilityLevel(strings.ToUpper(level)); l {
+	case CompatibilityNone, CompatibilityBackward, CompatibilityBackwardTransitive, CompatibilityForward,
+		CompatibilityForwardTransitive, CompatibilityFull, CompatibilityFullTransitive:
+		return l, nil
+	}
+
+	return "", fmt.Errorf("%w: %s", ErrInvalidCompatibilityLevel, level)
+}
+
+func (err *IncompatibleError) Error() string {
+	if len(err.Reasons) == 0 {
+		return fmt.Sprintf("%v: %s", ErrIncompatibleSchema, err.Subject)
+	}
+
+	return fmt.Sprintf("%v: %s, %s", ErrIncompatibleSchema, err.Subject, strings.Join(err.Reasons, "; "))
+}
+
+// Is makes errors.Is(err, ErrIncompatibleSchema) true
+func (err *IncompatibleError) Is(target error) bool {
+	return target == ErrIncompatibleSchema
+}
@@ -0,0 +1,16 @@
+package schema
+
+import "errors"
+
+var (
+	ErrInvalidSubject            = errors.New("invalid subject")
+	ErrInvalidSchemaID           = errors.New("invalid schema id")
+	ErrSubjectNotFound           = errors.New("subject not found")
+	ErrVersionNotFound           = errors.New("version not found")
+	ErrSchemaNotFound            = errors.New("schema not found")
+	ErrInvalidSchema             = errors.New("invalid schema")
+	ErrInvalidVersion            = errors.New("invalid version")
+	ErrIncompatibleSchema        = errors.New("incompatible schema")
+	ErrInvalidCompatibilityLevel = errors.New("invalid compatibility level")
+	ErrRegistryUnavailable       = errors.New("schema registry unavailable")
+)
@@ -0,0 +1,163 @@
+package schema
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+type (
+	EventType string
+
+	// Event is a change of schemas, ID is unique per event so consumers can deduplicate
+	Event interface {
+		EventID() string
+		EventType() EventType
+		EventSubject() Subject
+		OccurredAt() time.Time
+	}
+
+	// EventPublisher publishes domain events to interested parties
+	EventPublisher interface {
+		Publish(events ...Event) error
+	}
+
+	// EventHandler handles a published event
+	EventHandler func(event Event) error
+
+	// EventMetadata is embedded by events
+	EventMetadata struct {
+		ID   string    `json:"id"`
+		Time time.Time `json:"occurredAt"`
+	}
+
+	SchemaRegistered struct {
+		EventMetadata
+		Subject    Subject       `json:"subject"`
+		Version    SchemaVersion `json:"version"`
+		SchemaID   SchemaID      `json:"schemaId"`
+		SchemaType SchemaType    `json:"schemaType"`
+	}
+
+	SchemaVersionDeleted struct {
+		EventMetadata
+		Subject Subject       `json:"subject"`
+		Version SchemaVersion `json:"version"`
+	}
+
+	SubjectDeleted struct {
+		EventMetadata
+		Subject  Subject         `json:"subject"`
+		Versions []SchemaVersion `json:"versions"`
+	}
+
+	CompatibilityChanged struct {
+		EventMetadata
+		Subject  Subject            `json:"subject"`
+		Previous CompatibilityLevel `json:"previous,omitempty"`
+		Level    CompatibilityLevel `json:"level"`
+	}
+)
+
+const (
+	EventSchemaRegistered     EventType = "SchemaRegistered"
+	EventSchemaVersionDeleted EventType = "SchemaVersionDeleted"
+	EventSubjectDeleted       EventType = "SubjectDeleted"
+	EventCompatibilityChanged EventType = "CompatibilityChanged"
+)
+
+// NewEventMetadata returns metadata with a random id and the current time
+func NewEventMetadata() EventMetadata {
+	return EventMetadata{ID: newEventID(), Time: time.Now().UTC()}
+}
+
+// UnmarshalEvent decodes the json of an event of eventType, e.g. an event read back from a store
+func UnmarshalEvent(eventType EventType, data []byte) (Event, error) {
+	var event Event
+	switch eventType {
+	case EventSchemaRegistered:
+		event = &SchemaRegistered{}
+	case EventSchemaVersionDeleted:
+		event = &SchemaVersionDeleted{}
+	case EventSubjectDeleted:
+		event = &SubjectDeleted{}
+	case EventCompatibilityChanged:
+		event = &CompatibilityChanged{}
+	default:
+		return nil, fmt.Errorf("unknown event type %s", eventType)
+	}
+
+	if err := json.Unmarshal(data, event); err != nil {
+		return nil, fmt.Errorf("event %s could not be decoded, trace: %v", eventType, err)
+	}
+
+	// events are values like the ones published
+	switch e := event.(type) {
+	case *SchemaRegistered:
+		return *e, nil
+	case *SchemaVersionDeleted:
+		return *e, nil
+	case *SubjectDeleted:
+		return *e, nil
+	case *CompatibilityChanged:
+		return *e, nil
+	}
+
+	return event, nil
+}
+
+func newEventID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+
+	// uuid v4 layout
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	h := hex.EncodeToString(b)
+
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+}
+
+func (m EventMetadata) EventID() string {
+	return m.ID
+}
+
+func (m EventMetadata) OccurredAt() time.Time {
+	return m.Time
+}
+
+func (e SchemaRegistered) EventType() EventType {
+	return EventSchemaRegistered
+}
+
+func (e SchemaRegistered) EventSubject() Subject {
+	return e.Subject
+}
+
+func (e SchemaVersionDeleted) EventType() EventType {
+	return EventSchemaVersionDeleted
+}
+
+func (e SchemaVersionDeleted) EventSubject() Subject {
+	return e.Subject
+}
+
+func (e SubjectDeleted) EventType() EventType {
+	return EventSubjectDeleted
+}
+
+func (e SubjectDeleted) EventSubject() Subject {
+	return e.Subject
+}
+
+func (e CompatibilityChanged) EventType() EventType {
+	return EventCompatibilityChanged
+}
+
+func (e CompatibilityChanged) EventSubject() Subject {
+	return e.Subject
+}
@@ -2,6 +2,20 @@ package schema
 
 type (
 	Repository interface {
-		Add(subject string, schema string) (int, error)
+		Add(schema *Schema) (SchemaID, error)
+		Subjects() ([]Subject, error)
+		Versions(subject Subject) ([]SchemaVersion, error)
+		Get(subject Subject, version SchemaVersion) (*Schema, error)
+		GetByID(id SchemaID) (*Schema, error)
+		// SubjectsOf returns the subjects with a version registered with the schema of id
+		SubjectsOf(id SchemaID) ([]Subject, error)
+		// Find returns the registered schema of the subject with the same content, nil if it is not registered
+		Find(schema *Schema) (*Schema, error)
+		CheckCompatibility(schema *Schema, version SchemaVersion) (Compatibility, error)
+		Delete(subject Subject) ([]SchemaVersion, error)
+		DeleteVersion(subject Subject, version SchemaVersion) (SchemaVersion, error)
+		// CompatibilityLevel returns the level of subject, the global level if subject is empty or has no level
+		CompatibilityLevel(subject Subject) (CompatibilityLevel, error)
+		SetCompatibilityLevel(subject Subject, level CompatibilityLevel) (CompatibilityLevel, error)
 	}
 )
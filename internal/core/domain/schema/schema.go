@@ -0,0 +1,167 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/ybalcin/event-schema-manager/pkg/avro"
+)
+
+type (
+	// SchemaType is the format of a schema
+	SchemaType string
+
+	// Schema is a schema of a subject, it is registered when it has an id and a version
+	Schema struct {
+		subject    Subject
+		version    SchemaVersion
+		id         SchemaID
+		schemaType SchemaType
+		content    string
+		references []Reference
+	}
+
+	// Reference is a schema of another subject whose types are used by name in a schema
+	Reference struct {
+		Name    string        `json:"name"`
+		Subject Subject       `json:"subject"`
+		Version SchemaVersion `json:"version"`
+	}
+)
+
+const (
+	SchemaTypeAvro     SchemaType = "AVRO"
+	SchemaTypeJSON     SchemaType = "JSON"
+	SchemaTypeProtobuf SchemaType = "PROTOBUF"
+)
+
+var protobufDefinition = regexp.MustCompile(`\b(syntax|message|enum|service)\b`)
+
+// ParseSchemaType parses a schema type, the empty string is avro
+func ParseSchemaType(schemaType string) (SchemaType, error) {
+	switch t := SchemaType(strings.ToUpper(schemaType)); t {
+	case "":
+		return SchemaTypeAvro, nil
+	case SchemaTypeAvro, SchemaTypeJSON, SchemaTypeProtobuf:
+		return t, nil
+	}
+
+	return "", fmt.Errorf("%w: unknown schema type %s", ErrInvalidSchema, schemaType)
+}
+
+// NewSchema creates a schema that is not registered yet, content must be parseable as schemaType
+func NewSchema(subject Subject, schemaType SchemaType, content string) (*Schema, error) {
+	if subject == "" {
+		return nil, fmt.Errorf("%w: subject is required", ErrInvalidSubject)
+	}
+
+	schemaType, err := ParseSchemaType(string(schemaType))
+	if err != nil {
+		return nil, err
+	}
+
+	if err = validateContent(schemaType, content); err != nil {
+		return nil, err
+	}
+
+	return &Schema{
+		subject:    subject,
+		schemaType: schemaType,
+		content:    content,
+	}, nil
+}
+
+// RestoreSchema recreates a registered schema from a repository, schemas found by id have no subject and version
+func RestoreSchema(subject Subject, version SchemaVersion, id SchemaID, schemaType SchemaType, content string) *Schema {
+	if schemaType == "" {
+		schemaType = SchemaTypeAvro
+	}
+
+	return &Schema{
+		subject:    subject,
+		version:    version,
+		id:         id,
+		schemaType: schemaType,
+		content:    content,
+	}
+}
+
+func validateContent(schemaType SchemaType, content string) error {
+	if strings.TrimSpace(content) == "" {
+		return fmt.Errorf("%w: schema is required", ErrInvalidSchema)
+	}
+
+	switch schemaType {
+	case SchemaTypeAvro:
+		if _, err := avro.Parse(content); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidSchema, err)
+		}
+
+	case SchemaTypeJSON:
+		var v interface{}
+		if err := json.Unmarshal([]byte(content), &v); err != nil {
+			return fmt.Errorf("%w: schema is not valid json, trace: %v", ErrInvalidSchema, err)
+		}
+		switch v.(type) {
+		case map[string]interface{}, bool:
+		default:
+			return fmt.Errorf("%w: json schema must be an object or a boolean", ErrInvalidSchema)
+		}
+
+	case SchemaTypeProtobuf:
+		if !protobufDefinition.MatchString(content) {
+			return fmt.Errorf("%w: schema has no protobuf definition", ErrInvalidSchema)
+		}
+	}
+
+	return nil
+}
+
+func (s *Schema) Subject() Subject {
+	return s.subject
+}
+
+func (s *Schema) Version() SchemaVersion {
+	return s.version
+}
+
+func (s *Schema) ID() SchemaID {
+	return s.id
+}
+
+func (s *Schema) Type() SchemaType {
+	return s.schemaType
+}
+
+func (s *Schema) Content() string {
+	return s.content
+}
+
+// References returns the schemas of other subjects used by the schema
+func (s *Schema) References() []Reference {
+	return s.references
+}
+
+// IsRegistered returns true if the schema has an id given by the registry
+func (s *Schema) IsRegistered() bool {
+	return s.id > 0
+}
+
+// Registered returns a copy of the schema registered with id and version
+func (s *Schema) Registered(id SchemaID, version SchemaVersion) *Schema {
+	registered := *s
+	registered.id = id
+	registered.version = version
+
+	return &registered
+}
+
+// WithReferences returns a copy of the schema using the types of references
+func (s *Schema) WithReferences(references []Reference) *Schema {
+	referencing := *s
+	referencing.references = references
+
+	return &referencing
+}
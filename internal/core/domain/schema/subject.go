@@ -0,0 +1,39 @@
+package schema
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Subject is the name a schema is registered under, e.g. orders-value
+type Subject string
+
+const maxSubjectLength = 255
+
+// NewSubject validates name and returns it as a subject, names may be qualified with a context, e.g. :.team-a:orders-value
+func NewSubject(name string) (Subject, error) {
+	if strings.TrimSpace(name) == "" {
+		return "", fmt.Errorf("%w: subject is required", ErrInvalidSubject)
+	}
+
+	if len(name) > maxSubjectLength {
+		return "", fmt.Errorf("%w: subject must not be longer than %d characters", ErrInvalidSubject, maxSubjectLength)
+	}
+
+	if strings.TrimSpace(name) != name {
+		return "", fmt.Errorf("%w: subject must not start or end with spaces", ErrInvalidSubject)
+	}
+
+	for _, r := range name {
+		if unicode.IsControl(r) {
+			return "", fmt.Errorf("%w: subject must not contain control characters", ErrInvalidSubject)
+		}
+	}
+
+	return Subject(name), nil
+}
+
+func (s Subject) String() string {
+	return string(s)
+}
@@ -0,0 +1,70 @@
+package schema
+
+import (
+	"fmt"
+	"strconv"
+)
+
+type (
+	// SchemaVersion is the version of a schema under its subject, starting from 1
+	SchemaVersion int
+
+	// SchemaID is the globally unique id of a schema
+	SchemaID int
+)
+
+// Latest refers to the latest version of a subject
+const Latest SchemaVersion = -1
+
+// LatestVersion is the version string of the latest version of a subject
+const LatestVersion = "latest"
+
+// ParseSchemaVersion parses a positive version number or "latest"
+func ParseSchemaVersion(version string) (SchemaVersion, error) {
+	if version == LatestVersion {
+		return Latest, nil
+	}
+
+	v, err := strconv.Atoi(version)
+	if err != nil || v <= 0 {
+		return 0, fmt.Errorf("%w: %s", ErrInvalidVersion, version)
+	}
+
+	return SchemaVersion(v), nil
+}
+
+// IsLatest returns true if v refers to the latest version
+func (v SchemaVersion) IsLatest() bool {
+	return v == Latest
+}
+
+func (v SchemaVersion) String() string {
+	if v.IsLatest() {
+		return LatestVersion
+	}
+
+	return strconv.Itoa(int(v))
+}
+
+// NewSchemaID validates id and returns it as a schema id
+func NewSchemaID(id int) (SchemaID, error) {
+	if id <= 0 {
+		return 0, fmt.Errorf("%w: %d", ErrInvalidSchemaID, id)
+	}
+
+	return SchemaID(id), nil
+}
+
+// ParseSchemaID parses a positive schema id
+func ParseSchemaID(id string) (SchemaID, error) {
+	v, err := strconv.Atoi(id)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s", ErrInvalidSchemaID, id)
+	}
+
+	return NewSchemaID(v)
+}
+
+func (id SchemaID) String() string {
+	return strconv.Itoa(int(id))
+}
@@ -0,0 +1,97 @@
+package adapters
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/ybalcin/event-schema-manager/internal/core/application/audit"
+)
+
+// JSONLAuditStore appends audit entries to a json lines file, queries scan the file
+type JSONLAuditStore struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// maxAuditLineSize is the size of the longest entry that can be read, entries hold whole schema diffs
+const maxAuditLineSize = 16 << 20
+
+// NewJSONLAuditStore opens the audit file at path for appending, creating it if needed
+func NewJSONLAuditStore(path string) (*JSONLAuditStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	// a line torn by a crash is ended so the next entry starts on a line of its own
+	if info, err := file.Stat(); err == nil && info.Size() > 0 {
+		last := make([]byte, 1)
+		if _, err = file.ReadAt(last, info.Size()-1); err == nil && last[0] != '\n' {
+			_, _ = file.Write([]byte{'\n'})
+		}
+	}
+
+	return &JSONLAuditStore{file: file}, nil
+}
+
+// Append writes entry as a line and syncs the file
+func (s *JSONLAuditStore) Append(entry audit.Entry) error {
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err = s.file.Write(append(b, '\n')); err != nil {
+		return err
+	}
+
+	return s.file.Sync()
+}
+
+func (s *JSONLAuditStore) Query(query audit.Query) ([]audit.Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	reader, err := os.Open(s.file.Name())
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	entries := []audit.Entry{}
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), maxAuditLineSize)
+	for scanner.Scan() {
+		var entry audit.Entry
+		if err = json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			// a line torn by a crash
+			continue
+		}
+		if query.Matches(entry) {
+			entries = append(entries, entry)
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return query.Latest(entries), nil
+}
+
+// Close closes the audit file
+func (s *JSONLAuditStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.file.Close()
+}
@@ -0,0 +1,71 @@
+package adapters
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ybalcin/event-schema-manager/internal/core/application/audit"
+)
+
+func TestJSONLAuditStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit", "audit.jsonl")
+	start := time.Date(2021, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	store, err := NewJSONLAuditStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, actor := range []string{"alice", "bob", "alice"} {
+		entry := audit.Entry{
+			ID:        actor + string(rune('0'+i)),
+			Time:      start.Add(time.Duration(i) * time.Hour),
+			Actor:     actor,
+			Operation: audit.OperationRegister,
+			Subject:   "orders-value",
+		}
+		if err = store.Append(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	store.Close()
+
+	// a crash in the middle of a write
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = f.WriteString(`{"id":"torn","ti`); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	store, err = NewJSONLAuditStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	if err = store.Append(audit.Entry{ID: "after", Time: start.Add(3 * time.Hour), Actor: "carol", Subject: "payments-value"}); err != nil {
+		t.Fatal(err)
+	}
+
+	ids := func(query audit.Query) []string {
+		entries, err := store.Query(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids := []string{}
+		for _, e := range entries {
+			ids = append(ids, e.ID)
+		}
+		return ids
+	}
+
+	mustEqual(t, ids(audit.Query{}), []string{"alice0", "bob1", "alice2", "after"})
+	mustEqual(t, ids(audit.Query{Actor: "alice"}), []string{"alice0", "alice2"})
+	mustEqual(t, ids(audit.Query{Subject: "payments-value"}), []string{"after"})
+	mustEqual(t, ids(audit.Query{From: start.Add(time.Hour), To: start.Add(3 * time.Hour)}), []string{"bob1", "alice2"})
+	mustEqual(t, ids(audit.Query{Limit: 2}), []string{"alice2", "after"})
+}
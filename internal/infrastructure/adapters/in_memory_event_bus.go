@@ -0,0 +1,160 @@
+package adapters
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ybalcin/event-schema-manager/internal/core/domain/schema"
+)
+
+type (
+	// EventBusAdapter forwards events to an external broker like kafka or nats
+	EventBusAdapter interface {
+		Publish(message EventMessage) error
+	}
+
+	// EventMessage is the broker agnostic form of an event, Key is the subject so events of a subject keep their order
+	EventMessage struct {
+		ID         string           `json:"id"`
+		Type       schema.EventType `json:"type"`
+		Key        string           `json:"key"`
+		OccurredAt time.Time        `json:"occurredAt"`
+		Payload    json.RawMessage  `json:"payload"`
+	}
+
+	// InMemoryEventBus delivers events to its subscribers synchronously and forwards them to its adapters
+	InMemoryEventBus struct {
+		mu          sync.RWMutex
+		nextID      int
+		subscribers map[schema.EventType]map[int]schema.EventHandler
+		adapters    []EventBusAdapter
+	}
+
+	// PublishError collects the errors of the adapters an event could not be forwarded to
+	PublishError struct {
+		Errors []error
+	}
+)
+
+// allEvents is the key of subscribers of every event type
+const allEvents schema.EventType = ""
+
+func NewInMemoryEventBus(adapters ...EventBusAdapter) *InMemoryEventBus {
+	return &InMemoryEventBus{
+		subscribers: make(map[schema.EventType]map[int]schema.EventHandler),
+		adapters:    adapters,
+	}
+}
+
+// NewEventMessage encodes event as a message
+func NewEventMessage(event schema.Event) (EventMessage, error) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return EventMessage{}, fmt.Errorf("event %s could not be encoded, trace: %v", event.EventID(), err)
+	}
+
+	return EventMessage{
+		ID:         event.EventID(),
+		Type:       event.EventType(),
+		Key:        event.EventSubject().String(),
+		OccurredAt: event.OccurredAt(),
+		Payload:    payload,
+	}, nil
+}
+
+func (b *InMemoryEventBus) Subscribe(eventType schema.EventType, handler schema.EventHandler) func() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	id := b.nextID
+	b.nextID++
+
+	if b.subscribers[eventType] == nil {
+		b.subscribers[eventType] = make(map[int]schema.EventHandler)
+	}
+	b.subscribers[eventType][id] = handler
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		delete(b.subscribers[eventType], id)
+	}
+}
+
+// Publish delivers every event to every handler and adapter even if some fail.
+// Subscribers are in-process consumers that own their retries, their failures are logged and do not fail
+// the publish, otherwise the outbox would redeliver the event to every subscriber.
+// The failures of the adapters are returned as a *PublishError
+func (b *InMemoryEventBus) Publish(events ...schema.Event) error {
+	var errs []error
+	for _, event := range events {
+		for _, handler := range b.handlersOf(event.EventType()) {
+			if err := handler(event); err != nil {
+				log.Printf("event bus: handler of %s %s failed, trace: %v", event.EventType(), event.EventID(), err)
+			}
+		}
+
+		if len(b.adapters) == 0 {
+			continue
+		}
+
+		message, err := NewEventMessage(event)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		for _, adapter := range b.adapters {
+			if err := adapter.Publish(message); err != nil {
+				errs = append(errs, fmt.Errorf("adapter could not publish %s %s, trace: %v", event.EventType(), event.EventID(), err))
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return &PublishError{Errors: errs}
+	}
+
+	return nil
+}
+
+// handlersOf returns a copy of the handlers so they can subscribe and unsubscribe while handling
+func (b *InMemoryEventBus) handlersOf(eventType schema.EventType) []schema.EventHandler {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	handlers := make([]schema.EventHandler, 0, len(b.subscribers[eventType])+len(b.subscribers[allEvents]))
+	for _, key := range []schema.EventType{eventType, allEvents} {
+		for _, id := range sortedIDs(b.subscribers[key]) {
+			handlers = append(handlers, b.subscribers[key][id])
+		}
+	}
+
+	return handlers
+}
+
+// sortedIDs returns the subscription ids in subscription order
+func sortedIDs(handlers map[int]schema.EventHandler) []int {
+	ids := make([]int, 0, len(handlers))
+	for id := range handlers {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	return ids
+}
+
+func (err *PublishError) Error() string {
+	messages := make([]string, len(err.Errors))
+	for i, e := range err.Errors {
+		messages[i] = e.Error()
+	}
+
+	return "events could not be published: " + strings.Join(messages, "; ")
+}
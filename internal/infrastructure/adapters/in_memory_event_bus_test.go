@@ -0,0 +1,99 @@
+package adapters
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/ybalcin/event-schema-manager/internal/core/domain/schema"
+)
+
+type adapterFunc func(message EventMessage) error
+
+func (f adapterFunc) Publish(message EventMessage) error {
+	return f(message)
+}
+
+func TestInMemoryEventBus_Subscribe(t *testing.T) {
+	bus := NewInMemoryEventBus()
+
+	var received []string
+	bus.Subscribe(schema.EventSchemaRegistered, func(event schema.Event) error {
+		received = append(received, "registered "+event.EventSubject().String())
+		return nil
+	})
+	unsubscribe := bus.Subscribe(allEvents, func(event schema.Event) error {
+		received = append(received, "all "+string(event.EventType()))
+		return nil
+	})
+
+	err := bus.Publish(
+		schema.SchemaRegistered{EventMetadata: schema.NewEventMetadata(), Subject: "orders-value", Version: 1},
+		schema.SubjectDeleted{EventMetadata: schema.NewEventMetadata(), Subject: "orders-value"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unsubscribe()
+	if err = bus.Publish(schema.SubjectDeleted{EventMetadata: schema.NewEventMetadata(), Subject: "payments-value"}); err != nil {
+		t.Fatal(err)
+	}
+
+	mustEqual(t, received, []string{"registered orders-value", "all SchemaRegistered", "all SubjectDeleted"})
+}
+
+func TestInMemoryEventBus_Publish_HandlerErrors(t *testing.T) {
+	bus := NewInMemoryEventBus()
+
+	delivered := 0
+	bus.Subscribe(allEvents, func(event schema.Event) error {
+		return errors.New("queue is full")
+	})
+	bus.Subscribe(allEvents, func(event schema.Event) error {
+		delivered++
+		return nil
+	})
+
+	// a failing subscriber does not fail the publish, the outbox would redeliver to every subscriber
+	err := bus.Publish(schema.SubjectDeleted{EventMetadata: schema.NewEventMetadata(), Subject: "orders-value"})
+	mustEqual(t, err, nil)
+	mustEqual(t, delivered, 1)
+}
+
+func TestInMemoryEventBus_Publish_AdapterErrors(t *testing.T) {
+	var messages []EventMessage
+	bus := NewInMemoryEventBus(
+		adapterFunc(func(message EventMessage) error {
+			return errors.New("broker is down")
+		}),
+		adapterFunc(func(message EventMessage) error {
+			messages = append(messages, message)
+			return nil
+		}),
+	)
+
+	event := schema.SchemaVersionDeleted{EventMetadata: schema.NewEventMetadata(), Subject: "orders-value", Version: 2}
+	err := bus.Publish(event)
+
+	var publishErr *PublishError
+	if !errors.As(err, &publishErr) {
+		t.Fatalf("expected a *PublishError, but got %v", err)
+	}
+	mustEqual(t, len(publishErr.Errors), 1)
+
+	if len(messages) != 1 {
+		t.Fatalf("expected 1 message, but got %d", len(messages))
+	}
+	mustEqual(t, messages[0].ID, event.ID)
+	mustEqual(t, messages[0].Type, schema.EventSchemaVersionDeleted)
+	mustEqual(t, messages[0].Key, "orders-value")
+
+	var payload map[string]interface{}
+	if err = json.Unmarshal(messages[0].Payload, &payload); err != nil {
+		t.Fatal(err)
+	}
+	mustEqual(t, payload["subject"], "orders-value")
+	mustEqual(t, payload["version"], float64(2))
+	mustEqual(t, payload["id"], event.ID)
+}
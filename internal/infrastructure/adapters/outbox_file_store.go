@@ -0,0 +1,367 @@
+package adapters
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/ybalcin/event-schema-manager/internal/core/application/outbox"
+	"github.com/ybalcin/event-schema-manager/internal/core/domain/schema"
+)
+
+type (
+	// FileOutboxStore is an embedded outbox store, every change is appended to a log file and synced,
+	// the pending and parked records are rebuilt from the log on open
+	FileOutboxStore struct {
+		mu        sync.Mutex
+		path      string
+		file      *os.File
+		pending   []*outbox.Record
+		parked    []*outbox.Record
+		known     map[string]bool
+		delivered int
+	}
+
+	outboxLogEntry struct {
+		Op      string          `json:"op"`
+		Record  *outbox.Record  `json:"record,omitempty"`
+		Records []outbox.Record `json:"records,omitempty"`
+		ID      string          `json:"id,omitempty"`
+		Error   string          `json:"error,omitempty"`
+	}
+)
+
+const (
+	outboxLogFile = "outbox.log"
+
+	outboxOpAppend    = "append"
+	outboxOpDelivered = "delivered"
+	outboxOpFailed    = "failed"
+	outboxOpConfirmed = "confirmed"
+	outboxOpParked    = "parked"
+
+	// outboxCompactThreshold is the number of delivered records after which the log is rewritten
+	// with the pending records only
+	outboxCompactThreshold = 1000
+)
+
+var (
+	errOutboxClosed   = errors.New("outbox store is closed")
+	errRecordNotFound = errors.New("outbox record is not found")
+)
+
+// NewFileOutboxStore opens the outbox log in dir, creating it if needed
+func NewFileOutboxStore(dir string) (*FileOutboxStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	s := &FileOutboxStore{path: filepath.Join(dir, outboxLogFile)}
+	if err := s.load(); err != nil {
+		return nil, fmt.Errorf("outbox log %s could not be read, trace: %v", s.path, err)
+	}
+
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	s.file = file
+
+	return s, nil
+}
+
+func (s *FileOutboxStore) load() error {
+	s.known = make(map[string]bool)
+
+	file, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var size int64
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			if len(line) == 0 {
+				return nil
+			}
+			// a line without newline is a write torn by a crash, it was never acknowledged
+			// and it is cut so the next entries start on a line of their own
+			return os.Truncate(s.path, size)
+		}
+		if err != nil {
+			return err
+		}
+		size += int64(len(line))
+
+		var entry outboxLogEntry
+		if err = json.Unmarshal(line, &entry); err != nil {
+			return err
+		}
+		s.apply(entry)
+	}
+}
+
+// apply changes the in-memory state by entry
+func (s *FileOutboxStore) apply(entry outboxLogEntry) {
+	switch entry.Op {
+	case outboxOpAppend:
+		record := *entry.Record
+		s.known[record.ID] = true
+		if record.Parked {
+			s.parked = append(s.parked, &record)
+		} else {
+			s.pending = append(s.pending, &record)
+		}
+
+	case outboxOpConfirmed:
+		i := s.indexOf(entry.ID)
+		if i < 0 {
+			return
+		}
+		confirmed := make([]*outbox.Record, 0, len(entry.Records))
+		for j := range entry.Records {
+			record := entry.Records[j]
+			s.known[record.ID] = true
+			confirmed = append(confirmed, &record)
+		}
+		s.pending = append(s.pending[:i], append(confirmed, s.pending[i+1:]...)...)
+		s.delivered++
+
+	case outboxOpDelivered:
+		if i := s.indexOf(entry.ID); i >= 0 {
+			s.pending = append(s.pending[:i], s.pending[i+1:]...)
+			s.delivered++
+		}
+
+	case outboxOpParked:
+		if i := s.indexOf(entry.ID); i >= 0 {
+			record := s.pending[i]
+			record.Parked = true
+			record.LastError = entry.Error
+			s.pending = append(s.pending[:i], s.pending[i+1:]...)
+			s.parked = append(s.parked, record)
+		}
+
+	case outboxOpFailed:
+		for _, record := range s.pending {
+			if record.ID == entry.ID {
+				record.Attempts++
+				record.LastError = entry.Error
+				break
+			}
+		}
+	}
+}
+
+// indexOf returns the index of the pending record id, -1 if there is none
+func (s *FileOutboxStore) indexOf(id string) int {
+	for i, record := range s.pending {
+		if record.ID == id {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// write appends entries to the log, syncs it and applies them
+func (s *FileOutboxStore) write(entries ...outboxLogEntry) error {
+	if s.file == nil {
+		return errOutboxClosed
+	}
+
+	var buf []byte
+	for _, entry := range entries {
+		b, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		buf = append(append(buf, b...), '\n')
+	}
+
+	if _, err := s.file.Write(buf); err != nil {
+		return err
+	}
+	if err := s.file.Sync(); err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		s.apply(entry)
+	}
+
+	return nil
+}
+
+func (s *FileOutboxStore) Append(records ...outbox.Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var entries []outboxLogEntry
+	for i := range records {
+		if s.known[records[i].ID] {
+			continue
+		}
+		entries = append(entries, outboxLogEntry{Op: outboxOpAppend, Record: &records[i]})
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+
+	return s.write(entries...)
+}
+
+func (s *FileOutboxStore) Confirm(id string, records ...outbox.Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.indexOf(id)
+	if i < 0 || !s.pending[i].Staged {
+		return fmt.Errorf("%w: %s is not staged", errRecordNotFound, id)
+	}
+
+	var confirmed []outbox.Record
+	for _, record := range records {
+		if !s.known[record.ID] {
+			confirmed = append(confirmed, record)
+		}
+	}
+
+	return s.write(outboxLogEntry{Op: outboxOpConfirmed, ID: id, Records: confirmed})
+}
+
+func (s *FileOutboxStore) Pending(limit int, skip map[schema.Subject]bool) ([]outbox.Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records := []outbox.Record{}
+	for _, record := range s.pending {
+		if limit > 0 && len(records) == limit {
+			break
+		}
+		if !skip[record.Subject] {
+			records = append(records, *record)
+		}
+	}
+
+	return records, nil
+}
+
+func (s *FileOutboxStore) Parked() ([]outbox.Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records := make([]outbox.Record, len(s.parked))
+	for i, record := range s.parked {
+		records[i] = *record
+	}
+
+	return records, nil
+}
+
+func (s *FileOutboxStore) MarkDelivered(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.write(outboxLogEntry{Op: outboxOpDelivered, ID: id}); err != nil {
+		return err
+	}
+
+	if s.delivered >= outboxCompactThreshold {
+		return s.compact()
+	}
+
+	return nil
+}
+
+func (s *FileOutboxStore) MarkFailed(id string, cause error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.write(outboxLogEntry{Op: outboxOpFailed, ID: id, Error: cause.Error()})
+}
+
+func (s *FileOutboxStore) MarkParked(id string, cause error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.write(outboxLogEntry{Op: outboxOpParked, ID: id, Error: cause.Error()})
+}
+
+// Close closes the log file
+func (s *FileOutboxStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return nil
+	}
+
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+// compact rewrites the log with the pending and parked records, the new log is written to a temp file first and renamed
+// so a crash leaves either the old or the new log. Ids of delivered records are forgotten.
+func (s *FileOutboxStore) compact() error {
+	tmp, err := ioutil.TempFile(filepath.Dir(s.path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	writer := bufio.NewWriter(tmp)
+	for _, record := range append(append([]*outbox.Record(nil), s.parked...), s.pending...) {
+		b, err := json.Marshal(outboxLogEntry{Op: outboxOpAppend, Record: record})
+		if err != nil {
+			tmp.Close()
+			return err
+		}
+		if _, err = writer.Write(append(b, '\n')); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err = writer.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+
+	if err = os.Rename(tmp.Name(), s.path); err != nil {
+		return err
+	}
+
+	s.file.Close()
+	if s.file, err = os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND, 0o644); err != nil {
+		s.file = nil
+		return err
+	}
+
+	s.delivered = 0
+	s.known = make(map[string]bool, len(s.pending)+len(s.parked))
+	for _, record := range append(append([]*outbox.Record(nil), s.parked...), s.pending...) {
+		s.known[record.ID] = true
+	}
+
+	return nil
+}
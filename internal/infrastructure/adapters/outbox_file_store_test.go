@@ -0,0 +1,221 @@
+package adapters
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ybalcin/event-schema-manager/internal/core/application/outbox"
+	"github.com/ybalcin/event-schema-manager/internal/core/domain/schema"
+)
+
+func mustEqual(t *testing.T, actual, expected interface{}) {
+	t.Helper()
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %#v, but got %#v", expected, actual)
+	}
+}
+
+func testRecord(id string) outbox.Record {
+	return outbox.Record{
+		ID:         id,
+		Type:       "SchemaRegistered",
+		Subject:    "orders-value",
+		OccurredAt: time.Date(2021, 10, 1, 12, 0, 0, 0, time.UTC),
+		Payload:    []byte(`{"id":"` + id + `"}`),
+	}
+}
+
+func pendingIDs(t *testing.T, store *FileOutboxStore) []string {
+	t.Helper()
+
+	records, err := store.Pending(0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids := []string{}
+	for _, r := range records {
+		ids = append(ids, r.ID)
+	}
+	return ids
+}
+
+func mustOpenOutboxStore(t *testing.T, dir string) *FileOutboxStore {
+	t.Helper()
+
+	store, err := NewFileOutboxStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	return store
+}
+
+func TestFileOutboxStore_SurvivesReopen(t *testing.T) {
+	dir := t.TempDir()
+
+	store := mustOpenOutboxStore(t, dir)
+	if err := store.Append(testRecord("a"), testRecord("b"), testRecord("c")); err != nil {
+		t.Fatal(err)
+	}
+	// records are deduplicated by id
+	if err := store.Append(testRecord("a")); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.MarkDelivered("a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.MarkFailed("b", errors.New("bus is down")); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened := mustOpenOutboxStore(t, dir)
+	mustEqual(t, pendingIDs(t, reopened), []string{"b", "c"})
+
+	records, err := reopened.Pending(1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := testRecord("b")
+	expected.Attempts, expected.LastError = 1, "bus is down"
+	mustEqual(t, records, []outbox.Record{expected})
+
+	// records of skipped subjects are left out
+	if records, err = reopened.Pending(0, map[schema.Subject]bool{"orders-value": true}); err != nil {
+		t.Fatal(err)
+	}
+	mustEqual(t, len(records), 0)
+
+	// delivered ids are still known after reopen
+	if err = reopened.Append(testRecord("a")); err != nil {
+		t.Fatal(err)
+	}
+	mustEqual(t, pendingIDs(t, reopened), []string{"b", "c"})
+}
+
+func TestFileOutboxStore_TornWrite(t *testing.T) {
+	dir := t.TempDir()
+
+	store := mustOpenOutboxStore(t, dir)
+	if err := store.Append(testRecord("a")); err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+
+	// a crash in the middle of a write
+	f, err := os.OpenFile(filepath.Join(dir, outboxLogFile), os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = f.WriteString(`{"op":"append","rec`); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	reopened := mustOpenOutboxStore(t, dir)
+	if err = reopened.Append(testRecord("b")); err != nil {
+		t.Fatal(err)
+	}
+	reopened.Close()
+
+	mustEqual(t, pendingIDs(t, mustOpenOutboxStore(t, dir)), []string{"a", "b"})
+}
+
+func TestFileOutboxStore_Compact(t *testing.T) {
+	dir := t.TempDir()
+
+	store := mustOpenOutboxStore(t, dir)
+	for i := 0; i < outboxCompactThreshold; i++ {
+		id := fmt.Sprintf("delivered-%d", i)
+		if err := store.Append(testRecord(id)); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.MarkDelivered(id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.Append(testRecord("pending")); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.MarkDelivered("pending"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Append(testRecord("last")); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, outboxLogFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) > 1024 {
+		t.Errorf("log must be compacted, but it has %d bytes", len(b))
+	}
+
+	store.Close()
+	mustEqual(t, pendingIDs(t, mustOpenOutboxStore(t, dir)), []string{"last"})
+}
+
+func TestFileOutboxStore_StagedAndParked(t *testing.T) {
+	dir := t.TempDir()
+
+	staged, discarded := testRecord("staged"), testRecord("discarded")
+	staged.Staged, discarded.Staged = true, true
+
+	store := mustOpenOutboxStore(t, dir)
+	if err := store.Append(testRecord("a"), staged, discarded, testRecord("b")); err != nil {
+		t.Fatal(err)
+	}
+	// the staged record is replaced in its place
+	if err := store.Confirm("staged", testRecord("made-1"), testRecord("made-2")); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Confirm("discarded"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Confirm("a", testRecord("c")); !errors.Is(err, errRecordNotFound) {
+		t.Fatalf("only staged records can be confirmed, but got %v", err)
+	}
+	if err := store.MarkParked("a", errors.New("broker rejected the event")); err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+
+	reopened := mustOpenOutboxStore(t, dir)
+	mustEqual(t, pendingIDs(t, reopened), []string{"made-1", "made-2", "b"})
+
+	parked, err := reopened.Parked()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := testRecord("a")
+	expected.Parked, expected.LastError = true, "broker rejected the event"
+	mustEqual(t, parked, []outbox.Record{expected})
+
+	// parked records survive compaction
+	reopened.mu.Lock()
+	err = reopened.compact()
+	reopened.mu.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	reopened.Close()
+
+	compacted := mustOpenOutboxStore(t, dir)
+	mustEqual(t, pendingIDs(t, compacted), []string{"made-1", "made-2", "b"})
+	if parked, err = compacted.Parked(); err != nil {
+		t.Fatal(err)
+	}
+	mustEqual(t, parked, []outbox.Record{expected})
+}
@@ -0,0 +1,70 @@
+package adapters
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	"github.com/ybalcin/event-schema-manager/internal/core/application/ownership"
+)
+
+// FileOwnershipStore keeps owners in memory and writes all of them to a json file on every change
+type FileOwnershipStore struct {
+	mu   sync.Mutex
+	path string
+	*ownership.MemoryStore
+}
+
+// NewFileOwnershipStore opens the store file at path, it is created on the first change
+func NewFileOwnershipStore(path string) (*FileOwnershipStore, error) {
+	s := &FileOwnershipStore{path: path, MemoryStore: ownership.NewMemoryStore()}
+
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var saved []ownership.Owner
+	if err = json.Unmarshal(b, &saved); err != nil {
+		return nil, err
+	}
+	for _, o := range saved {
+		_ = s.MemoryStore.Save(o)
+	}
+
+	return s, nil
+}
+
+func (s *FileOwnershipStore) Save(owner ownership.Owner) error {
+	return s.change(func() error { return s.MemoryStore.Save(owner) })
+}
+
+func (s *FileOwnershipStore) Delete(subject string) error {
+	return s.change(func() error { return s.MemoryStore.Delete(subject) })
+}
+
+// change applies fn to the memory store and writes the store file
+func (s *FileOwnershipStore) change(fn func() error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := fn(); err != nil {
+		return err
+	}
+
+	all, err := s.MemoryStore.List()
+	if err != nil {
+		return err
+	}
+
+	b, err := json.MarshalIndent(all, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(s.path, b, 0o644)
+}
@@ -0,0 +1,94 @@
+package adapters
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/ybalcin/event-schema-manager/internal/core/application/proposals"
+)
+
+// FileProposalStore keeps proposals in memory and writes all of them to a json file on every change
+type FileProposalStore struct {
+	mu   sync.Mutex
+	path string
+	*proposals.MemoryStore
+}
+
+// NewFileProposalStore opens the store file at path, it is created on the first change
+func NewFileProposalStore(path string) (*FileProposalStore, error) {
+	s := &FileProposalStore{path: path, MemoryStore: proposals.NewMemoryStore()}
+
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var saved []*proposals.Proposal
+	if err = json.Unmarshal(b, &saved); err != nil {
+		return nil, err
+	}
+	for _, p := range saved {
+		_ = s.MemoryStore.Save(p)
+	}
+
+	return s, nil
+}
+
+func (s *FileProposalStore) Save(proposal *proposals.Proposal) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.MemoryStore.Save(proposal); err != nil {
+		return err
+	}
+
+	all, err := s.MemoryStore.List(proposals.Filter{})
+	if err != nil {
+		return err
+	}
+
+	b, err := json.MarshalIndent(all, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(s.path, b, 0o644)
+}
+
+// writeFileAtomic writes b to a temp file in the directory of path and renames it to path,
+// so a crash leaves either the old or the new file
+func writeFileAtomic(path string, b []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
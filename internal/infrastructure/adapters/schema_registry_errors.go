@@ -0,0 +1,41 @@
+package adapters
+
+import (
+	"fmt"
+
+	"github.com/ybalcin/event-schema-manager/internal/core/domain/schema"
+	"github.com/ybalcin/event-schema-manager/pkg/schemaregistry"
+)
+
+// translateError maps schema registry errors to domain errors, the registry error is kept as the detail
+func translateError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var domainErr error
+	switch {
+	case schemaregistry.IsSubjectNotFound(err):
+		domainErr = schema.ErrSubjectNotFound
+	case schemaregistry.IsVersionNotFound(err):
+		domainErr = schema.ErrVersionNotFound
+	case schemaregistry.IsSchemaNotFound(err):
+		domainErr = schema.ErrSchemaNotFound
+	case schemaregistry.IsInvalidAvroSchema(err):
+		domainErr = schema.ErrInvalidSchema
+	case schemaregistry.IsInvalidVersion(err):
+		domainErr = schema.ErrInvalidVersion
+	case schemaregistry.IsIncompatibleSchema(err):
+		domainErr = schema.ErrIncompatibleSchema
+	case schemaregistry.IsUnavailable(err):
+		domainErr = schema.ErrRegistryUnavailable
+	default:
+		return err
+	}
+
+	if resErr, ok := err.(schemaregistry.ResourceError); ok && resErr.Message != "" {
+		return fmt.Errorf("%w: %s", domainErr, resErr.Message)
+	}
+
+	return fmt.Errorf("%w: %v", domainErr, err)
+}
@@ -0,0 +1,52 @@
+package adapters
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/ybalcin/event-schema-manager/internal/core/domain/schema"
+	"github.com/ybalcin/event-schema-manager/pkg/schemaregistry"
+)
+
+func TestTranslateError(t *testing.T) {
+	transportErr := &url.Error{Op: http.MethodGet, URL: "http://registry:8081/subjects", Err: errors.New("connection refused")}
+	decodeErr := &json.SyntaxError{Offset: 3}
+	requiredErr := errors.New("httpClient: subject is required")
+
+	for _, tc := range []struct {
+		err      error
+		expected error
+		message  string
+	}{
+		{schemaregistry.ResourceError{ErrorCode: 40401, Message: "Subject 'orders' not found."}, schema.ErrSubjectNotFound,
+			"subject not found: Subject 'orders' not found."},
+		{schemaregistry.ResourceError{ErrorCode: 40402}, schema.ErrVersionNotFound, ""},
+		{schemaregistry.ResourceError{ErrorCode: 40403}, schema.ErrSchemaNotFound, ""},
+		{schemaregistry.ResourceError{ErrorCode: 42201, Message: "Invalid schema"}, schema.ErrInvalidSchema, "invalid schema: Invalid schema"},
+		{schemaregistry.ResourceError{ErrorCode: 42202}, schema.ErrInvalidVersion, ""},
+		{schemaregistry.ResourceError{ErrorCode: 409}, schema.ErrIncompatibleSchema, ""},
+		{schemaregistry.ResourceError{ErrorCode: 50001}, schema.ErrRegistryUnavailable, ""},
+		{schemaregistry.ResourceError{ErrorCode: http.StatusBadGateway}, schema.ErrRegistryUnavailable, ""},
+		{transportErr, schema.ErrRegistryUnavailable, "schema registry unavailable: " + transportErr.Error()},
+		{decodeErr, decodeErr, ""},
+		{requiredErr, requiredErr, ""},
+	} {
+		err := translateError(tc.err)
+		if !errors.Is(err, tc.expected) {
+			t.Errorf("translateError(%v) expected %v, but got %v", tc.err, tc.expected, err)
+		}
+		if tc.expected != schema.ErrRegistryUnavailable && errors.Is(err, schema.ErrRegistryUnavailable) {
+			t.Errorf("translateError(%v) must not be unavailable", tc.err)
+		}
+		if tc.message != "" && err.Error() != tc.message {
+			t.Errorf("translateError(%v) expected message %q, but got %q", tc.err, tc.message, err.Error())
+		}
+	}
+
+	if translateError(nil) != nil {
+		t.Error("translateError(nil) expected nil")
+	}
+}
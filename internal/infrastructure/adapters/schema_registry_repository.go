@@ -1,6 +1,7 @@
 package adapters
 
 import (
+	"github.com/ybalcin/event-schema-manager/internal/core/domain/schema"
 	"github.com/ybalcin/event-schema-manager/pkg/schemaregistry"
 )
 
@@ -16,6 +17,199 @@ func NewSchemaRegistryRepository(schemaRegistryClient schemaregistry.Client) *sc
 	}
 }
 
-func (c *schemaRegistryRepository) Add(subject string, schema string) (int, error) {
-	return c.schemaRegistryClient.RegisterNewSchema(subject, schema)
+func (c *schemaRegistryRepository) Add(sc *schema.Schema) (schema.SchemaID, error) {
+	id, err := c.schemaRegistryClient.RegisterSchema(sc.Subject().String(), schemaregistry.Schema{
+		Schema:     sc.Content(),
+		SchemaType: schemaTypeOf(sc.Type()),
+		References: toRegistryReferences(sc.References()),
+	})
+	if err != nil {
+		return 0, translateError(err)
+	}
+
+	return schema.SchemaID(id), nil
+}
+
+func (c *schemaRegistryRepository) Subjects() ([]schema.Subject, error) {
+	names, err := c.schemaRegistryClient.Subjects()
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	subjects := make([]schema.Subject, len(names))
+	for i, name := range names {
+		subjects[i] = schema.Subject(name)
+	}
+
+	return subjects, nil
+}
+
+func (c *schemaRegistryRepository) Versions(subject schema.Subject) ([]schema.SchemaVersion, error) {
+	numbers, err := c.schemaRegistryClient.Versions(subject.String())
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	versions := make([]schema.SchemaVersion, len(numbers))
+	for i, v := range numbers {
+		versions[i] = schema.SchemaVersion(v)
+	}
+
+	return versions, nil
+}
+
+func (c *schemaRegistryRepository) Get(subject schema.Subject, version schema.SchemaVersion) (*schema.Schema, error) {
+	sc, err := c.schemaRegistryClient.GetSchemaByVersion(subject.String(), version.String())
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return toDomainSchema(sc), nil
+}
+
+func (c *schemaRegistryRepository) GetByID(id schema.SchemaID) (*schema.Schema, error) {
+	sc, err := c.schemaRegistryClient.GetSchema(int(id))
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return toDomainSchema(sc), nil
+}
+
+func (c *schemaRegistryRepository) SubjectsOf(id schema.SchemaID) ([]schema.Subject, error) {
+	versions, err := c.schemaRegistryClient.SchemaSubjectVersions(int(id))
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	var subjects []schema.Subject
+	for _, v := range versions {
+		subject := schema.Subject(v.Subject)
+		if len(subjects) == 0 || subjects[len(subjects)-1] != subject {
+			subjects = append(subjects, subject)
+		}
+	}
+
+	return subjects, nil
+}
+
+func (c *schemaRegistryRepository) Find(sc *schema.Schema) (*schema.Schema, error) {
+	found, registered, err := c.schemaRegistryClient.LookupSchema(sc.Subject().String(), schemaregistry.Schema{
+		Schema:     sc.Content(),
+		SchemaType: schemaTypeOf(sc.Type()),
+	})
+	if err != nil {
+		if schemaregistry.IsSubjectNotFound(err) {
+			return nil, nil
+		}
+		return nil, translateError(err)
+	}
+
+	if !found {
+		return nil, nil
+	}
+
+	if registered.Subject == "" {
+		registered.Subject = sc.Subject().String()
+	}
+	if registered.SchemaType == "" {
+		registered.SchemaType = schemaTypeOf(sc.Type())
+	}
+
+	return toDomainSchema(&registered), nil
+}
+
+func (c *schemaRegistryRepository) CheckCompatibility(sc *schema.Schema, version schema.SchemaVersion) (schema.Compatibility, error) {
+	isCompatible, reasons, err := c.schemaRegistryClient.CheckSchemaCompatibility(sc.Subject().String(), schemaregistry.Schema{
+		Schema:     sc.Content(),
+		SchemaType: schemaTypeOf(sc.Type()),
+	}, version.String())
+	if err != nil {
+		return schema.Compatibility{}, translateError(err)
+	}
+
+	return schema.Compatibility{IsCompatible: isCompatible, Reasons: reasons}, nil
+}
+
+func (c *schemaRegistryRepository) Delete(subject schema.Subject) ([]schema.SchemaVersion, error) {
+	deleted, err := c.schemaRegistryClient.DeleteSubject(subject.String())
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	versions := make([]schema.SchemaVersion, len(deleted))
+	for i, d := range deleted {
+		versions[i] = schema.SchemaVersion(d)
+	}
+
+	return versions, nil
+}
+
+func (c *schemaRegistryRepository) DeleteVersion(subject schema.Subject, version schema.SchemaVersion) (schema.SchemaVersion, error) {
+	deleted, err := c.schemaRegistryClient.DeleteSchemaVersion(subject.String(), version.String())
+	if err != nil {
+		return 0, translateError(err)
+	}
+
+	return schema.SchemaVersion(deleted), nil
+}
+
+func (c *schemaRegistryRepository) CompatibilityLevel(subject schema.Subject) (schema.CompatibilityLevel, error) {
+	level, err := c.schemaRegistryClient.GetCompatibilityLevel(subject.String())
+	if err != nil {
+		return "", translateError(err)
+	}
+
+	return schema.CompatibilityLevel(level), nil
+}
+
+func (c *schemaRegistryRepository) SetCompatibilityLevel(subject schema.Subject, level schema.CompatibilityLevel) (schema.CompatibilityLevel, error) {
+	updated, err := c.schemaRegistryClient.SetCompatibilityLevel(subject.String(), string(level))
+	if err != nil {
+		return "", translateError(err)
+	}
+
+	return schema.CompatibilityLevel(updated), nil
+}
+
+// schemaTypeOf returns the registry schema type, avro is the default of the registry and is omitted
+func schemaTypeOf(schemaType schema.SchemaType) string {
+	if schemaType == schema.SchemaTypeAvro {
+		return ""
+	}
+
+	return string(schemaType)
+}
+
+func toDomainSchema(sc *schemaregistry.Schema) *schema.Schema {
+	restored := schema.RestoreSchema(
+		schema.Subject(sc.Subject),
+		schema.SchemaVersion(sc.Version),
+		schema.SchemaID(sc.ID),
+		schema.SchemaType(sc.SchemaType),
+		sc.Schema,
+	)
+	if len(sc.References) == 0 {
+		return restored
+	}
+
+	references := make([]schema.Reference, len(sc.References))
+	for i, ref := range sc.References {
+		references[i] = schema.Reference{Name: ref.Name, Subject: schema.Subject(ref.Subject), Version: schema.SchemaVersion(ref.Version)}
+	}
+
+	return restored.WithReferences(references)
+}
+
+func toRegistryReferences(references []schema.Reference) []schemaregistry.Reference {
+	if len(references) == 0 {
+		return nil
+	}
+
+	refs := make([]schemaregistry.Reference, len(references))
+	for i, ref := range references {
+		refs[i] = schemaregistry.Reference{Name: ref.Name, Subject: ref.Subject.String(), Version: int(ref.Version)}
+	}
+
+	return refs
 }
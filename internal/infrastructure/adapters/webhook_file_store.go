@@ -0,0 +1,94 @@
+package adapters
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	"github.com/ybalcin/event-schema-manager/internal/core/application/webhooks"
+)
+
+type (
+	// FileWebhookStore keeps subscriptions and dead letters in memory and writes all of them to a json file
+	// on every change
+	FileWebhookStore struct {
+		mu   sync.Mutex
+		path string
+		*webhooks.MemoryStore
+	}
+
+	webhookSnapshot struct {
+		Subscriptions []webhooks.Subscription `json:"subscriptions"`
+		DeadLetters   []webhooks.DeadLetter   `json:"deadLetters"`
+	}
+)
+
+// NewFileWebhookStore opens the store file at path, it is created on the first change
+func NewFileWebhookStore(path string) (*FileWebhookStore, error) {
+	s := &FileWebhookStore{path: path, MemoryStore: webhooks.NewMemoryStore()}
+
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshot webhookSnapshot
+	if err = json.Unmarshal(b, &snapshot); err != nil {
+		return nil, err
+	}
+	for _, sub := range snapshot.Subscriptions {
+		_ = s.MemoryStore.SaveSubscription(sub)
+	}
+	for _, dl := range snapshot.DeadLetters {
+		_ = s.MemoryStore.SaveDeadLetter(dl)
+	}
+
+	return s, nil
+}
+
+func (s *FileWebhookStore) SaveSubscription(subscription webhooks.Subscription) error {
+	return s.change(func() error { return s.MemoryStore.SaveSubscription(subscription) })
+}
+
+func (s *FileWebhookStore) DeleteSubscription(id string) error {
+	return s.change(func() error { return s.MemoryStore.DeleteSubscription(id) })
+}
+
+func (s *FileWebhookStore) SaveDeadLetter(deadLetter webhooks.DeadLetter) error {
+	return s.change(func() error { return s.MemoryStore.SaveDeadLetter(deadLetter) })
+}
+
+func (s *FileWebhookStore) DeleteDeadLetter(id string) error {
+	return s.change(func() error { return s.MemoryStore.DeleteDeadLetter(id) })
+}
+
+// change applies fn to the memory store and writes the store file
+func (s *FileWebhookStore) change(fn func() error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := fn(); err != nil {
+		return err
+	}
+
+	var snapshot webhookSnapshot
+	var err error
+	if snapshot.Subscriptions, err = s.MemoryStore.Subscriptions(); err != nil {
+		return err
+	}
+	if snapshot.DeadLetters, err = s.MemoryStore.DeadLetters(); err != nil {
+		return err
+	}
+
+	b, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// secrets are in the file
+	return writeFileAtomic(s.path, b, 0o600)
+}
@@ -8,7 +8,74 @@ import (
 
 type (
 	AppConfig struct {
-		SchemaRegistryUrl string
+		HttpAddress             string
+		SchemaRegistryUrl       string
+		SchemaRegistryCacheDir  string
+		SchemaRegistryNormalize bool
+		// OutboxDir enables the outbox, events are stored there before they are published
+		OutboxDir string
+		// WebhookStorePath is the file webhook subscriptions are kept in, they are kept in memory if it is empty
+		WebhookStorePath string
+		// WebhookAllowedHosts are the hosts webhooks may be delivered to even if they resolve to internal addresses
+		WebhookAllowedHosts []string
+		// AuditLogPath is the json lines file of the audit log, it is kept in memory if it is empty
+		AuditLogPath string
+		// ProposalStorePath is the file schema proposals are kept in, they are kept in memory if it is empty
+		ProposalStorePath string
+		// OwnershipStorePath is the file subject owners are kept in, they are kept in memory if it is empty
+		OwnershipStorePath string
+		// EnforceOwnership allows only the owning team to change an owned subject
+		EnforceOwnership bool
+		// SearchAllVersions indexes every version of the subjects for search, only the latest ones by default
+		SearchAllVersions bool
+		// Approvals are the subject prefixes whose changes require approved proposals
+		Approvals []ApprovalConfig
+		Lint      []LintConfig
+		// Auth enables authentication and role based authorization if it has api keys or jwt keys
+		Auth AuthConfig
+		// PortalInsecureCookie lets the portal session cookie be sent over plain http, for local development
+		PortalInsecureCookie bool
+	}
+
+	AuthConfig struct {
+		APIKeys []APIKeyConfig
+		JWT     JWTConfig
+	}
+
+	// APIKeyConfig is an api key known by its hex SHA256 hash, Roles are grants like producer:orders-*
+	APIKeyConfig struct {
+		Name   string
+		SHA256 string
+		Roles  []string
+		Teams  []string
+	}
+
+	// JWTConfig verifies HS tokens with HMACSecret and RS tokens with the PEM public keys of RSAPublicKeyFiles by kid
+	JWTConfig struct {
+		HMACSecret        string
+		RSAPublicKeyFiles map[string]string
+		Issuer            string
+		Audience          string
+	}
+
+	// ApprovalConfig requires RequiredApprovals approvals of Reviewers, anyone if it is empty,
+	// for the changes of subjects starting with SubjectPrefix
+	ApprovalConfig struct {
+		SubjectPrefix     string
+		RequiredApprovals int
+		Reviewers         []string
+	}
+
+	// LintConfig is the lint rule set of subjects starting with SubjectPrefix
+	LintConfig struct {
+		SubjectPrefix string
+		Rules         []LintRuleConfig
+	}
+
+	LintRuleConfig struct {
+		Name     string
+		Severity string
+		Params   map[string]string
 	}
 )
 
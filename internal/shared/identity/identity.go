@@ -0,0 +1,51 @@
+package identity
+
+import "context"
+
+type (
+	// Identity is the caller of a request, the teams it belongs to and the roles granted to it
+	Identity struct {
+		Actor  string
+		Teams  []string
+		Grants []Grant
+		// Authenticated is set if the identity is proved by credentials, otherwise the actor and the teams
+		// are only claimed by the caller
+		Authenticated bool
+	}
+
+	// Grant is a role on the subjects matching the glob Subjects
+	Grant struct {
+		Role     string
+		Subjects string
+	}
+
+	contextKey struct{}
+)
+
+// Anonymous is the actor of requests without an identity
+const Anonymous = "anonymous"
+
+// NewContext returns a copy of ctx carrying id
+func NewContext(ctx context.Context, id Identity) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the identity of ctx, the anonymous identity if it has none
+func FromContext(ctx context.Context) Identity {
+	if id, ok := ctx.Value(contextKey{}).(Identity); ok && id.Actor != "" {
+		return id
+	}
+
+	return Identity{Actor: Anonymous}
+}
+
+// InTeam returns true if the identity belongs to team
+func (id Identity) InTeam(team string) bool {
+	for _, t := range id.Teams {
+		if t == team {
+			return true
+		}
+	}
+
+	return false
+}
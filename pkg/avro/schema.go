@@ -0,0 +1,450 @@
+package avro
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+type (
+	// Schema is a node of a parsed avro schema
+	Schema struct {
+		Type        Type
+		Name        string // full name of named types and of references
+		Doc         string
+		Aliases     []string
+		Fields      []*Field
+		Symbols     []string
+		Default     interface{} // default symbol of enums
+		Items       *Schema
+		Values      *Schema
+		Branches    []*Schema
+		Size        int
+		LogicalType string
+		Props       map[string]interface{}
+	}
+
+	// Field is a field of a record
+	Field struct {
+		Name       string
+		Doc        string
+		Type       *Schema
+		Default    interface{}
+		HasDefault bool
+		Aliases    []string
+		Order      string
+		Props      map[string]interface{}
+	}
+
+	Type string
+
+	parser struct {
+		named map[string]*Schema
+	}
+)
+
+const (
+	Null    Type = "null"
+	Boolean Type = "boolean"
+	Int     Type = "int"
+	Long    Type = "long"
+	Float   Type = "float"
+	Double  Type = "double"
+	Bytes   Type = "bytes"
+	String  Type = "string"
+	Record  Type = "record"
+	Error   Type = "error"
+	Enum    Type = "enum"
+	Array   Type = "array"
+	Map     Type = "map"
+	Fixed   Type = "fixed"
+	Union   Type = "union"
+	// Reference is a use of a named type by its name
+	Reference Type = "reference"
+)
+
+var primitives = map[Type]bool{
+	Null: true, Boolean: true, Int: true, Long: true, Float: true, Double: true, Bytes: true, String: true,
+}
+
+// reservedAttributes are the attributes parsed into Schema and Field, the others are kept as props
+var reservedAttributes = map[string]bool{
+	"type": true, "name": true, "namespace": true, "doc": true, "aliases": true, "fields": true, "symbols": true,
+	"items": true, "values": true, "size": true, "logicalType": true, "default": true, "order": true,
+}
+
+var errInvalid = func(format string, args ...interface{}) error {
+	return fmt.Errorf("avro: "+format, args...)
+}
+
+// Parse parses an avro schema, names of unknown types are kept as references
+// since they may be defined in referenced schemas
+func Parse(schema string) (*Schema, error) {
+	decoder := json.NewDecoder(strings.NewReader(schema))
+	decoder.UseNumber()
+
+	var node interface{}
+	if err := decoder.Decode(&node); err != nil {
+		return nil, errInvalid("schema is not valid json, trace: %v", err)
+	}
+	if decoder.More() {
+		return nil, errInvalid("unexpected content after schema")
+	}
+
+	p := &parser{named: make(map[string]*Schema)}
+	return p.parse(node, "")
+}
+
+// IsPrimitive returns true if t is a primitive type
+func (t Type) IsPrimitive() bool {
+	return primitives[t]
+}
+
+// IsNamed returns true if t is a named type
+func (t Type) IsNamed() bool {
+	return t == Record || t == Error || t == Enum || t == Fixed
+}
+
+// ShortName returns the name of s without its namespace
+func (s *Schema) ShortName() string {
+	if i := strings.LastIndex(s.Name, "."); i >= 0 {
+		return s.Name[i+1:]
+	}
+
+	return s.Name
+}
+
+// Namespace returns the namespace of s
+func (s *Schema) Namespace() string {
+	if i := strings.LastIndex(s.Name, "."); i >= 0 {
+		return s.Name[:i]
+	}
+
+	return ""
+}
+
+// IsNullable returns true if s is null or a union with a null branch
+func (s *Schema) IsNullable() bool {
+	if s.Type == Null {
+		return true
+	}
+
+	if s.Type == Union {
+		for _, b := range s.Branches {
+			if b.Type == Null {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// String returns a short human readable form of the type, e.g. "array<string>" or "union<null, com.acme.Order>"
+func (s *Schema) String() string {
+	switch s.Type {
+	case Record, Error, Enum, Fixed, Reference:
+		return s.Name
+	case Array:
+		return "array<" + s.Items.String() + ">"
+	case Map:
+		return "map<" + s.Values.String() + ">"
+	case Union:
+		names := make([]string, len(s.Branches))
+		for i, b := range s.Branches {
+			names[i] = b.String()
+		}
+		return "union<" + strings.Join(names, ", ") + ">"
+	}
+
+	if s.LogicalType != "" {
+		return string(s.Type) + "(" + s.LogicalType + ")"
+	}
+
+	return string(s.Type)
+}
+
+func qualify(name, namespace string) string {
+	if strings.Contains(name, ".") || namespace == "" {
+		return name
+	}
+
+	return namespace + "." + name
+}
+
+func (p *parser) parse(node interface{}, namespace string) (*Schema, error) {
+	switch n := node.(type) {
+	case string:
+		return p.parseName(n, namespace), nil
+
+	case []interface{}:
+		union := &Schema{Type: Union}
+		for _, b := range n {
+			branch, err := p.parse(b, namespace)
+			if err != nil {
+				return nil, err
+			}
+			if branch.Type == Union {
+				return nil, errInvalid("unions can not contain unions")
+			}
+			union.Branches = append(union.Branches, branch)
+		}
+		return union, nil
+
+	case map[string]interface{}:
+		return p.parseObject(n, namespace)
+	}
+
+	return nil, errInvalid("%v is not a valid type", node)
+}
+
+func (p *parser) parseName(name, namespace string) *Schema {
+	if t := Type(name); t.IsPrimitive() {
+		return &Schema{Type: t}
+	}
+
+	fullName := qualify(name, namespace)
+	if _, ok := p.named[fullName]; !ok {
+		// a name without namespace may refer to a type of the null namespace
+		if _, ok = p.named[name]; ok {
+			fullName = name
+		}
+	}
+
+	return &Schema{Type: Reference, Name: fullName}
+}
+
+func (p *parser) parseObject(obj map[string]interface{}, namespace string) (*Schema, error) {
+	rawType, ok := obj["type"]
+	if !ok {
+		return nil, errInvalid("type is required")
+	}
+
+	typ, ok := rawType.(string)
+	if !ok {
+		// {"type": {...}} or {"type": [...]}
+		return p.parse(rawType, namespace)
+	}
+
+	s := &Schema{Type: Type(typ), Props: props(obj)}
+	s.Doc, _ = obj["doc"].(string)
+	s.LogicalType, _ = obj["logicalType"].(string)
+
+	switch s.Type {
+	case Record, Error, Enum, Fixed:
+		if err := p.define(s, obj, namespace); err != nil {
+			return nil, err
+		}
+	}
+
+	switch s.Type {
+	case Record, Error:
+		return s, p.parseFields(s, obj)
+
+	case Enum:
+		symbols, ok := obj["symbols"].([]interface{})
+		if !ok {
+			return nil, errInvalid("symbols of enum %s are required", s.Name)
+		}
+		for _, sym := range symbols {
+			symbol, ok := sym.(string)
+			if !ok {
+				return nil, errInvalid("symbol %v of enum %s must be a string", sym, s.Name)
+			}
+			s.Symbols = append(s.Symbols, symbol)
+		}
+		s.Default = obj["default"]
+		return s, nil
+
+	case Fixed:
+		size, err := toInt(obj["size"])
+		if err != nil {
+			return nil, errInvalid("size of fixed %s is not valid", s.Name)
+		}
+		s.Size = size
+		return s, nil
+
+	case Array:
+		items, ok := obj["items"]
+		if !ok {
+			return nil, errInvalid("items of array are required")
+		}
+		var err error
+		s.Items, err = p.parse(items, namespace)
+		return s, err
+
+	case Map:
+		values, ok := obj["values"]
+		if !ok {
+			return nil, errInvalid("values of map are required")
+		}
+		var err error
+		s.Values, err = p.parse(values, namespace)
+		return s, err
+	}
+
+	if s.Type.IsPrimitive() {
+		return s, nil
+	}
+
+	// reference to a named type, possibly with attributes
+	ref := p.parseName(typ, namespace)
+	ref.Doc, ref.Props = s.Doc, s.Props
+	return ref, nil
+}
+
+// define sets name and aliases of a named type and registers it
+func (p *parser) define(s *Schema, obj map[string]interface{}, namespace string) error {
+	name, _ := obj["name"].(string)
+	if name == "" {
+		return errInvalid("name of %s is required", s.Type)
+	}
+
+	if ns, ok := obj["namespace"].(string); ok {
+		namespace = ns
+	}
+	s.Name = qualify(name, namespace)
+	s.Aliases = stringSlice(obj["aliases"])
+
+	if _, ok := p.named[s.Name]; ok {
+		return errInvalid("%s is defined more than once", s.Name)
+	}
+	p.named[s.Name] = s
+
+	return nil
+}
+
+func (p *parser) parseFields(s *Schema, obj map[string]interface{}) error {
+	fields, ok := obj["fields"].([]interface{})
+	if !ok {
+		return errInvalid("fields of record %s are required", s.Name)
+	}
+
+	seen := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		fieldObj, ok := f.(map[string]interface{})
+		if !ok {
+			return errInvalid("field %v of record %s must be an object", f, s.Name)
+		}
+
+		field := &Field{Props: props(fieldObj)}
+		field.Name, _ = fieldObj["name"].(string)
+		if field.Name == "" {
+			return errInvalid("name of a field of record %s is required", s.Name)
+		}
+		if seen[field.Name] {
+			return errInvalid("field %s of record %s is defined more than once", field.Name, s.Name)
+		}
+		seen[field.Name] = true
+
+		rawType, ok := fieldObj["type"]
+		if !ok {
+			return errInvalid("type of field %s.%s is required", s.Name, field.Name)
+		}
+
+		var err error
+		if field.Type, err = p.parse(rawType, s.Namespace()); err != nil {
+			return err
+		}
+
+		field.Doc, _ = fieldObj["doc"].(string)
+		field.Order, _ = fieldObj["order"].(string)
+		field.Aliases = stringSlice(fieldObj["aliases"])
+		field.Default, field.HasDefault = fieldObj["default"]
+
+		s.Fields = append(s.Fields, field)
+	}
+
+	return nil
+}
+
+// Lookup returns the named type of s or of its children with the given full name
+func (s *Schema) Lookup(name string) *Schema {
+	var found *Schema
+	s.Walk(func(path string, node *Schema, field *Field) bool {
+		if node.Type.IsNamed() && node.Name == name {
+			found = node
+			return false
+		}
+		return true
+	})
+
+	return found
+}
+
+// Walk visits s and its children depth first, path is the json path of the node, e.g. $.address.city,
+// field is the record field the node is the type of, if any. Walk stops descending when visit returns false.
+// References are not followed so recursive schemas are visited once.
+func (s *Schema) Walk(visit func(path string, node *Schema, field *Field) bool) {
+	s.walk("$", nil, visit)
+}
+
+func (s *Schema) walk(path string, field *Field, visit func(path string, node *Schema, field *Field) bool) bool {
+	if !visit(path, s, field) {
+		return false
+	}
+
+	switch s.Type {
+	case Record, Error:
+		for _, f := range s.Fields {
+			if !f.Type.walk(path+"."+f.Name, f, visit) {
+				return false
+			}
+		}
+	case Array:
+		return s.Items.walk(path+"[]", nil, visit)
+	case Map:
+		return s.Values.walk(path+"{}", nil, visit)
+	case Union:
+		for _, b := range s.Branches {
+			if !b.walk(path, field, visit) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+func props(obj map[string]interface{}) map[string]interface{} {
+	var p map[string]interface{}
+	for k, v := range obj {
+		if reservedAttributes[k] {
+			continue
+		}
+		if p == nil {
+			p = make(map[string]interface{})
+		}
+		p[k] = v
+	}
+
+	return p
+}
+
+func stringSlice(v interface{}) []string {
+	items, ok := v.([]interface{})
+	if !ok {
+		return nil
+	}
+
+	var out []string
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			out = append(out, s)
+		}
+	}
+
+	return out
+}
+
+func toInt(v interface{}) (int, error) {
+	switch n := v.(type) {
+	case json.Number:
+		i, err := n.Int64()
+		return int(i), err
+	case float64:
+		return int(n), nil
+	}
+
+	return 0, errInvalid("%v is not a number", v)
+}
@@ -0,0 +1,133 @@
+package avro
+
+import (
+	"reflect"
+	"testing"
+)
+
+func mustEqual(t *testing.T, actual, expected interface{}) {
+	t.Helper()
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %#v, but got %#v", expected, actual)
+	}
+}
+
+const orderSchema = `{
+	"type": "record",
+	"name": "Order",
+	"namespace": "com.acme",
+	"doc": "An order",
+	"fields": [
+		{"name": "id", "type": {"type": "string", "logicalType": "uuid"}, "doc": "Order id"},
+		{"name": "status", "type": {"type": "enum", "name": "Status", "symbols": ["NEW", "PAID"], "default": "NEW"}},
+		{"name": "customer", "type": ["null", {"type": "record", "name": "Customer", "namespace": "com.acme.crm", "fields": [
+			{"name": "email", "type": "string", "aliases": ["mail"]}
+		]}], "default": null},
+		{"name": "lines", "type": {"type": "array", "items": {"type": "record", "name": "Line", "fields": [
+			{"name": "amount", "type": {"type": "bytes", "logicalType": "decimal", "precision": 10, "scale": 2}}
+		]}}},
+		{"name": "previous", "type": ["null", "Order"], "default": null},
+		{"name": "attributes", "type": {"type": "map", "values": "string"}},
+		{"name": "checksum", "type": {"type": "fixed", "name": "MD5", "size": 16}}
+	]
+}`
+
+func TestParse(t *testing.T) {
+	s, err := Parse(orderSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mustEqual(t, s.Type, Record)
+	mustEqual(t, s.Name, "com.acme.Order")
+	mustEqual(t, s.ShortName(), "Order")
+	mustEqual(t, s.Namespace(), "com.acme")
+	mustEqual(t, s.Doc, "An order")
+	mustEqual(t, len(s.Fields), 7)
+
+	id := s.Fields[0]
+	mustEqual(t, id.Doc, "Order id")
+	mustEqual(t, id.Type.String(), "string(uuid)")
+
+	status := s.Fields[1].Type
+	mustEqual(t, status.Name, "com.acme.Status")
+	mustEqual(t, status.Symbols, []string{"NEW", "PAID"})
+	mustEqual(t, status.Default, "NEW")
+
+	customer := s.Fields[2]
+	mustEqual(t, customer.HasDefault, true)
+	mustEqual(t, customer.Default, nil)
+	mustEqual(t, customer.Type.IsNullable(), true)
+	mustEqual(t, customer.Type.String(), "union<null, com.acme.crm.Customer>")
+	mustEqual(t, customer.Type.Branches[1].Fields[0].Aliases, []string{"mail"})
+
+	amount := s.Fields[3].Type.Items.Fields[0]
+	mustEqual(t, s.Fields[3].Type.Items.Name, "com.acme.Line")
+	mustEqual(t, amount.Type.LogicalType, "decimal")
+	mustEqual(t, len(amount.Type.Props), 2)
+
+	mustEqual(t, s.Fields[4].Type.Branches[1], &Schema{Type: Reference, Name: "com.acme.Order"})
+	mustEqual(t, s.Fields[5].Type.String(), "map<string>")
+	mustEqual(t, s.Fields[6].Type.Size, 16)
+
+	mustEqual(t, s.Lookup("com.acme.crm.Customer"), customer.Type.Branches[1])
+	if s.Lookup("com.acme.Unknown") != nil {
+		t.Error("unknown type must not be found")
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	invalid := []string{
+		``,
+		`loremipsum`,
+		`{"name": "x"}`,
+		`{"type": "record", "fields": []}`,
+		`{"type": "record", "name": "r"}`,
+		`{"type": "record", "name": "r", "fields": [{"name": "a"}]}`,
+		`{"type": "record", "name": "r", "fields": [{"name": "a", "type": "int"}, {"name": "a", "type": "int"}]}`,
+		`{"type": "enum", "name": "e"}`,
+		`{"type": "array"}`,
+		`{"type": "map"}`,
+		`{"type": "fixed", "name": "f"}`,
+		`[["null"]]`,
+		`"int" "int"`,
+		`{"type": "record", "name": "r", "fields": [{"name": "a", "type": {"type": "enum", "name": "r", "symbols": []}}]}`,
+	}
+
+	for _, schema := range invalid {
+		if _, err := Parse(schema); err == nil {
+			t.Errorf("%q must be invalid", schema)
+		}
+	}
+}
+
+func TestSchema_Walk(t *testing.T) {
+	s, err := Parse(orderSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var paths []string
+	s.Walk(func(path string, node *Schema, field *Field) bool {
+		if node.Type == Record || node.Type == Union {
+			return true
+		}
+		paths = append(paths, path+" "+node.String())
+		return true
+	})
+
+	mustEqual(t, paths, []string{
+		"$.id string(uuid)",
+		"$.status com.acme.Status",
+		"$.customer null",
+		"$.customer.email string",
+		"$.lines array<com.acme.Line>",
+		"$.lines[].amount bytes(decimal)",
+		"$.previous null",
+		"$.previous com.acme.Order",
+		"$.attributes map<string>",
+		"$.attributes{} string",
+		"$.checksum com.acme.MD5",
+	})
+}
@@ -0,0 +1,155 @@
+package backup
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/ybalcin/event-schema-manager/pkg/schemaregistry"
+)
+
+type (
+	// Archive is a portable copy of a registry. The compatibility level and mode of a subject are set only when
+	// they differ from the global ones.
+	Archive struct {
+		Format             int       `json:"format"`
+		CreatedAt          time.Time `json:"createdAt"`
+		CompatibilityLevel string    `json:"compatibilityLevel"`
+		Mode               string    `json:"mode"`
+		Subjects           []Subject `json:"subjects"`
+	}
+
+	Subject struct {
+		Name               string    `json:"name"`
+		CompatibilityLevel string    `json:"compatibilityLevel,omitempty"`
+		Mode               string    `json:"mode,omitempty"`
+		Versions           []Version `json:"versions"`
+	}
+
+	// Version is a version of a subject with the id of its schema
+	Version struct {
+		Version    int                        `json:"version"`
+		ID         int                        `json:"id"`
+		SchemaType string                     `json:"schemaType,omitempty"`
+		Schema     string                     `json:"schema"`
+		References []schemaregistry.Reference `json:"references,omitempty"`
+		Metadata   *schemaregistry.Metadata   `json:"metadata,omitempty"`
+		RuleSet    *schemaregistry.RuleSet    `json:"ruleSet,omitempty"`
+	}
+)
+
+// archiveFormat is the version of the archive format, archives of other formats can not be read
+const archiveFormat = 1
+
+var (
+	ErrInvalidArchive = errors.New("invalid archive")
+
+	errInvalidArchive = func(format string, args ...interface{}) error {
+		return fmt.Errorf("%w: %s", ErrInvalidArchive, fmt.Sprintf(format, args...))
+	}
+)
+
+func newVersion(sc *schemaregistry.Schema) Version {
+	return Version{
+		Version:    sc.Version,
+		ID:         sc.ID,
+		SchemaType: sc.SchemaType,
+		Schema:     sc.Schema,
+		References: sc.References,
+		Metadata:   sc.Metadata,
+		RuleSet:    sc.RuleSet,
+	}
+}
+
+// schema returns the version as a schema to be imported
+func (v Version) schema() schemaregistry.Schema {
+	return schemaregistry.Schema{
+		Schema:     v.Schema,
+		Version:    v.Version,
+		ID:         v.ID,
+		SchemaType: v.SchemaType,
+		References: v.References,
+		Metadata:   v.Metadata,
+		RuleSet:    v.RuleSet,
+	}
+}
+
+// compatibilityLevelOf returns the compatibility level of s, the global level if it has none
+func (a *Archive) compatibilityLevelOf(s *Subject) string {
+	if s.CompatibilityLevel != "" {
+		return s.CompatibilityLevel
+	}
+
+	return a.CompatibilityLevel
+}
+
+// modeOf returns the mode of s, the global mode if it has none
+func (a *Archive) modeOf(s *Subject) string {
+	if s.Mode != "" {
+		return s.Mode
+	}
+
+	return a.Mode
+}
+
+// Versions returns the number of versions of all subjects
+func (a *Archive) Versions() int {
+	n := 0
+	for _, s := range a.Subjects {
+		n += len(s.Versions)
+	}
+
+	return n
+}
+
+// Validate checks that the archive can be restored: subjects are unique, versions are ascending and have ids
+func (a *Archive) Validate() error {
+	if a.Format != archiveFormat {
+		return errInvalidArchive("format %d is not supported", a.Format)
+	}
+
+	names := make(map[string]bool, len(a.Subjects))
+	for _, s := range a.Subjects {
+		if s.Name == "" {
+			return errInvalidArchive("subject name is required")
+		}
+		if names[s.Name] {
+			return errInvalidArchive("subject %s is duplicated", s.Name)
+		}
+		names[s.Name] = true
+
+		for i, v := range s.Versions {
+			if v.Version <= 0 || v.ID <= 0 || v.Schema == "" {
+				return errInvalidArchive("version %d of %s needs a version, an id and a schema", i+1, s.Name)
+			}
+			if i > 0 && v.Version <= s.Versions[i-1].Version {
+				return errInvalidArchive("versions of %s are not ascending", s.Name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Write writes the archive as indented json
+func (a *Archive) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(a)
+}
+
+// ReadArchive reads and validates an archive written by Write
+func ReadArchive(r io.Reader) (*Archive, error) {
+	var a Archive
+	if err := json.NewDecoder(r).Decode(&a); err != nil {
+		return nil, errInvalidArchive("%v", err)
+	}
+
+	if err := a.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &a, nil
+}
@@ -0,0 +1,228 @@
+package backup
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/ybalcin/event-schema-manager/pkg/schemaregistry"
+	"github.com/ybalcin/event-schema-manager/pkg/schemaregistry/schemaregistrytest"
+)
+
+const (
+	addressSchema = `{"type":"record","name":"Address","namespace":"com.acme","fields":[{"name":"city","type":"string"}]}`
+	orderSchema   = `{"type":"record","name":"Order","fields":[{"name":"address","type":"com.acme.Address"}]}`
+)
+
+// failingLevels fails setting compatibility levels
+type failingLevels struct {
+	*schemaregistrytest.Registry
+}
+
+func (failingLevels) SetCompatibilityLevel(subject string, level string) (string, error) {
+	return "", errors.New("registry is down")
+}
+
+func mustEqual(t *testing.T, actual, expected interface{}) {
+	t.Helper()
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("expected %#v, got %#v", expected, actual)
+	}
+}
+
+func mustNoError(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// newSource returns a registry with a reference, a deleted version and a subject with its own level and mode
+func newSource(t *testing.T) *schemaregistrytest.Registry {
+	r := schemaregistrytest.NewRegistry()
+
+	register := func(subject string, sc schemaregistry.Schema) {
+		_, err := r.RegisterSchema(subject, sc)
+		mustNoError(t, err)
+	}
+	register("address-value", schemaregistry.Schema{Schema: addressSchema})
+	register("users-value", schemaregistry.Schema{Schema: `{"type":"string"}`})
+	register("users-value", schemaregistry.Schema{Schema: `{"type":"int"}`})
+	register("users-value", schemaregistry.Schema{Schema: `{"type":"long"}`})
+	register("orders-value", schemaregistry.Schema{
+		Schema:     orderSchema,
+		References: []schemaregistry.Reference{{Name: "com.acme.Address", Subject: "address-value", Version: 1}},
+	})
+	register("events-value", schemaregistry.Schema{Schema: `{"type":"object"}`, SchemaType: "JSON"})
+
+	_, err := r.DeleteSchemaVersion("users-value", "2")
+	mustNoError(t, err)
+	_, err = r.SetCompatibilityLevel("orders-value", schemaregistry.CompatibilityFull)
+	mustNoError(t, err)
+	_, err = r.SetMode("events-value", schemaregistry.ModeReadOnly)
+	mustNoError(t, err)
+
+	return r
+}
+
+func TestExport(t *testing.T) {
+	a, err := Export(newSource(t))
+	mustNoError(t, err)
+
+	mustEqual(t, a.Format, archiveFormat)
+	mustEqual(t, a.CompatibilityLevel, schemaregistry.CompatibilityBackward)
+	mustEqual(t, a.Mode, schemaregistry.ModeReadWrite)
+	mustEqual(t, a.Versions(), 5)
+
+	var names []string
+	for _, s := range a.Subjects {
+		names = append(names, s.Name)
+	}
+	mustEqual(t, names, []string{"address-value", "events-value", "orders-value", "users-value"})
+
+	mustEqual(t, a.Subjects[1].Mode, schemaregistry.ModeReadOnly)
+	mustEqual(t, a.Subjects[1].Versions[0].SchemaType, "JSON")
+	mustEqual(t, a.Subjects[2].CompatibilityLevel, schemaregistry.CompatibilityFull)
+	mustEqual(t, a.Subjects[2].Versions[0].References[0].Subject, "address-value")
+	mustEqual(t, a.Subjects[3].Versions, []Version{
+		{Version: 1, ID: 2, Schema: `{"type":"string"}`},
+		{Version: 3, ID: 4, Schema: `{"type":"long"}`},
+	})
+}
+
+func TestArchive_WriteRead(t *testing.T) {
+	a, err := Export(newSource(t))
+	mustNoError(t, err)
+
+	var b bytes.Buffer
+	mustNoError(t, a.Write(&b))
+	read, err := ReadArchive(&b)
+	mustNoError(t, err)
+	mustEqual(t, read.CreatedAt.Equal(a.CreatedAt), true)
+	read.CreatedAt = a.CreatedAt
+	mustEqual(t, read, a)
+
+	_, err = ReadArchive(bytes.NewBufferString(`{"format": 2}`))
+	mustEqual(t, errors.Is(err, ErrInvalidArchive), true)
+
+	_, err = ReadArchive(bytes.NewBufferString(`{"format": 1, "subjects": [
+		{"name": "a", "versions": [{"version": 2, "id": 1, "schema": "{}"}, {"version": 1, "id": 2, "schema": "{}"}]}
+	]}`))
+	mustEqual(t, errors.Is(err, ErrInvalidArchive), true)
+}
+
+func TestRestore(t *testing.T) {
+	source := newSource(t)
+	a, err := Export(source)
+	mustNoError(t, err)
+
+	target := schemaregistrytest.NewRegistry()
+	result, err := Restore(target, a)
+	mustNoError(t, err)
+	mustEqual(t, result, &Result{Subjects: 4, Versions: 5})
+
+	sc, err := target.GetSchemaByVersion("users-value", "3")
+	mustNoError(t, err)
+	mustEqual(t, sc.ID, 4)
+
+	versions, err := target.Versions("users-value")
+	mustNoError(t, err)
+	mustEqual(t, versions, []int{1, 3})
+
+	mode, _ := target.GetMode("")
+	mustEqual(t, mode, schemaregistry.ModeReadWrite)
+	mode, _ = target.GetMode("events-value")
+	mustEqual(t, mode, schemaregistry.ModeReadOnly)
+	level, _ := target.GetCompatibilityLevel("orders-value")
+	mustEqual(t, level, schemaregistry.CompatibilityFull)
+
+	// new schemas get ids after the restored ones
+	id, err := target.RegisterSchema("users-value", schemaregistry.Schema{Schema: `{"type":"double"}`})
+	mustNoError(t, err)
+	mustEqual(t, id, 7)
+}
+
+func TestRestore_ResetsModeOnFailure(t *testing.T) {
+	a, err := Export(newSource(t))
+	mustNoError(t, err)
+
+	target := schemaregistrytest.NewRegistry()
+	_, err = target.RegisterSchema("other-value", schemaregistry.Schema{Schema: `{"type":"boolean"}`})
+	mustNoError(t, err)
+
+	result, err := Restore(target, a)
+	if err == nil {
+		t.Fatal("restoring over a taken id must fail")
+	}
+	mustEqual(t, result.Versions, 0)
+
+	mode, _ := target.GetMode("")
+	mustEqual(t, mode, schemaregistry.ModeReadWrite)
+
+	// the mode is reset when the configs can not be set after the import
+	target = schemaregistrytest.NewRegistry()
+	if _, err = Restore(failingLevels{target}, a); err == nil {
+		t.Fatal("restoring the configs must fail")
+	}
+	mode, _ = target.GetMode("")
+	mustEqual(t, mode, schemaregistry.ModeReadWrite)
+}
+
+func TestVerify(t *testing.T) {
+	source := newSource(t)
+	a, err := Export(source)
+	mustNoError(t, err)
+
+	mismatches, err := Verify(source, a)
+	mustNoError(t, err)
+	mustEqual(t, len(mismatches), 0)
+
+	_, err = source.DeleteSubject("address-value")
+	mustNoError(t, err)
+	_, err = source.SetMode("users-value", schemaregistry.ModeReadOnly)
+	mustNoError(t, err)
+	_, err = source.RegisterSchema("orders-value", schemaregistry.Schema{Schema: `{"type":"null"}`})
+	mustNoError(t, err)
+
+	mismatches, err = Verify(source, a)
+	mustNoError(t, err)
+	mustEqual(t, mismatches, []Mismatch{
+		{Subject: "address-value", Reason: "subject is missing"},
+		{Subject: "orders-value", Version: 2, Reason: "version is not in the archive"},
+		{Subject: "users-value", Reason: "mode is READONLY instead of READWRITE"},
+	})
+	mustEqual(t, mismatches[1].String(), "orders-value version 2: version is not in the archive")
+}
+
+func TestImportOrder(t *testing.T) {
+	ref := func(subject string, version int) []schemaregistry.Reference {
+		return []schemaregistry.Reference{{Name: subject, Subject: subject, Version: version}}
+	}
+	a := &Archive{Format: archiveFormat, Subjects: []Subject{
+		{Name: "orders-value", Versions: []Version{
+			{Version: 1, ID: 1, Schema: "o1", References: ref("address-value", 2)},
+			{Version: 2, ID: 2, Schema: "o2"},
+		}},
+		{Name: "address-value", Versions: []Version{
+			{Version: 1, ID: 3, Schema: "a1"},
+			{Version: 2, ID: 4, Schema: "a2"},
+		}},
+		{Name: "users-value", Versions: []Version{
+			{Version: 1, ID: 5, Schema: "u1", References: ref("external-value", 1)},
+		}},
+	}}
+
+	entries, err := importOrder(a)
+	mustNoError(t, err)
+
+	var order []string
+	for _, e := range entries {
+		order = append(order, e.version.Schema)
+	}
+	mustEqual(t, order, []string{"a1", "a2", "o1", "o2", "u1"})
+
+	a.Subjects[1].Versions[0].References = ref("orders-value", 2)
+	_, err = importOrder(a)
+	mustEqual(t, errors.Is(err, ErrReferenceCycle), true)
+}
@@ -0,0 +1,82 @@
+package backup
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/ybalcin/event-schema-manager/pkg/schemaregistry"
+)
+
+// Export reads every version of every subject with the compatibility levels and modes of the registry
+func Export(client schemaregistry.Client) (*Archive, error) {
+	level, err := client.GetCompatibilityLevel("")
+	if err != nil {
+		return nil, err
+	}
+	mode, err := client.GetMode("")
+	if err != nil {
+		return nil, err
+	}
+
+	subjects, err := client.Subjects()
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(subjects)
+
+	a := &Archive{
+		Format:             archiveFormat,
+		CreatedAt:          time.Now().UTC(),
+		CompatibilityLevel: level,
+		Mode:               mode,
+		Subjects:           make([]Subject, 0, len(subjects)),
+	}
+	for _, name := range subjects {
+		s, err := exportSubject(client, a, name)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		a.Subjects = append(a.Subjects, s)
+	}
+
+	return a, nil
+}
+
+// exportSubject reads the versions of subject name, its level and mode are kept if they differ from the ones of a
+func exportSubject(client schemaregistry.Client, a *Archive, name string) (Subject, error) {
+	s := Subject{Name: name}
+
+	versions, err := client.Versions(name)
+	if err != nil {
+		return Subject{}, err
+	}
+	sort.Ints(versions)
+
+	for _, v := range versions {
+		sc, err := client.GetSchemaByVersion(name, strconv.Itoa(v))
+		if err != nil {
+			return Subject{}, err
+		}
+		s.Versions = append(s.Versions, newVersion(sc))
+	}
+
+	level, err := client.GetCompatibilityLevel(name)
+	if err != nil {
+		return Subject{}, err
+	}
+	if level != a.CompatibilityLevel {
+		s.CompatibilityLevel = level
+	}
+
+	mode, err := client.GetMode(name)
+	if err != nil {
+		return Subject{}, err
+	}
+	if mode != a.Mode {
+		s.Mode = mode
+	}
+
+	return s, nil
+}
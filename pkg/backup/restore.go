@@ -0,0 +1,212 @@
+package backup
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/ybalcin/event-schema-manager/pkg/schemaregistry"
+)
+
+type (
+	// Result is the outcome of a restore, Mismatches are the differences found by the verification
+	Result struct {
+		Subjects   int        `json:"subjects"`
+		Versions   int        `json:"versions"`
+		Mismatches []Mismatch `json:"mismatches,omitempty"`
+	}
+
+	// Mismatch is a difference between an archive and a registry, Version is 0 for differences of the subject
+	Mismatch struct {
+		Subject string `json:"subject"`
+		Version int    `json:"version,omitempty"`
+		Reason  string `json:"reason"`
+	}
+
+	// entry is a version of a subject in import order
+	entry struct {
+		subject *Subject
+		version *Version
+	}
+)
+
+var (
+	ErrReferenceCycle     = errors.New("schema references form a cycle")
+	ErrIDMismatch         = errors.New("schema is not imported with its id")
+	ErrVerificationFailed = errors.New("restored registry differs from the archive")
+)
+
+// Restore switches the registry to IMPORT mode and imports every version of the archive with its id and version,
+// referenced schemas before the schemas referencing them. The compatibility levels and modes of the archive are
+// set afterwards and the registry is verified against the archive. The mode of the registry is reset if the
+// import or setting the configs fails.
+func Restore(client schemaregistry.Client, a *Archive) (*Result, error) {
+	if err := a.Validate(); err != nil {
+		return nil, err
+	}
+
+	entries, err := importOrder(a)
+	if err != nil {
+		return nil, err
+	}
+
+	previousMode, err := client.GetMode("")
+	if err != nil {
+		return nil, err
+	}
+	if _, err = client.SetMode("", schemaregistry.ModeImport); err != nil {
+		return nil, err
+	}
+
+	result := &Result{Subjects: len(a.Subjects)}
+	if err = importEntries(client, entries, result); err != nil {
+		return result, resetMode(client, previousMode, err)
+	}
+	if err = restoreConfigs(client, a); err != nil {
+		return result, resetMode(client, previousMode, err)
+	}
+
+	if result.Mismatches, err = Verify(client, a); err != nil {
+		return result, err
+	}
+	if n := len(result.Mismatches); n > 0 {
+		return result, fmt.Errorf("%w: %d mismatches", ErrVerificationFailed, n)
+	}
+
+	return result, nil
+}
+
+// resetMode sets the global mode back to mode after a restore failed with err so the registry does not stay
+// in IMPORT mode
+func resetMode(client schemaregistry.Client, mode string, err error) error {
+	if _, resetErr := client.SetMode("", mode); resetErr != nil {
+		return fmt.Errorf("%w, resetting mode %s failed: %v", err, mode, resetErr)
+	}
+
+	return err
+}
+
+func importEntries(client schemaregistry.Client, entries []entry, result *Result) error {
+	for _, e := range entries {
+		id, err := client.ImportSchema(e.subject.Name, e.version.schema())
+		if err != nil {
+			return fmt.Errorf("%s version %d: %w", e.subject.Name, e.version.Version, err)
+		}
+		if id != e.version.ID {
+			return fmt.Errorf("%w: %s version %d got id %d instead of %d",
+				ErrIDMismatch, e.subject.Name, e.version.Version, id, e.version.ID)
+		}
+		result.Versions++
+	}
+
+	return nil
+}
+
+// restoreConfigs sets the compatibility levels and the modes of the archive, the global mode is set last to leave
+// the IMPORT mode
+func restoreConfigs(client schemaregistry.Client, a *Archive) error {
+	if _, err := client.SetCompatibilityLevel("", a.CompatibilityLevel); err != nil {
+		return err
+	}
+
+	for _, s := range a.Subjects {
+		if s.CompatibilityLevel != "" {
+			if _, err := client.SetCompatibilityLevel(s.Name, s.CompatibilityLevel); err != nil {
+				return fmt.Errorf("%s: %w", s.Name, err)
+			}
+		}
+		if s.Mode != "" {
+			if _, err := client.SetMode(s.Name, s.Mode); err != nil {
+				return fmt.Errorf("%s: %w", s.Name, err)
+			}
+		}
+	}
+
+	_, err := client.SetMode("", a.Mode)
+	return err
+}
+
+// importOrder orders the versions of the archive by id, moving the previous versions of a subject and the
+// referenced versions before the versions depending on them
+func importOrder(a *Archive) ([]entry, error) {
+	var all []entry
+	index := make(map[string]int)
+	for i := range a.Subjects {
+		s := &a.Subjects[i]
+		for j := range s.Versions {
+			index[keyOf(s.Name, s.Versions[j].Version)] = len(all)
+			all = append(all, entry{subject: s, version: &s.Versions[j]})
+		}
+	}
+
+	byID := make([]int, len(all))
+	for i := range byID {
+		byID[i] = i
+	}
+	sort.SliceStable(byID, func(i, j int) bool { return all[byID[i]].version.ID < all[byID[j]].version.ID })
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make([]int, len(all))
+	ordered := make([]entry, 0, len(all))
+
+	var visit func(i int) error
+	visit = func(i int) error {
+		switch state[i] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("%w: %s version %d", ErrReferenceCycle, all[i].subject.Name, all[i].version.Version)
+		}
+		state[i] = visiting
+
+		e := all[i]
+		var dependencies []int
+		if p := previousVersion(e); p != 0 {
+			dependencies = append(dependencies, index[keyOf(e.subject.Name, p)])
+		}
+		// references to versions outside of the archive have to be in the registry already
+		for _, ref := range e.version.References {
+			if j, ok := index[keyOf(ref.Subject, ref.Version)]; ok {
+				dependencies = append(dependencies, j)
+			}
+		}
+		for _, j := range dependencies {
+			if err := visit(j); err != nil {
+				return err
+			}
+		}
+
+		state[i] = visited
+		ordered = append(ordered, e)
+		return nil
+	}
+
+	for _, i := range byID {
+		if err := visit(i); err != nil {
+			return nil, err
+		}
+	}
+
+	return ordered, nil
+}
+
+// previousVersion returns the version of the subject of e before it, 0 for the first version
+func previousVersion(e entry) int {
+	versions := e.subject.Versions
+	for i := range versions {
+		if versions[i].Version == e.version.Version && i > 0 {
+			return versions[i-1].Version
+		}
+	}
+
+	return 0
+}
+
+func keyOf(subject string, version int) string {
+	return subject + "@" + strconv.Itoa(version)
+}
@@ -0,0 +1,104 @@
+package backup
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/ybalcin/event-schema-manager/pkg/schemaregistry"
+)
+
+// Verify compares the subjects of the archive with the registry: the versions with their ids, schemas and
+// references, the compatibility levels and the modes. Subjects of the registry that are not in the archive are
+// not compared.
+func Verify(client schemaregistry.Client, a *Archive) ([]Mismatch, error) {
+	actual := &Archive{}
+
+	var err error
+	if actual.CompatibilityLevel, err = client.GetCompatibilityLevel(""); err != nil {
+		return nil, err
+	}
+	if actual.Mode, err = client.GetMode(""); err != nil {
+		return nil, err
+	}
+
+	var mismatches []Mismatch
+	if actual.CompatibilityLevel != a.CompatibilityLevel {
+		mismatches = append(mismatches, Mismatch{Reason: differ("compatibility level", a.CompatibilityLevel, actual.CompatibilityLevel)})
+	}
+	if actual.Mode != a.Mode {
+		mismatches = append(mismatches, Mismatch{Reason: differ("mode", a.Mode, actual.Mode)})
+	}
+
+	for i := range a.Subjects {
+		expected := &a.Subjects[i]
+
+		s, err := exportSubject(client, actual, expected.Name)
+		if schemaregistry.IsSubjectNotFound(err) {
+			mismatches = append(mismatches, Mismatch{Subject: expected.Name, Reason: "subject is missing"})
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", expected.Name, err)
+		}
+
+		mismatches = append(mismatches, compareSubject(a, expected, actual, &s)...)
+	}
+
+	return mismatches, nil
+}
+
+func compareSubject(a *Archive, expected *Subject, actualArchive *Archive, actual *Subject) []Mismatch {
+	var mismatches []Mismatch
+	mismatch := func(version int, reason string) {
+		mismatches = append(mismatches, Mismatch{Subject: expected.Name, Version: version, Reason: reason})
+	}
+
+	if e, got := a.compatibilityLevelOf(expected), actualArchive.compatibilityLevelOf(actual); e != got {
+		mismatch(0, differ("compatibility level", e, got))
+	}
+	if e, got := a.modeOf(expected), actualArchive.modeOf(actual); e != got {
+		mismatch(0, differ("mode", e, got))
+	}
+
+	versions := make(map[int]Version, len(actual.Versions))
+	for _, v := range actual.Versions {
+		versions[v.Version] = v
+	}
+	for _, e := range expected.Versions {
+		got, ok := versions[e.Version]
+		delete(versions, e.Version)
+		switch {
+		case !ok:
+			mismatch(e.Version, "version is missing")
+		case got.ID != e.ID:
+			mismatch(e.Version, fmt.Sprintf("id is %d instead of %d", got.ID, e.ID))
+		case got.Schema != e.Schema || got.SchemaType != e.SchemaType:
+			mismatch(e.Version, "schema differs")
+		case !reflect.DeepEqual(got.References, e.References):
+			mismatch(e.Version, "references differ")
+		}
+	}
+	for _, got := range actual.Versions {
+		if _, ok := versions[got.Version]; ok {
+			mismatch(got.Version, "version is not in the archive")
+		}
+	}
+
+	return mismatches
+}
+
+func differ(what, expected, actual string) string {
+	return fmt.Sprintf("%s is %s instead of %s", what, actual, expected)
+}
+
+// String describes the mismatch
+func (m Mismatch) String() string {
+	switch {
+	case m.Subject == "":
+		return m.Reason
+	case m.Version == 0:
+		return m.Subject + ": " + m.Reason
+	default:
+		return fmt.Sprintf("%s version %d: %s", m.Subject, m.Version, m.Reason)
+	}
+}
@@ -0,0 +1,109 @@
+package gitops
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+type (
+	Action string
+
+	// Change is the difference between a subject in the registry and its file
+	Change struct {
+		Subject string
+		Action  Action
+		// File is the schema file of the subject, empty for orphaned subjects
+		File *File
+		// LatestVersion is the latest registered version, 0 if the subject is new
+		LatestVersion int
+		// Versions are the registered versions of an orphaned subject
+		Versions []int
+		// Reasons are the reasons of an incompatible change
+		Reasons []string
+	}
+
+	// Plan is the set of changes that reconciles the registry with the schema files, sorted by subject
+	Plan struct {
+		Changes []Change
+		// Prune deletes orphaned subjects when the plan is applied
+		Prune bool
+	}
+)
+
+const (
+	// ActionCreate registers the first version of a subject
+	ActionCreate Action = "create"
+	// ActionUpdate registers a new version of a subject
+	ActionUpdate Action = "update"
+	// ActionUnchanged is a subject whose schema is registered already
+	ActionUnchanged Action = "unchanged"
+	// ActionIncompatible is a schema that is not compatible with the latest version, it blocks the plan
+	ActionIncompatible Action = "incompatible"
+	// ActionOrphaned is a registered subject without a file, it is deleted if the plan prunes
+	ActionOrphaned Action = "orphaned"
+)
+
+// Count returns the number of changes with action
+func (p *Plan) Count(action Action) int {
+	n := 0
+	for _, c := range p.Changes {
+		if c.Action == action {
+			n++
+		}
+	}
+
+	return n
+}
+
+// HasChanges returns true if applying the plan changes the registry
+func (p *Plan) HasChanges() bool {
+	return p.Count(ActionCreate)+p.Count(ActionUpdate) > 0 || (p.Prune && p.Count(ActionOrphaned) > 0)
+}
+
+// Write prints the plan like terraform plan, unchanged subjects are only counted
+func (p *Plan) Write(w io.Writer) error {
+	var b strings.Builder
+
+	for _, c := range p.Changes {
+		switch c.Action {
+		case ActionCreate:
+			fmt.Fprintf(&b, "  + %s\n      new subject from %s\n", c.Subject, c.File.Path)
+		case ActionUpdate:
+			// the registry numbers the new version, deleted versions are not reused so it is not always latest+1
+			fmt.Fprintf(&b, "  ~ %s\n      new version after version %d from %s\n", c.Subject, c.LatestVersion, c.File.Path)
+		case ActionIncompatible:
+			fmt.Fprintf(&b, "  ! %s\n      %s is not compatible with version %d\n", c.Subject, c.File.Path, c.LatestVersion)
+			for _, r := range c.Reasons {
+				fmt.Fprintf(&b, "        - %s\n", r)
+			}
+		case ActionOrphaned:
+			if p.Prune {
+				fmt.Fprintf(&b, "  - %s\n      orphaned, %d versions will be deleted\n", c.Subject, len(c.Versions))
+			} else {
+				fmt.Fprintf(&b, "  ? %s\n      orphaned, it has no schema file\n", c.Subject)
+			}
+		}
+	}
+
+	if b.Len() > 0 {
+		b.WriteString("\n")
+	}
+
+	deleted := 0
+	if p.Prune {
+		deleted = p.Count(ActionOrphaned)
+	}
+	fmt.Fprintf(&b, "Plan: %d to create, %d to update, %d to delete, %d unchanged",
+		p.Count(ActionCreate), p.Count(ActionUpdate), deleted, p.Count(ActionUnchanged))
+	if n := p.Count(ActionIncompatible); n > 0 {
+		fmt.Fprintf(&b, ", %d incompatible", n)
+	}
+	if n := p.Count(ActionOrphaned); n > 0 && !p.Prune {
+		fmt.Fprintf(&b, ", %d orphaned", n)
+	}
+	b.WriteString(".\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
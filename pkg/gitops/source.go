@@ -0,0 +1,155 @@
+package gitops
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+type (
+	// File is a schema file mapped to its subject
+	File struct {
+		Subject    string
+		Path       string
+		SchemaType string
+		Schema     string
+	}
+
+	// Manifest maps schema files to subjects explicitly, paths are relative to the directory of the manifest
+	Manifest struct {
+		Subjects []ManifestEntry `json:"subjects"`
+	}
+
+	ManifestEntry struct {
+		Subject    string `json:"subject"`
+		File       string `json:"file"`
+		SchemaType string `json:"schemaType,omitempty"`
+	}
+)
+
+// ManifestFileName is the manifest looked up in the root of a schema directory
+const ManifestFileName = "schemas.json"
+
+const (
+	SchemaTypeAvro     = "AVRO"
+	SchemaTypeJSON     = "JSON"
+	SchemaTypeProtobuf = "PROTOBUF"
+)
+
+// schemaTypes are the schema types of the file extensions
+var schemaTypes = map[string]string{
+	".avsc":  SchemaTypeAvro,
+	".json":  SchemaTypeJSON,
+	".proto": SchemaTypeProtobuf,
+}
+
+// LoadDir reads the schema files of dir. If dir has a manifest the files are mapped by it, otherwise every
+// .avsc, .json and .proto file is mapped to the subject named as the file without its extension,
+// e.g. orders/orders-value.avsc is the subject orders-value.
+func LoadDir(dir string) ([]File, error) {
+	b, err := ioutil.ReadFile(filepath.Join(dir, ManifestFileName))
+	if os.IsNotExist(err) {
+		return loadByConvention(dir)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest Manifest
+	if err = json.Unmarshal(b, &manifest); err != nil {
+		return nil, fmt.Errorf("%s: %w", ManifestFileName, err)
+	}
+
+	files := make([]File, 0, len(manifest.Subjects))
+	for _, e := range manifest.Subjects {
+		if e.Subject == "" || e.File == "" {
+			return nil, fmt.Errorf("%s: subject and file are required, got %+v", ManifestFileName, e)
+		}
+
+		schemaType := e.SchemaType
+		if schemaType == "" {
+			schemaType = schemaTypes[strings.ToLower(filepath.Ext(e.File))]
+		}
+		if schemaType == "" {
+			return nil, fmt.Errorf("%s: schema type of %s is required", ManifestFileName, e.File)
+		}
+
+		f, err := readFile(dir, e.File, e.Subject, schemaType)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+
+	return sorted(files)
+}
+
+func loadByConvention(dir string) ([]File, error) {
+	var files []File
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			// hidden directories like .git are not schemas
+			if path != dir && strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		ext := strings.ToLower(filepath.Ext(path))
+		schemaType, ok := schemaTypes[ext]
+		if !ok {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		f, err := readFile(dir, rel, strings.TrimSuffix(info.Name(), filepath.Ext(info.Name())), schemaType)
+		if err != nil {
+			return err
+		}
+		files = append(files, f)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return sorted(files)
+}
+
+func readFile(dir, rel, subject, schemaType string) (File, error) {
+	b, err := ioutil.ReadFile(filepath.Join(dir, rel))
+	if err != nil {
+		return File{}, err
+	}
+
+	return File{
+		Subject:    subject,
+		Path:       filepath.ToSlash(rel),
+		SchemaType: schemaType,
+		Schema:     strings.TrimSpace(string(b)),
+	}, nil
+}
+
+// sorted sorts files by subject and rejects subjects mapped to more than one file
+func sorted(files []File) ([]File, error) {
+	sort.Slice(files, func(i, j int) bool { return files[i].Subject < files[j].Subject })
+
+	for i := 1; i < len(files); i++ {
+		if files[i].Subject == files[i-1].Subject {
+			return nil, fmt.Errorf("subject %s is mapped to both %s and %s", files[i].Subject, files[i-1].Path, files[i].Path)
+		}
+	}
+
+	return files, nil
+}
@@ -0,0 +1,196 @@
+package gitops
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ybalcin/event-schema-manager/pkg/schemaregistry"
+)
+
+type (
+	// Syncer reconciles the registry with schema files through a schema registry client
+	Syncer struct {
+		client schemaregistry.Client
+		prune  bool
+		// prefixes are the subjects managed by the files, only they are orphaned
+		prefixes []string
+	}
+
+	Option func(*Syncer)
+
+	// Result is a change that is applied, ID is the id of the registered schema
+	Result struct {
+		Change
+		ID int
+	}
+)
+
+var (
+	// ErrIncompatible is returned when a plan with incompatible changes is applied
+	ErrIncompatible = errors.New("plan has incompatible changes")
+	// ErrUnscopedPrune is returned when a plan prunes without subject prefixes
+	ErrUnscopedPrune = errors.New("pruning requires subject prefixes")
+)
+
+// WithPrune makes plans delete the orphaned subjects starting with one of prefixes, the registered subjects
+// outside of the prefixes are not managed by the files and are not orphaned
+func WithPrune(prefixes ...string) Option {
+	return func(s *Syncer) {
+		s.prune = true
+		s.prefixes = prefixes
+	}
+}
+
+func NewSyncer(client schemaregistry.Client, opts ...Option) *Syncer {
+	s := &Syncer{client: client}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Plan compares files with the registry, the registered subjects without a file are orphaned. Pruning plans
+// orphan only the subjects of their prefixes and require at least one so a partial directory can not delete
+// the subjects of others.
+func (s *Syncer) Plan(files []File) (*Plan, error) {
+	if s.prune && len(s.prefixes) == 0 {
+		return nil, ErrUnscopedPrune
+	}
+	plan := &Plan{Prune: s.prune}
+
+	desired := make(map[string]bool, len(files))
+	for i := range files {
+		f := &files[i]
+		desired[f.Subject] = true
+
+		change, err := s.changeOf(f)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.Subject, err)
+		}
+		plan.Changes = append(plan.Changes, change)
+	}
+
+	subjects, err := s.client.Subjects()
+	if err != nil {
+		return nil, err
+	}
+	for _, subject := range subjects {
+		if desired[subject] || !s.manages(subject) {
+			continue
+		}
+
+		versions, err := s.client.Versions(subject)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", subject, err)
+		}
+		change := Change{Subject: subject, Action: ActionOrphaned, Versions: versions}
+		if len(versions) > 0 {
+			change.LatestVersion = versions[len(versions)-1]
+		}
+		plan.Changes = append(plan.Changes, change)
+	}
+
+	sort.Slice(plan.Changes, func(i, j int) bool { return plan.Changes[i].Subject < plan.Changes[j].Subject })
+	return plan, nil
+}
+
+// manages returns true if subject starts with one of the prefixes, every subject is managed without prefixes
+func (s *Syncer) manages(subject string) bool {
+	if len(s.prefixes) == 0 {
+		return true
+	}
+	for _, prefix := range s.prefixes {
+		if strings.HasPrefix(subject, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *Syncer) changeOf(f *File) (Change, error) {
+	change := Change{Subject: f.Subject, File: f}
+
+	latest, err := s.client.GetLatestSchema(f.Subject)
+	if schemaregistry.IsSubjectNotFound(err) || schemaregistry.IsVersionNotFound(err) {
+		change.Action = ActionCreate
+		return change, nil
+	}
+	if err != nil {
+		return Change{}, err
+	}
+	change.LatestVersion = latest.Version
+
+	registered, _, err := s.client.LookupSchema(f.Subject, registrySchema(f))
+	if err != nil {
+		return Change{}, err
+	}
+	if registered {
+		change.Action = ActionUnchanged
+		return change, nil
+	}
+
+	compatible, reasons, err := s.client.CheckSchemaCompatibility(f.Subject, registrySchema(f), schemaregistry.SchemaLatestVersion)
+	if err != nil {
+		return Change{}, err
+	}
+	if !compatible {
+		change.Action, change.Reasons = ActionIncompatible, reasons
+		return change, nil
+	}
+
+	change.Action = ActionUpdate
+	return change, nil
+}
+
+// registrySchema returns the schema of f with its type, the registry defaults to avro
+func registrySchema(f *File) schemaregistry.Schema {
+	sc := schemaregistry.Schema{Schema: f.Schema}
+	if f.SchemaType != SchemaTypeAvro {
+		sc.SchemaType = f.SchemaType
+	}
+
+	return sc
+}
+
+// Apply registers the created and updated schemas and deletes the orphaned subjects if the plan prunes.
+// A plan with incompatible changes is not applied. Changes are applied in the order of the plan and the
+// results of the changes applied before a failure are returned with its error.
+func (s *Syncer) Apply(plan *Plan) ([]Result, error) {
+	if n := plan.Count(ActionIncompatible); n > 0 {
+		return nil, fmt.Errorf("%w: %d subjects", ErrIncompatible, n)
+	}
+
+	var results []Result
+	for _, c := range plan.Changes {
+		switch {
+		case c.Action == ActionCreate || c.Action == ActionUpdate:
+			id, err := s.client.RegisterSchema(c.Subject, registrySchema(c.File))
+			if err != nil {
+				return results, fmt.Errorf("%s: %w", c.Subject, err)
+			}
+			results = append(results, Result{Change: c, ID: id})
+		case c.Action == ActionOrphaned && plan.Prune:
+			if _, err := s.client.DeleteSubject(c.Subject); err != nil {
+				return results, fmt.Errorf("%s: %w", c.Subject, err)
+			}
+			results = append(results, Result{Change: c})
+		}
+	}
+
+	return results, nil
+}
+
+// String describes an applied change
+func (r Result) String() string {
+	switch r.Action {
+	case ActionCreate, ActionUpdate:
+		return r.Subject + ": registered schema id " + strconv.Itoa(r.ID)
+	default:
+		return r.Subject + ": deleted"
+	}
+}
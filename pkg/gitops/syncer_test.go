@@ -0,0 +1,270 @@
+package gitops
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/ybalcin/event-schema-manager/pkg/schemaregistry"
+)
+
+type (
+	// fakeClient keeps subjects in memory, schemas listed in incompatible are not compatible
+	fakeClient struct {
+		schemaregistry.Client
+		subjects     map[string][]string
+		incompatible map[string]bool
+		nextID       int
+	}
+)
+
+func newFakeClient() *fakeClient {
+	return &fakeClient{subjects: make(map[string][]string), incompatible: make(map[string]bool), nextID: 1}
+}
+
+func (c *fakeClient) Subjects() ([]string, error) {
+	var subjects []string
+	for s := range c.subjects {
+		subjects = append(subjects, s)
+	}
+
+	return subjects, nil
+}
+
+func (c *fakeClient) Versions(subject string) ([]int, error) {
+	var versions []int
+	for i := range c.subjects[subject] {
+		versions = append(versions, i+1)
+	}
+
+	return versions, nil
+}
+
+func (c *fakeClient) GetLatestSchema(subject string) (*schemaregistry.Schema, error) {
+	schemas, ok := c.subjects[subject]
+	if !ok {
+		return nil, schemaregistry.ResourceError{ErrorCode: 40401}
+	}
+
+	return &schemaregistry.Schema{Subject: subject, Version: len(schemas), Schema: schemas[len(schemas)-1]}, nil
+}
+
+func (c *fakeClient) LookupSchema(subject string, schema schemaregistry.Schema) (bool, schemaregistry.Schema, error) {
+	for i, s := range c.subjects[subject] {
+		if s == schema.Schema {
+			return true, schemaregistry.Schema{Subject: subject, Version: i + 1, Schema: s}, nil
+		}
+	}
+
+	return false, schemaregistry.Schema{}, nil
+}
+
+func (c *fakeClient) CheckSchemaCompatibility(subject string, schema schemaregistry.Schema, version string) (bool, []string, error) {
+	if c.incompatible[schema.Schema] {
+		return false, []string{"reader type: INT not compatible with writer type: STRING"}, nil
+	}
+
+	return true, nil, nil
+}
+
+func (c *fakeClient) RegisterSchema(subject string, schema schemaregistry.Schema) (int, error) {
+	c.subjects[subject] = append(c.subjects[subject], schema.Schema)
+	c.nextID++
+
+	return c.nextID - 1, nil
+}
+
+func (c *fakeClient) DeleteSubject(subject string) ([]int, error) {
+	delete(c.subjects, subject)
+	return nil, nil
+}
+
+func mustEqual(t *testing.T, actual, expected interface{}) {
+	t.Helper()
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %#v, but got %#v", expected, actual)
+	}
+}
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "gitops")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err = os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err = ioutil.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+func actions(plan *Plan) map[string]Action {
+	m := make(map[string]Action)
+	for _, c := range plan.Changes {
+		m[c.Subject] = c.Action
+	}
+
+	return m
+}
+
+func TestLoadDir_Convention(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"orders/orders-value.avsc":  `"string"`,
+		"users/users-value.proto":   `syntax = "proto3";`,
+		"README.md":                 "schemas",
+		".git/objects/x-value.avsc": `"string"`,
+	})
+
+	files, err := LoadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustEqual(t, files, []File{
+		{Subject: "orders-value", Path: "orders/orders-value.avsc", SchemaType: SchemaTypeAvro, Schema: `"string"`},
+		{Subject: "users-value", Path: "users/users-value.proto", SchemaType: SchemaTypeProtobuf, Schema: `syntax = "proto3";`},
+	})
+}
+
+func TestLoadDir_Manifest(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		ManifestFileName: `{"subjects": [{"subject": "orders-value", "file": "order.avsc"}, {"subject": "orders-key", "file": "key.txt", "schemaType": "AVRO"}]}`,
+		"order.avsc":     `"string"`,
+		"key.txt":        `"long"`,
+		"ignored.avsc":   `"int"`,
+	})
+
+	files, err := LoadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustEqual(t, len(files), 2)
+	mustEqual(t, files[0].Subject, "orders-key")
+	mustEqual(t, files[0].Schema, `"long"`)
+
+	duplicate := writeFiles(t, map[string]string{"a/orders-value.avsc": `"string"`, "b/orders-value.avsc": `"int"`})
+	if _, err = LoadDir(duplicate); err == nil {
+		t.Fatal("expected duplicate subjects to be rejected")
+	}
+}
+
+func TestSyncer_PlanAndApply(t *testing.T) {
+	client := newFakeClient()
+	client.subjects["orders-value"] = []string{`"string"`}
+	client.subjects["payments-value"] = []string{`"string"`}
+	client.subjects["legacy-value"] = []string{`"string"`, `"int"`}
+	// subjects outside of the prefixes are not managed by the files
+	client.subjects["billing-value"] = []string{`"string"`}
+
+	files := []File{
+		{Subject: "orders-value", Path: "orders-value.avsc", SchemaType: SchemaTypeAvro, Schema: `"string"`},
+		{Subject: "payments-value", Path: "payments-value.avsc", SchemaType: SchemaTypeAvro, Schema: `["null","string"]`},
+		{Subject: "users-value", Path: "users-value.avsc", SchemaType: SchemaTypeAvro, Schema: `"string"`},
+	}
+
+	syncer := NewSyncer(client, WithPrune("orders-", "payments-", "users-", "legacy-"))
+	plan, err := syncer.Plan(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustEqual(t, actions(plan), map[string]Action{
+		"orders-value":   ActionUnchanged,
+		"payments-value": ActionUpdate,
+		"users-value":    ActionCreate,
+		"legacy-value":   ActionOrphaned,
+	})
+	mustEqual(t, plan.Changes[0].Versions, []int{1, 2})
+	mustEqual(t, plan.Changes[0].LatestVersion, 2)
+
+	var out bytes.Buffer
+	if err = plan.Write(&out); err != nil {
+		t.Fatal(err)
+	}
+	mustEqual(t, out.String(), `  - legacy-value
+      orphaned, 2 versions will be deleted
+  ~ payments-value
+      new version after version 1 from payments-value.avsc
+  + users-value
+      new subject from users-value.avsc
+
+Plan: 1 to create, 1 to update, 1 to delete, 1 unchanged.
+`)
+
+	results, err := syncer.Apply(plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustEqual(t, len(results), 3)
+	mustEqual(t, client.subjects["payments-value"], []string{`"string"`, `["null","string"]`})
+	mustEqual(t, client.subjects["users-value"], []string{`"string"`})
+	if _, ok := client.subjects["legacy-value"]; ok {
+		t.Error("expected orphaned subject to be pruned")
+	}
+	if _, ok := client.subjects["billing-value"]; !ok {
+		t.Error("expected unmanaged subject to be kept")
+	}
+
+	// the registry is in sync
+	if plan, err = syncer.Plan(files); err != nil {
+		t.Fatal(err)
+	}
+	mustEqual(t, plan.HasChanges(), false)
+}
+
+func TestSyncer_Apply_Incompatible(t *testing.T) {
+	client := newFakeClient()
+	client.subjects["orders-value"] = []string{`"string"`}
+	client.subjects["legacy-value"] = []string{`"string"`}
+	client.incompatible[`"int"`] = true
+
+	syncer := NewSyncer(client)
+	plan, err := syncer.Plan([]File{{Subject: "orders-value", Path: "orders-value.avsc", SchemaType: SchemaTypeAvro, Schema: `"int"`}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustEqual(t, plan.Changes[1].Reasons, []string{"reader type: INT not compatible with writer type: STRING"})
+
+	var out bytes.Buffer
+	_ = plan.Write(&out)
+	mustEqual(t, out.String(), `  ? legacy-value
+      orphaned, it has no schema file
+  ! orders-value
+      orders-value.avsc is not compatible with version 1
+        - reader type: INT not compatible with writer type: STRING
+
+Plan: 0 to create, 0 to update, 0 to delete, 0 unchanged, 1 incompatible, 1 orphaned.
+`)
+
+	if _, err = syncer.Apply(plan); !errors.Is(err, ErrIncompatible) {
+		t.Fatalf("expected incompatible plan to be rejected, but got %v", err)
+	}
+	mustEqual(t, client.subjects["orders-value"], []string{`"string"`})
+
+	// schemas of every type are checked
+	client.incompatible[`{"type": "integer"}`] = true
+	plan, err = syncer.Plan([]File{{Subject: "orders-value", Path: "orders-value.json", SchemaType: SchemaTypeJSON, Schema: `{"type": "integer"}`}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustEqual(t, plan.Changes[1].Action, ActionIncompatible)
+}
+
+func TestSyncer_Plan_UnscopedPrune(t *testing.T) {
+	if _, err := NewSyncer(newFakeClient(), WithPrune()).Plan(nil); !errors.Is(err, ErrUnscopedPrune) {
+		t.Fatalf("expected unscoped prune to be rejected, but got %v", err)
+	}
+}
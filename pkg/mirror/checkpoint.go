@@ -0,0 +1,102 @@
+package mirror
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+type (
+	// Checkpoints are the last versions mirrored by subject, later runs start after them
+	Checkpoints map[string]int
+
+	CheckpointStore interface {
+		Load() (Checkpoints, error)
+		Save(checkpoints Checkpoints) error
+	}
+
+	MemoryCheckpointStore struct {
+		mu          sync.Mutex
+		checkpoints Checkpoints
+	}
+
+	// FileCheckpointStore keeps checkpoints in a json file, a missing file has no checkpoints
+	FileCheckpointStore struct {
+		path string
+	}
+)
+
+func NewMemoryCheckpointStore() *MemoryCheckpointStore {
+	return &MemoryCheckpointStore{checkpoints: Checkpoints{}}
+}
+
+func (s *MemoryCheckpointStore) Load() (Checkpoints, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.checkpoints.clone(), nil
+}
+
+func (s *MemoryCheckpointStore) Save(checkpoints Checkpoints) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.checkpoints = checkpoints.clone()
+	return nil
+}
+
+func NewFileCheckpointStore(path string) *FileCheckpointStore {
+	return &FileCheckpointStore{path: path}
+}
+
+func (s *FileCheckpointStore) Load() (Checkpoints, error) {
+	b, err := ioutil.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return Checkpoints{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	checkpoints := Checkpoints{}
+	if err = json.Unmarshal(b, &checkpoints); err != nil {
+		return nil, err
+	}
+
+	return checkpoints, nil
+}
+
+// Save writes the checkpoints to a temporary file and renames it, a crash leaves the previous checkpoints
+func (s *FileCheckpointStore) Save(checkpoints Checkpoints) error {
+	b, err := json.MarshalIndent(checkpoints, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+	if _, err = tmp.Write(b); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), s.path)
+}
+
+func (c Checkpoints) clone() Checkpoints {
+	cloned := make(Checkpoints, len(c))
+	for subject, version := range c {
+		cloned[subject] = version
+	}
+
+	return cloned
+}
@@ -0,0 +1,40 @@
+package mirror
+
+import (
+	"path"
+)
+
+// Filter selects subjects by glob patterns like orders-*, a subject is mirrored if it matches an include pattern,
+// or there are none, and no exclude pattern
+type Filter struct {
+	Include []string
+	Exclude []string
+}
+
+// Validate checks the syntax of the patterns
+func (f Filter) Validate() error {
+	for _, patterns := range [][]string{f.Include, f.Exclude} {
+		for _, p := range patterns {
+			if _, err := path.Match(p, ""); err != nil {
+				return errInvalidPattern("%s: %v", p, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Matches reports whether subject is mirrored
+func (f Filter) Matches(subject string) bool {
+	return (len(f.Include) == 0 || matchesAny(f.Include, subject)) && !matchesAny(f.Exclude, subject)
+}
+
+func matchesAny(patterns []string, subject string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, subject); ok {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,97 @@
+package mirror
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+type (
+	// Metrics tracks the progress of a mirror, it serves them in the Prometheus text format
+	Metrics struct {
+		mu    sync.Mutex
+		stats Stats
+	}
+
+	// Stats are the counters of every run and the gauges of the last run
+	Stats struct {
+		Runs             int            `json:"runs"`
+		FailedRuns       int            `json:"failedRuns"`
+		VersionsMirrored int            `json:"versionsMirrored"`
+		Conflicts        int            `json:"conflicts"`
+		Pending          int            `json:"pending"`
+		LastRunAt        time.Time      `json:"lastRunAt"`
+		LastSuccessAt    time.Time      `json:"lastSuccessAt"`
+		LastError        string         `json:"lastError,omitempty"`
+		Checkpoints      map[string]int `json:"checkpoints"`
+	}
+)
+
+// record adds the report of a run, err is the error the run failed with
+func (m *Metrics) record(report *Report, checkpoints Checkpoints, at time.Time, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.stats.Runs++
+	m.stats.LastRunAt = at
+	m.stats.VersionsMirrored += len(report.Mirrored)
+	m.stats.Conflicts = len(report.Conflicts)
+	m.stats.Pending = report.Pending
+	m.stats.Checkpoints = checkpoints.clone()
+
+	if err != nil {
+		m.stats.FailedRuns++
+		m.stats.LastError = err.Error()
+		return
+	}
+	m.stats.LastSuccessAt = at
+	m.stats.LastError = ""
+}
+
+// Snapshot returns a copy of the stats
+func (m *Metrics) Snapshot() Stats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats := m.stats
+	stats.Checkpoints = Checkpoints(m.stats.Checkpoints).clone()
+	return stats
+}
+
+// ServeHTTP writes the metrics in the Prometheus text format
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	stats := m.Snapshot()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	metric := func(name, kind, help string, value interface{}) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %v\n", name, help, name, kind, name, value)
+	}
+	metric("esm_mirror_runs_total", "counter", "Mirror runs.", stats.Runs)
+	metric("esm_mirror_failed_runs_total", "counter", "Mirror runs that failed.", stats.FailedRuns)
+	metric("esm_mirror_versions_mirrored_total", "counter", "Versions copied to the target registry.", stats.VersionsMirrored)
+	metric("esm_mirror_conflicts", "gauge", "Conflicts found by the last run.", stats.Conflicts)
+	metric("esm_mirror_pending_versions", "gauge", "Versions left behind by the last run.", stats.Pending)
+	metric("esm_mirror_last_run_timestamp_seconds", "gauge", "Time of the last run.", unix(stats.LastRunAt))
+	metric("esm_mirror_last_success_timestamp_seconds", "gauge", "Time of the last successful run.", unix(stats.LastSuccessAt))
+
+	subjects := make([]string, 0, len(stats.Checkpoints))
+	for subject := range stats.Checkpoints {
+		subjects = append(subjects, subject)
+	}
+	sort.Strings(subjects)
+
+	fmt.Fprint(w, "# HELP esm_mirror_checkpoint_version Last version mirrored by subject.\n# TYPE esm_mirror_checkpoint_version gauge\n")
+	for _, subject := range subjects {
+		fmt.Fprintf(w, "esm_mirror_checkpoint_version{subject=%q} %d\n", subject, stats.Checkpoints[subject])
+	}
+}
+
+func unix(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+
+	return t.Unix()
+}
@@ -0,0 +1,335 @@
+package mirror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/ybalcin/event-schema-manager/pkg/schemaregistry"
+)
+
+type (
+	// Mirror copies the versions of the subjects of a source registry to a target registry. Versions are copied
+	// in the order of their source ids, so referenced schemas are copied before the schemas referencing them.
+	// Deleted subjects and versions are not copied.
+	Mirror struct {
+		source      schemaregistry.Client
+		target      schemaregistry.Client
+		filter      Filter
+		preserveIDs bool
+		interval    time.Duration
+		checkpoints CheckpointStore
+		metrics     *Metrics
+		now         func() time.Time
+	}
+
+	Option func(*Mirror)
+
+	// Report is the outcome of a run, Pending is the number of versions left behind by conflicts
+	Report struct {
+		Mirrored  []Mirrored `json:"mirrored"`
+		Conflicts []Conflict `json:"conflicts"`
+		Pending   int        `json:"pending"`
+	}
+
+	// Mirrored is a version copied to the target, TargetID differs from SourceID unless ids are preserved
+	Mirrored struct {
+		Subject  string `json:"subject"`
+		Version  int    `json:"version"`
+		SourceID int    `json:"sourceId"`
+		TargetID int    `json:"targetId"`
+	}
+
+	// Conflict is a version that can not be mirrored, the later versions of its subject wait until it is resolved
+	Conflict struct {
+		Subject string       `json:"subject"`
+		Version int          `json:"version"`
+		Kind    ConflictKind `json:"kind"`
+		Reason  string       `json:"reason"`
+	}
+
+	ConflictKind string
+
+	// pending is a version of the source that is not mirrored yet
+	pending struct {
+		subject string
+		schema  *schemaregistry.Schema
+	}
+)
+
+const (
+	// ConflictIDCollision is a source id taken by another schema in the target, only when ids are preserved
+	ConflictIDCollision ConflictKind = "id-collision"
+	// ConflictDiverged is a version the target has with another schema or rejects as incompatible
+	ConflictDiverged ConflictKind = "diverged-version"
+
+	defaultInterval = 30 * time.Second
+)
+
+var (
+	ErrInvalidPattern = errors.New("invalid subject pattern")
+
+	errInvalidPattern = func(format string, args ...interface{}) error {
+		return fmt.Errorf("%w: %s", ErrInvalidPattern, fmt.Sprintf(format, args...))
+	}
+)
+
+// WithFilter mirrors only the subjects matched by filter
+func WithFilter(filter Filter) Option {
+	return func(m *Mirror) {
+		m.filter = filter
+	}
+}
+
+// WithPreservedIDs imports versions with their source ids and version numbers, the target is switched to IMPORT
+// mode and stays in it
+func WithPreservedIDs() Option {
+	return func(m *Mirror) {
+		m.preserveIDs = true
+	}
+}
+
+// WithInterval sets the polling interval of Run, 30 seconds by default
+func WithInterval(interval time.Duration) Option {
+	return func(m *Mirror) {
+		m.interval = interval
+	}
+}
+
+// WithCheckpoints keeps the checkpoints in store, they are kept in memory by default
+func WithCheckpoints(store CheckpointStore) Option {
+	return func(m *Mirror) {
+		m.checkpoints = store
+	}
+}
+
+func New(source, target schemaregistry.Client, opts ...Option) (*Mirror, error) {
+	m := &Mirror{
+		source:      source,
+		target:      target,
+		interval:    defaultInterval,
+		checkpoints: NewMemoryCheckpointStore(),
+		metrics:     &Metrics{},
+		now:         time.Now,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	if err := m.filter.Validate(); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+func (m *Mirror) Metrics() *Metrics {
+	return m.metrics
+}
+
+// Run mirrors every interval until ctx is done, handle is called with the outcome of every run
+func (m *Mirror) Run(ctx context.Context, handle func(*Report, error)) error {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for ctx.Err() == nil {
+		report, err := m.RunOnce()
+		if handle != nil {
+			handle(report, err)
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-ticker.C:
+		}
+	}
+
+	return ctx.Err()
+}
+
+// RunOnce mirrors the versions added to the source since the checkpoints. Conflicts are reported and hold their
+// subject back, the other subjects are mirrored. The checkpoints of the mirrored versions are saved even if the
+// run fails.
+func (m *Mirror) RunOnce() (*Report, error) {
+	report := &Report{}
+
+	checkpoints, err := m.checkpoints.Load()
+	if err != nil {
+		m.metrics.record(report, nil, m.now(), err)
+		return report, err
+	}
+
+	err = m.run(checkpoints, report)
+	if saveErr := m.checkpoints.Save(checkpoints); err == nil {
+		err = saveErr
+	}
+
+	m.metrics.record(report, checkpoints, m.now(), err)
+	return report, err
+}
+
+func (m *Mirror) run(checkpoints Checkpoints, report *Report) error {
+	if m.preserveIDs {
+		if err := m.ensureImportMode(); err != nil {
+			return err
+		}
+	}
+
+	versions, err := m.pendingVersions(checkpoints)
+	if err != nil {
+		return err
+	}
+
+	blocked := make(map[string]bool)
+	for i, p := range versions {
+		if blocked[p.subject] {
+			report.Pending++
+			continue
+		}
+
+		mirrored, conflict, err := m.mirror(p)
+		if err != nil {
+			report.Pending += len(versions) - i
+			return fmt.Errorf("%s version %d: %w", p.subject, p.schema.Version, err)
+		}
+		if conflict != nil {
+			blocked[p.subject] = true
+			report.Conflicts = append(report.Conflicts, *conflict)
+			report.Pending++
+			continue
+		}
+
+		checkpoints[p.subject] = p.schema.Version
+		if mirrored != nil {
+			report.Mirrored = append(report.Mirrored, *mirrored)
+		}
+	}
+
+	return nil
+}
+
+func (m *Mirror) ensureImportMode() error {
+	mode, err := m.target.GetMode("")
+	if err != nil || mode == schemaregistry.ModeImport {
+		return err
+	}
+
+	_, err = m.target.SetMode("", schemaregistry.ModeImport)
+	return err
+}
+
+// pendingVersions returns the versions of the source after the checkpoints ordered by id
+func (m *Mirror) pendingVersions(checkpoints Checkpoints) ([]pending, error) {
+	subjects, err := m.source.Subjects()
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []pending
+	for _, subject := range subjects {
+		if !m.filter.Matches(subject) {
+			continue
+		}
+
+		numbers, err := m.source.Versions(subject)
+		if schemaregistry.IsSubjectNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", subject, err)
+		}
+
+		for _, v := range numbers {
+			if v <= checkpoints[subject] {
+				continue
+			}
+
+			sc, err := m.source.GetSchemaByVersion(subject, strconv.Itoa(v))
+			if err != nil {
+				return nil, fmt.Errorf("%s version %d: %w", subject, v, err)
+			}
+			versions = append(versions, pending{subject: subject, schema: sc})
+		}
+	}
+
+	sort.SliceStable(versions, func(i, j int) bool {
+		a, b := versions[i].schema, versions[j].schema
+		if a.ID != b.ID {
+			return a.ID < b.ID
+		}
+		if versions[i].subject != versions[j].subject {
+			return versions[i].subject < versions[j].subject
+		}
+		return a.Version < b.Version
+	})
+
+	return versions, nil
+}
+
+// mirror copies a version to the target, a version the target has already is not mirrored again. Without
+// preserved ids the target numbers the versions itself, references keep the version numbers of the source.
+func (m *Mirror) mirror(p pending) (*Mirrored, *Conflict, error) {
+	if m.preserveIDs {
+		return m.importVersion(p)
+	}
+
+	// the schema is looked up with its type and references, avro is only the default type
+	lookup := schemaregistry.Schema{Schema: p.schema.Schema, SchemaType: p.schema.SchemaType, References: p.schema.References}
+	registered, _, err := m.target.LookupSchema(p.subject, lookup)
+	if err != nil && !schemaregistry.IsSubjectNotFound(err) && !schemaregistry.IsSchemaNotFound(err) {
+		return nil, nil, err
+	}
+	if registered {
+		return nil, nil, nil
+	}
+
+	id, err := m.target.RegisterSchema(p.subject, *p.schema)
+	if schemaregistry.IsIncompatibleSchema(err) {
+		return nil, conflictOf(p, ConflictDiverged, "target rejects the schema: "+err.Error()), nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &Mirrored{Subject: p.subject, Version: p.schema.Version, SourceID: p.schema.ID, TargetID: id}, nil, nil
+}
+
+// importVersion imports a version with its id and version number unless the target has them for another schema
+func (m *Mirror) importVersion(p pending) (*Mirrored, *Conflict, error) {
+	existing, err := m.target.GetSchemaByVersion(p.subject, strconv.Itoa(p.schema.Version))
+	switch {
+	case err == nil && existing.Schema == p.schema.Schema && existing.ID == p.schema.ID:
+		return nil, nil, nil
+	case err == nil:
+		return nil, conflictOf(p, ConflictDiverged, fmt.Sprintf("target has schema id %d as the version", existing.ID)), nil
+	case !schemaregistry.IsSubjectNotFound(err) && !schemaregistry.IsVersionNotFound(err):
+		return nil, nil, err
+	}
+
+	taken, err := m.target.GetSchema(p.schema.ID)
+	switch {
+	case err == nil && taken.Schema != p.schema.Schema:
+		return nil, conflictOf(p, ConflictIDCollision, fmt.Sprintf("target has another schema with id %d", p.schema.ID)), nil
+	case err != nil && !schemaregistry.IsSchemaNotFound(err):
+		return nil, nil, err
+	}
+
+	id, err := m.target.ImportSchema(p.subject, *p.schema)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &Mirrored{Subject: p.subject, Version: p.schema.Version, SourceID: p.schema.ID, TargetID: id}, nil, nil
+}
+
+func conflictOf(p pending, kind ConflictKind, reason string) *Conflict {
+	return &Conflict{Subject: p.subject, Version: p.schema.Version, Kind: kind, Reason: reason}
+}
+
+// String describes the conflict
+func (c Conflict) String() string {
+	return fmt.Sprintf("%s version %d: %s: %s", c.Subject, c.Version, c.Kind, c.Reason)
+}
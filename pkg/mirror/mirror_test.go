@@ -0,0 +1,208 @@
+package mirror
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ybalcin/event-schema-manager/pkg/schemaregistry"
+	"github.com/ybalcin/event-schema-manager/pkg/schemaregistry/schemaregistrytest"
+)
+
+func mustEqual(t *testing.T, actual, expected interface{}) {
+	t.Helper()
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("expected %#v, got %#v", expected, actual)
+	}
+}
+
+func mustNoError(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func register(t *testing.T, r *schemaregistrytest.Registry, subject, schema string) int {
+	t.Helper()
+	id, err := r.RegisterSchema(subject, schemaregistry.Schema{Schema: schema})
+	mustNoError(t, err)
+	return id
+}
+
+func newSource(t *testing.T) *schemaregistrytest.Registry {
+	source := schemaregistrytest.NewRegistry()
+	register(t, source, "users-value", `"string"`)
+	register(t, source, "orders-value", `"int"`)
+	register(t, source, "users-value", `"long"`)
+	register(t, source, "internal-value", `"bytes"`)
+	return source
+}
+
+func TestFilter(t *testing.T) {
+	f := Filter{Include: []string{"orders-*", "users-*"}, Exclude: []string{"*-key"}}
+
+	mustEqual(t, f.Matches("orders-value"), true)
+	mustEqual(t, f.Matches("orders-key"), false)
+	mustEqual(t, f.Matches("payments-value"), false)
+	mustEqual(t, Filter{}.Matches("payments-value"), true)
+
+	_, err := New(nil, nil, WithFilter(Filter{Exclude: []string{"["}}))
+	mustEqual(t, errors.Is(err, ErrInvalidPattern), true)
+}
+
+func TestMirror_RunOnce_PreservesIDs(t *testing.T) {
+	source, target := newSource(t), schemaregistrytest.NewRegistry()
+	m, err := New(source, target, WithPreservedIDs(), WithFilter(Filter{Exclude: []string{"internal-*"}}))
+	mustNoError(t, err)
+
+	report, err := m.RunOnce()
+	mustNoError(t, err)
+	mustEqual(t, report, &Report{Mirrored: []Mirrored{
+		{Subject: "users-value", Version: 1, SourceID: 1, TargetID: 1},
+		{Subject: "orders-value", Version: 1, SourceID: 2, TargetID: 2},
+		{Subject: "users-value", Version: 2, SourceID: 3, TargetID: 3},
+	}})
+
+	mode, _ := target.GetMode("")
+	mustEqual(t, mode, schemaregistry.ModeImport)
+	subjects, _ := target.Subjects()
+	mustEqual(t, subjects, []string{"orders-value", "users-value"})
+
+	report, err = m.RunOnce()
+	mustNoError(t, err)
+	mustEqual(t, len(report.Mirrored), 0)
+
+	register(t, source, "orders-value", `"double"`)
+	report, err = m.RunOnce()
+	mustNoError(t, err)
+	mustEqual(t, report.Mirrored, []Mirrored{{Subject: "orders-value", Version: 2, SourceID: 5, TargetID: 5}})
+
+	stats := m.Metrics().Snapshot()
+	mustEqual(t, stats.Runs, 3)
+	mustEqual(t, stats.VersionsMirrored, 4)
+	mustEqual(t, stats.Checkpoints, map[string]int{"orders-value": 2, "users-value": 2})
+}
+
+func TestMirror_RunOnce_ReportsConflicts(t *testing.T) {
+	source, target := newSource(t), schemaregistrytest.NewRegistry()
+
+	// id 2 of orders-value is taken by another schema, version 1 of internal-value diverged
+	register(t, target, "other-value", `"string"`)
+	register(t, target, "other-value", `"boolean"`)
+	_, err := target.SetMode("", schemaregistry.ModeImport)
+	mustNoError(t, err)
+	_, err = target.ImportSchema("internal-value", schemaregistry.Schema{Schema: `"null"`, ID: 9, Version: 1})
+	mustNoError(t, err)
+
+	m, err := New(source, target, WithPreservedIDs())
+	mustNoError(t, err)
+
+	report, err := m.RunOnce()
+	mustNoError(t, err)
+	mustEqual(t, report.Conflicts, []Conflict{
+		{Subject: "orders-value", Version: 1, Kind: ConflictIDCollision, Reason: "target has another schema with id 2"},
+		{Subject: "internal-value", Version: 1, Kind: ConflictDiverged, Reason: "target has schema id 9 as the version"},
+	})
+	mustEqual(t, report.Pending, 2)
+	mustEqual(t, len(report.Mirrored), 2)
+
+	// id 1 is taken by the same schema, users-value is mirrored
+	versions, _ := target.Versions("users-value")
+	mustEqual(t, versions, []int{1, 2})
+
+	stats := m.Metrics().Snapshot()
+	mustEqual(t, stats.Conflicts, 2)
+	mustEqual(t, stats.Pending, 2)
+}
+
+func TestMirror_RunOnce_WithoutPreservedIDs(t *testing.T) {
+	source, target := newSource(t), schemaregistrytest.NewRegistry()
+	register(t, target, "other-value", `"float"`)
+	target.Incompatible = func(subject, schema string, versions []schemaregistry.Schema) []string {
+		if subject == "users-value" {
+			return []string{"type changed"}
+		}
+		return nil
+	}
+
+	m, err := New(source, target, WithFilter(Filter{Include: []string{"users-*", "orders-*"}}))
+	mustNoError(t, err)
+
+	report, err := m.RunOnce()
+	mustNoError(t, err)
+	mustEqual(t, report.Mirrored, []Mirrored{
+		{Subject: "users-value", Version: 1, SourceID: 1, TargetID: 2},
+		{Subject: "orders-value", Version: 1, SourceID: 2, TargetID: 3},
+	})
+	mustEqual(t, len(report.Conflicts), 1)
+	mustEqual(t, report.Conflicts[0].Kind, ConflictDiverged)
+
+	mode, _ := target.GetMode("")
+	mustEqual(t, mode, schemaregistry.ModeReadWrite)
+}
+
+func TestMirror_RunOnce_LooksUpSchemaTypes(t *testing.T) {
+	source, target := schemaregistrytest.NewRegistry(), schemaregistrytest.NewRegistry()
+	events := schemaregistry.Schema{Schema: `{"type":"object"}`, SchemaType: schemaregistry.SchemaTypeJSON}
+	for _, r := range []*schemaregistrytest.Registry{source, target} {
+		_, err := r.RegisterSchema("events-value", events)
+		mustNoError(t, err)
+	}
+
+	m, err := New(source, target)
+	mustNoError(t, err)
+
+	// a json schema the target has already is not mirrored again
+	report, err := m.RunOnce()
+	mustNoError(t, err)
+	mustEqual(t, len(report.Mirrored), 0)
+	versions, _ := target.Versions("events-value")
+	mustEqual(t, versions, []int{1})
+}
+
+func TestMirror_Run(t *testing.T) {
+	source, target := newSource(t), schemaregistrytest.NewRegistry()
+	store := NewFileCheckpointStore(filepath.Join(t.TempDir(), "checkpoints.json"))
+	m, err := New(source, target, WithPreservedIDs(), WithInterval(time.Millisecond), WithCheckpoints(store))
+	mustNoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var mirrored []int
+	err = m.Run(ctx, func(report *Report, err error) {
+		mustNoError(t, err)
+		mirrored = append(mirrored, len(report.Mirrored))
+		if len(mirrored) == 1 {
+			register(t, source, "users-value", `"double"`)
+		}
+		if len(mirrored) == 3 {
+			cancel()
+		}
+	})
+	mustEqual(t, err, context.Canceled)
+	mustEqual(t, mirrored, []int{4, 1, 0})
+
+	checkpoints, err := store.Load()
+	mustNoError(t, err)
+	mustEqual(t, checkpoints, Checkpoints{"internal-value": 1, "orders-value": 1, "users-value": 3})
+}
+
+func TestMetrics_ServeHTTP(t *testing.T) {
+	m, err := New(newSource(t), schemaregistrytest.NewRegistry())
+	mustNoError(t, err)
+	_, err = m.RunOnce()
+	mustNoError(t, err)
+
+	rec := httptest.NewRecorder()
+	m.Metrics().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	body := rec.Body.String()
+	mustEqual(t, strings.Contains(body, "esm_mirror_runs_total 1\n"), true)
+	mustEqual(t, strings.Contains(body, "esm_mirror_versions_mirrored_total 4\n"), true)
+	mustEqual(t, strings.Contains(body, "esm_mirror_checkpoint_version{subject=\"users-value\"} 2\n"), true)
+}
@@ -0,0 +1,285 @@
+package schemadiff
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/ybalcin/event-schema-manager/pkg/avro"
+)
+
+// avroDiff compares two parsed avro schemas, references are resolved to the named types of their schema
+type avroDiff struct {
+	previous *avro.Schema
+	current  *avro.Schema
+	// compared are the pairs of named types compared already, recursive types are compared once
+	compared map[string]bool
+	changes  []Change
+}
+
+// CompareAvro returns the structural difference from the previous to the current avro schema. Fields are matched
+// by name, a new field is a renamed field if one of its aliases is the name of a removed field.
+func CompareAvro(previous, current string) (*Diff, error) {
+	p, err := avro.Parse(previous)
+	if err != nil {
+		return nil, err
+	}
+	c, err := avro.Parse(current)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &avroDiff{previous: p, current: c, compared: make(map[string]bool)}
+	d.compare("$", p, c)
+
+	changes := d.changes
+	if changes == nil {
+		changes = []Change{}
+	}
+	return &Diff{SchemaType: SchemaTypeAvro, Changes: changes}, nil
+}
+
+func (d *avroDiff) add(kind Kind, path, before, after string) {
+	d.changes = append(d.changes, Change{Kind: kind, Path: path, Before: before, After: after})
+}
+
+// resolve returns the named type a reference refers to in root, unknown references are returned as they are
+func resolve(root, s *avro.Schema) *avro.Schema {
+	if s.Type != avro.Reference {
+		return s
+	}
+	if named := root.Lookup(s.Name); named != nil {
+		return named
+	}
+
+	return s
+}
+
+func (d *avroDiff) compare(path string, previous, current *avro.Schema) {
+	p, c := resolve(d.previous, previous), resolve(d.current, current)
+
+	if p.Type == avro.Union || c.Type == avro.Union {
+		d.compareUnions(path, p, c)
+		return
+	}
+
+	if p.Type != c.Type || typeName(p) != typeName(c) && !p.Type.IsNamed() {
+		d.add(TypeChanged, path, typeName(p), typeName(c))
+		return
+	}
+
+	switch p.Type {
+	case avro.Record, avro.Error:
+		if d.compareNamed(path, p, c) {
+			d.compareFields(path, p, c)
+		}
+	case avro.Enum:
+		if d.compareNamed(path, p, c) {
+			d.compareSymbols(path, p, c)
+		}
+	case avro.Fixed:
+		if d.compareNamed(path, p, c) && p.Size != c.Size {
+			d.add(TypeChanged, path, typeName(p), typeName(c))
+		}
+	case avro.Array:
+		d.compare(path+"[]", p.Items, c.Items)
+	case avro.Map:
+		d.compare(path+"{}", p.Values, c.Values)
+	}
+}
+
+// compareNamed compares the names, docs and aliases of named types, it returns false if the pair is compared already
+func (d *avroDiff) compareNamed(path string, previous, current *avro.Schema) bool {
+	key := previous.Name + "|" + current.Name
+	if d.compared[key] {
+		return false
+	}
+	d.compared[key] = true
+
+	if previous.Name != current.Name {
+		d.add(NameChanged, path, previous.Name, current.Name)
+	}
+	if previous.Doc != current.Doc {
+		d.add(DocChanged, path, previous.Doc, current.Doc)
+	}
+	d.compareAliases(path, previous.Aliases, current.Aliases)
+
+	return true
+}
+
+func (d *avroDiff) compareFields(path string, previous, current *avro.Schema) {
+	byName := make(map[string]*avro.Field, len(previous.Fields))
+	for _, f := range previous.Fields {
+		byName[f.Name] = f
+	}
+
+	// fields of the current schema paired with the previous ones by name, then by aliases
+	pairs := make(map[*avro.Field]*avro.Field, len(current.Fields))
+	matched := make(map[*avro.Field]bool, len(previous.Fields))
+	for _, f := range current.Fields {
+		if p, ok := byName[f.Name]; ok {
+			pairs[f], matched[p] = p, true
+		}
+	}
+	for _, f := range current.Fields {
+		if _, ok := pairs[f]; ok {
+			continue
+		}
+		for _, alias := range f.Aliases {
+			if p, ok := byName[alias]; ok && !matched[p] {
+				pairs[f], matched[p] = p, true
+				break
+			}
+		}
+	}
+
+	for _, f := range current.Fields {
+		fieldPath := path + "." + f.Name
+		p, ok := pairs[f]
+		if !ok {
+			d.add(FieldAdded, fieldPath, "", typeName(resolve(d.current, f.Type)))
+			continue
+		}
+
+		if p.Name != f.Name {
+			d.add(FieldRenamed, fieldPath, p.Name, f.Name)
+		}
+		d.compareField(fieldPath, p, f)
+	}
+
+	for _, p := range previous.Fields {
+		if !matched[p] {
+			d.add(FieldRemoved, path+"."+p.Name, typeName(resolve(d.previous, p.Type)), "")
+		}
+	}
+}
+
+func (d *avroDiff) compareField(path string, previous, current *avro.Field) {
+	if previous.Doc != current.Doc {
+		d.add(DocChanged, path, previous.Doc, current.Doc)
+	}
+
+	if previous.HasDefault != current.HasDefault || !reflect.DeepEqual(previous.Default, current.Default) {
+		d.add(DefaultChanged, path, defaultOf(previous.Default, previous.HasDefault), defaultOf(current.Default, current.HasDefault))
+	}
+
+	d.compareAliases(path, previous.Aliases, current.Aliases)
+	d.compare(path, previous.Type, current.Type)
+}
+
+func (d *avroDiff) compareSymbols(path string, previous, current *avro.Schema) {
+	for _, s := range current.Symbols {
+		if !contains(previous.Symbols, s) {
+			d.add(SymbolAdded, path, "", s)
+		}
+	}
+	for _, s := range previous.Symbols {
+		if !contains(current.Symbols, s) {
+			d.add(SymbolRemoved, path, s, "")
+		}
+	}
+
+	if !reflect.DeepEqual(previous.Default, current.Default) {
+		d.add(DefaultChanged, path, defaultOf(previous.Default, previous.Default != nil), defaultOf(current.Default, current.Default != nil))
+	}
+}
+
+func (d *avroDiff) compareAliases(path string, previous, current []string) {
+	for _, a := range current {
+		if !contains(previous, a) {
+			d.add(AliasAdded, path, "", a)
+		}
+	}
+	for _, a := range previous {
+		if !contains(current, a) {
+			d.add(AliasRemoved, path, a, "")
+		}
+	}
+}
+
+// compareUnions reports a type change if the branches differ, then compares the branches in both
+func (d *avroDiff) compareUnions(path string, previous, current *avro.Schema) {
+	p, c := branchesOf(d.previous, previous), branchesOf(d.current, current)
+
+	if typeName(previous) != typeName(current) {
+		d.add(TypeChanged, path, typeName(previous), typeName(current))
+	}
+
+	for _, cb := range c {
+		for _, pb := range p {
+			if branchKey(pb) == branchKey(cb) {
+				d.compare(path, pb, cb)
+			}
+		}
+	}
+}
+
+// branchesOf returns the resolved branches of a union, a type that is not a union is its only branch
+func branchesOf(root, s *avro.Schema) []*avro.Schema {
+	if s.Type != avro.Union {
+		return []*avro.Schema{resolve(root, s)}
+	}
+
+	branches := make([]*avro.Schema, len(s.Branches))
+	for i, b := range s.Branches {
+		branches[i] = resolve(root, b)
+	}
+
+	return branches
+}
+
+// branchKey identifies a branch of a union, unions can have one branch of every unnamed type
+func branchKey(s *avro.Schema) string {
+	if s.Name != "" {
+		return s.Name
+	}
+
+	return string(s.Type)
+}
+
+// typeName returns the type as it is shown in changes, with the size of fixed types and the precision and scale
+// of decimals
+func typeName(s *avro.Schema) string {
+	name := s.String()
+
+	switch {
+	case s.Type == avro.Fixed:
+		name = fmt.Sprintf("%s(size %d)", name, s.Size)
+	case s.LogicalType == "decimal":
+		name = fmt.Sprintf("%s(decimal %v, %v)", s.Type, s.Props["precision"], propOr(s.Props, "scale", 0))
+	}
+
+	return name
+}
+
+func propOr(props map[string]interface{}, key string, fallback interface{}) interface{} {
+	if v, ok := props[key]; ok {
+		return v
+	}
+
+	return fallback
+}
+
+// defaultOf returns a default as json, the empty string if there is no default
+func defaultOf(v interface{}, ok bool) string {
+	if !ok {
+		return ""
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+
+	return string(b)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
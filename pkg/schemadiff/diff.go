@@ -0,0 +1,113 @@
+package schemadiff
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type (
+	// Diff is the structural difference between two schemas, changes are in the order of the current schema
+	// followed by the removals
+	Diff struct {
+		SchemaType string   `json:"schemaType"`
+		Changes    []Change `json:"changes"`
+	}
+
+	// Change is a difference at the json path of a node, e.g. $.address.city. Before and After are the changed
+	// values, type names, docs, names or symbols, defaults are json.
+	Change struct {
+		Kind   Kind   `json:"kind"`
+		Path   string `json:"path"`
+		Before string `json:"before,omitempty"`
+		After  string `json:"after,omitempty"`
+	}
+
+	Kind string
+)
+
+const (
+	FieldAdded     Kind = "field-added"
+	FieldRemoved   Kind = "field-removed"
+	FieldRenamed   Kind = "field-renamed"
+	TypeChanged    Kind = "type-changed"
+	NameChanged    Kind = "name-changed"
+	DefaultChanged Kind = "default-changed"
+	DocChanged     Kind = "doc-changed"
+	SymbolAdded    Kind = "symbol-added"
+	SymbolRemoved  Kind = "symbol-removed"
+	AliasAdded     Kind = "alias-added"
+	AliasRemoved   Kind = "alias-removed"
+
+	SchemaTypeAvro = "AVRO"
+)
+
+var ErrUnsupportedSchemaType = errors.New("structural diff is not supported for the schema type")
+
+// Compare returns the structural difference from previous to current, avro is the only supported schema type
+// and the empty type is avro
+func Compare(schemaType, previous, current string) (*Diff, error) {
+	switch strings.ToUpper(schemaType) {
+	case "", SchemaTypeAvro:
+		return CompareAvro(previous, current)
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrUnsupportedSchemaType, schemaType)
+}
+
+// HasChanges returns true if the schemas differ
+func (d *Diff) HasChanges() bool {
+	return len(d.Changes) > 0
+}
+
+// String describes the change, e.g. "$.amount: type changed from int to long"
+func (c Change) String() string {
+	var description string
+	switch c.Kind {
+	case FieldAdded:
+		description = "field added with type " + c.After
+	case FieldRemoved:
+		description = "field removed, it had type " + c.Before
+	case FieldRenamed:
+		description = "field renamed from " + c.Before + " to " + c.After
+	case TypeChanged:
+		description = "type changed from " + c.Before + " to " + c.After
+	case NameChanged:
+		description = "name changed from " + c.Before + " to " + c.After
+	case DefaultChanged:
+		switch {
+		case c.Before == "":
+			description = "default " + c.After + " added"
+		case c.After == "":
+			description = "default " + c.Before + " removed"
+		default:
+			description = "default changed from " + c.Before + " to " + c.After
+		}
+	case DocChanged:
+		description = fmt.Sprintf("doc changed from %q to %q", c.Before, c.After)
+	case SymbolAdded:
+		description = "enum symbol " + c.After + " added"
+	case SymbolRemoved:
+		description = "enum symbol " + c.Before + " removed"
+	case AliasAdded:
+		description = "alias " + c.After + " added"
+	case AliasRemoved:
+		description = "alias " + c.Before + " removed"
+	default:
+		description = string(c.Kind)
+	}
+
+	return c.Path + ": " + description
+}
+
+// symbol returns + for additions, - for removals and ~ for the other changes
+func (c Change) symbol() string {
+	switch c.Kind {
+	case FieldAdded, SymbolAdded, AliasAdded:
+		return "+"
+	case FieldRemoved, SymbolRemoved, AliasRemoved:
+		return "-"
+	}
+
+	return "~"
+}
@@ -0,0 +1,138 @@
+package schemadiff
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+const (
+	orderV1 = `{
+		"type": "record", "name": "Order", "namespace": "com.acme", "doc": "An order",
+		"fields": [
+			{"name": "id", "type": "string"},
+			{"name": "amount", "type": "int", "default": 0},
+			{"name": "email", "type": "string"},
+			{"name": "status", "type": {"type": "enum", "name": "Status", "symbols": ["NEW", "PAID", "LOST"]}},
+			{"name": "address", "type": {"type": "record", "name": "Address", "fields": [
+				{"name": "city", "type": "string", "doc": "City name"}
+			]}},
+			{"name": "previous", "type": ["null", "Address"], "default": null},
+			{"name": "price", "type": {"type": "bytes", "logicalType": "decimal", "precision": 10, "scale": 2}}
+		]
+	}`
+	orderV2 = `{
+		"type": "record", "name": "Order", "namespace": "com.acme", "doc": "An order of a customer",
+		"aliases": ["PurchaseOrder"],
+		"fields": [
+			{"name": "id", "type": "string"},
+			{"name": "amount", "type": "long", "default": 1},
+			{"name": "customerEmail", "type": "string", "aliases": ["email"]},
+			{"name": "status", "type": {"type": "enum", "name": "Status", "symbols": ["NEW", "PAID", "REFUNDED"]}},
+			{"name": "address", "type": {"type": "record", "name": "Address", "fields": [
+				{"name": "city", "type": "string", "doc": "City"},
+				{"name": "zip", "type": ["null", "string"], "default": null}
+			]}},
+			{"name": "previous", "type": ["null", "Address", "string"], "default": null},
+			{"name": "price", "type": {"type": "bytes", "logicalType": "decimal", "precision": 12, "scale": 2}}
+		]
+	}`
+)
+
+func mustEqual(t *testing.T, actual, expected interface{}) {
+	t.Helper()
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("expected %#v, got %#v", expected, actual)
+	}
+}
+
+func TestCompareAvro(t *testing.T) {
+	d, err := Compare("AVRO", orderV1, orderV2)
+	mustEqual(t, err, nil)
+
+	mustEqual(t, d.Changes, []Change{
+		{Kind: DocChanged, Path: "$", Before: "An order", After: "An order of a customer"},
+		{Kind: AliasAdded, Path: "$", After: "PurchaseOrder"},
+		{Kind: DefaultChanged, Path: "$.amount", Before: "0", After: "1"},
+		{Kind: TypeChanged, Path: "$.amount", Before: "int", After: "long"},
+		{Kind: FieldRenamed, Path: "$.customerEmail", Before: "email", After: "customerEmail"},
+		{Kind: AliasAdded, Path: "$.customerEmail", After: "email"},
+		{Kind: SymbolAdded, Path: "$.status", After: "REFUNDED"},
+		{Kind: SymbolRemoved, Path: "$.status", Before: "LOST"},
+		{Kind: DocChanged, Path: "$.address.city", Before: "City name", After: "City"},
+		{Kind: FieldAdded, Path: "$.address.zip", After: "union<null, string>"},
+		{Kind: TypeChanged, Path: "$.previous", Before: "union<null, com.acme.Address>", After: "union<null, com.acme.Address, string>"},
+		{Kind: TypeChanged, Path: "$.price", Before: "bytes(decimal 10, 2)", After: "bytes(decimal 12, 2)"},
+	})
+}
+
+func TestCompareAvro_RemovedFieldsAndRecursiveTypes(t *testing.T) {
+	previous := `{"type": "record", "name": "Node", "fields": [
+		{"name": "value", "type": "int"},
+		{"name": "next", "type": ["null", "Node"]},
+		{"name": "label", "type": {"type": "fixed", "name": "Label", "size": 4}}
+	]}`
+	current := `{"type": "record", "name": "Node", "fields": [
+		{"name": "next", "type": ["null", "Node"]},
+		{"name": "label", "type": {"type": "fixed", "name": "Label", "size": 8}}
+	]}`
+
+	d, err := CompareAvro(previous, current)
+	mustEqual(t, err, nil)
+	mustEqual(t, d.Changes, []Change{
+		{Kind: TypeChanged, Path: "$.label", Before: "Label(size 4)", After: "Label(size 8)"},
+		{Kind: FieldRemoved, Path: "$.value", Before: "int"},
+	})
+
+	d, err = CompareAvro(current, current)
+	mustEqual(t, err, nil)
+	mustEqual(t, d.HasChanges(), false)
+}
+
+func TestCompare_Errors(t *testing.T) {
+	_, err := Compare("JSON", "{}", "{}")
+	mustEqual(t, errors.Is(err, ErrUnsupportedSchemaType), true)
+
+	_, err = Compare("", "{", `"string"`)
+	mustEqual(t, err != nil, true)
+}
+
+func TestDiff_Write(t *testing.T) {
+	d := &Diff{SchemaType: SchemaTypeAvro, Changes: []Change{
+		{Kind: FieldAdded, Path: "$.zip", After: "string"},
+		{Kind: DefaultChanged, Path: "$.amount", Before: "0"},
+		{Kind: DocChanged, Path: "$", Before: "a|b", After: "c"},
+	}}
+
+	var b bytes.Buffer
+	mustEqual(t, d.Write(&b, FormatText), nil)
+	mustEqual(t, b.String(), "+ $.zip: field added with type string\n~ $.amount: default 0 removed\n~ $: doc changed from \"a|b\" to \"c\"\n")
+
+	b.Reset()
+	mustEqual(t, d.Write(&b, FormatMarkdown), nil)
+	mustEqual(t, b.String(), "| Change | Path | Before | After |\n| --- | --- | --- | --- |\n"+
+		"| field-added | `$.zip` |  | `string` |\n"+
+		"| default-changed | `$.amount` | `0` |  |\n"+
+		"| doc-changed | `$` | `a\\|b` | `c` |\n")
+
+	b.Reset()
+	mustEqual(t, (&Diff{SchemaType: SchemaTypeAvro, Changes: []Change{}}).Write(&b, FormatJSON), nil)
+	mustEqual(t, b.String(), "{\n  \"schemaType\": \"AVRO\",\n  \"changes\": []\n}\n")
+
+	f, err := ParseFormat("Markdown")
+	mustEqual(t, err, nil)
+	mustEqual(t, f, FormatMarkdown)
+	_, err = ParseFormat("html")
+	mustEqual(t, errors.Is(err, ErrUnknownFormat), true)
+}
+
+func TestLineDiff(t *testing.T) {
+	// json is indented, so the changed field is the only changed line
+	diff := LineDiff(`{"type":"record","name":"Order","fields":[{"name":"id","type":"string"}]}`,
+		`{"type":"record","name":"Order","fields":[{"name":"id","type":"long"}]}`)
+	mustEqual(t, diff, "-      \"type\": \"string\"\n+      \"type\": \"long\"")
+
+	mustEqual(t, LineDiff("", "FULL"), "+FULL")
+	mustEqual(t, LineDiff("BACKWARD", "BACKWARD"), "")
+}
@@ -0,0 +1,91 @@
+package schemadiff
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Format is an output format of a diff
+type Format string
+
+const (
+	FormatText     Format = "text"
+	FormatMarkdown Format = "markdown"
+	FormatJSON     Format = "json"
+)
+
+var ErrUnknownFormat = errors.New("unknown diff format")
+
+// ParseFormat parses a format, the empty string is text
+func ParseFormat(format string) (Format, error) {
+	switch f := Format(strings.ToLower(format)); f {
+	case "":
+		return FormatText, nil
+	case FormatText, FormatMarkdown, FormatJSON:
+		return f, nil
+	}
+
+	return "", fmt.Errorf("%w: %s", ErrUnknownFormat, format)
+}
+
+// Write writes the diff in format
+func (d *Diff) Write(w io.Writer, format Format) error {
+	switch format {
+	case FormatMarkdown:
+		return d.writeMarkdown(w)
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(d)
+	default:
+		return d.writeText(w)
+	}
+}
+
+// writeText writes a line for every change prefixed by +, - or ~
+func (d *Diff) writeText(w io.Writer) error {
+	var b strings.Builder
+	if !d.HasChanges() {
+		b.WriteString("No changes.\n")
+	}
+	for _, c := range d.Changes {
+		fmt.Fprintf(&b, "%s %s\n", c.symbol(), c)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeMarkdown writes the changes as a table
+func (d *Diff) writeMarkdown(w io.Writer) error {
+	var b strings.Builder
+	if !d.HasChanges() {
+		b.WriteString("No changes.\n")
+	} else {
+		b.WriteString("| Change | Path | Before | After |\n| --- | --- | --- | --- |\n")
+	}
+	for _, c := range d.Changes {
+		fmt.Fprintf(&b, "| %s | %s | %s | %s |\n", c.Kind, markdownCode(c.Path), markdownCode(c.Before), markdownCode(c.After))
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// markdownCode formats s as inline code of a table cell, empty values stay empty
+func markdownCode(s string) string {
+	if s == "" {
+		return ""
+	}
+
+	s = strings.ReplaceAll(s, "|", `\|`)
+	s = strings.ReplaceAll(s, "\n", " ")
+	if strings.Contains(s, "`") {
+		return "`` " + s + " ``"
+	}
+
+	return "`" + s + "`"
+}
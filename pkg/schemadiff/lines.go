@@ -0,0 +1,61 @@
+package schemadiff
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+// LineDiff returns the lines removed from previous with a "-" prefix and the lines added by current with a "+" prefix,
+// json contents are indented first so schemas written on one line are compared field by field
+func LineDiff(previous, current string) string {
+	a, b := lines(previous), lines(current)
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var out []string
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			i++
+			j++
+		case j < len(b) && (i == len(a) || lcs[i][j+1] > lcs[i+1][j]):
+			out = append(out, "+"+b[j])
+			j++
+		default:
+			out = append(out, "-"+a[i])
+			i++
+		}
+	}
+
+	return strings.Join(out, "\n")
+}
+
+func lines(content string) []string {
+	if strings.TrimSpace(content) == "" {
+		return nil
+	}
+
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, []byte(content), "", "  "); err == nil {
+		content = indented.String()
+	}
+
+	return strings.Split(strings.TrimRight(content, "\n"), "\n")
+}
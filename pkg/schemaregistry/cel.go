@@ -0,0 +1,568 @@
+package schemaregistry
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// a small subset of CEL that is enough for condition rules: literals, field selection, comparison,
+// logical and arithmetic operators, size(), has() and the string functions startsWith, endsWith,
+// contains and matches
+
+type (
+	celTokenKind int
+
+	celToken struct {
+		kind celTokenKind
+		text string
+	}
+
+	celNode interface {
+		eval(vars map[string]interface{}) (interface{}, error)
+	}
+
+	celLiteral struct{ value interface{} }
+	celIdent   struct{ name string }
+	celSelect  struct {
+		operand celNode
+		field   string
+	}
+	celUnary struct {
+		op      string
+		operand celNode
+	}
+	celBinary struct {
+		op          string
+		left, right celNode
+	}
+	celCall struct {
+		target celNode
+		name   string
+		args   []celNode
+	}
+
+	celParser struct {
+		tokens []celToken
+		pos    int
+	}
+)
+
+const (
+	celEOF celTokenKind = iota
+	celIdentToken
+	celNumberToken
+	celStringToken
+	celOperatorToken
+)
+
+var celOperators = []string{"==", "!=", "<=", ">=", "&&", "||", "<", ">", "!", "+", "-", "*", "/", "%", "(", ")", ".", ","}
+
+func evalCondition(expr string, vars map[string]interface{}) (bool, error) {
+	node, err := parseCEL(expr)
+	if err != nil {
+		return false, err
+	}
+
+	v, err := node.eval(vars)
+	if err != nil {
+		return false, err
+	}
+
+	result, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("expression must evaluate to bool, got %v", v)
+	}
+
+	return result, nil
+}
+
+func tokenizeCEL(expr string) ([]celToken, error) {
+	var tokens []celToken
+
+	for i := 0; i < len(expr); {
+		ch := rune(expr[i])
+		switch {
+		case unicode.IsSpace(ch):
+			i++
+
+		case ch == '_' || unicode.IsLetter(ch):
+			start := i
+			for i < len(expr) && (expr[i] == '_' || unicode.IsLetter(rune(expr[i])) || unicode.IsDigit(rune(expr[i]))) {
+				i++
+			}
+			tokens = append(tokens, celToken{celIdentToken, expr[start:i]})
+
+		case unicode.IsDigit(ch):
+			start := i
+			for i < len(expr) && (unicode.IsDigit(rune(expr[i])) || expr[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, celToken{celNumberToken, expr[start:i]})
+
+		case ch == '\'' || ch == '"':
+			var sb strings.Builder
+			i++
+			for ; i < len(expr) && rune(expr[i]) != ch; i++ {
+				if expr[i] == '\\' && i+1 < len(expr) {
+					i++
+				}
+				sb.WriteByte(expr[i])
+			}
+			if i >= len(expr) {
+				return nil, fmt.Errorf("unterminated string in %q", expr)
+			}
+			i++
+			tokens = append(tokens, celToken{celStringToken, sb.String()})
+
+		default:
+			matched := false
+			for _, op := range celOperators {
+				if strings.HasPrefix(expr[i:], op) {
+					tokens = append(tokens, celToken{celOperatorToken, op})
+					i += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("unexpected character %q in %q", ch, expr)
+			}
+		}
+	}
+
+	return append(tokens, celToken{kind: celEOF}), nil
+}
+
+func parseCEL(expr string) (celNode, error) {
+	tokens, err := tokenizeCEL(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &celParser{tokens: tokens}
+	node, err := p.parseBinary(0)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.peek().kind != celEOF {
+		return nil, fmt.Errorf("unexpected %q in %q", p.peek().text, expr)
+	}
+
+	return node, nil
+}
+
+// celPrecedence lists binary operators from the lowest to the highest precedence
+var celPrecedence = [][]string{
+	{"||"},
+	{"&&"},
+	{"==", "!=", "<", "<=", ">", ">="},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+func (p *celParser) peek() celToken {
+	return p.tokens[p.pos]
+}
+
+func (p *celParser) next() celToken {
+	t := p.tokens[p.pos]
+	if t.kind != celEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *celParser) accept(op string) bool {
+	if t := p.peek(); t.kind == celOperatorToken && t.text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *celParser) expect(op string) error {
+	if !p.accept(op) {
+		return fmt.Errorf("expected %q but got %q", op, p.peek().text)
+	}
+	return nil
+}
+
+func (p *celParser) parseBinary(level int) (celNode, error) {
+	if level == len(celPrecedence) {
+		return p.parseUnary()
+	}
+
+	left, err := p.parseBinary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		op := ""
+		for _, candidate := range celPrecedence[level] {
+			if p.accept(candidate) {
+				op = candidate
+				break
+			}
+		}
+		if op == "" {
+			return left, nil
+		}
+
+		right, err := p.parseBinary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = celBinary{op: op, left: left, right: right}
+	}
+}
+
+func (p *celParser) parseUnary() (celNode, error) {
+	for _, op := range []string{"!", "-"} {
+		if p.accept(op) {
+			operand, err := p.parseUnary()
+			if err != nil {
+				return nil, err
+			}
+			return celUnary{op: op, operand: operand}, nil
+		}
+	}
+
+	return p.parsePostfix()
+}
+
+func (p *celParser) parsePostfix() (celNode, error) {
+	node, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.accept(".") {
+		t := p.next()
+		if t.kind != celIdentToken {
+			return nil, fmt.Errorf("expected field name but got %q", t.text)
+		}
+
+		if p.accept("(") {
+			args, err := p.parseArgs()
+			if err != nil {
+				return nil, err
+			}
+			node = celCall{target: node, name: t.text, args: args}
+			continue
+		}
+
+		node = celSelect{operand: node, field: t.text}
+	}
+
+	return node, nil
+}
+
+func (p *celParser) parseArgs() ([]celNode, error) {
+	var args []celNode
+	if p.accept(")") {
+		return args, nil
+	}
+
+	for {
+		arg, err := p.parseBinary(0)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+
+		if p.accept(")") {
+			return args, nil
+		}
+		if err = p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *celParser) parsePrimary() (celNode, error) {
+	t := p.next()
+
+	switch t.kind {
+	case celNumberToken:
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, err
+		}
+		return celLiteral{f}, nil
+
+	case celStringToken:
+		return celLiteral{t.text}, nil
+
+	case celIdentToken:
+		switch t.text {
+		case "true":
+			return celLiteral{true}, nil
+		case "false":
+			return celLiteral{false}, nil
+		case "null":
+			return celLiteral{nil}, nil
+		}
+
+		if p.accept("(") {
+			args, err := p.parseArgs()
+			if err != nil {
+				return nil, err
+			}
+			return celCall{name: t.text, args: args}, nil
+		}
+		return celIdent{t.text}, nil
+
+	case celOperatorToken:
+		if t.text == "(" {
+			node, err := p.parseBinary(0)
+			if err != nil {
+				return nil, err
+			}
+			return node, p.expect(")")
+		}
+	}
+
+	return nil, fmt.Errorf("unexpected %q", t.text)
+}
+
+func (n celLiteral) eval(map[string]interface{}) (interface{}, error) {
+	return n.value, nil
+}
+
+func (n celIdent) eval(vars map[string]interface{}) (interface{}, error) {
+	v, ok := vars[n.name]
+	if !ok {
+		return nil, fmt.Errorf("undeclared reference to %s", n.name)
+	}
+	return v, nil
+}
+
+func (n celSelect) eval(vars map[string]interface{}) (interface{}, error) {
+	operand, err := n.operand.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+
+	obj, ok := operand.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("can not select %s of %v", n.field, operand)
+	}
+
+	v, ok := obj[n.field]
+	if !ok {
+		return nil, fmt.Errorf("no such key: %s", n.field)
+	}
+	return v, nil
+}
+
+func (n celUnary) eval(vars map[string]interface{}) (interface{}, error) {
+	v, err := n.operand.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "!":
+		if b, ok := v.(bool); ok {
+			return !b, nil
+		}
+	case "-":
+		if f, ok := v.(float64); ok {
+			return -f, nil
+		}
+	}
+
+	return nil, fmt.Errorf("operator %s is not defined for %v", n.op, v)
+}
+
+func (n celBinary) eval(vars map[string]interface{}) (interface{}, error) {
+	left, err := n.left.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+
+	// logical operators short circuit
+	if n.op == "&&" || n.op == "||" {
+		l, ok := left.(bool)
+		if !ok {
+			return nil, fmt.Errorf("operator %s is not defined for %v", n.op, left)
+		}
+		if (n.op == "&&" && !l) || (n.op == "||" && l) {
+			return l, nil
+		}
+
+		right, err := n.right.eval(vars)
+		if err != nil {
+			return nil, err
+		}
+		r, ok := right.(bool)
+		if !ok {
+			return nil, fmt.Errorf("operator %s is not defined for %v", n.op, right)
+		}
+		return r, nil
+	}
+
+	right, err := n.right.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==":
+		return celEqual(left, right), nil
+	case "!=":
+		return !celEqual(left, right), nil
+	}
+
+	if l, ok := left.(string); ok {
+		if r, ok := right.(string); ok {
+			switch n.op {
+			case "+":
+				return l + r, nil
+			case "<":
+				return l < r, nil
+			case "<=":
+				return l <= r, nil
+			case ">":
+				return l > r, nil
+			case ">=":
+				return l >= r, nil
+			}
+		}
+	}
+
+	if l, ok := left.(float64); ok {
+		if r, ok := right.(float64); ok {
+			switch n.op {
+			case "+":
+				return l + r, nil
+			case "-":
+				return l - r, nil
+			case "*":
+				return l * r, nil
+			case "/":
+				if r == 0 {
+					return nil, fmt.Errorf("division by zero")
+				}
+				return l / r, nil
+			case "%":
+				// the modulus is taken of the integer parts, divisors between -1 and 1 are zero
+				if int64(r) == 0 {
+					return nil, fmt.Errorf("modulus by zero")
+				}
+				return float64(int64(l) % int64(r)), nil
+			case "<":
+				return l < r, nil
+			case "<=":
+				return l <= r, nil
+			case ">":
+				return l > r, nil
+			case ">=":
+				return l >= r, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("operator %s is not defined for %v and %v", n.op, left, right)
+}
+
+func celEqual(left, right interface{}) bool {
+	switch l := left.(type) {
+	case nil:
+		return right == nil
+	case bool, float64, string:
+		return l == right
+	}
+
+	return fmt.Sprint(left) == fmt.Sprint(right)
+}
+
+func (n celCall) eval(vars map[string]interface{}) (interface{}, error) {
+	if n.target == nil && n.name == "has" {
+		return n.evalHas(vars)
+	}
+
+	args := make([]interface{}, 0, len(n.args)+1)
+	if n.target != nil {
+		target, err := n.target.eval(vars)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, target)
+	}
+	for _, a := range n.args {
+		v, err := a.eval(vars)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, v)
+	}
+
+	switch n.name {
+	case "size":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("size expects one argument")
+		}
+		switch v := args[0].(type) {
+		case string:
+			return float64(len([]rune(v))), nil
+		case []interface{}:
+			return float64(len(v)), nil
+		case map[string]interface{}:
+			return float64(len(v)), nil
+		}
+		return nil, fmt.Errorf("size is not defined for %v", args[0])
+
+	case "startsWith", "endsWith", "contains", "matches":
+		if len(args) != 2 {
+			return nil, fmt.Errorf("%s expects a target and one argument", n.name)
+		}
+		s, ok := args[0].(string)
+		arg, argOk := args[1].(string)
+		if !ok || !argOk {
+			return nil, fmt.Errorf("%s is only defined for strings", n.name)
+		}
+
+		switch n.name {
+		case "startsWith":
+			return strings.HasPrefix(s, arg), nil
+		case "endsWith":
+			return strings.HasSuffix(s, arg), nil
+		case "contains":
+			return strings.Contains(s, arg), nil
+		}
+		return regexp.MatchString(arg, s)
+	}
+
+	return nil, fmt.Errorf("undeclared reference to function %s", n.name)
+}
+
+// evalHas implements has(message.field), it is true if the field is present
+func (n celCall) evalHas(vars map[string]interface{}) (interface{}, error) {
+	if len(n.args) != 1 {
+		return nil, fmt.Errorf("has expects one argument")
+	}
+
+	sel, ok := n.args[0].(celSelect)
+	if !ok {
+		return nil, fmt.Errorf("has argument must be a field selection")
+	}
+
+	operand, err := sel.operand.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+
+	obj, ok := operand.(map[string]interface{})
+	if !ok {
+		return false, nil
+	}
+
+	v, ok := obj[sel.field]
+	return ok && v != nil, nil
+}
@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"math"
 	"net"
 	"net/http"
 	"net/url"
@@ -24,19 +25,41 @@ type (
 	Client interface {
 		Subjects() (subjects []string, err error)
 		Versions(subject string) (versions []int, err error)
-		DeleteSubject(subject string) (versions []string, err error)
+		DeleteSubject(subject string) (versions []int, err error)
+		DeleteSchemaVersion(subject string, version string) (int, error)
 		IsRegistered(subject, schema string) (bool, Schema, error)
+		LookupSchema(subject string, schema Schema) (bool, Schema, error)
 		RegisterNewSchema(subject string, avroSchema string) (int, error)
+		RegisterSchema(subject string, schema Schema) (int, error)
 		GetSchemaById(id int) (string, error)
+		GetSchema(id int) (*Schema, error)
+		SchemaSubjectVersions(id int) ([]SubjectVersion, error)
 		GetSchemaByVersion(subject string, version string) (*Schema, error)
 		GetLatestSchema(subject string) (*Schema, error)
 		IsSchemaCompatible(subject string, avroSchema string, version int) (bool, error)
 		IsLatestSchemaCompatible(subject string, avroSchema string) (bool, error)
+		CheckCompatibility(subject string, avroSchema string, version string) (bool, []string, error)
+		CheckSchemaCompatibility(subject string, schema Schema, version string) (bool, []string, error)
+		GetCompatibilityLevel(subject string) (string, error)
+		SetCompatibilityLevel(subject string, level string) (string, error)
+		Contexts() (contexts []string, err error)
+		WithContext(name string) Client
+		GetMode(subject string) (string, error)
+		SetMode(subject string, mode string) (string, error)
+		ImportSchema(subject string, schema Schema) (int, error)
 	}
 
 	client struct {
 		baseUrl    string
 		httpClient httpDoer
+		inFlight   group
+		cache      *diskCache
+		onStale    StaleHandler
+		normalize  bool
+		context    string
+		username   string
+		password   string
+		token      string
 	}
 
 	Option func(*client)
@@ -76,7 +99,7 @@ func usingClient(httpClient *http.Client) Option {
 	}
 }
 
-func NewClient(baseUrl string) (Client, error) {
+func NewClient(baseUrl string, opts ...Option) (Client, error) {
 	if baseUrl == "" {
 		return nil, errRequired("baseUrl")
 	}
@@ -86,11 +109,21 @@ func NewClient(baseUrl string) (Client, error) {
 	}
 
 	c := &client{baseUrl: baseUrl}
+	for _, opt := range opts {
+		opt(c)
+	}
+
 	if c.httpClient == nil {
 		httpClient := &http.Client{}
 		usingClient(httpClient)(c)
 	}
 
+	if c.cache != nil {
+		if err := c.cache.load(); err != nil {
+			return nil, err
+		}
+	}
+
 	return c, nil
 }
 
@@ -125,6 +158,8 @@ const (
 	schemaNotFoundCode  = 40403
 	versionNotFound     = 40402
 	invalidAvroSchema   = 42201
+	invalidVersion      = 42202
+	incompatibleSchema  = 409
 )
 
 func IsInvalidAvroSchema(err error) bool {
@@ -139,6 +174,35 @@ func IsInvalidAvroSchema(err error) bool {
 	return false
 }
 
+func IsInvalidVersion(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if resErr, ok := err.(ResourceError); ok {
+		return resErr.ErrorCode == invalidVersion
+	}
+
+	return false
+}
+
+func IsIncompatibleSchema(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if resErr, ok := err.(ResourceError); ok {
+		return resErr.ErrorCode == incompatibleSchema
+	}
+
+	return false
+}
+
+// IsUnavailable returns true if the registry could not be reached or failed with a server error
+func IsUnavailable(err error) bool {
+	return isUnavailable(err)
+}
+
 func IsSubjectNotFound(err error) bool {
 	if err == nil {
 		return false
@@ -280,6 +344,7 @@ func (c *client) do(method, path, contentType string, send []byte) (*http.Respon
 		req.Header.Set(contentTypeHeaderKey, contentType)
 	}
 
+	c.authenticate(req)
 	req.Header.Add(acceptEncodingHeaderKey, gzipEncodingHeaderValue)
 	req.Header.Add(acceptHeaderKey, contentTypeJSON+", "+contentTypeSchemaJSON)
 
@@ -292,6 +357,9 @@ func (c *client) do(method, path, contentType string, send []byte) (*http.Respon
 		defer resp.Body.Close()
 		var resError ResourceError
 		c.readJSON(resp, &resError)
+		if resError.ErrorCode == 0 {
+			resError.ErrorCode = resp.StatusCode
+		}
 
 		return nil, resError
 	}
@@ -299,6 +367,70 @@ func (c *client) do(method, path, contentType string, send []byte) (*http.Respon
 	return resp, nil
 }
 
+// get sends a GET request and returns the response body,
+// identical concurrent requests are collapsed into one and share the result
+func (c *client) get(path string) ([]byte, error) {
+	body, err, _ := c.inFlight.do(path, func() ([]byte, error) {
+		return c.fetch(path)
+	})
+
+	return body, err
+}
+
+func (c *client) fetch(path string) ([]byte, error) {
+	resp, err := c.do(http.MethodGet, path, "", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.readResponseBody(resp)
+}
+
+func (c *client) getJSON(path string, val interface{}) error {
+	b, err := c.get(path)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(b, val)
+}
+
+// getCachedJSON is getJSON backed by the disk cache, if enabled, successful responses are
+// persisted and served back when the registry is unavailable
+func (c *client) getCachedJSON(path string, val interface{}) error {
+	if c.cache == nil {
+		return c.getJSON(path, val)
+	}
+
+	// the response is cached once by the request the concurrent callers share
+	b, err, _ := c.inFlight.do(path, func() ([]byte, error) {
+		b, err := c.fetch(path)
+		if err == nil {
+			// the cache is best effort, a failed write must not fail the request
+			_ = c.cache.put(path, b)
+		}
+		return b, err
+	})
+	if err == nil {
+		return json.Unmarshal(b, val)
+	}
+
+	if !isUnavailable(err) {
+		return err
+	}
+
+	entry, ok := c.cache.get(path)
+	if !ok {
+		return err
+	}
+
+	if c.onStale != nil {
+		c.onStale(path, entry.FetchedAt, err)
+	}
+
+	return json.Unmarshal(entry.Body, val)
+}
+
 const (
 	subjectsPath = "subjects"
 	subjectPath  = subjectsPath + "/%s"
@@ -314,14 +446,19 @@ var errRequired = func(field string) error {
 // Subjects returns list of subjects
 func (c *client) Subjects() (subjects []string, err error) {
 
+	path := subjectsPath
+	if prefix := c.contextPrefix(); prefix != "" {
+		path += "?subjectPrefix=" + url.QueryEscape(prefix)
+	}
+
 	// GET /subjects
-	resp, resError := c.do(http.MethodGet, subjectsPath, "", nil)
-	if resError != nil {
-		err = resError
+	if err = c.getJSON(path, &subjects); err != nil {
 		return
 	}
 
-	err = c.readJSON(resp, &subjects)
+	for i, s := range subjects {
+		subjects[i] = c.unqualifySubject(s)
+	}
 	return
 }
 
@@ -333,26 +470,20 @@ func (c *client) Versions(subject string) (versions []int, err error) {
 	}
 
 	// GET /subjects/{string: subject}/versions
-	path := fmt.Sprintf(versionsPath, subject)
-	resp, resError := c.do(http.MethodGet, path, "", nil)
-	if resError != nil {
-		err = resError
-		return
-	}
-
-	err = c.readJSON(resp, &versions)
+	path := fmt.Sprintf(versionsPath, c.escapeSubject(subject))
+	err = c.getJSON(path, &versions)
 	return
 }
 
 // DeleteSubject deletes subject and returns deleted versions belong with it
-func (c *client) DeleteSubject(subject string) (versions []string, err error) {
+func (c *client) DeleteSubject(subject string) (versions []int, err error) {
 	if subject == "" {
 		err = errRequired("subject")
 		return
 	}
 
 	// DELETE /subjects/{string: subject}
-	path := fmt.Sprintf(subjectPath, subject)
+	path := fmt.Sprintf(subjectPath, c.escapeSubject(subject))
 	resp, resError := c.do(http.MethodDelete, path, "", nil)
 	if resError != nil {
 		err = resError
@@ -363,49 +494,122 @@ func (c *client) DeleteSubject(subject string) (versions []string, err error) {
 	return
 }
 
+// DeleteSchemaVersion deletes a version of subject, a number or "latest", and returns the deleted version number
+func (c *client) DeleteSchemaVersion(subject string, version string) (int, error) {
+	if subject == "" {
+		return 0, errRequired("subject")
+	}
+	if version == "" {
+		return 0, errRequired("version")
+	}
+	if err := checkSchemaVersionNumber(version); err != nil {
+		return 0, err
+	}
+
+	// DELETE /subjects/{string: subject}/versions/{string: version}
+	path := fmt.Sprintf(versionPath, c.escapeSubject(subject), version)
+	resp, err := c.do(http.MethodDelete, path, "", nil)
+	if err != nil {
+		return 0, err
+	}
+
+	var deleted int
+	err = c.readJSON(resp, &deleted)
+	return deleted, err
+}
+
 type (
 	schemaOnlyJSON struct {
 		Schema string `json:"schema"`
 	}
 
+	// lookupSchemaJSON is the body of lookups and compatibility checks, the registry parses the schema as avro
+	// if it has no type
+	lookupSchemaJSON struct {
+		Schema     string      `json:"schema"`
+		SchemaType string      `json:"schemaType,omitempty"`
+		References []Reference `json:"references,omitempty"`
+	}
+
 	idOnlyJSON struct {
 		ID int `json:"id"`
 	}
 
 	isCompatibleJSON struct {
-		IsCompatible bool `json:"is_compatible"`
+		IsCompatible bool     `json:"is_compatible"`
+		Messages     []string `json:"messages,omitempty"`
+	}
+
+	registerSchemaJSON struct {
+		Schema     string      `json:"schema"`
+		SchemaType string      `json:"schemaType,omitempty"`
+		References []Reference `json:"references,omitempty"`
+		Metadata   *Metadata   `json:"metadata,omitempty"`
+		RuleSet    *RuleSet    `json:"ruleSet,omitempty"`
+		ID         int         `json:"id,omitempty"`
+		Version    int         `json:"version,omitempty"`
 	}
 
 	Schema struct {
-		Schema  string `json:"schema"`
+		Schema     string      `json:"schema"`
+		Subject    string      `json:"subject"`
+		Version    int         `json:"version"`
+		ID         int         `json:"id,omitempty"`
+		SchemaType string      `json:"schemaType,omitempty"`
+		References []Reference `json:"references,omitempty"`
+		Metadata   *Metadata   `json:"metadata,omitempty"`
+		RuleSet    *RuleSet    `json:"ruleSet,omitempty"`
+	}
+
+	// Reference is a schema referenced by name, e.g. an imported type, registered as a version of a subject
+	Reference struct {
+		Name    string `json:"name"`
+		Subject string `json:"subject"`
+		Version int    `json:"version"`
+	}
+
+	// SubjectVersion is a version of a subject registered with a schema
+	SubjectVersion struct {
 		Subject string `json:"subject"`
 		Version int    `json:"version"`
-		ID      int    `json:"id,omitempty"`
 	}
 )
 
 // SchemaLatestVersion only valid string for version, it's the "latest" version string
 const SchemaLatestVersion = "latest"
 
-// IsRegistered returns true if the given schema is registered already
+// schema types of the registry, schemas without a type are avro schemas
+const (
+	SchemaTypeAvro     = "AVRO"
+	SchemaTypeJSON     = "JSON"
+	SchemaTypeProtobuf = "PROTOBUF"
+)
+
+// IsRegistered returns true if the given avro schema is registered already
 func (c *client) IsRegistered(subject, schema string) (bool, Schema, error) {
+	return c.LookupSchema(subject, Schema{Schema: schema})
+}
+
+// LookupSchema returns true and the registered version if the schema with its type and references is registered
+// under subject already
+func (c *client) LookupSchema(subject string, schema Schema) (bool, Schema, error) {
 	var sc Schema
 
 	if subject == "" {
 		return false, sc, errRequired("subject")
 	}
-	if schema == "" {
+	if schema.Schema == "" {
 		return false, sc, errRequired("schema")
 	}
 
-	schemaOnly := schemaOnlyJSON{schema}
-	send, err := json.Marshal(schemaOnly)
+	lookup := lookupSchemaJSON{Schema: schema.Schema, SchemaType: schema.SchemaType, References: schema.References}
+	send, err := json.Marshal(lookup)
 	if err != nil {
 		return false, sc, err
 	}
 
 	// POST /subjects/{string: subject}
-	path := fmt.Sprintf(subjectPath, subject)
+	path := fmt.Sprintf(subjectPath, c.escapeSubject(subject)) + c.normalizeQuery()
 	resp, resErr := c.do(http.MethodPost, path, "", send)
 	if resErr != nil {
 		// is schema found?
@@ -419,27 +623,42 @@ func (c *client) IsRegistered(subject, schema string) (bool, Schema, error) {
 	if err = c.readJSON(resp, &sc); err != nil {
 		return true, sc, err // found but error when unmarshal
 	}
+	sc.Subject = c.unqualifySubject(sc.Subject)
 
 	return true, sc, nil
 }
 
 // RegisterNewSchema registers a new schema and returns id of it
 func (c *client) RegisterNewSchema(subject string, avroSchema string) (int, error) {
+	return c.RegisterSchema(subject, Schema{Schema: avroSchema})
+}
+
+// RegisterSchema registers a new schema with its references, metadata and rule set and returns id of it
+func (c *client) RegisterSchema(subject string, schema Schema) (int, error) {
+	return c.register(subject, registerSchemaJSON{
+		Schema:     schema.Schema,
+		SchemaType: schema.SchemaType,
+		References: schema.References,
+		Metadata:   schema.Metadata,
+		RuleSet:    schema.RuleSet,
+	})
+}
+
+func (c *client) register(subject string, register registerSchemaJSON) (int, error) {
 	if subject == "" {
 		return 0, errRequired("subject")
 	}
-	if avroSchema == "" {
+	if register.Schema == "" {
 		return 0, errRequired("schema")
 	}
 
-	schema := schemaOnlyJSON{avroSchema}
-	send, err := json.Marshal(schema)
+	send, err := json.Marshal(register)
 	if err != nil {
 		return 0, err
 	}
 
 	// POST /subjects/{string: subject}/versions
-	path := fmt.Sprintf(versionsPath, subject)
+	path := fmt.Sprintf(versionsPath, c.escapeSubject(subject)) + c.normalizeQuery()
 	resp, err := c.do(http.MethodPost, path, contentTypeSchemaJSON, send)
 	if err != nil {
 		return 0, err
@@ -452,20 +671,49 @@ func (c *client) RegisterNewSchema(subject string, avroSchema string) (int, erro
 
 // GetSchemaById gets schema by id
 func (c *client) GetSchemaById(id int) (string, error) {
+	sc, err := c.GetSchema(id)
+	if err != nil {
+		return "", err
+	}
+
+	return sc.Schema, nil
+}
+
+// GetSchema gets schema by id with its type, the subject and version of the result are not set
+func (c *client) GetSchema(id int) (*Schema, error) {
 
 	// GET /schemas/ids/{int: id}
 	path := fmt.Sprintf(schemaPath, id)
-	resp, err := c.do(http.MethodGet, path, "", nil)
-	if err != nil {
-		return "", err
+	if prefix := c.contextPrefix(); prefix != "" {
+		path += "?subject=" + url.QueryEscape(prefix)
+	}
+	var sc Schema
+	if err := c.getCachedJSON(path, &sc); err != nil {
+		return nil, err
 	}
 
-	var sc schemaOnlyJSON
-	if err = c.readJSON(resp, &sc); err != nil {
-		return "", err
+	sc.ID = id
+	return &sc, nil
+}
+
+// SchemaSubjectVersions returns the versions of the subjects registered with the schema of id, they change with
+// every registration and deletion so they are not cached
+func (c *client) SchemaSubjectVersions(id int) ([]SubjectVersion, error) {
+
+	// GET /schemas/ids/{int: id}/versions
+	path := fmt.Sprintf(schemaPath, id) + "/versions"
+	if prefix := c.contextPrefix(); prefix != "" {
+		path += "?subject=" + url.QueryEscape(prefix)
+	}
+	var versions []SubjectVersion
+	if err := c.getJSON(path, &versions); err != nil {
+		return nil, err
 	}
 
-	return sc.Schema, nil
+	for i, v := range versions {
+		versions[i].Subject = c.unqualifySubject(v.Subject)
+	}
+	return versions, nil
 }
 
 // GetSchemaByVersion gets schema by version number
@@ -483,16 +731,12 @@ func (c *client) GetSchemaByVersion(subject string, version string) (*Schema, er
 	}
 
 	// GET /subjects/{string: subject}/versions/{string: version}
-	path := fmt.Sprintf(versionPath, subject, version)
-	resp, err := c.do(http.MethodGet, path, "", nil)
-	if err != nil {
-		return nil, err
-	}
-
+	path := fmt.Sprintf(versionPath, c.escapeSubject(subject), version)
 	var schema Schema
-	if err = c.readJSON(resp, &schema); err != nil {
+	if err := c.getCachedJSON(path, &schema); err != nil {
 		return nil, err
 	}
+	schema.Subject = c.unqualifySubject(schema.Subject)
 
 	return &schema, nil
 }
@@ -511,7 +755,7 @@ func checkSchemaVersionNumber(versionNumber interface{}) error {
 
 	if verStr, ok := versionNumber.(string); ok {
 		if v, err := strconv.Atoi(verStr); err == nil {
-			if v <= 0 || v > 2^31-1 {
+			if v <= 0 || v > math.MaxInt32 {
 				return fmt.Errorf(stringNotValid, versionNumber)
 			} else {
 				return nil
@@ -524,7 +768,7 @@ func checkSchemaVersionNumber(versionNumber interface{}) error {
 	}
 
 	if verInt, ok := versionNumber.(int); ok {
-		if verInt <= 0 || verInt > 2^31-1 {
+		if verInt <= 0 || verInt > math.MaxInt32 {
 			return fmt.Errorf(stringNotValid, versionNumber)
 		}
 	}
@@ -532,43 +776,73 @@ func checkSchemaVersionNumber(versionNumber interface{}) error {
 	return nil
 }
 
-func (c *client) isSchemaCompatibleAtVersion(subject string, avroSchema string, version interface{}) (bool, error) {
+func (c *client) isSchemaCompatibleAtVersion(subject string, schema Schema, version interface{}, verbose bool) (isCompatibleJSON, error) {
+	var result isCompatibleJSON
+
 	if subject == "" {
-		return false, errRequired("subject")
+		return result, errRequired("subject")
 	}
-	if avroSchema == "" {
-		return false, errRequired("avroSchema")
+	if schema.Schema == "" {
+		return result, errRequired("avroSchema")
 	}
 	if err := checkSchemaVersionNumber(version); err != nil {
-		return false, err
+		return result, err
 	}
 
-	schema := schemaOnlyJSON{avroSchema}
+	lookup := lookupSchemaJSON{Schema: schema.Schema, SchemaType: schema.SchemaType, References: schema.References}
 
-	send, err := json.Marshal(schema)
+	send, err := json.Marshal(lookup)
 	if err != nil {
-		return false, err
+		return result, err
+	}
+
+	query := url.Values{}
+	if c.normalize {
+		query.Set("normalize", "true")
+	}
+	if verbose {
+		query.Set("verbose", "true")
 	}
 
 	// POST /compatibility/subjects/{string: subject}/versions/{string: version}
-	path := fmt.Sprintf("compatibility/"+versionPath, subject, fmt.Sprintf("%v", version))
+	path := fmt.Sprintf("compatibility/"+versionPath, c.escapeSubject(subject), fmt.Sprintf("%v", version))
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
 	resp, err := c.do(http.MethodPost, path, contentTypeSchemaJSON, send)
 	if err != nil {
-		return false, err
+		return result, err
 	}
 
-	var isCompatibleJSON isCompatibleJSON
-	err = c.readJSON(resp, &isCompatibleJSON)
-
-	return isCompatibleJSON.IsCompatible, err
+	err = c.readJSON(resp, &result)
+	return result, err
 }
 
 // IsSchemaCompatible is schema is compatible with version
 func (c *client) IsSchemaCompatible(subject string, avroSchema string, version int) (bool, error) {
-	return c.isSchemaCompatibleAtVersion(subject, avroSchema, version)
+	result, err := c.isSchemaCompatibleAtVersion(subject, Schema{Schema: avroSchema}, version, false)
+	return result.IsCompatible, err
 }
 
 // IsLatestSchemaCompatible is schema is compatible with last version
 func (c *client) IsLatestSchemaCompatible(subject string, avroSchema string) (bool, error) {
-	return c.isSchemaCompatibleAtVersion(subject, avroSchema, SchemaLatestVersion)
+	result, err := c.isSchemaCompatibleAtVersion(subject, Schema{Schema: avroSchema}, SchemaLatestVersion, false)
+	return result.IsCompatible, err
+}
+
+// CheckCompatibility checks if avro schema is compatible with version, a number or "latest",
+// and returns the reasons reported by the registry when it is not
+func (c *client) CheckCompatibility(subject string, avroSchema string, version string) (bool, []string, error) {
+	return c.CheckSchemaCompatibility(subject, Schema{Schema: avroSchema}, version)
+}
+
+// CheckSchemaCompatibility checks if schema with its type and references is compatible with version, a number
+// or "latest", and returns the reasons reported by the registry when it is not
+func (c *client) CheckSchemaCompatibility(subject string, schema Schema, version string) (bool, []string, error) {
+	if version == "" {
+		return false, nil, errRequired("version")
+	}
+
+	result, err := c.isSchemaCompatibleAtVersion(subject, schema, version, true)
+	return result.IsCompatible, result.Messages, err
 }
@@ -4,9 +4,15 @@ import (
 	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io/ioutil"
+	"math"
 	"net/http"
 	"reflect"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"testing"
 )
 
@@ -178,6 +184,42 @@ func TestIsVersionNotFound(t *testing.T) {
 	}
 }
 
+func TestIsInvalidVersion(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected bool
+	}{
+		{nil, false},
+		{ResourceError{ErrorCode: invalidVersion}, true},
+		{ResourceError{ErrorCode: 123}, false},
+		{errors.New(""), false},
+	}
+
+	for _, c := range tests {
+		if c.expected != IsInvalidVersion(c.err) {
+			t.Fail()
+		}
+	}
+}
+
+func TestIsIncompatibleSchema(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected bool
+	}{
+		{nil, false},
+		{ResourceError{ErrorCode: incompatibleSchema}, true},
+		{ResourceError{ErrorCode: 123}, false},
+		{errors.New(""), false},
+	}
+
+	for _, c := range tests {
+		if c.expected != IsIncompatibleSchema(c.err) {
+			t.Fail()
+		}
+	}
+}
+
 func TestResourceError_Error(t *testing.T) {
 	resErr := ResourceError{}
 
@@ -236,7 +278,7 @@ func TestClient_Versions(t *testing.T) {
 }
 
 func TestClient_DeleteSubject(t *testing.T) {
-	expected := []string{"1", "2", "3"}
+	expected := []int{1, 2, 3}
 
 	type testItem struct {
 		subject     string
@@ -252,7 +294,7 @@ func TestClient_DeleteSubject(t *testing.T) {
 	for _, c := range testsError {
 		cli := client{httpClient: c.mockHandler}
 		versions, err := cli.DeleteSubject(c.subject)
-		mustEqual(t, versions, ([]string)(nil))
+		mustEqual(t, versions, ([]int)(nil))
 		mustEqual(t, err, c.expected)
 	}
 
@@ -404,6 +446,31 @@ func TestClient_GetSchemaById(t *testing.T) {
 	}
 }
 
+func TestClient_GetSchema(t *testing.T) {
+	cli := client{httpClient: mockHttpError(http.StatusNotFound, schemaNotFoundCode, nil, "")}
+	sc, err := cli.GetSchema(1)
+	mustEqual(t, err, ResourceError{ErrorCode: schemaNotFoundCode})
+	mustEqual(t, sc, (*Schema)(nil))
+
+	cli = client{httpClient: mockHttpSuccess(nil, Schema{Schema: "{}", SchemaType: "JSON"})}
+	sc, err = cli.GetSchema(2)
+	mustEqual(t, err, nil)
+	mustEqual(t, *sc, Schema{Schema: "{}", SchemaType: "JSON", ID: 2})
+}
+
+func TestClient_SchemaSubjectVersions(t *testing.T) {
+	cli := client{httpClient: mockHttpError(http.StatusNotFound, schemaNotFoundCode, nil, "")}
+	versions, err := cli.SchemaSubjectVersions(1)
+	mustEqual(t, err, ResourceError{ErrorCode: schemaNotFoundCode})
+	mustEqual(t, versions, []SubjectVersion(nil))
+
+	expected := []SubjectVersion{{Subject: testSubject, Version: 1}, {Subject: "other", Version: 3}}
+	cli = client{httpClient: mockHttpSuccess(nil, expected)}
+	versions, err = cli.SchemaSubjectVersions(2)
+	mustEqual(t, err, nil)
+	mustEqual(t, versions, expected)
+}
+
 func TestClient_GetSchemaByVersion(t *testing.T) {
 
 	expectedRespBody := Schema{
@@ -441,6 +508,7 @@ func TestClient_GetSchemaByVersion(t *testing.T) {
 
 	testsSuccess := []testItem{
 		{testSubject, "1", expectedRespBody, mockHttpSuccess(nil, expectedRespBody)},
+		{testSubject, "29", expectedRespBody, mockHttpSuccess(nil, expectedRespBody)}, // versions are not capped by a small bound
 	}
 
 	for _, c := range testsSuccess {
@@ -452,6 +520,15 @@ func TestClient_GetSchemaByVersion(t *testing.T) {
 	}
 }
 
+func TestCheckSchemaVersionNumber(t *testing.T) {
+	for _, valid := range []interface{}{"1", "29", "latest", strconv.Itoa(math.MaxInt32), 29, math.MaxInt32} {
+		mustEqual(t, checkSchemaVersionNumber(valid), nil)
+	}
+	for _, invalid := range []interface{}{"0", "-1", "abc", strconv.Itoa(math.MaxInt32 + 1), 0, -1} {
+		mustNotNil(t, checkSchemaVersionNumber(invalid))
+	}
+}
+
 func TestClient_GetLatestSchema(t *testing.T) {
 
 	type testItem struct {
@@ -516,3 +593,202 @@ func TestClient_IsSchemaCompatible(t *testing.T) {
 		mustEqual(t, is, c.expected)
 	}
 }
+
+func (g *group) waiters(key string) int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if c, ok := g.m[key]; ok {
+		return c.dups
+	}
+
+	return 0
+}
+
+func TestClient_GetSchemaById_CollapsesConcurrentRequests(t *testing.T) {
+	const callers = 100
+
+	var calls int32
+	release := make(chan struct{})
+	handler := mockHttpSuccess(nil, schemaOnlyJSON{validSchema})
+
+	cli := &client{httpClient: doFn(func(req *http.Request) (*http.Response, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return handler(req)
+	})}
+
+	var wg sync.WaitGroup
+	results := make([]string, callers)
+	errs := make([]error, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = cli.GetSchemaById(1)
+		}(i)
+	}
+
+	// wait until every caller joined the in-flight request
+	path := fmt.Sprintf(schemaPath, 1)
+	for cli.inFlight.waiters(path) != callers-1 {
+		runtime.Gosched()
+	}
+	close(release)
+	wg.Wait()
+
+	mustEqual(t, atomic.LoadInt32(&calls), int32(1))
+	for i := 0; i < callers; i++ {
+		mustEqual(t, errs[i], nil)
+		mustEqual(t, results[i], validSchema)
+	}
+}
+
+func TestGroup_Do_ReleasesWaitersOnPanic(t *testing.T) {
+	var g group
+	release := make(chan struct{})
+
+	panicked := make(chan interface{})
+	go func() {
+		defer func() { panicked <- recover() }()
+		_, _, _ = g.do("key", func() ([]byte, error) {
+			<-release
+			panic("broken response")
+		})
+	}()
+	for {
+		g.mu.Lock()
+		_, ok := g.m["key"]
+		g.mu.Unlock()
+		if ok {
+			break
+		}
+		runtime.Gosched()
+	}
+
+	waited := make(chan error)
+	go func() {
+		_, err, _ := g.do("key", func() ([]byte, error) { return nil, nil })
+		waited <- err
+	}()
+	for g.waiters("key") != 1 {
+		runtime.Gosched()
+	}
+	close(release)
+
+	mustEqual(t, <-panicked, "broken response")
+	mustEqual(t, <-waited, errPanicked)
+
+	// the key is free for the next request
+	val, err, _ := g.do("key", func() ([]byte, error) { return []byte("ok"), nil })
+	mustEqual(t, err, nil)
+	mustEqual(t, string(val), "ok")
+}
+
+func TestClient_GetSchemaById_DoesNotCollapseSequentialRequests(t *testing.T) {
+	var calls int32
+	handler := mockHttpSuccess(nil, schemaOnlyJSON{validSchema})
+
+	cli := &client{httpClient: doFn(func(req *http.Request) (*http.Response, error) {
+		atomic.AddInt32(&calls, 1)
+		return handler(req)
+	})}
+
+	for i := 0; i < 3; i++ {
+		if _, err := cli.GetSchemaById(1); err != nil {
+			t.Error(err)
+		}
+	}
+
+	mustEqual(t, atomic.LoadInt32(&calls), int32(3))
+}
+
+func TestClient_CheckCompatibility(t *testing.T) {
+	var paths []string
+	messages := []string{"READER_FIELD_MISSING_DEFAULT_VALUE: favorite_color"}
+	cli := client{httpClient: recordPaths(&paths, mockHttpSuccess(nil, isCompatibleJSON{IsCompatible: false, Messages: messages}))}
+
+	is, reasons, err := cli.CheckCompatibility(testSubject, validSchema, SchemaLatestVersion)
+	mustEqual(t, err, nil)
+	mustEqual(t, is, false)
+	mustEqual(t, reasons, messages)
+	mustEqual(t, paths, []string{"/compatibility/subjects/testsubject/versions/latest?verbose=true"})
+
+	_, _, err = cli.CheckCompatibility(testSubject, validSchema, "")
+	mustEqual(t, err, errRequired("version"))
+
+	_, _, err = cli.CheckCompatibility(testSubject, validSchema, "abc")
+	mustNotNil(t, err)
+}
+
+func recordBodies(bodies *[]string, handler doFn) doFn {
+	return func(req *http.Request) (*http.Response, error) {
+		b, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		*bodies = append(*bodies, string(b))
+		return handler(req)
+	}
+}
+
+func TestClient_LookupAndCheckSendSchemaType(t *testing.T) {
+	protobuf := Schema{
+		Schema:     `syntax = "proto3"; message Order { string id = 1; }`,
+		SchemaType: "PROTOBUF",
+		References: []Reference{{Name: "customer.proto", Subject: "customers-value", Version: 1}},
+	}
+	expected := `{"schema":"syntax = \"proto3\"; message Order { string id = 1; }","schemaType":"PROTOBUF",` +
+		`"references":[{"name":"customer.proto","subject":"customers-value","version":1}]}`
+
+	var bodies []string
+	cli := client{httpClient: recordBodies(&bodies, mockHttpSuccess(nil, Schema{Subject: testSubject, Version: 1, ID: 3}))}
+	found, sc, err := cli.LookupSchema(testSubject, protobuf)
+	mustEqual(t, err, nil)
+	mustEqual(t, found, true)
+	mustEqual(t, sc.ID, 3)
+
+	cli.httpClient = recordBodies(&bodies, mockHttpSuccess(nil, isCompatibleJSON{IsCompatible: true}))
+	compatible, _, err := cli.CheckSchemaCompatibility(testSubject, protobuf, SchemaLatestVersion)
+	mustEqual(t, err, nil)
+	mustEqual(t, compatible, true)
+
+	// avro schemas are sent without a type
+	_, _, err = cli.CheckCompatibility(testSubject, validSchema, SchemaLatestVersion)
+	mustEqual(t, err, nil)
+
+	avro, _ := json.Marshal(schemaOnlyJSON{validSchema})
+	mustEqual(t, bodies, []string{expected, expected, string(avro)})
+}
+
+func TestClient_DeleteSchemaVersion(t *testing.T) {
+	type testItem struct {
+		subject     string
+		version     string
+		expected    interface{}
+		mockHandler doFn
+	}
+
+	testsError := []testItem{
+		{"", "1", errRequired("subject"), nil},         // if subject is empty should return err
+		{testSubject, "", errRequired("version"), nil}, // if version is empty should return err
+		{testSubject, "abc", struct{}{}, nil},          // if version is invalid should return err
+		{testSubject, "1", ResourceError{ErrorCode: versionNotFound}, mockHttpError(http.StatusNotFound, versionNotFound, nil, "")}, // if version is not found should return err
+	}
+
+	for _, c := range testsError {
+		cli := client{httpClient: c.mockHandler}
+		deleted, err := cli.DeleteSchemaVersion(c.subject, c.version)
+		mustEqual(t, deleted, 0)
+		if c.expected != struct{}{} {
+			mustEqual(t, err, c.expected)
+		} else {
+			mustNotNil(t, err)
+		}
+	}
+
+	cli := client{httpClient: mockHttpSuccess(nil, 2)}
+	deleted, err := cli.DeleteSchemaVersion(testSubject, "2")
+	mustEqual(t, err, nil)
+	mustEqual(t, deleted, 2)
+}
@@ -0,0 +1,78 @@
+package schemaregistry
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+const (
+	configPath        = "config"
+	subjectConfigPath = configPath + "/%s"
+
+	CompatibilityNone               = "NONE"
+	CompatibilityBackward           = "BACKWARD"
+	CompatibilityBackwardTransitive = "BACKWARD_TRANSITIVE"
+	CompatibilityForward            = "FORWARD"
+	CompatibilityForwardTransitive  = "FORWARD_TRANSITIVE"
+	CompatibilityFull               = "FULL"
+	CompatibilityFullTransitive     = "FULL_TRANSITIVE"
+)
+
+type (
+	compatibilityLevelJSON struct {
+		CompatibilityLevel string `json:"compatibilityLevel"`
+	}
+
+	compatibilityJSON struct {
+		Compatibility string `json:"compatibility"`
+	}
+)
+
+func (c *client) configPathOf(subject string) string {
+	if subject == "" {
+		return configPath
+	}
+
+	return fmt.Sprintf(subjectConfigPath, c.escapeSubject(subject))
+}
+
+// GetCompatibilityLevel returns the compatibility level of subject, the global level if subject is empty
+// or it has no level of its own
+func (c *client) GetCompatibilityLevel(subject string) (string, error) {
+
+	// GET /config/{string: subject}
+	path := c.configPathOf(subject)
+	if subject != "" {
+		path += "?defaultToGlobal=true"
+	}
+
+	var level compatibilityLevelJSON
+	if err := c.getJSON(path, &level); err != nil {
+		return "", err
+	}
+
+	return level.CompatibilityLevel, nil
+}
+
+// SetCompatibilityLevel sets the compatibility level of subject, the global level if subject is empty
+func (c *client) SetCompatibilityLevel(subject string, level string) (string, error) {
+	if level == "" {
+		return "", errRequired("level")
+	}
+
+	send, err := json.Marshal(compatibilityJSON{Compatibility: level})
+	if err != nil {
+		return "", err
+	}
+
+	// PUT /config/{string: subject}
+	resp, err := c.do(http.MethodPut, c.configPathOf(subject), contentTypeSchemaJSON, send)
+	if err != nil {
+		return "", err
+	}
+
+	var updated compatibilityJSON
+	err = c.readJSON(resp, &updated)
+	return updated.Compatibility, err
+}
@@ -0,0 +1,39 @@
+package schemaregistry
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestClient_GetCompatibilityLevel(t *testing.T) {
+	var paths []string
+	cli := client{httpClient: recordPaths(&paths, mockHttpSuccess(nil, compatibilityLevelJSON{CompatibilityLevel: CompatibilityBackward}))}
+
+	level, err := cli.GetCompatibilityLevel(testSubject)
+	mustEqual(t, err, nil)
+	mustEqual(t, level, CompatibilityBackward)
+
+	level, err = cli.GetCompatibilityLevel("")
+	mustEqual(t, err, nil)
+	mustEqual(t, level, CompatibilityBackward)
+
+	mustEqual(t, paths, []string{"/config/testsubject?defaultToGlobal=true", "/config"})
+
+	cli = client{httpClient: mockHttpError(http.StatusNotFound, subjectNotFoundCode, nil, "")}
+	level, err = cli.GetCompatibilityLevel(testSubject)
+	mustEqual(t, err, ResourceError{ErrorCode: subjectNotFoundCode})
+	mustEqual(t, level, "")
+}
+
+func TestClient_SetCompatibilityLevel(t *testing.T) {
+	var paths []string
+	cli := client{httpClient: recordPaths(&paths, mockHttpSuccess(nil, compatibilityJSON{Compatibility: CompatibilityFull}))}
+
+	level, err := cli.SetCompatibilityLevel(testSubject, CompatibilityFull)
+	mustEqual(t, err, nil)
+	mustEqual(t, level, CompatibilityFull)
+	mustEqual(t, paths, []string{"/config/testsubject"})
+
+	_, err = cli.SetCompatibilityLevel(testSubject, "")
+	mustEqual(t, err, errRequired("level"))
+}
@@ -0,0 +1,78 @@
+package schemaregistry
+
+import (
+	"net/url"
+	"strings"
+)
+
+const (
+	contextsPath = "contexts"
+
+	// DefaultContext is the context subjects belong to when they are not qualified
+	DefaultContext = "."
+
+	contextDelimiter = ":"
+)
+
+// WithContext returns a view of the client scoped to a registry context, e.g. ".team-a",
+// subjects passed to the view are qualified as ":.team-a:subject"
+func (c *client) WithContext(name string) Client {
+	if name != "" && !strings.HasPrefix(name, ".") {
+		name = "." + name
+	}
+	if name == DefaultContext {
+		name = ""
+	}
+
+	return &client{
+		baseUrl:    c.baseUrl,
+		httpClient: c.httpClient,
+		cache:      c.cache,
+		onStale:    c.onStale,
+		normalize:  c.normalize,
+		context:    name,
+		username:   c.username,
+		password:   c.password,
+		token:      c.token,
+	}
+}
+
+// Contexts returns list of contexts
+func (c *client) Contexts() (contexts []string, err error) {
+
+	// GET /contexts
+	err = c.getJSON(contextsPath, &contexts)
+	return
+}
+
+// contextPrefix returns the qualified prefix of the context of the client, e.g. ":.team-a:"
+func (c *client) contextPrefix() string {
+	if c.context == "" {
+		return ""
+	}
+
+	return contextDelimiter + c.context + contextDelimiter
+}
+
+// qualifySubject prefixes subject with the context of the client, unless it is qualified already
+func (c *client) qualifySubject(subject string) string {
+	if c.context == "" || strings.HasPrefix(subject, contextDelimiter+".") {
+		return subject
+	}
+
+	return c.contextPrefix() + subject
+}
+
+// escapeSubject returns subject qualified with the context and escaped to be used as a path segment
+func (c *client) escapeSubject(subject string) string {
+	return url.PathEscape(c.qualifySubject(subject))
+}
+
+// unqualifySubject removes the context prefix of the client from subject
+func (c *client) unqualifySubject(subject string) string {
+	if prefix := c.contextPrefix(); prefix != "" {
+		return strings.TrimPrefix(subject, prefix)
+	}
+
+	return subject
+}
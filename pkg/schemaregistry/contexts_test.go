@@ -0,0 +1,86 @@
+package schemaregistry
+
+import (
+	"net/http"
+	"testing"
+)
+
+// recordPaths wraps handler and records the escaped path and the raw query of each request
+func recordPaths(paths *[]string, handler doFn) doFn {
+	return func(req *http.Request) (*http.Response, error) {
+		p := req.URL.EscapedPath()
+		if req.URL.RawQuery != "" {
+			p += "?" + req.URL.RawQuery
+		}
+		*paths = append(*paths, p)
+		return handler(req)
+	}
+}
+
+func TestClient_EscapesSubjects(t *testing.T) {
+	var paths []string
+	cli := client{baseUrl: "http://localhost:8081", httpClient: recordPaths(&paths, mockHttpSuccess(nil, []int{1}))}
+
+	if _, err := cli.Versions("orders/value"); err != nil {
+		t.Error(err)
+	}
+	if _, err := cli.Versions("orders value?"); err != nil {
+		t.Error(err)
+	}
+
+	mustEqual(t, paths, []string{"/subjects/orders%2Fvalue/versions", "/subjects/orders%20value%3F/versions"})
+}
+
+func TestClient_WithContext(t *testing.T) {
+	var paths []string
+	root := &client{baseUrl: "http://localhost:8081"}
+	teamA := root.WithContext(".team-a").(*client)
+
+	teamA.httpClient = recordPaths(&paths, mockHttpSuccess(nil, []int{1}))
+	if _, err := teamA.Versions(testSubject); err != nil {
+		t.Error(err)
+	}
+
+	teamA.httpClient = recordPaths(&paths, mockHttpSuccess(nil, Schema{Schema: validSchema, Subject: ":.team-a:" + testSubject, Version: 1, ID: 1}))
+	sc, err := teamA.GetSchemaByVersion(testSubject, "1")
+	mustEqual(t, err, nil)
+	mustEqual(t, sc.Subject, testSubject)
+
+	// already qualified subjects are not qualified again
+	if _, err = teamA.GetSchemaByVersion(":.team-b:"+testSubject, "1"); err != nil {
+		t.Error(err)
+	}
+
+	teamA.httpClient = recordPaths(&paths, mockHttpSuccess(nil, schemaOnlyJSON{validSchema}))
+	if _, err = teamA.GetSchemaById(1); err != nil {
+		t.Error(err)
+	}
+
+	teamA.httpClient = recordPaths(&paths, mockHttpSuccess(nil, []string{":.team-a:a", ":.team-a:b"}))
+	subjects, err := teamA.Subjects()
+	mustEqual(t, err, nil)
+	mustEqual(t, subjects, []string{"a", "b"})
+
+	mustEqual(t, paths, []string{
+		"/subjects/:.team-a:testsubject/versions",
+		"/subjects/:.team-a:testsubject/versions/1",
+		"/subjects/:.team-b:testsubject/versions/1",
+		"/schemas/ids/1?subject=%3A.team-a%3A",
+		"/subjects?subjectPrefix=%3A.team-a%3A",
+	})
+
+	// the default context is not qualified
+	mustEqual(t, root.WithContext("team-a").(*client).context, ".team-a")
+	mustEqual(t, root.WithContext(DefaultContext).(*client).qualifySubject(testSubject), testSubject)
+}
+
+func TestClient_Contexts(t *testing.T) {
+	expected := []string{".", ".team-a"}
+	var paths []string
+	cli := client{httpClient: recordPaths(&paths, mockHttpSuccess(nil, expected))}
+
+	contexts, err := cli.Contexts()
+	mustEqual(t, err, nil)
+	mustEqual(t, contexts, expected)
+	mustEqual(t, paths, []string{"/contexts"})
+}
@@ -0,0 +1,28 @@
+package schemaregistry
+
+import "net/http"
+
+const authorizationHeaderKey = "Authorization"
+
+// WithBasicAuth authenticates every request with username and password, e.g. an api key and its secret
+func WithBasicAuth(username, password string) Option {
+	return func(c *client) {
+		c.username, c.password = username, password
+	}
+}
+
+// WithBearerToken authenticates every request with token
+func WithBearerToken(token string) Option {
+	return func(c *client) {
+		c.token = token
+	}
+}
+
+func (c *client) authenticate(req *http.Request) {
+	switch {
+	case c.token != "":
+		req.Header.Set(authorizationHeaderKey, "Bearer "+c.token)
+	case c.username != "":
+		req.SetBasicAuth(c.username, c.password)
+	}
+}
@@ -0,0 +1,33 @@
+package schemaregistry
+
+import (
+	"net/http"
+	"testing"
+)
+
+// recordAuthorization wraps handler and records the authorization header of each request
+func recordAuthorization(headers *[]string, handler doFn) doFn {
+	return func(req *http.Request) (*http.Response, error) {
+		*headers = append(*headers, req.Header.Get(authorizationHeaderKey))
+		return handler(req)
+	}
+}
+
+func TestClient_Credentials(t *testing.T) {
+	var headers []string
+	handler := recordAuthorization(&headers, mockHttpSuccess(nil, []string{testSubject}))
+
+	basic := &client{baseUrl: "http://localhost:8081", httpClient: handler}
+	WithBasicAuth("key", "secret")(basic)
+	bearer := &client{baseUrl: "http://localhost:8081", httpClient: handler}
+	WithBearerToken("token")(bearer)
+	anonymous := &client{baseUrl: "http://localhost:8081", httpClient: handler}
+
+	for _, cli := range []*client{basic, bearer, anonymous} {
+		if _, err := cli.Subjects(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mustEqual(t, headers, []string{"Basic a2V5OnNlY3JldA==", "Bearer token", ""})
+}
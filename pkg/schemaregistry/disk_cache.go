@@ -0,0 +1,174 @@
+package schemaregistry
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	// StaleHandler is called when a cached response is served because the registry could not be reached
+	StaleHandler func(resource string, fetchedAt time.Time, cause error)
+
+	cacheEntry struct {
+		Resource  string          `json:"resource"`
+		FetchedAt time.Time       `json:"fetched_at"`
+		Body      json.RawMessage `json:"body"`
+	}
+
+	// diskCache keeps registry responses in memory and persists them to a directory,
+	// one file per resource, so they survive restarts
+	diskCache struct {
+		dir     string
+		mu      sync.RWMutex
+		entries map[string]cacheEntry
+	}
+)
+
+const cacheFileExt = ".json"
+
+// WithDiskCache enables a persistent cache of schemas fetched by id or version,
+// it is used as a fallback when the registry can not be reached
+func WithDiskCache(dir string) Option {
+	return func(c *client) {
+		c.cache = &diskCache{dir: dir}
+	}
+}
+
+// WithStaleHandler sets the handler called when the disk cache serves a response
+func WithStaleHandler(handler StaleHandler) Option {
+	return func(c *client) {
+		c.onStale = handler
+	}
+}
+
+// load creates the cache directory if needed and reads the entries persisted before
+func (dc *diskCache) load() error {
+	if err := os.MkdirAll(dc.dir, 0o755); err != nil {
+		return err
+	}
+
+	files, err := ioutil.ReadDir(dc.dir)
+	if err != nil {
+		return err
+	}
+
+	entries := make(map[string]cacheEntry, len(files))
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), cacheFileExt) {
+			continue
+		}
+
+		b, err := ioutil.ReadFile(filepath.Join(dc.dir, f.Name()))
+		if err != nil {
+			return err
+		}
+
+		var entry cacheEntry
+		if err = json.Unmarshal(b, &entry); err != nil || entry.Resource == "" {
+			// skip corrupted entries, they are overwritten on the next successful fetch
+			continue
+		}
+		entries[entry.Resource] = entry
+	}
+
+	dc.mu.Lock()
+	dc.entries = entries
+	dc.mu.Unlock()
+
+	return nil
+}
+
+func (dc *diskCache) get(resource string) (cacheEntry, bool) {
+	dc.mu.RLock()
+	defer dc.mu.RUnlock()
+
+	entry, ok := dc.entries[resource]
+	return entry, ok
+}
+
+// put stores body of resource, the file is written to a temp file first and renamed
+// so readers never observe a partially written entry. An unchanged body is not written again,
+// only the fetch time kept in memory is updated.
+func (dc *diskCache) put(resource string, body []byte) error {
+	entry := cacheEntry{
+		Resource:  resource,
+		FetchedAt: time.Now().UTC(),
+		Body:      body,
+	}
+
+	dc.mu.Lock()
+	if cached, ok := dc.entries[resource]; ok && bytes.Equal(cached.Body, body) {
+		dc.entries[resource] = entry
+		dc.mu.Unlock()
+		return nil
+	}
+	dc.mu.Unlock()
+
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(dc.dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+
+	if err = os.Rename(tmp.Name(), filepath.Join(dc.dir, cacheFileName(resource))); err != nil {
+		return err
+	}
+
+	dc.mu.Lock()
+	if dc.entries == nil {
+		dc.entries = make(map[string]cacheEntry)
+	}
+	dc.entries[resource] = entry
+	dc.mu.Unlock()
+
+	return nil
+}
+
+func cacheFileName(resource string) string {
+	sum := sha256.Sum256([]byte(resource))
+	return hex.EncodeToString(sum[:]) + cacheFileExt
+}
+
+// isUnavailable returns true if err means the registry could not serve the request, either a transport failure
+// or a server side error. Errors of invalid arguments or of responses that can not be decoded are not.
+func isUnavailable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if resErr, ok := err.(ResourceError); ok {
+		return resErr.ErrorCode >= 50000 || (resErr.ErrorCode >= 500 && resErr.ErrorCode < 600)
+	}
+
+	var urlErr *url.Error
+	var netErr net.Error
+	return errors.As(err, &urlErr) || errors.As(err, &netErr)
+}
@@ -0,0 +1,185 @@
+package schemaregistry
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func mockNetworkError() doFn {
+	return func(req *http.Request) (*http.Response, error) {
+		return nil, &url.Error{Op: req.Method, URL: req.URL.String(), Err: errors.New("dial tcp: connection refused")}
+	}
+}
+
+func newCachedClient(t *testing.T, dir string, doer httpDoer, opts ...Option) *client {
+	opts = append([]Option{WithDiskCache(dir)}, opts...)
+	cli, err := NewClient("http://localhost:8081", opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := cli.(*client)
+	c.httpClient = doer
+	return c
+}
+
+func TestClient_DiskCache_ServesStaleWhenRegistryIsDown(t *testing.T) {
+	dir := t.TempDir()
+	expected := Schema{Schema: validSchema, Subject: testSubject, Version: 1, ID: 1}
+
+	// warm up the cache while the registry is reachable
+	warm := newCachedClient(t, dir, mockHttpSuccess(nil, schemaOnlyJSON{validSchema}))
+	if _, err := warm.GetSchemaById(1); err != nil {
+		t.Fatal(err)
+	}
+	warm.httpClient = mockHttpSuccess(nil, expected)
+	if _, err := warm.GetSchemaByVersion(testSubject, "1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// a new client, e.g. after a restart, must reload the cache from disk
+	var staleResources []string
+	cli := newCachedClient(t, dir, mockNetworkError(), WithStaleHandler(func(resource string, fetchedAt time.Time, cause error) {
+		mustNotNil(t, cause)
+		if fetchedAt.IsZero() {
+			t.Error("fetchedAt must be set")
+		}
+		staleResources = append(staleResources, resource)
+	}))
+
+	sc, err := cli.GetSchemaById(1)
+	mustEqual(t, err, nil)
+	mustEqual(t, sc, validSchema)
+
+	schema, err := cli.GetSchemaByVersion(testSubject, "1")
+	mustEqual(t, err, nil)
+	mustEqual(t, *schema, expected)
+
+	mustEqual(t, staleResources, []string{"schemas/ids/1", "subjects/testsubject/versions/1"})
+}
+
+func TestClient_DiskCache_ReturnsErrorWhenNotCached(t *testing.T) {
+	cli := newCachedClient(t, t.TempDir(), mockNetworkError())
+
+	sc, err := cli.GetSchemaById(1)
+	mustNotNil(t, err)
+	mustEqual(t, sc, "")
+}
+
+func TestClient_DiskCache_DoesNotHideResourceErrors(t *testing.T) {
+	dir := t.TempDir()
+
+	warm := newCachedClient(t, dir, mockHttpSuccess(nil, schemaOnlyJSON{validSchema}))
+	if _, err := warm.GetSchemaById(1); err != nil {
+		t.Fatal(err)
+	}
+
+	cli := newCachedClient(t, dir, mockHttpError(http.StatusNotFound, schemaNotFoundCode, nil, ""))
+	sc, err := cli.GetSchemaById(1)
+	mustEqual(t, err, ResourceError{ErrorCode: schemaNotFoundCode})
+	mustEqual(t, sc, "")
+}
+
+func TestDiskCache_Load_SkipsCorruptedEntries(t *testing.T) {
+	dir := t.TempDir()
+
+	dc := &diskCache{dir: dir}
+	if err := dc.load(); err != nil {
+		t.Fatal(err)
+	}
+	if err := dc.put("schemas/ids/1", []byte(`{"schema":"x"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "corrupted"+cacheFileExt), []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded := &diskCache{dir: dir}
+	if err := reloaded.load(); err != nil {
+		t.Fatal(err)
+	}
+
+	entry, ok := reloaded.get("schemas/ids/1")
+	mustEqual(t, ok, true)
+	mustEqual(t, string(entry.Body), `{"schema":"x"}`)
+
+	// no temp files must be left behind
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range files {
+		if filepath.Ext(f.Name()) != cacheFileExt {
+			t.Errorf("unexpected file %s", f.Name())
+		}
+	}
+	if _, err = os.Stat(filepath.Join(dir, cacheFileName("schemas/ids/1"))); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDiskCache_Put_SkipsUnchangedBodies(t *testing.T) {
+	dir := t.TempDir()
+	dc := &diskCache{dir: dir}
+	if err := dc.load(); err != nil {
+		t.Fatal(err)
+	}
+
+	file := filepath.Join(dir, cacheFileName("schemas/ids/1"))
+	if err := dc.put("schemas/ids/1", []byte(`{"schema":"x"}`)); err != nil {
+		t.Fatal(err)
+	}
+	written := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(file, written, written); err != nil {
+		t.Fatal(err)
+	}
+
+	// an unchanged body is not written again, a changed one is
+	if err := dc.put("schemas/ids/1", []byte(`{"schema":"x"}`)); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustEqual(t, info.ModTime().Equal(written), true)
+
+	if err = dc.put("schemas/ids/1", []byte(`{"schema":"y"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if info, err = os.Stat(file); err != nil {
+		t.Fatal(err)
+	}
+	mustEqual(t, info.ModTime().Equal(written), false)
+}
+
+func TestIsUnavailable(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected bool
+	}{
+		{nil, false},
+		{&url.Error{Op: http.MethodGet, URL: "http://localhost:8081", Err: errors.New("connection refused")}, true},
+		{&net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, true},
+		{errRequired("subject"), false},
+		{&json.SyntaxError{}, false},
+		{errors.New("version must be a number"), false},
+		{ResourceError{ErrorCode: 50001}, true},
+		{ResourceError{ErrorCode: http.StatusServiceUnavailable}, true},
+		{ResourceError{ErrorCode: schemaNotFoundCode}, false},
+	}
+
+	for _, c := range tests {
+		if c.expected != isUnavailable(c.err) {
+			t.Errorf("isUnavailable(%v) expected %v", c.err, c.expected)
+		}
+	}
+}
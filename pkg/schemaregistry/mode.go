@@ -0,0 +1,87 @@
+package schemaregistry
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+const (
+	modePath        = "mode"
+	subjectModePath = modePath + "/%s"
+
+	ModeReadWrite = "READWRITE"
+	ModeReadOnly  = "READONLY"
+	ModeImport    = "IMPORT"
+)
+
+type modeJSON struct {
+	Mode string `json:"mode"`
+}
+
+func (c *client) modePathOf(subject string) string {
+	if subject == "" {
+		return modePath
+	}
+
+	return fmt.Sprintf(subjectModePath, c.escapeSubject(subject))
+}
+
+// GetMode returns the mode of subject, the global mode if subject is empty or it has no mode of its own
+func (c *client) GetMode(subject string) (string, error) {
+
+	// GET /mode/{string: subject}
+	path := c.modePathOf(subject)
+	if subject != "" {
+		path += "?defaultToGlobal=true"
+	}
+
+	var mode modeJSON
+	if err := c.getJSON(path, &mode); err != nil {
+		return "", err
+	}
+
+	return mode.Mode, nil
+}
+
+// SetMode sets the mode of subject, the global mode if subject is empty
+func (c *client) SetMode(subject string, mode string) (string, error) {
+	if mode == "" {
+		return "", errRequired("mode")
+	}
+
+	send, err := json.Marshal(modeJSON{Mode: mode})
+	if err != nil {
+		return "", err
+	}
+
+	// PUT /mode/{string: subject}
+	resp, err := c.do(http.MethodPut, c.modePathOf(subject), contentTypeSchemaJSON, send)
+	if err != nil {
+		return "", err
+	}
+
+	var updated modeJSON
+	err = c.readJSON(resp, &updated)
+	return updated.Mode, err
+}
+
+// ImportSchema registers schema with its id and version, the subject or the registry must be in IMPORT mode
+func (c *client) ImportSchema(subject string, schema Schema) (int, error) {
+	if schema.ID == 0 {
+		return 0, errRequired("id")
+	}
+	if schema.Version == 0 {
+		return 0, errRequired("version")
+	}
+
+	return c.register(subject, registerSchemaJSON{
+		Schema:     schema.Schema,
+		SchemaType: schema.SchemaType,
+		References: schema.References,
+		Metadata:   schema.Metadata,
+		RuleSet:    schema.RuleSet,
+		ID:         schema.ID,
+		Version:    schema.Version,
+	})
+}
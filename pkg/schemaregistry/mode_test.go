@@ -0,0 +1,67 @@
+package schemaregistry
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"testing"
+)
+
+func TestClient_GetMode(t *testing.T) {
+	var paths []string
+	cli := client{httpClient: recordPaths(&paths, mockHttpSuccess(nil, modeJSON{Mode: ModeReadWrite}))}
+
+	mode, err := cli.GetMode(testSubject)
+	mustEqual(t, err, nil)
+	mustEqual(t, mode, ModeReadWrite)
+
+	_, err = cli.GetMode("")
+	mustEqual(t, err, nil)
+
+	mustEqual(t, paths, []string{"/mode/testsubject?defaultToGlobal=true", "/mode"})
+}
+
+func TestClient_SetMode(t *testing.T) {
+	var paths []string
+	cli := client{httpClient: recordPaths(&paths, mockHttpSuccess(nil, modeJSON{Mode: ModeImport}))}
+
+	mode, err := cli.SetMode("", ModeImport)
+	mustEqual(t, err, nil)
+	mustEqual(t, mode, ModeImport)
+	mustEqual(t, paths, []string{"/mode"})
+
+	_, err = cli.SetMode(testSubject, "")
+	mustEqual(t, err, errRequired("mode"))
+}
+
+func TestClient_ImportSchema(t *testing.T) {
+	schema := Schema{
+		Schema:     validSchema,
+		ID:         12,
+		Version:    3,
+		References: []Reference{{Name: "com.acme.Address", Subject: "address-value", Version: 1}},
+	}
+
+	var sent registerSchemaJSON
+	success := mockHttpSuccess(nil, idOnlyJSON{ID: 12})
+	cli := client{httpClient: doFn(func(req *http.Request) (*http.Response, error) {
+		b, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		if err = json.Unmarshal(b, &sent); err != nil {
+			return nil, err
+		}
+		return success(req)
+	})}
+
+	id, err := cli.ImportSchema(testSubject, schema)
+	mustEqual(t, err, nil)
+	mustEqual(t, id, 12)
+	mustEqual(t, sent, registerSchemaJSON{Schema: schema.Schema, References: schema.References, ID: 12, Version: 3})
+
+	_, err = cli.ImportSchema(testSubject, Schema{Schema: validSchema, Version: 1})
+	mustEqual(t, err, errRequired("id"))
+	_, err = cli.ImportSchema(testSubject, Schema{Schema: validSchema, ID: 1})
+	mustEqual(t, err, errRequired("version"))
+}
@@ -0,0 +1,280 @@
+package schemaregistry
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// WithNormalization makes the registry normalize schemas on register, lookup and compatibility checks,
+// so semantically identical schemas with different formatting resolve to the same id
+func WithNormalization() Option {
+	return func(c *client) {
+		c.normalize = true
+	}
+}
+
+const normalizeQuery = "?normalize=true"
+
+func (c *client) normalizeQuery() string {
+	if c.normalize {
+		return normalizeQuery
+	}
+
+	return ""
+}
+
+var avroPrimitives = map[string]bool{
+	"null":    true,
+	"boolean": true,
+	"int":     true,
+	"long":    true,
+	"float":   true,
+	"double":  true,
+	"bytes":   true,
+	"string":  true,
+}
+
+var avroNamedTypes = map[string]bool{
+	"record": true,
+	"error":  true,
+	"enum":   true,
+	"fixed":  true,
+}
+
+// avroKeyOrder is the order of well known attributes in a normalized schema, other attributes follow sorted by name
+var avroKeyOrder = []string{"name", "type", "fields", "symbols", "items", "values", "size"}
+
+// NormalizeSchema returns the normalized form of an avro schema: whitespace is removed, names are fully qualified,
+// attributes are ordered and primitive types written as objects are collapsed, docs and defaults are kept
+func NormalizeSchema(avroSchema string) (string, error) {
+	if strings.TrimSpace(avroSchema) == "" {
+		return "", errRequired("avroSchema")
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(avroSchema))
+	decoder.UseNumber()
+
+	var node interface{}
+	if err := decoder.Decode(&node); err != nil {
+		return "", fmt.Errorf("httpClient: schema is not valid json, trace: %v", err)
+	}
+
+	normalized, err := normalizeAvroNode(node, "")
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err = writeOrdered(&buf, normalized); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// SchemasEqual returns true if both avro schemas have the same normalized form
+func SchemasEqual(a, b string) (bool, error) {
+	na, err := NormalizeSchema(a)
+	if err != nil {
+		return false, err
+	}
+
+	nb, err := NormalizeSchema(b)
+	if err != nil {
+		return false, err
+	}
+
+	return na == nb, nil
+}
+
+func qualifyName(name, namespace string) string {
+	if name == "" || strings.Contains(name, ".") || avroPrimitives[name] || namespace == "" {
+		return name
+	}
+
+	return namespace + "." + name
+}
+
+func namespaceOf(fullName string) string {
+	if i := strings.LastIndex(fullName, "."); i >= 0 {
+		return fullName[:i]
+	}
+
+	return ""
+}
+
+func normalizeAvroNode(node interface{}, namespace string) (interface{}, error) {
+	switch n := node.(type) {
+	case string:
+		return qualifyName(n, namespace), nil
+
+	case []interface{}:
+		union := make([]interface{}, len(n))
+		for i, branch := range n {
+			b, err := normalizeAvroNode(branch, namespace)
+			if err != nil {
+				return nil, err
+			}
+			union[i] = b
+		}
+		return union, nil
+
+	case map[string]interface{}:
+		return normalizeAvroObject(n, namespace)
+	}
+
+	return nil, fmt.Errorf("httpClient: %v is not a valid avro type", node)
+}
+
+func normalizeAvroObject(obj map[string]interface{}, namespace string) (interface{}, error) {
+	typ, _ := obj["type"].(string)
+
+	// {"type": "string"} is the same as "string"
+	if len(obj) == 1 && avroPrimitives[typ] {
+		return typ, nil
+	}
+
+	out := make(map[string]interface{}, len(obj))
+	for k, v := range obj {
+		out[k] = v
+	}
+
+	if avroNamedTypes[typ] {
+		name, _ := obj["name"].(string)
+		if name == "" {
+			return nil, errRequired("name of " + typ)
+		}
+
+		ns := namespace
+		if explicit, ok := obj["namespace"].(string); ok {
+			ns = explicit
+		}
+		fullName := qualifyName(name, ns)
+
+		delete(out, "namespace")
+		out["name"] = fullName
+		namespace = namespaceOf(fullName)
+	} else if t, ok := obj["type"]; ok && typ == "" {
+		// nested type definition
+		nested, err := normalizeAvroNode(t, namespace)
+		if err != nil {
+			return nil, err
+		}
+		out["type"] = nested
+	} else if typ != "" && typ != "array" && typ != "map" && !avroPrimitives[typ] {
+		// reference to a named type
+		out["type"] = qualifyName(typ, namespace)
+	}
+
+	if fields, ok := obj["fields"].([]interface{}); ok {
+		normalizedFields := make([]interface{}, len(fields))
+		for i, f := range fields {
+			field, ok := f.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("httpClient: field %v must be an object", f)
+			}
+
+			normalizedField := make(map[string]interface{}, len(field))
+			for k, v := range field {
+				normalizedField[k] = v
+			}
+
+			fieldType, err := normalizeAvroNode(field["type"], namespace)
+			if err != nil {
+				return nil, err
+			}
+			normalizedField["type"] = fieldType
+			normalizedFields[i] = normalizedField
+		}
+		out["fields"] = normalizedFields
+	}
+
+	for _, key := range []string{"items", "values"} {
+		if v, ok := obj[key]; ok {
+			nested, err := normalizeAvroNode(v, namespace)
+			if err != nil {
+				return nil, err
+			}
+			out[key] = nested
+		}
+	}
+
+	return out, nil
+}
+
+// writeOrdered writes v as compact json, object keys follow avroKeyOrder then the alphabetical order
+func writeOrdered(buf *bytes.Buffer, v interface{}) error {
+	switch n := v.(type) {
+	case map[string]interface{}:
+		buf.WriteByte('{')
+		for i, key := range orderedKeys(n) {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeOrdered(buf, key); err != nil {
+				return err
+			}
+			buf.WriteByte(':')
+			if err := writeOrdered(buf, n[key]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+		return nil
+
+	case []interface{}:
+		buf.WriteByte('[')
+		for i, item := range n {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeOrdered(buf, item); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+		return nil
+	}
+
+	encoder := json.NewEncoder(buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return err
+	}
+	// Encode terminates each value with a newline
+	buf.Truncate(buf.Len() - 1)
+
+	return nil
+}
+
+func orderedKeys(obj map[string]interface{}) []string {
+	keys := make([]string, 0, len(obj))
+	for _, known := range avroKeyOrder {
+		if _, ok := obj[known]; ok {
+			keys = append(keys, known)
+		}
+	}
+
+	rest := make([]string, 0, len(obj))
+	for k := range obj {
+		if !isKnownKey(k) {
+			rest = append(rest, k)
+		}
+	}
+	sort.Strings(rest)
+
+	return append(keys, rest...)
+}
+
+func isKnownKey(key string) bool {
+	for _, known := range avroKeyOrder {
+		if known == key {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,92 @@
+package schemaregistry
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestNormalizeSchema(t *testing.T) {
+	tests := []struct {
+		schema   string
+		expected string
+	}{
+		{`"string"`, `"string"`},
+		{`{"type": "string"}`, `"string"`},
+		{`{"type": "string", "logicalType": "uuid"}`, `{"type":"string","logicalType":"uuid"}`},
+		{
+			validSchema,
+			`{"name":"example.avro.user","type":"record","fields":[{"name":"name","type":"string"},{"name":"favorite_number","type":"int"}]}`,
+		},
+		{
+			`{"type":"record","name":"a.b.Outer","doc":"<outer>","fields":[
+				{"name":"inner","type":{"type":"record","name":"Inner","fields":[{"name":"x","type":{"type":"long"}}]}},
+				{"name":"again","type":["null","Inner"],"default":null},
+				{"name":"tags","type":{"type":"array","items":"Inner"}}
+			]}`,
+			`{"name":"a.b.Outer","type":"record","fields":[{"name":"inner","type":{"name":"a.b.Inner","type":"record","fields":[{"name":"x","type":"long"}]}},{"name":"again","type":["null","a.b.Inner"],"default":null},{"name":"tags","type":{"type":"array","items":"a.b.Inner"}}],"doc":"<outer>"}`,
+		},
+	}
+
+	for _, c := range tests {
+		normalized, err := NormalizeSchema(c.schema)
+		mustEqual(t, err, nil)
+		mustEqual(t, normalized, c.expected)
+	}
+
+	for _, invalid := range []string{"", "loremipsum", `{"type":"record","fields":[]}`, `{"type":"record","name":"r","fields":[{"name":"f"}]}`} {
+		if _, err := NormalizeSchema(invalid); err == nil {
+			t.Errorf("%q must not be normalized", invalid)
+		}
+	}
+}
+
+func TestSchemasEqual(t *testing.T) {
+	formatted := `{
+		"type": "record",
+		"namespace": "example.avro",
+		"name": "user",
+		"fields": [
+			{"type": {"type": "string"}, "name": "name"},
+			{"name": "favorite_number", "type": "int"}
+		]
+	}`
+
+	equal, err := SchemasEqual(validSchema, formatted)
+	mustEqual(t, err, nil)
+	mustEqual(t, equal, true)
+
+	equal, err = SchemasEqual(validSchema, `{"type":"record","name":"user","fields":[{"name":"name","type":"string"}]}`)
+	mustEqual(t, err, nil)
+	mustEqual(t, equal, false)
+}
+
+func TestClient_Normalization(t *testing.T) {
+	var queries []string
+	handler := func(respBody interface{}) doFn {
+		success := mockHttpSuccess(nil, respBody)
+		return func(req *http.Request) (*http.Response, error) {
+			queries = append(queries, req.URL.RawQuery)
+			return success(req)
+		}
+	}
+
+	cli := client{baseUrl: "http://localhost:8081"}
+	WithNormalization()(&cli)
+
+	cli.httpClient = handler(idOnlyJSON{ID: 1})
+	if _, err := cli.RegisterNewSchema(testSubject, validSchema); err != nil {
+		t.Error(err)
+	}
+
+	cli.httpClient = handler(Schema{Schema: validSchema, Subject: testSubject, Version: 1, ID: 1})
+	if _, _, err := cli.IsRegistered(testSubject, validSchema); err != nil {
+		t.Error(err)
+	}
+
+	cli.httpClient = handler(isCompatibleJSON{IsCompatible: true})
+	if _, err := cli.IsLatestSchemaCompatible(testSubject, validSchema); err != nil {
+		t.Error(err)
+	}
+
+	mustEqual(t, queries, []string{"normalize=true", "normalize=true", "normalize=true"})
+}
@@ -0,0 +1,175 @@
+package schemaregistry
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+type (
+	// Metadata is the data contract metadata of a schema version
+	Metadata struct {
+		Tags       map[string][]string `json:"tags,omitempty"`
+		Properties map[string]string   `json:"properties,omitempty"`
+		Sensitive  []string            `json:"sensitive,omitempty"`
+	}
+
+	// RuleSet holds the rules of a schema version
+	RuleSet struct {
+		MigrationRules []Rule `json:"migrationRules,omitempty"`
+		DomainRules    []Rule `json:"domainRules,omitempty"`
+	}
+
+	// Rule is a condition or a transform applied to messages of a schema
+	Rule struct {
+		Name      string            `json:"name"`
+		Doc       string            `json:"doc,omitempty"`
+		Kind      RuleKind          `json:"kind"`
+		Mode      RuleMode          `json:"mode"`
+		Type      string            `json:"type"`
+		Tags      []string          `json:"tags,omitempty"`
+		Params    map[string]string `json:"params,omitempty"`
+		Expr      string            `json:"expr,omitempty"`
+		OnSuccess string            `json:"onSuccess,omitempty"`
+		OnFailure string            `json:"onFailure,omitempty"`
+		Disabled  bool              `json:"disabled,omitempty"`
+	}
+
+	RuleKind string
+	RuleMode string
+
+	// RuleConditionError is returned when a message does not satisfy a condition rule
+	RuleConditionError struct {
+		Rule string
+		Expr string
+		Err  error
+	}
+)
+
+const (
+	RuleKindCondition RuleKind = "CONDITION"
+	RuleKindTransform RuleKind = "TRANSFORM"
+
+	RuleModeWrite     RuleMode = "WRITE"
+	RuleModeRead      RuleMode = "READ"
+	RuleModeWriteRead RuleMode = "WRITEREAD"
+	RuleModeUpgrade   RuleMode = "UPGRADE"
+	RuleModeDowngrade RuleMode = "DOWNGRADE"
+
+	ruleTypeCEL       = "CEL"
+	ruleActionNone    = "NONE"
+	metadataOwner     = "owner"
+	metadataTeam      = "team"
+	metadataSensitive = "sensitivity"
+)
+
+func (err RuleConditionError) Error() string {
+	if err.Err != nil {
+		return fmt.Sprintf("httpClient: rule %s (%s) could not be evaluated, trace: %v", err.Rule, err.Expr, err.Err)
+	}
+
+	return fmt.Sprintf("httpClient: rule %s (%s) failed", err.Rule, err.Expr)
+}
+
+// IsRuleConditionFailed returns true if err is returned because a condition rule failed
+func IsRuleConditionFailed(err error) bool {
+	_, ok := err.(RuleConditionError)
+	return ok
+}
+
+// Owner returns the owner property of metadata
+func (m *Metadata) Owner() string {
+	return m.property(metadataOwner)
+}
+
+// Team returns the team property of metadata
+func (m *Metadata) Team() string {
+	return m.property(metadataTeam)
+}
+
+// Sensitivity returns the sensitivity property of metadata
+func (m *Metadata) Sensitivity() string {
+	return m.property(metadataSensitive)
+}
+
+func (m *Metadata) property(key string) string {
+	if m == nil {
+		return ""
+	}
+
+	return m.Properties[key]
+}
+
+// appliesTo returns true if the rule runs when a message is processed in mode
+func (r Rule) appliesTo(mode RuleMode) bool {
+	if r.Disabled {
+		return false
+	}
+
+	if r.Mode == RuleModeWriteRead {
+		return mode == RuleModeWrite || mode == RuleModeRead
+	}
+
+	return r.Mode == mode
+}
+
+// EvaluateConditions evaluates the CEL condition domain rules of mode against message,
+// message is exposed to expressions as "message", e.g. size(message.ssn) == 9
+func (rs *RuleSet) EvaluateConditions(mode RuleMode, message interface{}) error {
+	if rs == nil {
+		return nil
+	}
+
+	var msg interface{}
+	for _, rule := range rs.DomainRules {
+		if rule.Kind != RuleKindCondition || !strings.EqualFold(rule.Type, ruleTypeCEL) || !rule.appliesTo(mode) {
+			continue
+		}
+
+		if msg == nil {
+			var err error
+			if msg, err = toGeneric(message); err != nil {
+				return err
+			}
+		}
+
+		result, err := evalCondition(rule.Expr, map[string]interface{}{"message": msg})
+		if err != nil {
+			return RuleConditionError{Rule: rule.Name, Expr: rule.Expr, Err: err}
+		}
+
+		if !result && !strings.EqualFold(rule.OnFailure, ruleActionNone) {
+			return RuleConditionError{Rule: rule.Name, Expr: rule.Expr}
+		}
+	}
+
+	return nil
+}
+
+// SerializeJSON encodes message as json after the write condition rules of schema passed
+func SerializeJSON(schema *Schema, message interface{}) ([]byte, error) {
+	if schema == nil {
+		return nil, errRequired("schema")
+	}
+
+	if err := schema.RuleSet.EvaluateConditions(RuleModeWrite, message); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(message)
+}
+
+// toGeneric converts message to maps, slices and primitives the way it is encoded as json
+func toGeneric(message interface{}) (interface{}, error) {
+	b, err := json.Marshal(message)
+	if err != nil {
+		return nil, err
+	}
+
+	var generic interface{}
+	if err = json.Unmarshal(b, &generic); err != nil {
+		return nil, err
+	}
+
+	return generic, nil
+}
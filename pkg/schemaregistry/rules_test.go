@@ -0,0 +1,161 @@
+package schemaregistry
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"testing"
+)
+
+func TestEvalCondition(t *testing.T) {
+	vars := map[string]interface{}{
+		"message": map[string]interface{}{
+			"ssn":    "123456789",
+			"age":    float64(42),
+			"email":  "jane@example.com",
+			"tags":   []interface{}{"a", "b"},
+			"active": true,
+			"note":   nil,
+			"address": map[string]interface{}{
+				"country": "TR",
+			},
+		},
+	}
+
+	tests := []struct {
+		expr     string
+		expected bool
+	}{
+		{"size(message.ssn) == 9", true},
+		{"message.age >= 18 && message.age < 65", true},
+		{"message.age > 50 || message.active", true},
+		{"!message.active", false},
+		{"message.email.endsWith('@example.com')", true},
+		{"message.email.matches('^[a-z]+@')", true},
+		{"message.address.country == \"TR\"", true},
+		{"size(message.tags) == 2 && message.tags != null", true},
+		{"has(message.address) && !has(message.phone)", true},
+		{"has(message.note)", false},
+		{"(message.age + 8) % 10 == 0", true},
+		{"message.age * 2 == 84 && -message.age == -42", true},
+		{"false && message.unknown == 1", false},
+	}
+
+	for _, c := range tests {
+		result, err := evalCondition(c.expr, vars)
+		if err != nil {
+			t.Errorf("%s: %v", c.expr, err)
+			continue
+		}
+		if result != c.expected {
+			t.Errorf("%s: expected %v", c.expr, c.expected)
+		}
+	}
+
+	for _, invalid := range []string{"", "message.age", "message.unknown == 1", "size(", "message.age > 'a'", "foo(1)", "1 +", "'abc", "message.age % 0.5 == 0"} {
+		if _, err := evalCondition(invalid, vars); err == nil {
+			t.Errorf("%q must fail", invalid)
+		}
+	}
+}
+
+func TestRuleSet_EvaluateConditions(t *testing.T) {
+	type customer struct {
+		Ssn   string `json:"ssn"`
+		Email string `json:"email"`
+	}
+
+	ruleSet := &RuleSet{
+		DomainRules: []Rule{
+			{Name: "checkSsnLen", Kind: RuleKindCondition, Mode: RuleModeWrite, Type: "CEL", Expr: "size(message.ssn) == 9"},
+			{Name: "checkEmail", Kind: RuleKindCondition, Mode: RuleModeWriteRead, Type: "CEL", Expr: "message.email.contains('@')", OnFailure: "NONE"},
+			{Name: "disabled", Kind: RuleKindCondition, Mode: RuleModeWrite, Type: "CEL", Expr: "false", Disabled: true},
+			{Name: "readOnly", Kind: RuleKindCondition, Mode: RuleModeRead, Type: "CEL", Expr: "false"},
+			{Name: "encrypt", Kind: RuleKindTransform, Mode: RuleModeWrite, Type: "ENCRYPT", Tags: []string{"PII"}},
+		},
+	}
+
+	mustEqual(t, ruleSet.EvaluateConditions(RuleModeWrite, customer{Ssn: "123456789", Email: "invalid"}), nil)
+
+	err := ruleSet.EvaluateConditions(RuleModeWrite, customer{Ssn: "1234"})
+	mustEqual(t, err, RuleConditionError{Rule: "checkSsnLen", Expr: "size(message.ssn) == 9"})
+	mustEqual(t, IsRuleConditionFailed(err), true)
+
+	mustEqual(t, IsRuleConditionFailed(ruleSet.EvaluateConditions(RuleModeRead, customer{})), true)
+	mustEqual(t, (*RuleSet)(nil).EvaluateConditions(RuleModeWrite, customer{}), nil)
+}
+
+func TestSerializeJSON(t *testing.T) {
+	schema := &Schema{
+		Schema: validSchema,
+		RuleSet: &RuleSet{DomainRules: []Rule{
+			{Name: "positive", Kind: RuleKindCondition, Mode: RuleModeWrite, Type: "CEL", Expr: "message.favorite_number > 0"},
+		}},
+	}
+
+	b, err := SerializeJSON(schema, map[string]interface{}{"name": "jane", "favorite_number": 7})
+	mustEqual(t, err, nil)
+	mustEqual(t, string(b), `{"favorite_number":7,"name":"jane"}`)
+
+	b, err = SerializeJSON(schema, map[string]interface{}{"name": "jane", "favorite_number": -1})
+	mustEqual(t, IsRuleConditionFailed(err), true)
+	mustEqual(t, b, ([]byte)(nil))
+
+	_, err = SerializeJSON(nil, nil)
+	mustEqual(t, err, errRequired("schema"))
+}
+
+func TestMetadata(t *testing.T) {
+	m := &Metadata{Properties: map[string]string{"owner": "jane", "team": "payments", "sensitivity": "confidential"}}
+	mustEqual(t, m.Owner(), "jane")
+	mustEqual(t, m.Team(), "payments")
+	mustEqual(t, m.Sensitivity(), "confidential")
+	mustEqual(t, (*Metadata)(nil).Owner(), "")
+}
+
+func TestClient_RegisterSchema(t *testing.T) {
+	schema := Schema{
+		Schema:   validSchema,
+		Metadata: &Metadata{Properties: map[string]string{"owner": "jane"}, Tags: map[string][]string{"name": {"PII"}}},
+		RuleSet: &RuleSet{DomainRules: []Rule{
+			{Name: "checkName", Kind: RuleKindCondition, Mode: RuleModeWrite, Type: "CEL", Expr: "size(message.name) > 0"},
+		}},
+	}
+
+	var sent registerSchemaJSON
+	success := mockHttpSuccess(nil, idOnlyJSON{ID: 7})
+	cli := client{httpClient: doFn(func(req *http.Request) (*http.Response, error) {
+		b, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		if err = json.Unmarshal(b, &sent); err != nil {
+			return nil, err
+		}
+		return success(req)
+	})}
+
+	id, err := cli.RegisterSchema(testSubject, schema)
+	mustEqual(t, err, nil)
+	mustEqual(t, id, 7)
+	mustEqual(t, sent, registerSchemaJSON{Schema: schema.Schema, Metadata: schema.Metadata, RuleSet: schema.RuleSet})
+
+	_, err = cli.RegisterSchema(testSubject, Schema{})
+	mustEqual(t, err, errRequired("schema"))
+}
+
+func TestClient_GetSchemaByVersion_ReadsMetadataAndRuleSet(t *testing.T) {
+	expected := Schema{
+		Schema:   validSchema,
+		Subject:  testSubject,
+		Version:  2,
+		ID:       3,
+		Metadata: &Metadata{Properties: map[string]string{"team": "payments"}, Sensitive: []string{"ssn"}},
+		RuleSet:  &RuleSet{DomainRules: []Rule{{Name: "r", Kind: RuleKindCondition, Mode: RuleModeWrite, Type: "CEL", Expr: "true"}}},
+	}
+
+	cli := client{httpClient: mockHttpSuccess(nil, expected)}
+	sc, err := cli.GetSchemaByVersion(testSubject, "2")
+	mustEqual(t, err, nil)
+	mustEqual(t, *sc, expected)
+}
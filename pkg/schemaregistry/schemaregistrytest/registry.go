@@ -0,0 +1,436 @@
+// Package schemaregistrytest provides an in-memory schema registry for tests of schema registry clients
+package schemaregistrytest
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/ybalcin/event-schema-manager/pkg/avro"
+	"github.com/ybalcin/event-schema-manager/pkg/schemaregistry"
+)
+
+type (
+	// Registry is an in-memory schemaregistry.Client. Schemas get registry wide ids, the same schema has the same
+	// id in every subject. Every schema is compatible unless Incompatible reports reasons for it. Like the registry,
+	// schemas without a type are parsed as avro and are rejected if they are not valid.
+	Registry struct {
+		mu       sync.Mutex
+		subjects map[string][]schemaregistry.Schema
+		ids      map[string]int
+		schemas  map[int]schemaregistry.Schema
+		levels   map[string]string
+		modes    map[string]string
+		nextID   int
+
+		// Incompatible returns the reasons schema is not compatible with the versions of subject,
+		// nil if it is compatible
+		Incompatible func(subject, schema string, versions []schemaregistry.Schema) []string
+	}
+)
+
+const (
+	subjectNotFoundCode    = 40401
+	versionNotFoundCode    = 40402
+	schemaNotFoundCode     = 40403
+	incompatibleSchemaCode = 409
+	invalidSchemaCode      = 42201
+	notPermittedCode       = 42205
+)
+
+var _ schemaregistry.Client = (*Registry)(nil)
+
+// NewRegistry returns an empty registry with the BACKWARD global compatibility level
+func NewRegistry() *Registry {
+	return &Registry{
+		subjects: make(map[string][]schemaregistry.Schema),
+		ids:      make(map[string]int),
+		schemas:  make(map[int]schemaregistry.Schema),
+		levels:   map[string]string{"": schemaregistry.CompatibilityBackward},
+		modes:    map[string]string{"": schemaregistry.ModeReadWrite},
+		nextID:   1,
+	}
+}
+
+func notFound(code int, message string) error {
+	return schemaregistry.ResourceError{ErrorCode: code, Message: message}
+}
+
+func notPermitted(message string) error {
+	return schemaregistry.ResourceError{ErrorCode: notPermittedCode, Message: message}
+}
+
+func (r *Registry) Subjects() ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	subjects := []string{}
+	for s := range r.subjects {
+		subjects = append(subjects, s)
+	}
+	sort.Strings(subjects)
+
+	return subjects, nil
+}
+
+func (r *Registry) Versions(subject string) ([]int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	schemas, ok := r.subjects[subject]
+	if !ok {
+		return nil, notFound(subjectNotFoundCode, "subject not found")
+	}
+
+	versions := make([]int, len(schemas))
+	for i, sc := range schemas {
+		versions[i] = sc.Version
+	}
+
+	return versions, nil
+}
+
+func (r *Registry) DeleteSubject(subject string) ([]int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	schemas, ok := r.subjects[subject]
+	if !ok {
+		return nil, notFound(subjectNotFoundCode, "subject not found")
+	}
+	delete(r.subjects, subject)
+
+	versions := make([]int, len(schemas))
+	for i, sc := range schemas {
+		versions[i] = sc.Version
+	}
+
+	return versions, nil
+}
+
+func (r *Registry) DeleteSchemaVersion(subject string, version string) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i, err := r.indexOf(subject, version)
+	if err != nil {
+		return 0, err
+	}
+
+	schemas := r.subjects[subject]
+	deleted := schemas[i].Version
+	r.subjects[subject] = append(schemas[:i:i], schemas[i+1:]...)
+	if len(r.subjects[subject]) == 0 {
+		delete(r.subjects, subject)
+	}
+
+	return deleted, nil
+}
+
+// indexOf returns the index of version, a number or latest, in the versions of subject
+func (r *Registry) indexOf(subject, version string) (int, error) {
+	schemas, ok := r.subjects[subject]
+	if !ok {
+		return 0, notFound(subjectNotFoundCode, "subject not found")
+	}
+
+	if version == schemaregistry.SchemaLatestVersion {
+		return len(schemas) - 1, nil
+	}
+
+	n, err := strconv.Atoi(version)
+	if err != nil {
+		return 0, notFound(versionNotFoundCode, "version not found")
+	}
+	for i, sc := range schemas {
+		if sc.Version == n {
+			return i, nil
+		}
+	}
+
+	return 0, notFound(versionNotFoundCode, "version not found")
+}
+
+// checkSchema rejects schemas without a type that are not valid avro, as the registry does
+func checkSchema(schema schemaregistry.Schema) error {
+	if schema.SchemaType != "" && schema.SchemaType != schemaregistry.SchemaTypeAvro {
+		return nil
+	}
+	if _, err := avro.Parse(schema.Schema); err != nil {
+		return schemaregistry.ResourceError{ErrorCode: invalidSchemaCode, Message: "Invalid schema: " + err.Error()}
+	}
+
+	return nil
+}
+
+func sameType(a, b string) bool {
+	if a == "" {
+		a = schemaregistry.SchemaTypeAvro
+	}
+	if b == "" {
+		b = schemaregistry.SchemaTypeAvro
+	}
+
+	return a == b
+}
+
+func (r *Registry) IsRegistered(subject, schema string) (bool, schemaregistry.Schema, error) {
+	return r.LookupSchema(subject, schemaregistry.Schema{Schema: schema})
+}
+
+// LookupSchema returns the version of subject with the content and the type of schema
+func (r *Registry) LookupSchema(subject string, schema schemaregistry.Schema) (bool, schemaregistry.Schema, error) {
+	if err := checkSchema(schema); err != nil {
+		return false, schemaregistry.Schema{}, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, sc := range r.subjects[subject] {
+		if sc.Schema == schema.Schema && sameType(sc.SchemaType, schema.SchemaType) {
+			return true, sc, nil
+		}
+	}
+
+	return false, schemaregistry.Schema{}, nil
+}
+
+func (r *Registry) RegisterNewSchema(subject string, avroSchema string) (int, error) {
+	return r.RegisterSchema(subject, schemaregistry.Schema{Schema: avroSchema})
+}
+
+// RegisterSchema registers schema as the next version of subject, a registered schema returns its id
+func (r *Registry) RegisterSchema(subject string, schema schemaregistry.Schema) (int, error) {
+	if err := checkSchema(schema); err != nil {
+		return 0, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	schemas := r.subjects[subject]
+	for _, sc := range schemas {
+		if sc.Schema == schema.Schema {
+			return sc.ID, nil
+		}
+	}
+
+	if mode := r.modeOf(subject); mode != schemaregistry.ModeReadWrite {
+		return 0, notPermitted("subject is in " + mode + " mode")
+	}
+
+	if r.Incompatible != nil && len(schemas) > 0 {
+		if reasons := r.Incompatible(subject, schema.Schema, schemas); len(reasons) > 0 {
+			return 0, schemaregistry.ResourceError{ErrorCode: incompatibleSchemaCode, Message: reasons[0]}
+		}
+	}
+
+	id, ok := r.ids[schema.Schema]
+	if !ok {
+		id = r.nextID
+		r.nextID++
+		r.ids[schema.Schema] = id
+	}
+
+	version := 1
+	if len(schemas) > 0 {
+		version = schemas[len(schemas)-1].Version + 1
+	}
+
+	registered := schema
+	registered.Subject, registered.Version, registered.ID = subject, version, id
+	r.subjects[subject] = append(schemas, registered)
+	r.schemas[id] = registered
+
+	return id, nil
+}
+
+// ImportSchema registers schema with its id and version, the subject must be in IMPORT mode. The id can not be
+// taken by another schema and the version can not be taken by another id.
+func (r *Registry) ImportSchema(subject string, schema schemaregistry.Schema) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if mode := r.modeOf(subject); mode != schemaregistry.ModeImport {
+		return 0, notPermitted("subject is in " + mode + " mode")
+	}
+	if sc, ok := r.schemas[schema.ID]; ok && sc.Schema != schema.Schema {
+		return 0, notPermitted("id " + strconv.Itoa(schema.ID) + " is taken by another schema")
+	}
+
+	schemas := r.subjects[subject]
+	i := sort.Search(len(schemas), func(i int) bool { return schemas[i].Version >= schema.Version })
+	if i < len(schemas) && schemas[i].Version == schema.Version {
+		if schemas[i].ID != schema.ID {
+			return 0, notPermitted("version " + strconv.Itoa(schema.Version) + " is taken by another schema")
+		}
+		return schema.ID, nil
+	}
+
+	imported := schema
+	imported.Subject = subject
+	schemas = append(schemas, schemaregistry.Schema{})
+	copy(schemas[i+1:], schemas[i:])
+	schemas[i] = imported
+	r.subjects[subject] = schemas
+
+	r.ids[schema.Schema] = schema.ID
+	r.schemas[schema.ID] = imported
+	if schema.ID >= r.nextID {
+		r.nextID = schema.ID + 1
+	}
+
+	return schema.ID, nil
+}
+
+func (r *Registry) GetSchemaById(id int) (string, error) {
+	sc, err := r.GetSchema(id)
+	if err != nil {
+		return "", err
+	}
+
+	return sc.Schema, nil
+}
+
+func (r *Registry) GetSchema(id int) (*schemaregistry.Schema, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sc, ok := r.schemas[id]
+	if !ok {
+		return nil, notFound(schemaNotFoundCode, "schema not found")
+	}
+
+	return &schemaregistry.Schema{Schema: sc.Schema, ID: id, SchemaType: sc.SchemaType, References: sc.References}, nil
+}
+
+func (r *Registry) SchemaSubjectVersions(id int) ([]schemaregistry.SubjectVersion, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.schemas[id]; !ok {
+		return nil, notFound(schemaNotFoundCode, "schema not found")
+	}
+
+	subjects := make([]string, 0, len(r.subjects))
+	for s := range r.subjects {
+		subjects = append(subjects, s)
+	}
+	sort.Strings(subjects)
+
+	versions := []schemaregistry.SubjectVersion{}
+	for _, s := range subjects {
+		for _, sc := range r.subjects[s] {
+			if sc.ID == id {
+				versions = append(versions, schemaregistry.SubjectVersion{Subject: s, Version: sc.Version})
+			}
+		}
+	}
+
+	return versions, nil
+}
+
+func (r *Registry) GetSchemaByVersion(subject string, version string) (*schemaregistry.Schema, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i, err := r.indexOf(subject, version)
+	if err != nil {
+		return nil, err
+	}
+
+	sc := r.subjects[subject][i]
+	return &sc, nil
+}
+
+func (r *Registry) GetLatestSchema(subject string) (*schemaregistry.Schema, error) {
+	return r.GetSchemaByVersion(subject, schemaregistry.SchemaLatestVersion)
+}
+
+func (r *Registry) IsSchemaCompatible(subject string, avroSchema string, version int) (bool, error) {
+	compatible, _, err := r.CheckCompatibility(subject, avroSchema, strconv.Itoa(version))
+	return compatible, err
+}
+
+func (r *Registry) IsLatestSchemaCompatible(subject string, avroSchema string) (bool, error) {
+	compatible, _, err := r.CheckCompatibility(subject, avroSchema, schemaregistry.SchemaLatestVersion)
+	return compatible, err
+}
+
+func (r *Registry) CheckCompatibility(subject string, avroSchema string, version string) (bool, []string, error) {
+	return r.CheckSchemaCompatibility(subject, schemaregistry.Schema{Schema: avroSchema}, version)
+}
+
+func (r *Registry) CheckSchemaCompatibility(subject string, schema schemaregistry.Schema, version string) (bool, []string, error) {
+	if err := checkSchema(schema); err != nil {
+		return false, nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i, err := r.indexOf(subject, version)
+	if err != nil {
+		return false, nil, err
+	}
+
+	if r.Incompatible == nil {
+		return true, nil, nil
+	}
+
+	reasons := r.Incompatible(subject, schema.Schema, r.subjects[subject][i:i+1])
+	return len(reasons) == 0, reasons, nil
+}
+
+func (r *Registry) GetCompatibilityLevel(subject string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if level, ok := r.levels[subject]; ok {
+		return level, nil
+	}
+
+	return r.levels[""], nil
+}
+
+func (r *Registry) SetCompatibilityLevel(subject string, level string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.levels[subject] = level
+	return level, nil
+}
+
+func (r *Registry) Contexts() ([]string, error) {
+	return []string{schemaregistry.DefaultContext}, nil
+}
+
+// WithContext returns the registry itself, the in-memory registry has only the default context
+func (r *Registry) WithContext(name string) schemaregistry.Client {
+	return r
+}
+
+func (r *Registry) GetMode(subject string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.modeOf(subject), nil
+}
+
+func (r *Registry) SetMode(subject string, mode string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.modes[subject] = mode
+	return mode, nil
+}
+
+// modeOf returns the mode of subject, the global mode if it has no mode of its own
+func (r *Registry) modeOf(subject string) string {
+	if mode, ok := r.modes[subject]; ok {
+		return mode
+	}
+
+	return r.modes[""]
+}
@@ -0,0 +1,64 @@
+package schemaregistry
+
+import (
+	"errors"
+	"sync"
+)
+
+// errPanicked is returned to the waiters of a request that panicked
+var errPanicked = errors.New("schemaregistry: request panicked")
+
+type (
+	// call is an in-flight or completed request of a group
+	call struct {
+		wg   sync.WaitGroup
+		val  []byte
+		err  error
+		dups int
+	}
+
+	// group collapses identical concurrent requests into one, the zero value is ready to use
+	group struct {
+		mu sync.Mutex
+		m  map[string]*call
+	}
+)
+
+// do executes fn once for all concurrent callers of the same key and shares its result,
+// shared reports whether the result was given to more than one caller
+func (g *group) do(key string, fn func() ([]byte, error)) (val []byte, err error, shared bool) {
+	g.mu.Lock()
+	if g.m == nil {
+		g.m = make(map[string]*call)
+	}
+
+	if c, ok := g.m[key]; ok {
+		c.dups++
+		g.mu.Unlock()
+		c.wg.Wait()
+
+		return c.val, c.err, true
+	}
+
+	c := new(call)
+	c.wg.Add(1)
+	g.m[key] = c
+	g.mu.Unlock()
+
+	// the waiters are released and the key is freed even if fn panics, the panic goes on in this caller
+	returned := false
+	defer func() {
+		if !returned {
+			c.err = errPanicked
+		}
+		g.mu.Lock()
+		delete(g.m, key)
+		g.mu.Unlock()
+		c.wg.Done()
+	}()
+
+	c.val, c.err = fn()
+	returned = true
+
+	return c.val, c.err, c.dups > 0
+}